@@ -2,36 +2,59 @@ package main
 
 import (
 	"context"
+	"log"
 	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
 )
 
 const (
-	// gossipInterval is how often to retry unacked messages
+	// gossipInterval is how often neighbors with unacked messages are synced
 	gossipInterval = 5 * time.Second
+
+	// heartbeatInterval is how often idle neighbors are heartbeated by the
+	// failure detector
+	heartbeatInterval = 1 * time.Second
 )
 
-// startGossipLoop periodically retries unacked messages to handle network faults.
-func startGossipLoop(ctx context.Context, state *NodeState, broadcast func(string, int)) {
-	go func() {
-		ticker := time.NewTicker(gossipInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				// Handle context cancellation for graceful shutdown
-				return
-			case <-ticker.C:
-				// Get unacked messages without holding lock
-				unacked := state.GetUnackedCopy()
-
-				// Broadcast unacked messages
-				for message, neighbors := range unacked {
-					for _, neighbor := range neighbors {
-						go broadcast(neighbor, message)
-					}
-				}
-			}
+// startGossipLoop periodically reconciles with neighbors that have unacked
+// messages to handle network faults. Rather than resending every unacked
+// message, each sync compares Merkle trees and transfers only the messages
+// either side is missing. Neighbors suspected by the failure detector are
+// skipped until they recover.
+func startGossipLoop(ctx context.Context, n *maelstrom.Node, state *NodeState, store *MessageStore, detector *maelstrom.FailureDetector) {
+	// Syncs run through n.Go so that they are scheduled deterministically
+	// when the node is simulated
+	maelstrom.Every(ctx, n.Clock(), gossipInterval, func() {
+		for _, neighbor := range detector.Available(state.GetNeighborsCopy()) {
+			neighbor := neighbor
+			n.Go(func() { syncNeighbor(ctx, state, store, neighbor) })
+		}
+	})
+
+	// Catch a neighbor up as soon as it becomes reachable again
+	detector.OnChange(func(peer string, suspected bool) {
+		if !suspected {
+			n.Go(func() { syncNeighbor(ctx, state, store, peer) })
 		}
-	}()
+	})
+}
+
+// syncNeighbor reconciles with a neighbor that has unacked messages. A
+// successful sync means the neighbor holds every message we had when it
+// started, so those messages are marked acked.
+func syncNeighbor(ctx context.Context, state *NodeState, store *MessageStore, neighbor string) {
+	unacked := state.GetUnackedFor(neighbor)
+	if len(unacked) == 0 {
+		return
+	}
+
+	if err := store.ae.Sync(ctx, neighbor); err != nil {
+		// Log sync errors but don't fail - the next round will retry
+		log.Printf("sync with %s failed: %v", neighbor, err)
+		return
+	}
+	for _, message := range unacked {
+		state.MarkAcked(message, neighbor)
+	}
 }
@@ -3,12 +3,20 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"os"
 
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/topology"
 )
 
+// topologyEnv selects a computed overlay instead of the topology supplied by
+// Maelstrom, e.g. "tree:4" or "star:2". See topology.Plan for all options.
+// When unset, the supplied topology is used exactly as sent.
+const topologyEnv = "BROADCAST_TOPOLOGY"
+
 // handleTopology processes topology messages and updates neighbors.
-func handleTopology(n *maelstrom.Node, state *NodeState) func(maelstrom.Message) error {
+func handleTopology(n *maelstrom.Node, state *NodeState, detector *maelstrom.FailureDetector) func(maelstrom.Message) error {
 	return func(msg maelstrom.Message) error {
 		var body struct {
 			Topology map[string][]string `json:"topology"`
@@ -17,13 +25,27 @@ func handleTopology(n *maelstrom.Node, state *NodeState) func(maelstrom.Message)
 			return fmt.Errorf("failed to unmarshal topology: %w", err)
 		}
 
+		// Without an overlay, use Maelstrom's topology exactly as sent
 		neighbors, ok := body.Topology[n.ID()]
 		if !ok {
 			return fmt.Errorf("node ID %q not found in topology", n.ID())
 		}
 
+		// Every node computes the same overlay from the shared node list
+		if spec := os.Getenv(topologyEnv); spec != "" && spec != "maelstrom" {
+			g, err := topology.Plan(spec, n.NodeIDs(), topology.FromMaelstrom(body.Topology))
+			if err != nil {
+				return err
+			}
+			neighbors = g.Neighbors(n.ID())
+			log.Printf("Using topology %q: diameter=%d, max fan-out=%d", spec, g.Diameter(), g.MaxFanOut())
+		}
+
 		state.SetNeighbors(neighbors)
 
+		// Only monitor neighbors to keep heartbeat traffic proportional to fan-out
+		detector.SetPeers(neighbors)
+
 		return n.Reply(msg, map[string]any{
 			"type": "topology_ok",
 		})
@@ -31,7 +53,7 @@ func handleTopology(n *maelstrom.Node, state *NodeState) func(maelstrom.Message)
 }
 
 // handleBroadcast processes broadcast messages and propagates them to neighbors.
-func handleBroadcast(n *maelstrom.Node, state *NodeState, broadcast func(string, int)) func(maelstrom.Message) error {
+func handleBroadcast(n *maelstrom.Node, state *NodeState, store *MessageStore, broadcast func(string, int)) func(maelstrom.Message) error {
 	return func(msg maelstrom.Message) error {
 		var body struct {
 			Message int `json:"message"`
@@ -40,18 +62,8 @@ func handleBroadcast(n *maelstrom.Node, state *NodeState, broadcast func(string,
 			return fmt.Errorf("failed to unmarshal broadcast: %w", err)
 		}
 
-		isNew := state.AddMessage(body.Message)
-		neighbors := state.GetNeighborsCopy()
-
-		// Track all neighbors as unacked for this message
-		state.TrackUnacked(body.Message, neighbors)
-
-		// Only broadcast if this is a new message
-		if isNew {
-			for _, neighbor := range neighbors {
-				go broadcast(neighbor, body.Message)
-			}
-		}
+		// The sender already has the message, so don't echo it back
+		propagate(state, broadcast, body.Message, store.Add(body.Message), msg.Src)
 
 		return n.Reply(msg, map[string]any{
 			"type": "broadcast_ok",
@@ -59,6 +71,27 @@ func handleBroadcast(n *maelstrom.Node, state *NodeState, broadcast func(string,
 	}
 }
 
+// propagate tracks a message as unacked by every neighbor except src and,
+// if it is new, sends it to them.
+func propagate(state *NodeState, broadcast func(string, int), message int, isNew bool, src string) {
+	var neighbors []string
+	for _, neighbor := range state.GetNeighborsCopy() {
+		if neighbor != src {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+
+	// Track all neighbors as unacked for this message
+	state.TrackUnacked(message, neighbors)
+
+	// Only broadcast if this is a new message
+	if isNew {
+		for _, neighbor := range neighbors {
+			broadcast(neighbor, message)
+		}
+	}
+}
+
 // handleRead returns all messages seen by this node.
 func handleRead(n *maelstrom.Node, state *NodeState) func(maelstrom.Message) error {
 	return func(msg maelstrom.Message) error {
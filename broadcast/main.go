@@ -28,12 +28,24 @@ func main() {
 	// Create broadcast function
 	broadcast := createBroadcastFunc(n, state)
 
+	// Expose seen messages to anti-entropy. Messages learned through a sync
+	// are passed on like any other new message, except back to the peer.
+	store := NewMessageStore(n, state)
+	store.onNew = func(message int, peer string) {
+		propagate(state, broadcast, message, true, peer)
+	}
+
+	// Track which neighbors are reachable so retries can route around partitions
+	detector := maelstrom.NewFailureDetector(n)
+	detector.HeartbeatInterval = heartbeatInterval
+	detector.Start(ctx)
+
 	// Start periodic gossip loop for retry logic
-	startGossipLoop(ctx, state, broadcast)
+	startGossipLoop(ctx, n, state, store, detector)
 
 	// Register message handlers
-	n.Handle("topology", handleTopology(n, state))
-	n.Handle("broadcast", handleBroadcast(n, state, broadcast))
+	n.Handle("topology", handleTopology(n, state, detector))
+	n.Handle("broadcast", handleBroadcast(n, state, store, broadcast))
 	n.Handle("read", handleRead(n, state))
 
 	// Run the node
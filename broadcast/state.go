@@ -1,6 +1,9 @@
 package main
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 // NodeState holds the node's state including messages and neighbor acknowledgments
 type NodeState struct {
@@ -44,7 +47,15 @@ func (s *NodeState) AddMessage(message int) (isNew bool) {
 	return !seen
 }
 
-// GetMessages returns a copy of all messages (thread-safe)
+// HasMessage reports whether a message has been seen
+func (s *NodeState) HasMessage(message int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, seen := s.messages[message]
+	return seen
+}
+
+// GetMessages returns a sorted copy of all messages (thread-safe)
 func (s *NodeState) GetMessages() []int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -52,6 +63,7 @@ func (s *NodeState) GetMessages() []int {
 	for msg := range s.messages {
 		messages = append(messages, msg)
 	}
+	sort.Ints(messages)
 	return messages
 }
 
@@ -75,18 +87,14 @@ func (s *NodeState) MarkAcked(message int, neighbor string) {
 	}
 }
 
-// GetUnackedCopy returns a copy of unacked messages (thread-safe)
-func (s *NodeState) GetUnackedCopy() map[int][]string {
+// GetUnackedFor returns the messages a neighbor has not acknowledged (thread-safe)
+func (s *NodeState) GetUnackedFor(neighbor string) []int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	unacked := make(map[int][]string)
+	var unacked []int
 	for message, neighbors := range s.messageToUnackedNeighbors {
-		if len(neighbors) > 0 {
-			neighborList := make([]string, 0, len(neighbors))
-			for neighbor := range neighbors {
-				neighborList = append(neighborList, neighbor)
-			}
-			unacked[message] = neighborList
+		if _, ok := neighbors[neighbor]; ok {
+			unacked = append(unacked, message)
 		}
 	}
 	return unacked
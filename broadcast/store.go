@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/antientropy"
+)
+
+// messageVersion is the version of every message in the anti-entropy tree.
+// Messages never change once seen, so only presence matters.
+const messageVersion = "1"
+
+// MessageStore exposes the seen messages to anti-entropy so neighbors can
+// reconcile by exchanging only the messages the other side is missing.
+type MessageStore struct {
+	state *NodeState
+	ae    *antientropy.AntiEntropy
+
+	// onNew is called for each message first learned through a sync with
+	// peer
+	onNew func(message int, peer string)
+}
+
+// NewMessageStore creates a MessageStore and registers its anti-entropy
+// handlers on n.
+func NewMessageStore(n *maelstrom.Node, state *NodeState) *MessageStore {
+	s := &MessageStore{state: state}
+	s.ae = antientropy.New(n, s)
+	return s
+}
+
+// Add records a message and returns whether it was new
+func (s *MessageStore) Add(message int) (isNew bool) {
+	if isNew = s.state.AddMessage(message); isNew {
+		s.ae.Update(strconv.Itoa(message), messageVersion)
+	}
+	return isNew
+}
+
+// Get returns the requested messages (antientropy.Store)
+func (s *MessageStore) Get(keys []string) map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if message, err := strconv.Atoi(key); err == nil && s.state.HasMessage(message) {
+			values[key] = json.RawMessage(key)
+		}
+	}
+	return values
+}
+
+// Merge adds messages received from a neighbor (antientropy.Store). Keys are
+// merged in order so that new messages are propagated in a repeatable order.
+func (s *MessageStore) Merge(peer string, values map[string]json.RawMessage) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var message int
+		if err := json.Unmarshal(values[key], &message); err != nil {
+			return err
+		}
+		if s.Add(message) && s.onNew != nil {
+			s.onNew(message, peer)
+		}
+	}
+	return nil
+}
@@ -74,40 +74,36 @@ func (s *CounterServer) handleAdd(msg maelstrom.Message) error {
 
 		// Another go routine handler already updated the value,
 		// or transient network error, retry
-		time.Sleep(100 * time.Millisecond)
+		s.node.Clock().Sleep(100 * time.Millisecond)
 		continue
 	}
 
 	return s.node.Reply(msg, map[string]any{"type": "add_ok"})
 }
 
-// Eventually consistent read
+// Eventually consistent read. Each node's counter is read in turn with
+// SyncRPC, which a simulation can schedule, rather than by goroutines joined
+// over a channel.
 func (s *CounterServer) handleRead(msg maelstrom.Message) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	nodeIDs := s.node.NodeIDs()
-	results := make(chan int, len(nodeIDs))
-
-	// Fire off all reads concurrently
-	for _, id := range nodeIDs {
-		go func(nodeID string) {
-			for {
-				val, err := s.kv.ReadInt(ctx, s.keyFor(nodeID))
-				if err != nil {
-					// retry read
-					time.Sleep(100 * time.Millisecond)
-					continue
-				}
-				results <- val
-				break
-			}
-		}(id)
-	}
-
 	total := 0
-	for i := 0; i < len(nodeIDs); i++ {
-		total += <-results // Wait for all reads to complete
+	for _, nodeID := range s.node.NodeIDs() {
+		for {
+			val, err := s.kv.ReadInt(ctx, s.keyFor(nodeID))
+			if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+				break // nothing added through that node yet
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			} else if err != nil {
+				// retry read
+				s.node.Clock().Sleep(100 * time.Millisecond)
+				continue
+			}
+			total += val
+			break
+		}
 	}
 
 	return s.node.Reply(msg, map[string]any{
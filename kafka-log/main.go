@@ -5,24 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/lock"
 )
 
 type LogServer struct {
 	n  *maelstrom.Node
 	kv *maelstrom.KV
 
-	mu sync.Mutex
+	mu     sync.Mutex
+	topics map[string]*lock.Mutex
 }
 
 func main() {
 	n := maelstrom.NewNode()
 	s := &LogServer{
-		n:  n,
-		kv: maelstrom.NewLinKV(n),
+		n:      n,
+		kv:     maelstrom.NewLinKV(n),
+		topics: make(map[string]*lock.Mutex),
 	}
 
 	n.Handle("send", s.handleSend)
@@ -44,41 +48,111 @@ func (s *LogServer) handleSend(msg maelstrom.Message) error {
 		return err
 	}
 
-	ctx := context.Background()
-	offsetKey := body.Key + "_offset_counter"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// Read current offset
-	currOffset, err := s.kv.ReadInt(ctx, offsetKey)
+	// Serialize appends to the topic across nodes with the distributed lock.
+	// Handlers on this node share it, as locking is reentrant, but append
+	// claims each offset with a create-only CAS so they can't collide. No
+	// local mutex is held across the RPCs, which keeps simulations running.
+	l := s.topic(body.Key)
+	token, err := l.Lock(ctx)
 	if err != nil {
-		if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
-			currOffset = 0
-		} else {
-			return err
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := l.Unlock(ctx); err != nil {
+			log.Printf("unlock %s: %s", body.Key, err)
 		}
+	}()
+
+	offset, err := s.append(ctx, body.Key, body.Msg, token)
+	if err != nil {
+		return err
 	}
+	return s.n.Reply(msg, map[string]any{"type": "send_ok", "offset": offset})
+}
 
-	// Retry loop to get an offset (ticket system)
+// append writes val at the next offset of the topic. Must hold the topic
+// lock under the fencing token.
+func (s *LogServer) append(ctx context.Context, key string, val, token int) (int, error) {
+	if err := s.fence(ctx, key, token); err != nil {
+		return 0, err
+	}
+	offsetKey := key + "_offset_counter"
+
+	// Read next offset
+	next, err := s.kv.ReadInt(ctx, offsetKey)
+	if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+		next = 0
+	} else if err != nil {
+		return 0, err
+	}
+	offset := next
+
+	// Write message to the offset, only if it is free. A previous holder may
+	// have crashed after writing a message but before advancing the counter;
+	// that message is kept and we move on to the next offset.
 	for {
-		// CAS to exactly curr + 1
-		err = s.kv.CompareAndSwap(ctx, offsetKey, currOffset, currOffset+1, true)
+		msgKey := fmt.Sprintf("%s_msg_%d", key, offset)
+		err := s.kv.CompareAndSwap(ctx, msgKey, nil, val, true)
 		if err == nil {
-			// Success: We got the offset
 			break
-		} else if maelstrom.ErrorCode(err) == maelstrom.PreconditionFailed {
-			// Another node got curr+1. We loop to try for curr+2.
-			currOffset++
-			continue
-		} else {
+		} else if maelstrom.ErrorCode(err) != maelstrom.PreconditionFailed {
+			return 0, err
+		}
+		offset++
+	}
+
+	// Advance the counter past the message, unless a newer holder already
+	// has: the message is stored either way, and the counter never moves
+	// backwards.
+	err = s.kv.CompareAndSwap(ctx, offsetKey, next, offset+1, true)
+	if err != nil && maelstrom.ErrorCode(err) != maelstrom.PreconditionFailed {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// fence records token as the newest holder of the topic lock to append. Fails
+// if a holder with a newer token has appended since, i.e. our lock expired.
+func (s *LogServer) fence(ctx context.Context, key string, token int) error {
+	fenceKey := key + "_fence"
+	for {
+		cur, err := s.kv.ReadInt(ctx, fenceKey)
+		if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+			cur = 0
+		} else if err != nil {
+			return err
+		}
+
+		if cur > token {
+			return maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, fmt.Sprintf("lock on %s was taken over", key))
+		} else if cur == token {
+			return nil
+		}
+		err = s.kv.CompareAndSwap(ctx, fenceKey, cur, token, true)
+		if err == nil {
+			return nil
+		} else if maelstrom.ErrorCode(err) != maelstrom.PreconditionFailed {
 			return err
 		}
 	}
+}
 
-	// Write message to the offset
-	msgKey := fmt.Sprintf("%s_msg_%d", body.Key, currOffset)
-	if err := s.kv.Write(ctx, msgKey, body.Msg); err != nil {
-		return err
+// topic returns the lock for a topic, creating it if necessary.
+func (s *LogServer) topic(key string) *lock.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.topics[key]
+	if l == nil {
+		l = lock.NewMutex(s.n, s.kv, key+"_lock")
+		s.topics[key] = l
 	}
-	return s.n.Reply(msg, map[string]any{"type": "send_ok", "offset": currOffset})
+	return l
 }
 
 func (s *LogServer) handlePoll(msg maelstrom.Message) error {
@@ -94,38 +168,28 @@ func (s *LogServer) handlePoll(msg maelstrom.Message) error {
 
 	res := make(map[string][][2]int)
 
-	// Poll each topic concurrently.
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	// Poll each topic in turn, in a fixed order so simulations replay;
+	// waiting on goroutines would stall a simulation.
 	const maxMsgs = 20
-
-	for topic, start := range body.Offsets {
-		wg.Add(1)
-		go func(t string, sOff int) {
-			// Get messages from the topic starting at the start offset
-			defer wg.Done()
-			var msgs [][2]int
-
-			// Stop at the first error since there's sequence guarantee
-			for i := sOff; ; i++ {
-				val, err := s.kv.ReadInt(ctx, fmt.Sprintf("%s_msg_%d", t, i))
-				if err != nil {
-					break
-				}
-				msgs = append(msgs, [2]int{i, val})
-				if len(msgs) >= maxMsgs {
-					break
-				}
+	for _, topic := range sortedKeys(body.Offsets) {
+		var msgs [][2]int
+
+		// Stop at the first error since there's sequence guarantee
+		for i := body.Offsets[topic]; ; i++ {
+			val, err := s.kv.ReadInt(ctx, fmt.Sprintf("%s_msg_%d", topic, i))
+			if err != nil {
+				break
 			}
-
-			if len(msgs) > 0 {
-				mu.Lock()
-				res[t] = msgs
-				mu.Unlock()
+			msgs = append(msgs, [2]int{i, val})
+			if len(msgs) >= maxMsgs {
+				break
 			}
-		}(topic, start)
+		}
+
+		if len(msgs) > 0 {
+			res[topic] = msgs
+		}
 	}
-	wg.Wait()
 
 	return s.n.Reply(msg, map[string]any{"type": "poll_ok", "msgs": res})
 }
@@ -143,9 +207,9 @@ func (s *LogServer) handleCommitOffsets(msg maelstrom.Message) error {
 	}
 
 	ctx := context.Background()
-	for topic, offset := range body.Offsets {
+	for _, topic := range sortedKeys(body.Offsets) {
 		// Simply overwrite the latest committed offset for this topic
-		s.kv.Write(ctx, s.commitKey(topic), offset)
+		s.kv.Write(ctx, s.commitKey(topic), body.Offsets[topic])
 	}
 	return s.nodeReply(msg, map[string]any{"type": "commit_offsets_ok"})
 }
@@ -169,6 +233,16 @@ func (s *LogServer) handleListCommittedOffsets(msg maelstrom.Message) error {
 	return s.nodeReply(msg, map[string]any{"type": "list_committed_offsets_ok", "offsets": res})
 }
 
+// sortedKeys returns the topics of offsets in order.
+func sortedKeys(offsets map[string]int) []string {
+	keys := make([]string, 0, len(offsets))
+	for k := range offsets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (s *LogServer) nodeReply(msg maelstrom.Message, body any) error {
 	return s.n.Reply(msg, body)
 }
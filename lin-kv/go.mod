@@ -0,0 +1,7 @@
+module maelstrom-lin-kv
+
+go 1.25.5
+
+require github.com/jepsen-io/maelstrom/demo/go v0.0.0-20251128144731-cb7f07239012
+
+replace github.com/jepsen-io/maelstrom/demo/go => ../maelstrom/demo/go
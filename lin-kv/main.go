@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/raft"
+)
+
+// Time a client request may wait for consensus before timing out.
+const requestTimeout = 2 * time.Second
+
+type KVServer struct {
+	n     *maelstrom.Node
+	raft  *raft.Raft
+	store *Store
+}
+
+func main() {
+	n := maelstrom.NewNode()
+	store := NewStore()
+	s := &KVServer{
+		n:     n,
+		raft:  raft.New(n, store),
+		store: store,
+	}
+
+	n.Handle("read", s.handleRead)
+	n.Handle("write", s.handleWrite)
+	n.Handle("cas", s.handleCAS)
+
+	// Run elections & heartbeats for the life of the process, once init has
+	// set the node's ID & peers that the ticks read.
+	n.Handle("init", func(msg maelstrom.Message) error {
+		s.raft.Start(context.Background())
+		return nil
+	})
+
+	if err := n.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (s *KVServer) handleRead(msg maelstrom.Message) error {
+	if leader, ok := s.forwardTo(msg); ok {
+		return s.n.Forward(msg, leader)
+	}
+
+	var body struct {
+		Key any `json:"key"`
+	}
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	// Reads don't go through the log; the leader confirms it is still
+	// leader with a quorum before reading its own state.
+	v, err := s.raft.Read(ctx, func() (any, error) {
+		return s.store.Read(body.Key)
+	})
+	if err != nil {
+		return rpcError(err)
+	}
+	return s.n.Reply(msg, map[string]any{"type": "read_ok", "value": v})
+}
+
+func (s *KVServer) handleWrite(msg maelstrom.Message) error {
+	if leader, ok := s.forwardTo(msg); ok {
+		return s.n.Forward(msg, leader)
+	}
+
+	var body struct {
+		Key   any `json:"key"`
+		Value any `json:"value"`
+	}
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	op := Op{F: "write", Key: body.Key, Value: body.Value}
+	if err := s.propose(op); err != nil {
+		return err
+	}
+	return s.n.Reply(msg, map[string]any{"type": "write_ok"})
+}
+
+func (s *KVServer) handleCAS(msg maelstrom.Message) error {
+	if leader, ok := s.forwardTo(msg); ok {
+		return s.n.Forward(msg, leader)
+	}
+
+	var body struct {
+		Key  any `json:"key"`
+		From any `json:"from"`
+		To   any `json:"to"`
+	}
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	op := Op{F: "cas", Key: body.Key, From: body.From, Value: body.To}
+	if err := s.propose(op); err != nil {
+		return err
+	}
+	return s.n.Reply(msg, map[string]any{"type": "cas_ok"})
+}
+
+// forwardTo returns the leader to forward a client request to. Requests
+// that were already forwarded by another node are handled locally, so a
+// stale view of the leader can't bounce a request around the cluster.
+func (s *KVServer) forwardTo(msg maelstrom.Message) (string, bool) {
+	leader := s.raft.Status().Leader
+	if leader == "" || leader == s.n.ID() {
+		return "", false
+	}
+	for _, id := range s.n.NodeIDs() {
+		if id == msg.Src {
+			return "", false
+		}
+	}
+	return leader, true
+}
+
+// propose commits op through the Raft log and waits for it to be applied
+func (s *KVServer) propose(op Op) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if _, err := s.raft.Propose(ctx, op); err != nil {
+		return rpcError(err)
+	}
+	return nil
+}
+
+// rpcError maps consensus errors onto Maelstrom error codes. Errors where the
+// operation definitely did not happen must not be reported as timeouts.
+func rpcError(err error) error {
+	var rpcErr *maelstrom.RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, raft.ErrNotLeader):
+		return maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, "not the leader")
+	case errors.Is(err, raft.ErrLeadershipLost):
+		return maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, "leadership lost")
+	case errors.Is(err, context.DeadlineExceeded):
+		return maelstrom.NewRPCError(maelstrom.Timeout, "timed out waiting for consensus")
+	default:
+		return err
+	}
+}
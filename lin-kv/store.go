@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Op is a single write or compare-and-set in the replicated log.
+type Op struct {
+	F     string `json:"f"`
+	Key   any    `json:"key"`
+	From  any    `json:"from,omitempty"`
+	Value any    `json:"value"`
+}
+
+// Store is the key/value state machine replicated by Raft.
+type Store struct {
+	mu   sync.Mutex
+	data map[string]any
+}
+
+func NewStore() *Store {
+	return &Store{data: make(map[string]any)}
+}
+
+// Apply implements raft.StateMachine
+func (s *Store) Apply(buf json.RawMessage) (any, error) {
+	var op Op
+	if err := json.Unmarshal(buf, &op); err != nil {
+		return nil, err
+	}
+	k, err := storeKey(op.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch op.F {
+	case "write":
+		s.data[k] = op.Value
+		return nil, nil
+
+	case "cas":
+		curr, ok := s.data[k]
+		if !ok {
+			return nil, maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+		} else if !reflect.DeepEqual(curr, op.From) {
+			return nil, maelstrom.NewRPCError(maelstrom.PreconditionFailed,
+				fmt.Sprintf("expected %v, but had %v", op.From, curr))
+		}
+		s.data[k] = op.Value
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.F)
+	}
+}
+
+// Read returns the current value of key
+func (s *Store) Read(key any) (any, error) {
+	k, err := storeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[k]
+	if !ok {
+		return nil, maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+	}
+	return v, nil
+}
+
+// storeKey converts a JSON key into a comparable map key
+func storeKey(key any) (string, error) {
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,321 @@
+// Package antientropy reconciles replicas by comparing Merkle trees instead
+// of shipping whole stores. Each replica keeps a Tree of its keys' versions.
+// To sync, a node walks down the tree with a peer, level by level, following
+// only the branches whose hashes differ, then exchanges the values of the
+// keys whose versions differ in the mismatched leaves. The first request
+// carries the sender's root hash, so replicas that are already in sync
+// exchange only that.
+package antientropy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Anti-entropy defaults.
+const (
+	DefaultInterval       = 1 * time.Second
+	DefaultRequestTimeout = 1 * time.Second
+)
+
+// Store is the application's replica. Values are opaque to anti-entropy and
+// conflicting versions are resolved by the store, e.g. with last-writer-wins.
+type Store interface {
+	// Get returns the encoded values of the given keys. Missing keys are
+	// omitted.
+	Get(keys []string) map[string]json.RawMessage
+
+	// Merge merges values received from peer. The store must call Update
+	// for every key whose version changes as a result.
+	Merge(peer string, values map[string]json.RawMessage) error
+}
+
+// AntiEntropy maintains the Merkle tree for a store and syncs it with peers.
+type AntiEntropy struct {
+	mu    sync.Mutex
+	node  *maelstrom.Node
+	store Store
+	tree  *Tree
+
+	// Interval between rounds started by Start.
+	Interval time.Duration
+
+	// Time to wait for each request to a peer.
+	RequestTimeout time.Duration
+
+	// Peers returns the peers to sync with on each round. Defaults to one
+	// random other node.
+	Peers func() []string
+}
+
+// New returns anti-entropy for store on node and registers its message
+// handlers. This must be called before Run().
+func New(node *maelstrom.Node, store Store) *AntiEntropy {
+	a := &AntiEntropy{
+		node:  node,
+		store: store,
+		tree:  NewTree(DefaultDepth),
+
+		Interval:       DefaultInterval,
+		RequestTimeout: DefaultRequestTimeout,
+	}
+	a.Peers = a.randomPeer
+
+	node.Handle("ae_hashes", a.handleHashes)
+	node.Handle("ae_diff", a.handleDiff)
+	node.Handle("ae_push", a.handlePush)
+	return a
+}
+
+// Update records the current version of key. The store must call this
+// whenever a key is written locally or merged from a peer.
+func (a *AntiEntropy) Update(key, version string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tree.Set(key, version)
+}
+
+// Delete removes key from the tree.
+func (a *AntiEntropy) Delete(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tree.Delete(key)
+}
+
+// Root returns the current root hash.
+func (a *AntiEntropy) Root() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.tree.Root()
+}
+
+// Start syncs with Peers() every Interval until ctx is canceled.
+func (a *AntiEntropy) Start(ctx context.Context) {
+	maelstrom.Every(ctx, a.node.Clock(), a.Interval, func() {
+		for _, peer := range a.Peers() {
+			peer := peer
+			a.node.Go(func() {
+				if err := a.Sync(ctx, peer); err != nil && ctx.Err() == nil {
+					log.Printf("anti-entropy with %s: %s", peer, err)
+				}
+			})
+		}
+	})
+}
+
+// Sync reconciles the local replica with peer in both directions.
+func (a *AntiEntropy) Sync(ctx context.Context, peer string) error {
+	ctx, cancel := context.WithTimeout(ctx, a.RequestTimeout*time.Duration(a.tree.Depth()+3))
+	defer cancel()
+
+	// Walk down the tree, level by level, following differing branches.
+	level, nodes := 0, []int{0}
+	for level < a.tree.Depth() {
+		req := hashesRequest{Type: "ae_hashes", Level: level, Nodes: nodes}
+		if level == 0 {
+			root := a.Root()
+			req.Root = &root
+		}
+
+		// The peer returns no hashes if our roots match.
+		var resp hashesResponse
+		if err := a.rpc(ctx, peer, req, &resp); err != nil {
+			return err
+		}
+
+		a.mu.Lock()
+		var next []int
+		for j, i := range nodes {
+			if j >= len(resp.Hashes) {
+				break
+			}
+			for k, h := range a.tree.Children(level, i) {
+				if k < len(resp.Hashes[j]) && resp.Hashes[j][k] != h {
+					next = append(next, i*Fanout+k)
+				}
+			}
+		}
+		a.mu.Unlock()
+
+		if len(next) == 0 {
+			return nil // in sync
+		}
+		level, nodes = level+1, next
+	}
+
+	// Exchange versions for the differing leaves. The peer returns its
+	// values that we're missing and the keys it wants from us.
+	a.mu.Lock()
+	versions := a.tree.Entries(nodes)
+	a.mu.Unlock()
+
+	var diff diffResponse
+	if err := a.rpc(ctx, peer, diffRequest{Type: "ae_diff", Leaves: nodes, Versions: versions}, &diff); err != nil {
+		return err
+	}
+	if len(diff.Values) > 0 {
+		if err := a.store.Merge(peer, diff.Values); err != nil {
+			return err
+		}
+	}
+
+	// Don't push back keys where we just adopted the peer's value.
+	var want []string
+	a.mu.Lock()
+	for _, k := range diff.Want {
+		if _, ok := diff.Values[k]; ok {
+			if v, _ := a.tree.Version(k); v != versions[k] {
+				continue
+			}
+		}
+		want = append(want, k)
+	}
+	a.mu.Unlock()
+
+	if len(want) > 0 {
+		push := pushRequest{Type: "ae_push", Values: a.store.Get(want)}
+		if err := a.rpc(ctx, peer, push, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AntiEntropy) rpc(ctx context.Context, peer string, req, resp any) error {
+	msg, err := a.node.SyncRPC(ctx, peer, req)
+	if err != nil {
+		return err
+	} else if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Body, resp)
+}
+
+// randomPeer returns a single random other node.
+func (a *AntiEntropy) randomPeer() []string {
+	var peers []string
+	for _, id := range a.node.NodeIDs() {
+		if id != a.node.ID() {
+			peers = append(peers, id)
+		}
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+	return []string{peers[a.node.Rand().Intn(len(peers))]}
+}
+
+type hashesRequest struct {
+	Type  string `json:"type"`
+	Level int    `json:"level"`
+	Nodes []int  `json:"nodes"`
+
+	// Root is the sender's root hash, sent with the first request.
+	Root *uint64 `json:"root,omitempty"`
+}
+
+type hashesResponse struct {
+	Type   string     `json:"type"`
+	Hashes [][]uint64 `json:"hashes"`
+}
+
+// handleHashes returns the child hashes of the requested nodes, or none if
+// the sender's root hash matches ours.
+func (a *AntiEntropy) handleHashes(msg maelstrom.Message) error {
+	var req hashesRequest
+	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		return err
+	} else if req.Level < 0 || req.Level >= a.tree.Depth() {
+		return maelstrom.NewRPCError(maelstrom.MalformedRequest, "invalid tree level")
+	}
+
+	resp := hashesResponse{Type: "ae_hashes_ok", Hashes: make([][]uint64, 0, len(req.Nodes))}
+	a.mu.Lock()
+	if req.Root != nil && *req.Root == a.tree.Root() {
+		a.mu.Unlock()
+		return a.node.Reply(msg, resp)
+	}
+	for _, i := range req.Nodes {
+		if i < 0 || i >= len(a.tree.levels[req.Level]) {
+			a.mu.Unlock()
+			return maelstrom.NewRPCError(maelstrom.MalformedRequest, "invalid tree node")
+		}
+		resp.Hashes = append(resp.Hashes, a.tree.Children(req.Level, i))
+	}
+	a.mu.Unlock()
+
+	return a.node.Reply(msg, resp)
+}
+
+type diffRequest struct {
+	Type     string            `json:"type"`
+	Leaves   []int             `json:"leaves"`
+	Versions map[string]string `json:"versions"`
+}
+
+type diffResponse struct {
+	Type   string                     `json:"type"`
+	Values map[string]json.RawMessage `json:"values,omitempty"`
+	Want   []string                   `json:"want,omitempty"`
+}
+
+// handleDiff compares the sender's versions for a set of leaves against our
+// own, returning our differing values and the keys we want in return.
+func (a *AntiEntropy) handleDiff(msg maelstrom.Message) error {
+	var req diffRequest
+	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		return err
+	}
+	for _, leaf := range req.Leaves {
+		if leaf < 0 || leaf >= len(a.tree.leaves) {
+			return maelstrom.NewRPCError(maelstrom.MalformedRequest, "invalid leaf")
+		}
+	}
+
+	a.mu.Lock()
+	ours := a.tree.Entries(req.Leaves)
+	a.mu.Unlock()
+
+	var send []string
+	for k, v := range ours {
+		if theirs, ok := req.Versions[k]; !ok || theirs != v {
+			send = append(send, k)
+		}
+	}
+
+	resp := diffResponse{Type: "ae_diff_ok"}
+	for k, v := range req.Versions {
+		if mine, ok := ours[k]; !ok || mine != v {
+			resp.Want = append(resp.Want, k)
+		}
+	}
+	sort.Strings(resp.Want) // map order would make replies nondeterministic
+	sort.Strings(send)
+	if len(send) > 0 {
+		resp.Values = a.store.Get(send)
+	}
+	return a.node.Reply(msg, resp)
+}
+
+type pushRequest struct {
+	Type   string                     `json:"type"`
+	Values map[string]json.RawMessage `json:"values"`
+}
+
+// handlePush merges values pushed by a peer.
+func (a *AntiEntropy) handlePush(msg maelstrom.Message) error {
+	var req pushRequest
+	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		return err
+	}
+	if err := a.store.Merge(msg.Src, req.Values); err != nil {
+		return err
+	}
+	return a.node.Reply(msg, maelstrom.MessageBody{Type: "ae_push_ok"})
+}
@@ -0,0 +1,192 @@
+package antientropy_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/antientropy"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// Ensure trees with the same contents have the same hashes regardless of
+// insertion order, and that updates & deletes are reflected in the root.
+func TestTree(t *testing.T) {
+	a, b := antientropy.NewTree(2), antientropy.NewTree(2)
+	for i := 0; i < 100; i++ {
+		a.Set(fmt.Sprint(i), "v1")
+		b.Set(fmt.Sprint(99-i), "v1")
+	}
+	if a.Root() != b.Root() {
+		t.Fatal("expected equal roots")
+	}
+
+	a.Set("5", "v2")
+	if a.Root() == b.Root() {
+		t.Fatal("expected roots to differ after update")
+	} else if v, _ := a.Version("5"); v != "v2" {
+		t.Fatalf("version=%q, want v2", v)
+	}
+
+	a.Set("5", "v1")
+	a.Set("extra", "v1")
+	a.Delete("extra")
+	if a.Root() != b.Root() || a.Len() != 100 {
+		t.Fatal("expected roots to match after revert")
+	}
+}
+
+// Ensure sync transfers only differing keys in both directions and that
+// replicas in sync exchange only the root level.
+func TestAntiEntropy_Sync(t *testing.T) {
+	t.Run("OK", func(t *testing.T) { testAntiEntropySync(t, false) })
+
+	// HLC timestamps are stamped on every request, which must not disturb
+	// the uint64 hashes in the bodies.
+	t.Run("HLC", func(t *testing.T) { testAntiEntropySync(t, true) })
+}
+
+func testAntiEntropySync(t *testing.T, hlc bool) {
+	nw := simnet.New()
+	defer nw.Close()
+
+	stores := make(map[string]*mapStore)
+	for _, id := range []string{"n1", "n2"} {
+		n := nw.NewNode(id)
+		if hlc {
+			n.EnableHLC()
+		}
+		s := &mapStore{data: make(map[string]string)}
+		s.ae = antientropy.New(n, s)
+		stores[id] = s
+	}
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	transferred, hashes := 0, 0
+	nw.Observe(func(msg maelstrom.Message, dropped bool) {
+		var body struct {
+			Type   string                     `json:"type"`
+			Values map[string]json.RawMessage `json:"values"`
+			Hashes [][]uint64                 `json:"hashes"`
+		}
+		_ = json.Unmarshal(msg.Body, &body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		counts[body.Type]++
+		transferred += len(body.Values)
+		for _, h := range body.Hashes {
+			hashes += len(h)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		stores["n1"].write(fmt.Sprint(i), "a")
+		stores["n2"].write(fmt.Sprint(i), "a")
+	}
+	stores["n1"].write("only-n1", "x")
+	stores["n2"].write("only-n2", "y")
+	stores["n2"].write("7", "b")
+
+	if err := stores["n1"].ae.Sync(ctx, "n2"); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := stores["n1"].snapshot(), stores["n2"].snapshot(); !reflect.DeepEqual(a, b) {
+		t.Fatal("stores did not converge")
+	} else if a["7"] != "b" || a["only-n1"] != "x" || a["only-n2"] != "y" {
+		t.Fatalf("unexpected store: 7=%q only-n1=%q only-n2=%q", a["7"], a["only-n1"], a["only-n2"])
+	}
+
+	// Each side merges what it was missing from the other.
+	if got := stores["n1"].peers; !reflect.DeepEqual(got, []string{"n2"}) {
+		t.Fatalf("n1 merged from %v, want [n2]", got)
+	} else if got := stores["n2"].peers; !reflect.DeepEqual(got, []string{"n1"}) {
+		t.Fatalf("n2 merged from %v, want [n1]", got)
+	}
+
+	mu.Lock()
+	if transferred != 3 {
+		t.Fatalf("transferred %d values, want 3", transferred)
+	}
+	counts, hashes = make(map[string]int), 0
+	mu.Unlock()
+
+	// A second sync finds equal roots after a single round trip.
+	if err := stores["n2"].ae.Sync(ctx, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := map[string]int{"ae_hashes": 1, "ae_hashes_ok": 1}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("messages=%v, want %v", counts, want)
+	} else if hashes != 0 {
+		t.Fatalf("exchanged %d child hashes, want 0", hashes)
+	}
+}
+
+// mapStore is a string map where values are their own versions and higher
+// values win.
+type mapStore struct {
+	mu    sync.Mutex
+	data  map[string]string
+	ae    *antientropy.AntiEntropy
+	peers []string // merged from, in order
+}
+
+func (s *mapStore) write(k, v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[k] = v
+	s.ae.Update(k, v)
+}
+
+func (s *mapStore) snapshot() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]string)
+	for k, v := range s.data {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *mapStore) Get(keys []string) map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]json.RawMessage)
+	for _, k := range keys {
+		if v, ok := s.data[k]; ok {
+			m[k], _ = json.Marshal(v)
+		}
+	}
+	return m
+}
+
+func (s *mapStore) Merge(peer string, values map[string]json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers = append(s.peers, peer)
+	for k, raw := range values {
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v > s.data[k] {
+			s.data[k] = v
+			s.ae.Update(k, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package antientropy
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// Fanout is the number of children of each interior node of a Tree.
+const Fanout = 16
+
+// DefaultDepth is the default number of levels below the root. With the
+// default fan-out this gives 256 leaves.
+const DefaultDepth = 2
+
+// Tree is a fixed-shape Merkle tree over a set of versioned keys. Each key is
+// assigned to a leaf by its hash. A node's hash is the sum of the hashes of
+// the (key, version) pairs beneath it, so updates only touch the path from a
+// leaf to the root and two replicas holding the same versions of the same
+// keys always have the same hashes.
+//
+// Tree is not safe for concurrent use.
+type Tree struct {
+	depth  int
+	levels [][]uint64 // levels[0] is the root, levels[depth] the leaves
+	items  map[string]uint64
+	leaves []map[string]string // keys & versions per leaf
+}
+
+// NewTree returns an empty tree with the given depth.
+func NewTree(depth int) *Tree {
+	if depth < 1 {
+		depth = 1
+	}
+	t := &Tree{
+		depth: depth,
+		items: make(map[string]uint64),
+	}
+	for l, width := 0, 1; l <= depth; l, width = l+1, width*Fanout {
+		t.levels = append(t.levels, make([]uint64, width))
+	}
+	t.leaves = make([]map[string]string, len(t.levels[depth]))
+	return t
+}
+
+// Depth returns the number of levels below the root.
+func (t *Tree) Depth() int { return t.depth }
+
+// Len returns the number of keys in the tree.
+func (t *Tree) Len() int { return len(t.items) }
+
+// Root returns the hash of the whole tree.
+func (t *Tree) Root() uint64 { return t.levels[0][0] }
+
+// Hash returns the hash of the node at index i of level l.
+func (t *Tree) Hash(l, i int) uint64 { return t.levels[l][i] }
+
+// Children returns the hashes of the children of the node at index i of
+// level l, which must be above the leaves.
+func (t *Tree) Children(l, i int) []uint64 {
+	return append([]uint64{}, t.levels[l+1][i*Fanout:(i+1)*Fanout]...)
+}
+
+// Set records version as the current version of key.
+func (t *Tree) Set(key, version string) {
+	t.Delete(key)
+
+	h := itemHash(key, version)
+	t.items[key] = h
+	leaf := t.Leaf(key)
+	if t.leaves[leaf] == nil {
+		t.leaves[leaf] = make(map[string]string)
+	}
+	t.leaves[leaf][key] = version
+	t.add(leaf, h)
+}
+
+// Delete removes key from the tree.
+func (t *Tree) Delete(key string) {
+	h, ok := t.items[key]
+	if !ok {
+		return
+	}
+	delete(t.items, key)
+	leaf := t.Leaf(key)
+	delete(t.leaves[leaf], key)
+	t.add(leaf, -h)
+}
+
+// Version returns the version of key.
+func (t *Tree) Version(key string) (string, bool) {
+	v, ok := t.leaves[t.Leaf(key)][key]
+	return v, ok
+}
+
+// Leaf returns the index of the leaf holding key.
+func (t *Tree) Leaf(key string) int {
+	return int(hashString(key) % uint64(len(t.leaves)))
+}
+
+// Entries returns the keys & versions held by the given leaves.
+func (t *Tree) Entries(leaves []int) map[string]string {
+	m := make(map[string]string)
+	for _, leaf := range leaves {
+		for k, v := range t.leaves[leaf] {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Keys returns every key in sorted order.
+func (t *Tree) Keys() []string {
+	keys := make([]string, 0, len(t.items))
+	for k := range t.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// add adds h to every node on the path from leaf to the root.
+func (t *Tree) add(leaf int, h uint64) {
+	for l, i := t.depth, leaf; l >= 0; l, i = l-1, i/Fanout {
+		t.levels[l][i] += h
+	}
+}
+
+func itemHash(key, version string) uint64 {
+	return hashString(key + "\x00" + version)
+}
+
+// hashString returns a well-mixed 64-bit hash of s.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
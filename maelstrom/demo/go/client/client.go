@@ -0,0 +1,312 @@
+// Package client drives a Maelstrom node from Go, as a Maelstrom client
+// process does, so tests can make typed requests such as Echo or Txn rather
+// than writing raw JSON lines to the node's STDIN.
+//
+// A Client sends its requests over a Conn: a Stream over a node's STDIN &
+// STDOUT, a node binary started as a subprocess, an in-process node attached
+// through pipes, or a client of a simulated network.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// ID is the client ID used by the clients this package connects.
+const ID = "c1"
+
+// DefaultTimeout is the default time to wait for each reply.
+const DefaultTimeout = 5 * time.Second
+
+// Conn sends requests to nodes and waits for their replies. *Stream,
+// *simnet.Client & *sim.Client implement Conn.
+type Conn interface {
+	RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error)
+}
+
+// Client performs typed requests against a single node. RPC errors returned
+// by the node are returned as *maelstrom.RPCError.
+type Client struct {
+	conn  Conn
+	node  string
+	close func() error
+
+	// Time to wait for each reply, in addition to the context's deadline.
+	// Zero waits until the context is done.
+	Timeout time.Duration
+}
+
+// New returns a client sending requests to node over conn.
+func New(conn Conn, node string) *Client {
+	return &Client{conn: conn, node: node, close: func() error { return nil }}
+}
+
+// NewPipe returns a client for a node that reads requests from stdin and
+// writes replies to stdout.
+func NewPipe(node string, stdin io.WriteCloser, stdout io.Reader) *Client {
+	s := NewStream(ID, stdin, stdout)
+	return &Client{conn: s, node: node, close: s.Close, Timeout: DefaultTimeout}
+}
+
+// Start starts cmd, a node binary, and returns a client for it. The caller
+// may set cmd's environment & Stderr but not its Stdin or Stdout.
+func Start(node string, cmd *exec.Cmd) (*Client, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := NewPipe(node, stdin, stdout)
+	c.close = func() error {
+		err := c.conn.(*Stream).Close()
+		if e := cmd.Wait(); e != nil {
+			return e
+		}
+		return err
+	}
+	return c, nil
+}
+
+// Attach runs n in the background, connected to the returned client through
+// in-memory pipes. Handlers must be registered before calling Attach. Close
+// stops the node and returns the error from its Run().
+func Attach(n *maelstrom.Node, node string) *Client {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	n.Stdin, n.Stdout = inr, outw
+
+	done := make(chan error, 1)
+	go func() {
+		err := n.Run()
+		outw.Close()
+		done <- err
+	}()
+
+	c := NewPipe(node, inw, outr)
+	c.close = func() error {
+		c.conn.(*Stream).Close()
+		return <-done
+	}
+	return c
+}
+
+// Node returns the ID of the node the client sends requests to.
+func (c *Client) Node() string { return c.node }
+
+// Close releases the client's connection, stopping the node if the client
+// started it.
+func (c *Client) Close() error { return c.close() }
+
+// RPC sends body to the node and decodes the reply body into v, if v is not
+// nil. Returns the reply.
+func (c *Client) RPC(ctx context.Context, body any, v any) (maelstrom.Message, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	msg, err := c.conn.RPC(ctx, c.node, body)
+	if err != nil {
+		return msg, err
+	}
+	if v != nil {
+		if err := json.Unmarshal(msg.Body, v); err != nil {
+			return msg, fmt.Errorf("decode %s: %w", msg.Type(), err)
+		}
+	}
+	return msg, nil
+}
+
+// call sends a request of type typ and checks the reply is of type typ_ok.
+func (c *Client) call(ctx context.Context, typ string, body map[string]any, v any) error {
+	if body == nil {
+		body = make(map[string]any)
+	}
+	body["type"] = typ
+
+	msg, err := c.RPC(ctx, body, v)
+	if err != nil {
+		return err
+	} else if got, want := msg.Type(), typ+"_ok"; got != want {
+		return fmt.Errorf("%s: unexpected reply type %q, want %q", typ, got, want)
+	}
+	return nil
+}
+
+// Init initializes the node with the IDs of every node in the cluster.
+func (c *Client) Init(ctx context.Context, nodeIDs []string) error {
+	return c.call(ctx, "init", map[string]any{"node_id": c.node, "node_ids": nodeIDs}, nil)
+}
+
+// Echo sends s to the node and returns the value it echoed.
+func (c *Client) Echo(ctx context.Context, s string) (string, error) {
+	var body struct {
+		Echo string `json:"echo"`
+	}
+	err := c.call(ctx, "echo", map[string]any{"echo": s}, &body)
+	return body.Echo, err
+}
+
+// Generate asks the node for a unique ID, which may be any JSON value.
+func (c *Client) Generate(ctx context.Context) (any, error) {
+	var body struct {
+		ID any `json:"id"`
+	}
+	err := c.call(ctx, "generate", nil, &body)
+	return body.ID, err
+}
+
+// Topology sends the node the neighbors of every node.
+func (c *Client) Topology(ctx context.Context, topology map[string][]string) error {
+	return c.call(ctx, "topology", map[string]any{"topology": topology}, nil)
+}
+
+// Broadcast broadcasts message through the node.
+func (c *Client) Broadcast(ctx context.Context, message int) error {
+	return c.call(ctx, "broadcast", map[string]any{"message": message}, nil)
+}
+
+// Read returns the broadcast messages the node has seen.
+func (c *Client) Read(ctx context.Context) ([]int, error) {
+	var body struct {
+		Messages []int `json:"messages"`
+	}
+	err := c.call(ctx, "read", nil, &body)
+	return body.Messages, err
+}
+
+// Add adds delta to the node's grow-only counter.
+func (c *Client) Add(ctx context.Context, delta int) error {
+	return c.call(ctx, "add", map[string]any{"delta": delta}, nil)
+}
+
+// ReadCounter returns the value of the node's grow-only counter.
+func (c *Client) ReadCounter(ctx context.Context) (int, error) {
+	var body struct {
+		Value int `json:"value"`
+	}
+	err := c.call(ctx, "read", nil, &body)
+	return body.Value, err
+}
+
+// Send appends msg to the log for key and returns its offset.
+func (c *Client) Send(ctx context.Context, key string, msg int) (int, error) {
+	var body struct {
+		Offset int `json:"offset"`
+	}
+	err := c.call(ctx, "send", map[string]any{"key": key, "msg": msg}, &body)
+	return body.Offset, err
+}
+
+// Poll returns messages from the log of each key, starting at the given
+// offsets.
+func (c *Client) Poll(ctx context.Context, offsets map[string]int) (map[string][]LogEntry, error) {
+	var body struct {
+		Msgs map[string][]LogEntry `json:"msgs"`
+	}
+	err := c.call(ctx, "poll", map[string]any{"offsets": offsets}, &body)
+	return body.Msgs, err
+}
+
+// CommitOffsets commits the offsets each key has been processed up to.
+func (c *Client) CommitOffsets(ctx context.Context, offsets map[string]int) error {
+	return c.call(ctx, "commit_offsets", map[string]any{"offsets": offsets}, nil)
+}
+
+// ListCommittedOffsets returns the committed offsets of keys. Keys without a
+// committed offset are omitted.
+func (c *Client) ListCommittedOffsets(ctx context.Context, keys []string) (map[string]int, error) {
+	var body struct {
+		Offsets map[string]int `json:"offsets"`
+	}
+	err := c.call(ctx, "list_committed_offsets", map[string]any{"keys": keys}, &body)
+	return body.Offsets, err
+}
+
+// Txn executes a transaction and returns it with the values read filled in.
+func (c *Client) Txn(ctx context.Context, txn []MicroOp) ([]MicroOp, error) {
+	var body struct {
+		Txn []MicroOp `json:"txn"`
+	}
+	err := c.call(ctx, "txn", map[string]any{"txn": txn}, &body)
+	return body.Txn, err
+}
+
+// LogEntry is a message in a log, encoded as [offset, msg].
+type LogEntry struct {
+	Offset int
+	Msg    int
+}
+
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{e.Offset, e.Msg})
+}
+
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	var a []int
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	} else if len(a) != 2 {
+		return fmt.Errorf("invalid log entry: %s", data)
+	}
+	e.Offset, e.Msg = a[0], a[1]
+	return nil
+}
+
+// MicroOp is a read or write of a register in a transaction, encoded as
+// ["r", key, value] or ["w", key, value]. Value is nil for a read that has not
+// been performed or found no value.
+type MicroOp struct {
+	F     string
+	Key   int
+	Value *int
+}
+
+// R returns a read of key.
+func R(key int) MicroOp { return MicroOp{F: "r", Key: key} }
+
+// W returns a write of value to key.
+func W(key, value int) MicroOp { return MicroOp{F: "w", Key: key, Value: &value} }
+
+func (op MicroOp) String() string {
+	if op.Value == nil {
+		return fmt.Sprintf("[%s %d nil]", op.F, op.Key)
+	}
+	return fmt.Sprintf("[%s %d %d]", op.F, op.Key, *op.Value)
+}
+
+func (op MicroOp) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{op.F, op.Key, op.Value})
+}
+
+func (op *MicroOp) UnmarshalJSON(data []byte) error {
+	var a []json.RawMessage
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	} else if len(a) != 3 {
+		return fmt.Errorf("invalid micro-op: %s", data)
+	}
+	*op = MicroOp{}
+	if err := json.Unmarshal(a[0], &op.F); err != nil {
+		return fmt.Errorf("invalid micro-op: %s", data)
+	} else if err := json.Unmarshal(a[1], &op.Key); err != nil {
+		return fmt.Errorf("invalid micro-op: %s", data)
+	} else if err := json.Unmarshal(a[2], &op.Value); err != nil {
+		return fmt.Errorf("invalid micro-op: %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/client"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// TestMain runs the test binary as an echo node when started by a client.
+func TestMain(m *testing.M) {
+	if os.Getenv("CLIENT_TEST_NODE") == "" {
+		os.Exit(m.Run())
+	}
+
+	n := maelstrom.NewNode()
+	handleEcho(n)
+	if err := n.Run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func handleEcho(n *maelstrom.Node) {
+	n.Handle("echo", func(msg maelstrom.Message) error {
+		var body map[string]any
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		body["type"] = "echo_ok"
+		return n.Reply(msg, body)
+	})
+}
+
+// handler returns the reply to a request body, whose type attach sets.
+type handler = func(n *maelstrom.Node, body map[string]any) (map[string]any, error)
+
+// attach starts an in-process node with the given handlers and initializes it.
+func attach(t *testing.T, handlers map[string]handler) *client.Client {
+	t.Helper()
+	n := maelstrom.NewNode()
+	for typ, h := range handlers {
+		typ, h := typ, h
+		n.Handle(typ, func(msg maelstrom.Message) error {
+			var body map[string]any
+			if err := json.Unmarshal(msg.Body, &body); err != nil {
+				return err
+			}
+			reply, err := h(n, body)
+			if err != nil {
+				return err
+			}
+			reply["type"] = typ + "_ok"
+			return n.Reply(msg, reply)
+		})
+	}
+
+	c := client.Attach(n, "n1")
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if err := c.Init(context.Background(), []string{"n1"}); err != nil {
+		t.Fatal(err)
+	} else if got, want := n.ID(), "n1"; got != want {
+		t.Fatalf("id=%s, want %s", got, want)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Echo", func(t *testing.T) {
+		c := attach(t, map[string]handler{
+			"echo": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return map[string]any{"echo": body["echo"]}, nil
+			},
+		})
+		if got, err := c.Echo(ctx, "hello"); err != nil {
+			t.Fatal(err)
+		} else if got != "hello" {
+			t.Fatalf("echo=%q", got)
+		}
+	})
+
+	t.Run("Generate", func(t *testing.T) {
+		c := attach(t, map[string]handler{
+			"generate": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return map[string]any{"id": n.ID() + "-1"}, nil
+			},
+		})
+		if got, err := c.Generate(ctx); err != nil {
+			t.Fatal(err)
+		} else if got != "n1-1" {
+			t.Fatalf("id=%v", got)
+		}
+	})
+
+	t.Run("Broadcast", func(t *testing.T) {
+		var messages []int
+		var topology any
+		c := attach(t, map[string]handler{
+			"topology": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				topology = body["topology"]
+				return map[string]any{}, nil
+			},
+			"broadcast": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				messages = append(messages, int(body["message"].(float64)))
+				return map[string]any{}, nil
+			},
+			"read": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return map[string]any{"messages": messages}, nil
+			},
+		})
+		if err := c.Topology(ctx, map[string][]string{"n1": {}}); err != nil {
+			t.Fatal(err)
+		} else if got, want := topology, map[string]any{"n1": []any{}}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("topology=%v, want %v", got, want)
+		}
+		for _, m := range []int{1, 2} {
+			if err := c.Broadcast(ctx, m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got, err := c.Read(ctx); err != nil {
+			t.Fatal(err)
+		} else if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("messages=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Counter", func(t *testing.T) {
+		var value int
+		c := attach(t, map[string]handler{
+			"add": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				value += int(body["delta"].(float64))
+				return map[string]any{}, nil
+			},
+			"read": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return map[string]any{"value": value}, nil
+			},
+		})
+		if err := c.Add(ctx, 3); err != nil {
+			t.Fatal(err)
+		} else if got, err := c.ReadCounter(ctx); err != nil {
+			t.Fatal(err)
+		} else if got != 3 {
+			t.Fatalf("value=%d", got)
+		}
+	})
+
+	t.Run("Kafka", func(t *testing.T) {
+		var log []int
+		committed := make(map[string]any)
+		c := attach(t, map[string]handler{
+			"send": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				log = append(log, int(body["msg"].(float64)))
+				return map[string]any{"offset": len(log) - 1}, nil
+			},
+			"poll": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				from := int(body["offsets"].(map[string]any)["k1"].(float64))
+				var msgs [][]int
+				for i := from; i < len(log); i++ {
+					msgs = append(msgs, []int{i, log[i]})
+				}
+				return map[string]any{"msgs": map[string]any{"k1": msgs}}, nil
+			},
+			"commit_offsets": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				committed = body["offsets"].(map[string]any)
+				return map[string]any{}, nil
+			},
+			"list_committed_offsets": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return map[string]any{"offsets": committed}, nil
+			},
+		})
+		for i, msg := range []int{10, 20, 30} {
+			if offset, err := c.Send(ctx, "k1", msg); err != nil {
+				t.Fatal(err)
+			} else if offset != i {
+				t.Fatalf("offset=%d, want %d", offset, i)
+			}
+		}
+		if got, err := c.Poll(ctx, map[string]int{"k1": 1}); err != nil {
+			t.Fatal(err)
+		} else if want := map[string][]client.LogEntry{"k1": {{1, 20}, {2, 30}}}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("msgs=%v, want %v", got, want)
+		}
+		if err := c.CommitOffsets(ctx, map[string]int{"k1": 2}); err != nil {
+			t.Fatal(err)
+		} else if got, err := c.ListCommittedOffsets(ctx, []string{"k1"}); err != nil {
+			t.Fatal(err)
+		} else if want := map[string]int{"k1": 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("offsets=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Txn", func(t *testing.T) {
+		registers := make(map[any]any)
+		c := attach(t, map[string]handler{
+			"txn": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				txn := body["txn"].([]any)
+				for _, mop := range txn {
+					mop := mop.([]any)
+					if mop[0] == "r" {
+						mop[2] = registers[mop[1]]
+					} else {
+						registers[mop[1]] = mop[2]
+					}
+				}
+				return map[string]any{"txn": txn}, nil
+			},
+		})
+		got, err := c.Txn(ctx, []client.MicroOp{client.W(1, 5), client.R(1), client.R(2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		five := 5
+		if want := []client.MicroOp{client.W(1, 5), {F: "r", Key: 1, Value: &five}, client.R(2)}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("txn=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrRPC", func(t *testing.T) {
+		c := attach(t, map[string]handler{
+			"send": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return nil, maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, "not leader")
+			},
+		})
+		if _, err := c.Send(ctx, "k1", 1); maelstrom.ErrorCode(err) != maelstrom.TemporarilyUnavailable {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrRPCTimeout", func(t *testing.T) {
+		c := attach(t, map[string]handler{
+			"send": func(n *maelstrom.Node, body map[string]any) (map[string]any, error) {
+				return nil, maelstrom.NewRPCError(maelstrom.Timeout, "timed out")
+			},
+		})
+		if _, err := c.Send(ctx, "k1", 1); maelstrom.ErrorCode(err) != maelstrom.Timeout {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrTimeout", func(t *testing.T) {
+		n := maelstrom.NewNode()
+		block := make(chan struct{})
+		n.Handle("read", func(msg maelstrom.Message) error {
+			<-block
+			return nil
+		})
+		c := client.Attach(n, "n1")
+		c.Timeout = 50 * time.Millisecond
+		if _, err := c.Read(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(block)
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrUnexpectedType", func(t *testing.T) {
+		n := maelstrom.NewNode()
+		n.Handle("echo", func(msg maelstrom.Message) error {
+			return n.Reply(msg, map[string]any{"type": "pong"})
+		})
+		c := client.Attach(n, "n1")
+		defer c.Close()
+		if _, err := c.Echo(ctx, "hello"); err == nil || err.Error() != `echo: unexpected reply type "pong", want "echo_ok"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// Ensure a client can start & stop a node binary.
+func TestStart(t *testing.T) {
+	t.Setenv("CLIENT_TEST_NODE", "1")
+
+	c, err := client.Start("n1", exec.Command(os.Args[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.Init(ctx, []string{"n1"}); err != nil {
+		t.Fatal(err)
+	} else if got, err := c.Echo(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	} else if got != "hello" {
+		t.Fatalf("echo=%q", got)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Echo(ctx, "hello"); err != client.ErrClosed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a client can send requests through a simulated network.
+func TestNew_Simnet(t *testing.T) {
+	nw := simnet.New()
+	handleEcho(nw.NewNode("n1"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	c := client.New(nw.NewClient("c1"), "n1")
+	if got, err := c.Echo(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	} else if got != "hello" {
+		t.Fatalf("echo=%q", got)
+	}
+}
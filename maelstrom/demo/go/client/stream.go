@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// ErrClosed is returned by requests on a stream whose node has stopped
+// writing output.
+var ErrClosed = errors.New("client: connection closed")
+
+// Stream is a connection to a node over its STDIN & STDOUT, such as the pipes
+// of a subprocess. It allocates message IDs and matches replies to requests.
+type Stream struct {
+	id string
+
+	wmu sync.Mutex
+	w   io.WriteCloser
+
+	reqs   maelstrom.Requests
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewStream returns a connection for client id that writes requests to stdin
+// and reads replies from stdout until it is exhausted. Lines that are not
+// replies to a pending request, such as requests from the node to other
+// nodes, are ignored.
+func NewStream(id string, stdin io.WriteCloser, stdout io.Reader) *Stream {
+	s := &Stream{
+		id:   id,
+		w:    stdin,
+		done: make(chan struct{}),
+	}
+	go s.readLoop(stdout)
+	return s
+}
+
+// ID returns the client's identifier.
+func (s *Stream) ID() string { return s.id }
+
+// RPC sends a request to dest and waits for the reply. RPC errors in the reply
+// body are returned as *maelstrom.RPCError.
+func (s *Stream) RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return maelstrom.Message{}, ErrClosed
+	}
+
+	ch := make(chan maelstrom.Message, 1)
+	req, msgID, err := s.reqs.New(s.id, dest, body, func(msg maelstrom.Message) { ch <- msg })
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	defer s.reqs.Cancel(msgID)
+
+	line, err := json.Marshal(req)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	s.wmu.Lock()
+	_, err = s.w.Write(append(line, '\n'))
+	s.wmu.Unlock()
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return maelstrom.Message{}, ctx.Err()
+	case <-s.done:
+		return maelstrom.Message{}, ErrClosed
+	case msg := <-ch:
+		if err := msg.RPCError(); err != nil {
+			return msg, err
+		}
+		return msg, nil
+	}
+}
+
+// Close closes the node's STDIN and waits for its STDOUT to be exhausted.
+func (s *Stream) Close() error {
+	s.wmu.Lock()
+	err := s.w.Close()
+	s.wmu.Unlock()
+	<-s.done
+	return err
+}
+
+// readLoop passes each reply to the waiting RPC call, if any.
+func (s *Stream) readLoop(r io.Reader) {
+	defer func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		close(s.done)
+	}()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg maelstrom.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("client: malformed output: %q", scanner.Text())
+			continue
+		} else if msg.Dest == s.id {
+			s.reqs.Deliver(msg)
+		}
+	}
+}
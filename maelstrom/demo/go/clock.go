@@ -0,0 +1,438 @@
+package maelstrom
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock represents a source of time, timers & tickers. Nodes use a real clock
+// by default but a fake or skewed clock can be injected with Node.SetClock()
+// to test time-dependent behavior deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// Sleep pauses the current goroutine for at least d.
+	Sleep(d time.Duration)
+
+	// After waits for d to elapse and then sends the current time on the
+	// returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc waits for d to elapse and then calls f in its own goroutine.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTimer returns a timer that fires once after d.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a ticker that fires every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer represents a single event, similar to time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered. Returns nil for
+	// timers created with AfterFunc.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. Returns false if the timer has
+	// already expired or been stopped.
+	Stop() bool
+
+	// Reset changes the timer to expire after d. Returns true if the timer
+	// had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, similar to time.Ticker.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+
+	// Reset stops the ticker and resets its period to d.
+	Reset(d time.Duration)
+}
+
+// RealClock is a Clock backed by the time package.
+type RealClock struct{}
+
+// NewRealClock returns a clock that uses the system time.
+func NewRealClock() Clock { return RealClock{} }
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (RealClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{timer: time.AfterFunc(d, f)}
+}
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTimer struct{ timer *time.Timer }
+
+func (t *realTimer) C() <-chan time.Time        { return t.timer.C }
+func (t *realTimer) Stop() bool                 { return t.timer.Stop() }
+func (t *realTimer) Reset(d time.Duration) bool { return t.timer.Reset(d) }
+
+type realTicker struct{ ticker *time.Ticker }
+
+func (t *realTicker) C() <-chan time.Time   { return t.ticker.C }
+func (t *realTicker) Stop()                 { t.ticker.Stop() }
+func (t *realTicker) Reset(d time.Duration) { t.ticker.Reset(d) }
+
+// Every calls fn every d, as measured by clock, until ctx is canceled. Each
+// call is scheduled d after the previous one returns, so calls never overlap.
+// Unlike a ticker loop, Every needs no goroutine of its own, so periodic work
+// also runs deterministically under a Simulation.
+func Every(ctx context.Context, clock Clock, d time.Duration, fn func()) {
+	var mu sync.Mutex
+	var timer Timer
+	tick := func() {
+		if ctx.Err() != nil {
+			return
+		}
+		fn()
+
+		mu.Lock()
+		defer mu.Unlock()
+		timer.Reset(d)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	timer = clock.AfterFunc(d, tick)
+}
+
+// FakeClock is a Clock whose time only moves when advanced manually with
+// Add() or Set(). Timers, tickers & sleepers fire as time passes their
+// deadlines.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{}
+}
+
+// NewFakeClock returns a fake clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the fake time elapsed since t.
+func (c *FakeClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+// Sleep blocks until the clock has been advanced by at least d.
+func (c *FakeClock) Sleep(d time.Duration) { <-c.After(d) }
+
+// After returns a channel that receives the fake time once d has elapsed.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time { return c.NewTimer(d).C() }
+
+// AfterFunc calls f in its own goroutine once d has elapsed.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.add(&fakeWaiter{clock: c, fn: f}, d)
+}
+
+// NewTimer returns a timer that fires once d has elapsed.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	return c.add(&fakeWaiter{clock: c, ch: make(chan time.Time, 1)}, d)
+}
+
+// NewTicker returns a ticker that fires every time d elapses.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &fakeTicker{w: c.add(&fakeWaiter{clock: c, ch: make(chan time.Time, 1), period: d}, d)}
+}
+
+// Add advances the clock by d, firing any timers that expire.
+func (c *FakeClock) Add(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to t, firing any timers that expire. Timers fire in
+// deadline order and observe the clock at their own deadline.
+func (c *FakeClock) Set(t time.Time) {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(t) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		if w.deadline.After(c.now) {
+			c.now = w.deadline
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+			c.insert(w)
+		} else {
+			w.active = false
+		}
+		now := c.now
+		c.mu.Unlock()
+
+		w.fire(now)
+	}
+
+	if t.After(c.now) {
+		c.now = t
+	}
+	c.mu.Unlock()
+}
+
+// Waiters returns the number of active timers, tickers & sleepers.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers, tickers or sleepers are waiting
+// on the clock. This allows tests to advance time only once goroutines under
+// test have reached their wait points.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) >= n {
+			c.mu.Unlock()
+			return
+		}
+		ch := c.changed
+		c.mu.Unlock()
+		<-ch
+	}
+}
+
+func (c *FakeClock) add(w *fakeWaiter, d time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w.deadline = c.now.Add(d)
+	w.active = true
+	c.insert(w)
+	return w
+}
+
+// insert adds w to the waiter list in deadline order. Must hold lock.
+func (c *FakeClock) insert(w *fakeWaiter) {
+	i := sort.Search(len(c.waiters), func(i int) bool {
+		return c.waiters[i].deadline.After(w.deadline)
+	})
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = w
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove deletes w from the waiter list. Must hold lock.
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for i := range c.waiters {
+		if c.waiters[i] == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeWaiter implements Timer for FakeClock and backs its tickers.
+type fakeWaiter struct {
+	clock    *FakeClock
+	deadline time.Time
+	period   time.Duration
+	active   bool
+	ch       chan time.Time
+	fn       func()
+}
+
+func (w *fakeWaiter) C() <-chan time.Time { return w.ch }
+
+func (w *fakeWaiter) fire(now time.Time) {
+	if w.fn != nil {
+		go w.fn()
+		return
+	}
+
+	// Drop the tick if the receiver is not keeping up, like time.Ticker.
+	select {
+	case w.ch <- now:
+	default:
+	}
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	wasActive := w.active
+	w.active = false
+	w.clock.remove(w)
+	return wasActive
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	wasActive := w.active
+	w.clock.remove(w)
+	if w.period > 0 {
+		w.period = d
+	}
+	w.deadline = w.clock.now.Add(d)
+	w.active = true
+	w.clock.insert(w)
+	return wasActive
+}
+
+// fakeTicker adapts a periodic fakeWaiter to the Ticker interface.
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t *fakeTicker) C() <-chan time.Time   { return t.w.ch }
+func (t *fakeTicker) Stop()                 { t.w.Stop() }
+func (t *fakeTicker) Reset(d time.Duration) { t.w.Reset(d) }
+
+// SkewedClock wraps another clock and shifts its readings by an offset and
+// optionally runs it faster or slower by a drift rate. Timer durations are
+// scaled by the drift rate so that they measure skewed time.
+type SkewedClock struct {
+	mu     sync.Mutex
+	base   Clock
+	offset time.Duration
+	rate   float64
+	anchor time.Time // base time at which rate was last changed
+	skewed time.Time // skewed time at anchor
+	file   *skewFile
+}
+
+// NewSkewedClock returns a clock which reads base shifted by offset.
+func NewSkewedClock(base Clock, offset time.Duration) *SkewedClock {
+	now := base.Now()
+	return &SkewedClock{
+		base:   base,
+		offset: offset,
+		rate:   1,
+		anchor: now,
+		skewed: now,
+	}
+}
+
+// Offset returns the current offset from the base clock, excluding drift.
+func (c *SkewedClock) Offset() time.Duration {
+	c.reload()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offset
+}
+
+// SetOffset changes the offset from the base clock. Negative offsets make the
+// clock jump backwards.
+func (c *SkewedClock) SetOffset(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = d
+}
+
+// SetRate changes the speed of the clock relative to the base clock. A rate of
+// 1.1 runs 10% fast; a rate of 0.9 runs 10% slow.
+func (c *SkewedClock) SetRate(rate float64) {
+	if rate <= 0 {
+		panic("non-positive rate for SkewedClock")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.base.Now()
+	c.skewed = c.drifted(now)
+	c.anchor = now
+	c.rate = rate
+}
+
+// Now returns the base time adjusted by the offset & drift.
+func (c *SkewedClock) Now() time.Time {
+	c.reload()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.drifted(c.base.Now()).Add(c.offset)
+}
+
+// drifted returns the skewed time for a base time, excluding offset. Must
+// hold lock.
+func (c *SkewedClock) drifted(now time.Time) time.Time {
+	return c.skewed.Add(time.Duration(float64(now.Sub(c.anchor)) * c.rate))
+}
+
+// Since returns the skewed time elapsed since t.
+func (c *SkewedClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+// Sleep pauses for d of skewed time.
+func (c *SkewedClock) Sleep(d time.Duration) { c.base.Sleep(c.scale(d)) }
+
+// After waits for d of skewed time.
+func (c *SkewedClock) After(d time.Duration) <-chan time.Time { return c.base.After(c.scale(d)) }
+
+// AfterFunc calls f after d of skewed time.
+func (c *SkewedClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &skewedTimer{clock: c, Timer: c.base.AfterFunc(c.scale(d), f)}
+}
+
+// NewTimer returns a timer that fires after d of skewed time.
+func (c *SkewedClock) NewTimer(d time.Duration) Timer {
+	return &skewedTimer{clock: c, Timer: c.base.NewTimer(c.scale(d))}
+}
+
+// NewTicker returns a ticker that fires every d of skewed time.
+func (c *SkewedClock) NewTicker(d time.Duration) Ticker {
+	return &skewedTicker{clock: c, Ticker: c.base.NewTicker(c.scale(d))}
+}
+
+// scale converts a skewed duration into a base duration.
+func (c *SkewedClock) scale(d time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(float64(d) / c.rate)
+}
+
+type skewedTimer struct {
+	Timer
+	clock *SkewedClock
+}
+
+func (t *skewedTimer) Reset(d time.Duration) bool { return t.Timer.Reset(t.clock.scale(d)) }
+
+type skewedTicker struct {
+	Ticker
+	clock *SkewedClock
+}
+
+func (t *skewedTicker) Reset(d time.Duration) { t.Ticker.Reset(t.clock.scale(d)) }
@@ -0,0 +1,198 @@
+package maelstrom_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_Timer(t *testing.T) {
+	c := maelstrom.NewFakeClock(epoch)
+	timer := c.NewTimer(10 * time.Second)
+
+	c.Add(9 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Add(1 * time.Second)
+	select {
+	case now := <-timer.C():
+		if got, want := now, epoch.Add(10*time.Second); !got.Equal(want) {
+			t.Fatalf("now=%s, want %s", got, want)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+
+	if timer.Stop() {
+		t.Fatal("expected expired timer to be inactive")
+	}
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	c := maelstrom.NewFakeClock(epoch)
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		c.Add(time.Second)
+		select {
+		case now := <-ticker.C():
+			if got, want := now, epoch.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+				t.Fatalf("tick %d=%s, want %s", i, got, want)
+			}
+		default:
+			t.Fatalf("tick %d not delivered", i)
+		}
+	}
+
+	ticker.Stop()
+	c.Add(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestFakeClock_Sleep(t *testing.T) {
+	c := maelstrom.NewFakeClock(epoch)
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Minute)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Add(time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for sleeper")
+	}
+}
+
+func TestFakeClock_AfterFunc(t *testing.T) {
+	c := maelstrom.NewFakeClock(epoch)
+
+	called := make(chan struct{})
+	c.AfterFunc(time.Second, func() { close(called) })
+
+	stopped := c.AfterFunc(time.Second, func() { t.Error("stopped timer called") })
+	if !stopped.Stop() {
+		t.Fatal("expected active timer")
+	}
+
+	c.Add(time.Second)
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for callback")
+	}
+}
+
+func TestSkewedClock(t *testing.T) {
+	base := maelstrom.NewFakeClock(epoch)
+	c := maelstrom.NewSkewedClock(base, -5*time.Second)
+	if got, want := c.Now(), epoch.Add(-5*time.Second); !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	c.SetRate(2)
+	base.Add(10 * time.Second)
+	if got, want := c.Now(), epoch.Add(15*time.Second); !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	// A skewed timer measures skewed time, so it fires after 5s of base time.
+	timer := c.NewTimer(10 * time.Second)
+	base.Add(5 * time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("timer did not fire")
+	}
+}
+
+// Ensure a skewed clock follows the offset & rate written to a file.
+func TestSkewedClock_WatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skew")
+	if err := os.WriteFile(path, []byte("2s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := maelstrom.NewFakeClock(epoch)
+	c := maelstrom.NewSkewedClock(base, 0)
+	c.WatchFile(path)
+	if got, want := c.Now(), epoch.Add(2*time.Second); !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	// Changes are picked up once the file is rechecked.
+	if err := os.WriteFile(path, []byte("-1s 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base.Add(time.Second)
+	if got, want := c.Now(), epoch; !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+	base.Add(time.Second) // runs at double speed
+	if got, want := c.Now(), epoch.Add(2*time.Second); !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	base.Add(time.Second)
+	if got := c.Offset(); got != 0 {
+		t.Fatalf("offset=%s, want 0", got)
+	}
+}
+
+func TestParseClockSkew(t *testing.T) {
+	for _, tt := range []struct {
+		s      string
+		offset time.Duration
+		rate   float64
+		err    bool
+	}{
+		{"", 0, 1, false},
+		{"250ms", 250 * time.Millisecond, 1, false},
+		{" -2s 1.05\n", -2 * time.Second, 1.05, false},
+		{"2s 0", 0, 0, true},
+		{"fast", 0, 0, true},
+		{"1s 1 1", 0, 0, true},
+	} {
+		offset, rate, err := maelstrom.ParseClockSkew(tt.s)
+		if (err != nil) != tt.err {
+			t.Fatalf("%q: err=%v", tt.s, err)
+		} else if offset != tt.offset || rate != tt.rate {
+			t.Fatalf("%q: offset=%s rate=%v, want %s %v", tt.s, offset, rate, tt.offset, tt.rate)
+		}
+	}
+}
+
+// Ensure the node exposes an injectable clock.
+func TestNode_SetClock(t *testing.T) {
+	n := maelstrom.NewNode()
+	if _, ok := n.Clock().(maelstrom.RealClock); !ok {
+		t.Fatalf("unexpected default clock: %T", n.Clock())
+	}
+
+	c := maelstrom.NewFakeClock(epoch)
+	n.SetClock(c)
+	if got, want := n.Clock().Now(), epoch; !got.Equal(want) {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+}
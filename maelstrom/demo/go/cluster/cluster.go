@@ -0,0 +1,344 @@
+// Package cluster runs Maelstrom-style tests locally without Java: node
+// binaries run as subprocesses connected through a simulated network, which
+// also hosts the KV services, while a workload drives clients against them.
+// Histories & results are written to a store/ directory that Maelstrom's own
+// tools and the store package can read.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/nemesis"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// Latency distributions, as in Maelstrom's --latency-dist.
+const (
+	Constant    = "constant"
+	Uniform     = "uniform"
+	Exponential = "exponential"
+)
+
+// Default configuration values.
+const (
+	DefaultNodeCount       = 5
+	DefaultStore           = "store"
+	DefaultShutdownTimeout = 5 * time.Second
+	DefaultNemesisInterval = 10 * time.Second
+)
+
+// Config describes a test.
+type Config struct {
+	// Name of the workload, e.g. "broadcast".
+	Workload string
+
+	// Node binary & its arguments.
+	Bin  string
+	Args []string
+
+	// Number of nodes, named n0, n1, ...
+	NodeCount int
+
+	// Mean one-way latency of messages and its distribution.
+	Latency     time.Duration
+	LatencyDist string
+
+	// Client configuration.
+	Client workload.Config
+
+	// Faults to inject at random, e.g. "partition" and "kill", with one
+	// fault beginning every NemesisInterval. NemesisSchedule is the path of
+	// a schedule file to follow instead.
+	Nemesis         []string
+	NemesisInterval time.Duration
+	NemesisSchedule string
+
+	// Simulate runs the test as a deterministic simulation seeded by
+	// Client.Seed: nodes are started with MAELSTROM_SIMULATION and stepped
+	// in virtual time, so a seed replays the same run. Faults cannot be
+	// injected into simulations.
+	Simulate bool
+
+	// Root of the store directory results are written to.
+	Store string
+
+	// Command line to record with the results.
+	Command string
+}
+
+// NewConfig returns a configuration with Maelstrom's defaults.
+func NewConfig() Config {
+	return Config{
+		NodeCount:   DefaultNodeCount,
+		LatencyDist: Constant,
+		Client:      workload.NewConfig(),
+		Store:       DefaultStore,
+
+		NemesisInterval: DefaultNemesisInterval,
+	}
+}
+
+// Run runs a test and returns the directory its results were written to.
+// Results are written even if the history is invalid; only failures to run
+// the test are returned as errors.
+func Run(ctx context.Context, cfg Config) (dir string, err error) {
+	w, err := workload.New(cfg.Workload, cfg.Client)
+	if err != nil {
+		return "", err
+	} else if cfg.NodeCount < 1 {
+		return "", fmt.Errorf("invalid node count %d", cfg.NodeCount)
+	} else if cfg.Simulate && (len(cfg.Nemesis) > 0 || cfg.NemesisSchedule != "") {
+		return "", errors.New("nemesis is not supported in simulations")
+	}
+
+	start := time.Now()
+	dir = filepath.Join(cfg.Store, cfg.Workload, start.Format("20060102T150405.000-0700"))
+	if err := os.MkdirAll(filepath.Join(dir, "node-logs"), 0o755); err != nil {
+		return "", err
+	}
+
+	latency, err := latencyFunc(cfg.Latency, cfg.LatencyDist, cfg.Client.Seed)
+	if err != nil {
+		return "", err
+	}
+	net := &netStats{}
+
+	var ops []history.Op
+	var fingerprint string
+	if cfg.Simulate {
+		ops, fingerprint, err = simulate(ctx, cfg, w, dir, latency, net)
+	} else {
+		ops, err = run(ctx, cfg, w, dir, latency, net)
+	}
+	if err != nil {
+		return dir, err
+	}
+
+	if err := history.WriteFile(filepath.Join(dir, "history.edn"), ops); err != nil {
+		return dir, err
+	}
+	results := buildResults(ops, w.Check(ops), net)
+	if cfg.Simulate {
+		results[edn.Keyword("simulation")] = map[any]any{
+			edn.Keyword("seed"):        cfg.Client.Seed,
+			edn.Keyword("fingerprint"): fingerprint,
+		}
+	}
+	if err := writeResults(filepath.Join(dir, "results.edn"), results); err != nil {
+		return dir, err
+	}
+	if err := writeLog(filepath.Join(dir, "jepsen.log"), start, cfg.Command); err != nil {
+		return dir, err
+	}
+	return dir, link(cfg.Store, dir)
+}
+
+// run runs a test in real time against node processes connected through a
+// simulated network and returns its history.
+func run(ctx context.Context, cfg Config, w workload.Workload, dir string, latency func(src, dest string) time.Duration, net *netStats) ([]history.Op, error) {
+	nw := simnet.New()
+	nw.SetLatency(latency)
+	nw.Observe(net.observe)
+
+	events, err := schedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	nodes := newNodeSet(ctx, nw, cfg, dir, usesClocks(events))
+	if err := nodes.startAll(); err != nil {
+		nodes.stop()
+		return nil, err
+	}
+
+	rec := history.NewRecorder(nil)
+	err = func() error {
+		if err := nw.Start(ctx); err != nil {
+			return err
+		}
+
+		// The nemesis stops at the time limit and recovers the cluster
+		// while the workload waits to perform its final operations, so a
+		// fault without a matching stop lasts until then. Faults that fail
+		// are recorded in the history & logged.
+		var nemDone chan error
+		if len(events) > 0 {
+			if err := writeSchedule(filepath.Join(dir, "nemesis.txt"), events); err != nil {
+				return err
+			}
+			nem := nemesis.New(nodes, rec, cfg.Client.Seed)
+			nemDone = make(chan error, 1)
+			limit := time.Now().Add(cfg.Client.TimeLimit)
+			go func() {
+				err := nem.Run(ctx, before(events, cfg.Client.TimeLimit))
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Until(limit)):
+				}
+				if rerr := nem.Recover(ctx); err == nil {
+					err = rerr
+				}
+				nemDone <- err
+			}()
+		}
+
+		newClient := func(id string) workload.Client { return nw.NewClient(id) }
+		err := workload.Run(ctx, w, cfg.Client, nodes.ids, newClient, rec)
+		if nemDone != nil {
+			if nerr := <-nemDone; nerr != nil {
+				log.Print(nerr)
+			}
+		}
+		return err
+	}()
+	nodes.stop()
+	if err != nil {
+		return nil, err
+	}
+	return rec.Ops(), nil
+}
+
+// schedule returns the nemesis events of a test: those of its schedule file
+// or random faults spread over its time limit.
+func schedule(cfg Config) ([]nemesis.Event, error) {
+	if cfg.NemesisSchedule != "" {
+		return nemesis.ReadSchedule(cfg.NemesisSchedule)
+	} else if len(cfg.Nemesis) == 0 {
+		return nil, nil
+	}
+	rng := rand.New(rand.NewSource(cfg.Client.Seed))
+	return nemesis.Generate(cfg.Nemesis, cfg.NemesisInterval, cfg.Client.TimeLimit, rng)
+}
+
+// before returns the events scheduled before the time limit.
+func before(events []nemesis.Event, limit time.Duration) []nemesis.Event {
+	for i, e := range events {
+		if e.At >= limit {
+			return events[:i]
+		}
+	}
+	return events
+}
+
+// usesClocks returns true if any event skews clocks.
+func usesClocks(events []nemesis.Event) bool {
+	for _, e := range events {
+		if e.F == nemesis.SkewClock || e.F == nemesis.ResetClock {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSchedule records the nemesis events of a test with its results.
+func writeSchedule(path string, events []nemesis.Event) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := nemesis.WriteSchedule(f, events); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// latencyFunc returns a function producing message delays with the given
+// mean & distribution.
+func latencyFunc(mean time.Duration, dist string, seed int64) (func(src, dest string) time.Duration, error) {
+	rng := rand.New(rand.NewSource(seed))
+	var mu sync.Mutex
+
+	switch dist {
+	case "", Constant:
+		return func(src, dest string) time.Duration { return mean }, nil
+	case Uniform:
+		return func(src, dest string) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			return time.Duration(rng.Float64() * 2 * float64(mean))
+		}, nil
+	case Exponential:
+		return func(src, dest string) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			return time.Duration(rng.ExpFloat64() * float64(mean))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %q", dist)
+	}
+}
+
+// writeLog writes a jepsen.log recording the command line, as Maelstrom
+// does.
+func writeLog(path string, start time.Time, command string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "%s\tINFO\t[distsys] Command line:\n%s\n", start.Format("2006-01-02 15:04:05,000"), command)
+	return f.Close()
+}
+
+// link points the latest symlinks of the store, and of the workload's
+// directory within it, at dir.
+func link(store, dir string) error {
+	rel, err := filepath.Rel(store, dir)
+	if err != nil {
+		return err
+	}
+	for _, l := range []struct{ path, target string }{
+		{filepath.Join(store, "latest"), rel},
+		{filepath.Join(filepath.Dir(dir), "latest"), filepath.Base(dir)},
+	} {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Symlink(l.target, l.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// netStats counts the messages routed by the network.
+type netStats struct {
+	mu                    sync.Mutex
+	all, clients, servers msgCounts
+}
+
+type msgCounts struct {
+	send, recv int
+}
+
+func (s *netStats) observe(msg maelstrom.Message, dropped bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := []*msgCounts{&s.all}
+	if simnet.IsClient(msg.Src) || simnet.IsClient(msg.Dest) {
+		counts = append(counts, &s.clients)
+	} else {
+		counts = append(counts, &s.servers)
+	}
+	for _, c := range counts {
+		c.send++
+		if !dropped {
+			c.recv++
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package cluster_test
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/cluster"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/nemesis"
+	"github.com/jepsen-io/maelstrom/demo/go/store"
+)
+
+// TestMain runs the test binary as an echo node when started by a cluster.
+func TestMain(m *testing.M) {
+	if os.Getenv("CLUSTER_TEST_NODE") == "" {
+		os.Exit(m.Run())
+	}
+
+	log.Printf("started")
+	n := maelstrom.NewNode()
+	n.Handle("echo", func(msg maelstrom.Message) error {
+		var body map[string]any
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		body["type"] = "echo_ok"
+		return n.Reply(msg, body)
+	})
+	if err := n.Run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("CLUSTER_TEST_NODE", "1")
+
+	cfg := cluster.NewConfig()
+	cfg.Workload = "echo"
+	cfg.Bin = os.Args[0]
+	cfg.NodeCount = 3
+	cfg.Latency = time.Millisecond
+	cfg.Client.Rate, cfg.Client.TimeLimit = 50, 500*time.Millisecond
+	cfg.Store = t.TempDir()
+	cfg.Command = "distsys run -w echo"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dir, err := cluster.Run(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := store.Open(filepath.Join(cfg.Store, "latest"))
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := r.Dir, dir; filepath.Base(got) != filepath.Base(want) {
+		t.Fatalf("latest=%s, want %s", got, want)
+	} else if got, want := r.Command, cfg.Command; got != want {
+		t.Fatalf("command=%q, want %q", got, want)
+	} else if len(r.NodeLogs) != 3 {
+		t.Fatalf("node logs=%d, want 3", len(r.NodeLogs))
+	}
+
+	s := r.Summary()
+	if s.Valid != store.Valid {
+		t.Fatalf("valid=%s", s.Valid)
+	} else if s.Ops < 10 || s.OK != s.Ops {
+		t.Fatalf("ops=%d, ok=%d", s.Ops, s.OK)
+	} else if s.MsgsPerOp <= 0 {
+		t.Fatalf("msgs/op=%f", s.MsgsPerOp)
+	}
+}
+
+// Ensure faults are injected on schedule, recorded in the history and
+// recovered from before the final operations.
+func TestRun_Nemesis(t *testing.T) {
+	t.Setenv("CLUSTER_TEST_NODE", "1")
+
+	sched := filepath.Join(t.TempDir(), "schedule.txt")
+	if err := os.WriteFile(sched, []byte(`
+100ms start-partition one
+100ms kill n1
+100ms skew-clock n0 1h
+200ms pause n2
+300ms stop-partition
+300ms resume
+350ms start
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := cluster.NewConfig()
+	cfg.Workload = "echo"
+	cfg.Bin = os.Args[0]
+	cfg.NodeCount = 3
+	cfg.Latency = time.Millisecond
+	cfg.Client.Rate, cfg.Client.TimeLimit = 50, 500*time.Millisecond
+	cfg.Client.Timeout = 100 * time.Millisecond
+	cfg.Store = t.TempDir()
+	cfg.NemesisSchedule = sched
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dir, err := cluster.Run(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := history.ReadFile(filepath.Join(dir, "history.edn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fs []string
+	for _, op := range ops {
+		if !op.IsClient() {
+			fs = append(fs, op.F)
+		}
+	}
+	if got, want := strings.Join(fs, " "), strings.Join([]string{
+		nemesis.StartPartition, nemesis.StartPartition,
+		nemesis.Kill, nemesis.Kill,
+		nemesis.SkewClock, nemesis.SkewClock,
+		nemesis.Pause, nemesis.Pause,
+		nemesis.StopPartition, nemesis.StopPartition,
+		nemesis.Resume, nemesis.Resume,
+		nemesis.Start, nemesis.Start,
+		nemesis.ResetClock, nemesis.ResetClock,
+	}, " "); got != want {
+		t.Fatalf("nemesis ops=%s, want %s", got, want)
+	}
+
+	// The restarted node appends to the log of the one it replaced, and the
+	// skewed clock was reset.
+	if buf, err := os.ReadFile(filepath.Join(dir, "node-logs", "n1.log")); err != nil {
+		t.Fatal(err)
+	} else if got := strings.Count(string(buf), "started"); got != 2 {
+		t.Fatalf("n1 started %d times, want 2", got)
+	}
+	if buf, err := os.ReadFile(filepath.Join(dir, "clocks", "n0")); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(buf), "0s\n"; got != want {
+		t.Fatalf("n0 clock=%q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nemesis.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if s := r.Summary(); s.Valid != store.Valid {
+		t.Fatalf("valid=%s", s.Valid)
+	} else if s.OK == s.Ops {
+		t.Fatalf("expected some ops to fail during faults: %+v", s)
+	}
+}
+
+// Ensure a fault without a matching stop lasts until the time limit.
+func TestRun_NemesisUnrecovered(t *testing.T) {
+	t.Setenv("CLUSTER_TEST_NODE", "1")
+
+	sched := filepath.Join(t.TempDir(), "schedule.txt")
+	if err := os.WriteFile(sched, []byte("100ms start-partition one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := cluster.NewConfig()
+	cfg.Workload = "echo"
+	cfg.Bin = os.Args[0]
+	cfg.NodeCount = 3
+	cfg.Latency = time.Millisecond
+	cfg.Client.Rate, cfg.Client.TimeLimit = 50, 500*time.Millisecond
+	cfg.Store = t.TempDir()
+	cfg.NemesisSchedule = sched
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dir, err := cluster.Run(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := history.ReadFile(filepath.Join(dir, "history.edn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		if op.F != nemesis.StopPartition {
+			continue
+		} else if at := time.Duration(op.Time); at < cfg.Client.TimeLimit-50*time.Millisecond {
+			t.Fatalf("partition healed at %s, before the time limit", at)
+		}
+		return
+	}
+	t.Fatal("expected partition to be healed")
+}
+
+// Ensure a simulated run replays exactly for the same seed.
+func TestRun_Simulate(t *testing.T) {
+	t.Setenv("CLUSTER_TEST_NODE", "1")
+
+	run := func(seed int64) (history []byte, fingerprint any) {
+		cfg := cluster.NewConfig()
+		cfg.Workload = "echo"
+		cfg.Bin = os.Args[0]
+		cfg.NodeCount = 3
+		cfg.Latency, cfg.LatencyDist = 10*time.Millisecond, cluster.Exponential
+		cfg.Client.Rate, cfg.Client.TimeLimit = 50, 5*time.Second
+		cfg.Client.Seed = seed
+		cfg.Store = t.TempDir()
+		cfg.Simulate = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		dir, err := cluster.Run(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := store.Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		} else if s := r.Summary(); s.Valid != store.Valid || s.Ops != 250 {
+			t.Fatalf("valid=%s, ops=%d", s.Valid, s.Ops)
+		}
+		sim, _ := r.Results.Raw[edn.Keyword("simulation")].(map[any]any)
+		if buf, err := os.ReadFile(filepath.Join(dir, "history.edn")); err != nil {
+			t.Fatal(err)
+		} else {
+			history = buf
+		}
+		return history, sim[edn.Keyword("fingerprint")]
+	}
+
+	h, fp := run(1)
+	if fp == nil {
+		t.Fatal("expected fingerprint in results")
+	} else if h2, fp2 := run(1); fp2 != fp || string(h2) != string(h) {
+		t.Fatalf("seed 1 replayed with fingerprint %v, want %v", fp2, fp)
+	} else if _, fp3 := run(2); fp3 == fp {
+		t.Fatal("expected another seed to produce another run")
+	}
+
+	cfg := cluster.NewConfig()
+	cfg.Workload, cfg.Bin, cfg.Simulate = "echo", os.Args[0], true
+	cfg.Nemesis = []string{"partition"}
+	if _, err := cluster.Run(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// nodeSet is the node processes of a test. It implements nemesis.Cluster:
+// partitions are applied by the network, pauses with SIGSTOP & SIGCONT and
+// clock skew through a file per node named by MAELSTROM_CLOCK_SKEW_FILE.
+type nodeSet struct {
+	ctx context.Context
+	nw  *simnet.Network
+	cfg Config
+	dir string
+	ids []string
+
+	// clocks is set if nodes should follow skew files in <dir>/clocks.
+	clocks bool
+
+	mu     sync.Mutex
+	procs  map[string]*exec.Cmd
+	paused map[string]bool
+}
+
+func newNodeSet(ctx context.Context, nw *simnet.Network, cfg Config, dir string, clocks bool) *nodeSet {
+	ids := make([]string, cfg.NodeCount)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("n%d", i)
+	}
+	return &nodeSet{
+		ctx:    ctx,
+		nw:     nw,
+		cfg:    cfg,
+		dir:    dir,
+		ids:    ids,
+		clocks: clocks,
+		procs:  make(map[string]*exec.Cmd),
+		paused: make(map[string]bool),
+	}
+}
+
+// startAll starts every node.
+func (s *nodeSet) startAll() error {
+	if s.clocks {
+		if err := os.MkdirAll(filepath.Join(s.dir, "clocks"), 0o755); err != nil {
+			return err
+		}
+	}
+	for _, id := range s.ids {
+		if err := s.start(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start starts a node binary and attaches it to the network. Its STDERR is
+// appended to node-logs/<id>.log, so the logs of a restarted node follow on
+// from those of the process it replaced.
+func (s *nodeSet) start(id string) error {
+	var env []string
+	if s.clocks {
+		env = append(env, maelstrom.ClockSkewFileEnv+"="+s.clockFile(id))
+	}
+	cmd, stdin, stdout, err := startNode(s.ctx, s.cfg, s.dir, id, env)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.procs[id] = cmd
+	s.mu.Unlock()
+	s.nw.AttachNode(id, stdin, stdout)
+	return nil
+}
+
+// startNode starts the node binary for id with env added to its
+// environment, appending its STDERR to node-logs/<id>.log.
+func startNode(ctx context.Context, cfg Config, dir, id string, env []string) (*exec.Cmd, io.WriteCloser, io.ReadCloser, error) {
+	logFile, err := os.OpenFile(filepath.Join(dir, "node-logs", id+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer logFile.Close() // the child has its own copy
+
+	cmd := exec.CommandContext(ctx, cfg.Bin, cfg.Args...)
+	cmd.Stderr = logFile
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, fmt.Errorf("start %s: %w", id, err)
+	}
+	return cmd, stdin, stdout, nil
+}
+
+func (s *nodeSet) clockFile(id string) string {
+	return filepath.Join(s.dir, "clocks", id)
+}
+
+func (s *nodeSet) proc(id string) (*exec.Cmd, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd := s.procs[id]
+	if cmd == nil {
+		return nil, fmt.Errorf("%s is not running", id)
+	}
+	return cmd, nil
+}
+
+func (s *nodeSet) Nodes() []string   { return s.ids }
+func (s *nodeSet) Block(a, b string) { s.nw.Block(a, b) }
+func (s *nodeSet) Heal()             { s.nw.Heal() }
+
+func (s *nodeSet) Pause(id string) error {
+	cmd, err := s.proc(id)
+	if err != nil {
+		return err
+	} else if err := pause(cmd.Process); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.paused[id] = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *nodeSet) Resume(id string) error {
+	cmd, err := s.proc(id)
+	if err != nil {
+		return err
+	} else if err := resume(cmd.Process); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.paused, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Kill kills a node's process. Messages sent to it are lost until it is
+// restarted.
+func (s *nodeSet) Kill(id string) error {
+	cmd, err := s.proc(id)
+	if err != nil {
+		return err
+	}
+	s.nw.DetachNode(id)
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	s.mu.Lock()
+	delete(s.procs, id)
+	delete(s.paused, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Restart starts a killed node again and initializes it. A node that fails
+// to initialize is killed again, so it can be restarted later.
+func (s *nodeSet) Restart(ctx context.Context, id string) error {
+	s.mu.Lock()
+	running := s.procs[id] != nil
+	s.mu.Unlock()
+	if running {
+		return fmt.Errorf("%s is already running", id)
+	}
+
+	if err := s.start(id); err != nil {
+		return err
+	} else if err := s.nw.Init(ctx, id); err != nil {
+		s.Kill(id)
+		return err
+	}
+	return nil
+}
+
+// SkewClock writes the node's clock skew file. The file is replaced
+// atomically so the node never reads a partial offset.
+func (s *nodeSet) SkewClock(id string, offset time.Duration) error {
+	if !s.clocks {
+		return fmt.Errorf("clock skew is disabled")
+	}
+	path := s.clockFile(id)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(offset.String()+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// stop closes every node's STDIN and waits for the nodes to exit, killing
+// those that don't within DefaultShutdownTimeout. Paused nodes are resumed
+// first.
+func (s *nodeSet) stop() {
+	s.mu.Lock()
+	procs := make([]*exec.Cmd, 0, len(s.procs))
+	for id, cmd := range s.procs {
+		if s.paused[id] {
+			resume(cmd.Process)
+		}
+		procs = append(procs, cmd)
+	}
+	s.paused = make(map[string]bool)
+	s.mu.Unlock()
+
+	s.nw.Close()
+	wait(procs)
+}
+
+// wait waits for processes whose STDIN has been closed to exit, killing
+// those that don't within DefaultShutdownTimeout.
+func wait(procs []*exec.Cmd) {
+	var wg sync.WaitGroup
+	for _, cmd := range procs {
+		cmd := cmd
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() { cmd.Wait(); close(done) }()
+			select {
+			case <-done:
+			case <-time.After(DefaultShutdownTimeout):
+				cmd.Process.Kill()
+				<-done
+			}
+		}()
+	}
+	wg.Wait()
+}
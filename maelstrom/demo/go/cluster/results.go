@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"os"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// buildResults assembles results in the shape of Maelstrom's results.edn.
+func buildResults(ops []history.Op, res workload.Result, net *netStats) map[any]any {
+	stats := buildStats(history.Pairs(ops))
+	count := stats[edn.Keyword("count")].(int)
+
+	okFraction := 0.0
+	if count > 0 {
+		okFraction = float64(stats[edn.Keyword("ok-count")].(int)) / float64(count)
+	}
+
+	wl := map[any]any{
+		edn.Keyword("valid?"): res.Valid,
+		edn.Keyword("errors"): append([]string{}, res.Errors...),
+	}
+	for k, v := range res.Details {
+		wl[edn.Keyword(k)] = v
+	}
+
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return map[any]any{
+		edn.Keyword("valid?"): res.Valid && stats[edn.Keyword("valid?")].(bool),
+		edn.Keyword("stats"):  stats,
+		edn.Keyword("availability"): map[any]any{
+			edn.Keyword("valid?"):      true,
+			edn.Keyword("ok-fraction"): okFraction,
+		},
+		edn.Keyword("net"): map[any]any{
+			edn.Keyword("valid?"):  true,
+			edn.Keyword("all"):     net.all.edn(count),
+			edn.Keyword("clients"): net.clients.edn(0),
+			edn.Keyword("servers"): net.servers.edn(count),
+		},
+		edn.Keyword("workload"): wl,
+	}
+}
+
+// buildStats counts operations by completion type, overall and for each
+// function. As in Jepsen, stats are valid if every function succeeded at
+// least once.
+func buildStats(pairs []history.Pair) map[any]any {
+	count := func(pairs []history.Pair) map[any]any {
+		var ok, fail, info int
+		for _, p := range pairs {
+			switch p.Complete.Type {
+			case history.OK:
+				ok++
+			case history.Fail:
+				fail++
+			default:
+				info++
+			}
+		}
+		return map[any]any{
+			edn.Keyword("valid?"):     ok > 0,
+			edn.Keyword("count"):      len(pairs),
+			edn.Keyword("ok-count"):   ok,
+			edn.Keyword("fail-count"): fail,
+			edn.Keyword("info-count"): info,
+		}
+	}
+
+	byF := make(map[string][]history.Pair)
+	for _, p := range pairs {
+		byF[p.Invoke.F] = append(byF[p.Invoke.F], p)
+	}
+
+	stats := count(pairs)
+	valid := true
+	fs := make(map[any]any, len(byF))
+	for f, pairs := range byF {
+		s := count(pairs)
+		fs[edn.Keyword(f)] = s
+		valid = valid && s[edn.Keyword("valid?")].(bool)
+	}
+	stats[edn.Keyword("by-f")] = fs
+	stats[edn.Keyword("valid?")] = valid
+	return stats
+}
+
+// edn returns the counts as a results map. Messages per operation are
+// included if ops is non-zero.
+func (c msgCounts) edn(ops int) map[any]any {
+	m := map[any]any{
+		edn.Keyword("send-count"): c.send,
+		edn.Keyword("recv-count"): c.recv,
+		edn.Keyword("msg-count"):  c.send,
+	}
+	if ops > 0 {
+		m[edn.Keyword("msgs-per-op")] = float64(c.send) / float64(ops)
+	}
+	return m
+}
+
+func writeResults(path string, results map[any]any) error {
+	buf, err := edn.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0o644)
+}
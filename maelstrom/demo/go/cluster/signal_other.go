@@ -0,0 +1,13 @@
+//go:build !unix
+
+package cluster
+
+import (
+	"errors"
+	"os"
+)
+
+var errPause = errors.New("pausing processes is not supported on this platform")
+
+func pause(p *os.Process) error  { return errPause }
+func resume(p *os.Process) error { return errPause }
@@ -0,0 +1,14 @@
+//go:build unix
+
+package cluster
+
+import (
+	"os"
+	"syscall"
+)
+
+// pause stops a process with SIGSTOP.
+func pause(p *os.Process) error { return p.Signal(syscall.SIGSTOP) }
+
+// resume continues a stopped process with SIGCONT.
+func resume(p *os.Process) error { return p.Signal(syscall.SIGCONT) }
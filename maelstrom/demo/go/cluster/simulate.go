@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/sim"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// simulate runs a test as a deterministic simulation and returns its history
+// and the fingerprint of the messages exchanged. Each node binary runs its
+// own simulation, seeded from the test's seed, and is stepped in lock-step
+// with the network, so the history is measured in virtual time.
+func simulate(ctx context.Context, cfg Config, w workload.Workload, dir string, latency func(src, dest string) time.Duration, net *netStats) ([]history.Op, string, error) {
+	s := maelstrom.NewSimulation(cfg.Client.Seed)
+	defer s.Close()
+	nw := sim.New(s)
+	nw.SetLatency(latency)
+	nw.Observe(net.observe)
+
+	ids := make([]string, cfg.NodeCount)
+	var procs []*exec.Cmd
+	defer func() {
+		nw.Close()
+		wait(procs)
+	}()
+	for i := range ids {
+		ids[i] = fmt.Sprintf("n%d", i)
+		seed := cfg.Client.Seed + int64(i) + 1
+		cmd, stdin, stdout, err := startNode(ctx, cfg, dir, ids[i], []string{maelstrom.SimulationEnv + "=" + strconv.FormatInt(seed, 10)})
+		if err != nil {
+			return nil, "", err
+		}
+		procs = append(procs, cmd)
+		nw.AttachProcess(ids[i], stdin, stdout)
+	}
+
+	rec := history.NewRecorder(s.Clock())
+	var err error
+	if serr := s.Run(func() {
+		if err = nw.Start(ctx); err != nil {
+			return
+		}
+		newClient := func(id string) workload.Client { return nw.NewClient(id) }
+		err = workload.Simulate(ctx, s, w, cfg.Client, ids, newClient, rec)
+	}); serr != nil {
+		return nil, "", serr
+	} else if err != nil {
+		return nil, "", err
+	}
+	return rec.Ops(), nw.Fingerprint(), nil
+}
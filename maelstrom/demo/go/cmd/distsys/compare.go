@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/jepsen-io/maelstrom/demo/go/store"
+)
+
+// compare prints a Markdown table comparing a run against a baseline and
+// fails if any metric regressed.
+func compare(args []string) error {
+	tol := store.DefaultTolerance
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Float64Var(&tol.Latency, "latency-tolerance", tol.Latency, "allowed fractional increase of p50, p95 & p99 latency")
+	fs.Float64Var(&tol.Msgs, "msgs-tolerance", tol.Msgs, "allowed fractional increase of msgs/op")
+	fs.Float64Var(&tol.Errors, "error-tolerance", tol.Errors, "allowed absolute increase of the error rate")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: distsys compare [flags] <baseline> <candidate>")
+		fmt.Fprintln(fs.Output(), "\nEach run is a store directory or a JSON summary from \"distsys report -json\".")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	baseline, err := store.ReadSummary(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	candidate, err := store.ReadSummary(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	c := store.Compare(baseline, candidate, tol)
+	fmt.Print(c.Markdown())
+
+	regressions := c.Regressions()
+	for _, m := range regressions {
+		log.Printf("%s regressed: %s -> %s", m.Name, m.Baseline, m.Candidate)
+	}
+	if len(regressions) > 0 {
+		return errFailed
+	}
+	return nil
+}
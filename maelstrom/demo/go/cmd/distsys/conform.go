@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jepsen-io/maelstrom/demo/go/conform"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// conformance checks that a node binary follows the Maelstrom protocol and
+// prints the violations found.
+func conformance(args []string) error {
+	cfg := conform.NewConfig()
+	fs := flag.NewFlagSet("conform", flag.ExitOnError)
+	fs.StringVar(&cfg.Bin, "bin", "", "node binary")
+	fs.StringVar(&cfg.Workload, "w", cfg.Workload, "workload whose requests exercise the nodes: "+strings.Join(workload.Names(), ", "))
+	fs.IntVar(&cfg.NodeCount, "node-count", cfg.NodeCount, "number of nodes")
+	fs.IntVar(&cfg.Ops, "ops", cfg.Ops, "number of operations to perform")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "time to wait for each reply")
+	fs.Int64Var(&cfg.Seed, "seed", 0, "seed for generated operations")
+	verbose := fs.Bool("v", false, "print the nodes' logs to STDERR")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: distsys conform --bin <binary> [flags] [-- args...]")
+		fmt.Fprintln(fs.Output(), "\nArguments after the flags are passed to the node binary.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if cfg.Bin == "" {
+		fs.Usage()
+		return errUsage
+	}
+	cfg.Args = fs.Args()
+	if *verbose {
+		cfg.Stderr = os.Stderr
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	r, err := conform.Run(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	for _, v := range r.Violations {
+		fmt.Println(v)
+	}
+	if !r.OK() {
+		fmt.Printf("FAIL  %d violations  %d requests  %d replies\n", len(r.Violations), r.Requests, r.Replies)
+		return errFailed
+	}
+	fmt.Printf("PASS  %d requests  %d replies\n", r.Requests, r.Replies)
+	return nil
+}
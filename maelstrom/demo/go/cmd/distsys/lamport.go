@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jepsen-io/maelstrom/demo/go/lamport"
+)
+
+// lamportDiagram renders a Lamport diagram of the messages in node logs or
+// recordings.
+func lamportDiagram(args []string) error {
+	var f lamport.Filter
+	fs := flag.NewFlagSet("lamport", flag.ExitOnError)
+	output := fs.String("o", "", "write the diagram to this file instead of STDOUT")
+	format := fs.String("format", "", `"svg" or "html"; defaults to the extension of -o, or svg`)
+	nodes := fs.String("nodes", "", "comma-separated nodes whose messages to show")
+	types := fs.String("types", "", "comma-separated message types to show, including their _ok replies")
+	fs.DurationVar(&f.From, "from", 0, "hide messages before this time since the first event")
+	fs.DurationVar(&f.To, "to", 0, "hide messages from this time since the first event")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: distsys lamport [flags] [run-dir | log-dir | file...]")
+		fmt.Fprintln(fs.Output(), "\nReads node logs (*.log) and recordings (*.jsonl), each named after its node.")
+		fmt.Fprintln(fs.Output(), "Paths default to store/latest.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *nodes != "" {
+		f.Nodes = strings.Split(*nodes, ",")
+	}
+	if *types != "" {
+		f.Types = strings.Split(*types, ",")
+	}
+	if *format == "" {
+		*format = "svg"
+		if ext := filepath.Ext(*output); ext == ".html" || ext == ".htm" {
+			*format = "html"
+		}
+	}
+	if *format != "svg" && *format != "html" {
+		fs.Usage()
+		return errUsage
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"store/latest"}
+	}
+	events, err := lamport.ReadFiles(paths...)
+	if err != nil {
+		return err
+	} else if len(events) == 0 {
+		return fmt.Errorf("no messages found in %s", strings.Join(paths, ", "))
+	}
+	d := lamport.Build(events).Filter(f)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *format == "html" {
+		err = d.WriteHTML(w, strings.Join(paths, " "))
+	} else {
+		err = d.WriteSVG(w)
+	}
+	return err
+}
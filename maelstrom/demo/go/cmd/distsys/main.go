@@ -0,0 +1,75 @@
+// Command distsys runs & inspects Maelstrom tests from the command line.
+//
+// Usage:
+//
+//	distsys <command> [flags] [args]
+//
+// Run "distsys <command> -h" for the flags of each command.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// errFailed is returned by commands whose checks failed. The reasons have
+// already been printed.
+var errFailed = errors.New("checks failed")
+
+// errUsage is returned by commands given invalid arguments. Usage has
+// already been printed.
+var errUsage = errors.New("invalid usage")
+
+// commands maps each subcommand to the function that runs it.
+var commands = map[string]struct {
+	run     func(args []string) error
+	summary string
+}{
+	"compare": {compare, "compare a test run against a baseline"},
+	"conform": {conformance, "check a node binary against the Maelstrom protocol"},
+	"lamport": {lamportDiagram, "draw a Lamport diagram of messages from node logs"},
+	"report":  {report, "summarize a test run from its store directory"},
+	"run":     {run, "run a test against a local cluster of node binaries"},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("distsys: ")
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		log.Printf("unknown command %q", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); errors.Is(err, errUsage) {
+		os.Exit(2)
+	} else if errors.Is(err, errFailed) {
+		os.Exit(1)
+	} else if err != nil {
+		log.Printf("ERROR: %s", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: distsys <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/store"
+)
+
+// report prints a summary of each run and checks it against the given
+// thresholds.
+func report(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print one JSON summary per line")
+	requireValid := fs.Bool("require-valid", false, "fail unless Maelstrom judged the run valid")
+	maxP99 := fs.Duration("max-p99", 0, "fail if the p99 latency exceeds this")
+	minAvailability := fs.Float64("min-availability", 0, "fail if the fraction of ok operations is below this")
+	maxMsgsPerOp := fs.Float64("max-msgs-per-op", 0, "fail if messages per operation exceed this")
+	maxServerMsgsPerOp := fs.Float64("max-server-msgs-per-op", 0, "fail if server-to-server messages per operation exceed this")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: distsys report [flags] [run-dir...]")
+		fmt.Fprintln(fs.Output(), "\nRun directories default to store/latest.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"store/latest"}
+	}
+
+	failed := false
+	enc := json.NewEncoder(os.Stdout)
+	for _, dir := range dirs {
+		run, err := store.Open(dir)
+		if err != nil {
+			return err
+		}
+		s := run.Summary()
+
+		if *asJSON {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		} else {
+			printSummary(s)
+		}
+
+		var reasons []string
+		if *requireValid && s.Valid != store.Valid {
+			reasons = append(reasons, fmt.Sprintf("run is %s", s.Valid))
+		}
+		if *maxP99 > 0 && s.Latency.P99 > *maxP99 {
+			reasons = append(reasons, fmt.Sprintf("p99 latency %s exceeds %s", s.Latency.P99, *maxP99))
+		}
+		if *minAvailability > 0 && s.Availability < *minAvailability {
+			reasons = append(reasons, fmt.Sprintf("availability %.4f is below %.4f", s.Availability, *minAvailability))
+		}
+		if *maxMsgsPerOp > 0 && s.MsgsPerOp > *maxMsgsPerOp {
+			reasons = append(reasons, fmt.Sprintf("%.2f msgs/op exceeds %.2f", s.MsgsPerOp, *maxMsgsPerOp))
+		}
+		if *maxServerMsgsPerOp > 0 && s.ServerMsgsPerOp > *maxServerMsgsPerOp {
+			reasons = append(reasons, fmt.Sprintf("%.2f server msgs/op exceeds %.2f", s.ServerMsgsPerOp, *maxServerMsgsPerOp))
+		}
+
+		for _, reason := range reasons {
+			log.Printf("%s: %s", run.Dir, reason)
+			failed = true
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func printSummary(s store.Summary) {
+	fmt.Printf("%s %s: %s\n", s.Name, s.Timestamp, s.Valid)
+	fmt.Printf("  ops           %d (%d ok, %d fail, %d info)\n", s.Ops, s.OK, s.Fail, s.Info)
+	fmt.Printf("  availability  %.2f%%\n", 100*s.Availability)
+	fmt.Printf("  msgs/op       %.2f (servers %.2f)\n", s.MsgsPerOp, s.ServerMsgsPerOp)
+	fmt.Printf("  latency       p50 %s  p95 %s  p99 %s  max %s\n",
+		round(s.Latency.P50), round(s.Latency.P95), round(s.Latency.P99), round(s.Latency.Max))
+}
+
+// round rounds a latency to a readable precision.
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/cluster"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/store"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// run runs a test against a local cluster of node binaries and prints a
+// summary of the results.
+func run(args []string) error {
+	cfg := cluster.NewConfig()
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.StringVar(&cfg.Workload, "w", "", "workload: "+strings.Join(workload.Names(), ", "))
+	fs.StringVar(&cfg.Bin, "bin", "", "node binary")
+	fs.IntVar(&cfg.NodeCount, "node-count", cfg.NodeCount, "number of nodes")
+	fs.DurationVar(&cfg.Client.TimeLimit, "time-limit", cfg.Client.TimeLimit, "duration of the test")
+	fs.Float64Var(&cfg.Client.Rate, "rate", cfg.Client.Rate, "operations per second; 0 is unlimited")
+	fs.IntVar(&cfg.Client.Concurrency, "concurrency", cfg.Client.Concurrency, "number of concurrent clients")
+	latency := fs.Int("latency", 0, "mean message latency in milliseconds")
+	fs.StringVar(&cfg.LatencyDist, "latency-dist", cfg.LatencyDist, "latency distribution: constant, uniform or exponential")
+	fs.DurationVar(&cfg.Client.Timeout, "timeout", cfg.Client.Timeout, "time to wait for each operation")
+	fs.DurationVar(&cfg.Client.Recovery, "recovery", cfg.Client.Recovery, "time to wait before final operations")
+	fs.Int64Var(&cfg.Client.Seed, "seed", time.Now().UnixNano(), "seed for generated operations & latencies")
+	fs.IntVar(&cfg.Client.KeyCount, "key-count", cfg.Client.KeyCount, "number of keys for keyed workloads")
+	fs.StringVar(&cfg.Client.KeyDist, "key-dist", cfg.Client.KeyDist, "key distribution: uniform or exponential")
+	mix := fs.String("mix", "", "relative weights of operations, e.g. read=1,add=3")
+	fs.IntVar(&cfg.Client.MaxTxnLen, "max-txn-length", cfg.Client.MaxTxnLen, "maximum micro-operations per transaction")
+	fs.StringVar(&cfg.Client.Consistency, "consistency-model", "", "consistency model to check transactions against (default strict-serializable)")
+	fs.StringVar(&cfg.Client.Topology, "topology", "", "broadcast topology, e.g. tree:4 (default grid)")
+	nem := fs.String("nemesis", "", "faults to inject: partition, pause, kill and/or clock, separated by commas")
+	fs.DurationVar(&cfg.NemesisInterval, "nemesis-interval", cfg.NemesisInterval, "time between faults")
+	fs.StringVar(&cfg.NemesisSchedule, "nemesis-schedule", "", "file of faults to inject instead of random ones")
+	fs.BoolVar(&cfg.Simulate, "simulate", false, "run as a deterministic simulation that replays exactly for the same seed")
+	fs.StringVar(&cfg.Store, "store", cfg.Store, "directory to write results to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: distsys run -w <workload> --bin <binary> [flags] [-- args...]")
+		fmt.Fprintln(fs.Output(), "\nArguments after the flags are passed to the node binary.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if cfg.Workload == "" || cfg.Bin == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *mix != "" {
+		m, err := parseMix(*mix)
+		if err != nil {
+			return err
+		}
+		cfg.Client.Mix = m
+	}
+	if *nem != "" {
+		cfg.Nemesis = strings.Split(*nem, ",")
+	}
+	cfg.Args = fs.Args()
+	cfg.Latency = time.Duration(*latency) * time.Millisecond
+	cfg.Command = strings.Join(os.Args, " ")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	dir, err := cluster.Run(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	r, err := store.Open(dir)
+	if err != nil {
+		return err
+	}
+	printSummary(r.Summary())
+	if seed, fp, ok := simulation(r.Results); ok {
+		fmt.Printf("  simulation    seed %v  fingerprint %v\n", seed, fp)
+	}
+	for _, e := range workloadErrors(r.Results) {
+		log.Print(e)
+	}
+	if r.Results == nil || r.Results.Valid != store.Valid {
+		return errFailed
+	}
+	return nil
+}
+
+// parseMix parses operation weights such as "read=1,add=3".
+func parseMix(s string) (map[string]float64, error) {
+	m := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		f, w, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix %q: want f=weight", part)
+		}
+		weight, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mix %q: %w", part, err)
+		}
+		m[strings.TrimSpace(f)] = weight
+	}
+	return m, nil
+}
+
+// simulation returns the seed & fingerprint of a simulated run.
+func simulation(r *store.Results) (seed, fingerprint any, ok bool) {
+	if r == nil {
+		return nil, nil, false
+	}
+	sim, ok := r.Raw[edn.Keyword("simulation")].(map[any]any)
+	return sim[edn.Keyword("seed")], sim[edn.Keyword("fingerprint")], ok
+}
+
+// workloadErrors returns the errors the workload checker reported.
+func workloadErrors(r *store.Results) []string {
+	if r == nil {
+		return nil
+	}
+	wl, _ := r.Raw[edn.Keyword("workload")].(map[any]any)
+	errs, _ := wl[edn.Keyword("errors")].([]any)
+	a := make([]string, 0, len(errs))
+	for _, e := range errs {
+		a = append(a, fmt.Sprint(e))
+	}
+	return a
+}
@@ -0,0 +1,245 @@
+package conform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// checker checks the messages written by nodes against the requests they
+// were sent.
+type checker struct {
+	mu         sync.Mutex
+	msgIDs     map[string]map[string]bool // msg_ids sent by each node
+	pending    map[request]bool           // client requests awaiting a reply
+	replied    map[request]bool
+	requests   int
+	replies    int
+	violations []Violation
+}
+
+// request identifies a request sent by a client to a node.
+type request struct {
+	node, client string
+	msgID        string
+}
+
+func newChecker() *checker {
+	return &checker{
+		msgIDs:  make(map[string]map[string]bool),
+		pending: make(map[request]bool),
+		replied: make(map[request]bool),
+	}
+}
+
+func (c *checker) request() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+}
+
+func (c *checker) reply() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies++
+}
+
+func (c *checker) violate(node, rule, text, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.violations = append(c.violations, Violation{Node: node, Rule: rule, Text: text, Line: line})
+}
+
+func (c *checker) report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &Report{
+		Requests:   c.requests,
+		Replies:    c.replies,
+		Violations: append([]Violation(nil), c.violations...),
+	}
+}
+
+// observe records the requests clients send to nodes, so that replies can be
+// matched against them.
+func (c *checker) observe(msg maelstrom.Message, dropped bool) {
+	if !simnet.IsClient(msg.Src) || simnet.IsClient(msg.Dest) {
+		return
+	}
+	body, err := decodeBody(msg.Body)
+	if err != nil {
+		return
+	}
+	if id, ok := body["msg_id"].(json.Number); ok {
+		c.mu.Lock()
+		c.pending[request{node: msg.Dest, client: msg.Src, msgID: id.String()}] = true
+		c.mu.Unlock()
+	}
+}
+
+// tap checks every line node id writes to r and passes on those that are
+// messages to w, for the network to route.
+func (c *checker) tap(id string, r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if c.check(id, line) {
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// check checks a line written by node id. Returns false if it is not a
+// message at all.
+func (c *checker) check(id string, line []byte) bool {
+	if len(bytes.TrimSpace(line)) == 0 {
+		c.violate(id, RuleJSON, "wrote a blank line to STDOUT", "")
+		return false
+	}
+
+	var msg struct {
+		Src  *string         `json:"src"`
+		Dest *string         `json:"dest"`
+		Body json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.violate(id, RuleJSON, "wrote a line to STDOUT that is not a JSON message", string(line))
+		return false
+	}
+	body, err := decodeBody(msg.Body)
+	if err != nil {
+		c.violate(id, RuleJSON, "sent a message whose body is not a JSON object", string(line))
+		return false
+	}
+
+	switch {
+	case msg.Src == nil:
+		c.violate(id, RuleSrc, "sent a message without a src", string(line))
+	case *msg.Src != id:
+		c.violate(id, RuleSrc, fmt.Sprintf("sent a message from %q, want its node ID %q", *msg.Src, id), string(line))
+	}
+	if msg.Dest == nil || *msg.Dest == "" {
+		c.violate(id, RuleDest, "sent a message without a dest", string(line))
+		return false
+	}
+	dest := *msg.Dest
+
+	typ, ok := body["type"].(string)
+	if !ok || typ == "" {
+		c.violate(id, RuleType, "sent a body without a string type", string(line))
+	}
+
+	// Only requests are answered by msg_id, so replies may reuse one, as
+	// echo replies that copy their request's body do.
+	_, isReply := body["in_reply_to"]
+	if v, ok := body["msg_id"]; ok {
+		if n, ok := v.(json.Number); !ok || !isInt(n) {
+			c.violate(id, RuleMsgID, fmt.Sprintf("sent msg_id %v, want an integer", v), string(line))
+		} else if !isReply && c.sent(id, n.String()) {
+			c.violate(id, RuleMsgID, fmt.Sprintf("reused msg_id %s", n), string(line))
+		}
+	}
+
+	if typ == "error" {
+		switch code, ok := body["code"].(json.Number); {
+		case !ok || !isInt(code):
+			c.violate(id, RuleError, fmt.Sprintf("sent an error with code %v, want an integer", body["code"]), string(line))
+		case code.String()[0] == '-':
+			c.violate(id, RuleError, fmt.Sprintf("sent an error with negative code %s", code), string(line))
+		}
+		if text, ok := body["text"]; ok {
+			if _, ok := text.(string); !ok {
+				c.violate(id, RuleError, fmt.Sprintf("sent an error with text %v, want a string", text), string(line))
+			}
+		}
+	}
+
+	if simnet.IsClient(dest) {
+		c.checkReply(id, dest, body, string(line))
+	}
+	return true
+}
+
+// checkReply checks that a message from node id to a client answers one of
+// the client's requests, exactly once.
+func (c *checker) checkReply(id, client string, body map[string]any, line string) {
+	v, ok := body["in_reply_to"]
+	if !ok {
+		c.violate(id, RuleInReplyTo, fmt.Sprintf("sent a message to client %s without in_reply_to", client), line)
+		return
+	}
+	n, ok := v.(json.Number)
+	if !ok || !isInt(n) {
+		c.violate(id, RuleInReplyTo, fmt.Sprintf("sent in_reply_to %v, want an integer", v), line)
+		return
+	}
+
+	req := request{node: id, client: client, msgID: n.String()}
+	c.mu.Lock()
+	pending, replied := c.pending[req], c.replied[req]
+	delete(c.pending, req)
+	c.replied[req] = true
+	c.mu.Unlock()
+
+	switch {
+	case replied:
+		c.violate(id, RuleInReplyTo, fmt.Sprintf("replied twice to msg_id %s of %s", n, client), line)
+	case !pending:
+		c.violate(id, RuleInReplyTo, fmt.Sprintf("replied to msg_id %s, which %s never sent", n, client), line)
+	}
+}
+
+// sent records that node id used msgID. Returns true if it already had.
+func (c *checker) sent(id, msgID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := c.msgIDs[id]
+	if ids == nil {
+		ids = make(map[string]bool)
+		c.msgIDs[id] = ids
+	}
+	dup := ids[msgID]
+	ids[msgID] = true
+	return dup
+}
+
+// decodeBody decodes a message body, keeping numbers as json.Number.
+func decodeBody(data []byte) (map[string]any, error) {
+	var body map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	} else if body == nil {
+		return nil, fmt.Errorf("body is null")
+	}
+	return body, nil
+}
+
+// isInt returns true if n is an integer.
+func isInt(n json.Number) bool {
+	_, err := n.Int64()
+	return err == nil
+}
+
+// typeOf returns the type of a request body, for messages.
+func typeOf(body any) string {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return "request"
+	}
+	var b maelstrom.MessageBody
+	if err := json.Unmarshal(buf, &b); err != nil || b.Type == "" {
+		return "request"
+	}
+	return fmt.Sprintf("%q", b.Type)
+}
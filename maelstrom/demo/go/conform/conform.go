@@ -0,0 +1,261 @@
+// Package conform checks that node binaries follow Maelstrom's protocol, as
+// described in doc/protocol.md: nodes answer "init" with "init_ok", set
+// "in_reply_to" on every reply to a client, use unique request IDs, send
+// well-formed error bodies and write nothing but JSON messages to STDOUT.
+//
+// Run starts the binaries on a simulated network, initializes them and
+// drives a short exercise of a workload's requests through them, checking
+// every line the nodes write along the way.
+package conform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+// Rules a violation can break.
+const (
+	RuleJSON      = "json"        // STDOUT carries only JSON messages
+	RuleSrc       = "src"         // messages are sent from the node's ID
+	RuleDest      = "dest"        // messages have a destination
+	RuleType      = "type"        // bodies have a "type"
+	RuleInit      = "init"        // "init" is answered with "init_ok"
+	RuleMsgID     = "msg-id"      // message IDs are unique integers
+	RuleInReplyTo = "in-reply-to" // replies to clients name their request
+	RuleError     = "error-body"  // error bodies have an integer code
+	RuleReply     = "reply"       // every client request is answered
+	RuleExit      = "exit"        // nodes exit cleanly when STDIN closes
+)
+
+// Default configuration values.
+const (
+	DefaultOps         = 20
+	DefaultTimeout     = time.Second
+	DefaultExitTimeout = 5 * time.Second
+)
+
+// Config describes a conformance run.
+type Config struct {
+	// Node binary & its arguments.
+	Bin  string
+	Args []string
+
+	// Number of nodes, named n0, n1, ...
+	NodeCount int
+
+	// Workload whose requests exercise the nodes, e.g. "echo", and the
+	// number of operations to perform after its setup.
+	Workload string
+	Ops      int
+
+	// Time to wait for each reply.
+	Timeout time.Duration
+
+	// Seed for generated operations.
+	Seed int64
+
+	// Stderr receives the nodes' logs. Discarded if nil.
+	Stderr io.Writer
+}
+
+// NewConfig returns a configuration exercising a single node with the echo
+// workload.
+func NewConfig() Config {
+	return Config{
+		NodeCount: 1,
+		Workload:  "echo",
+		Ops:       DefaultOps,
+		Timeout:   DefaultTimeout,
+	}
+}
+
+// Violation is a breach of the protocol by a node.
+type Violation struct {
+	Node string
+	Rule string
+	Text string
+
+	// Line is the offending output, if any.
+	Line string
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s: %s: %s", v.Node, v.Rule, v.Text)
+	if v.Line != "" {
+		s += fmt.Sprintf("\n    %s", v.Line)
+	}
+	return s
+}
+
+// Report is the outcome of a conformance run.
+type Report struct {
+	// Client requests sent to the nodes and the replies received.
+	Requests int
+	Replies  int
+
+	Violations []Violation
+}
+
+// OK returns true if no violations were found.
+func (r *Report) OK() bool { return len(r.Violations) == 0 }
+
+// Run starts cfg.NodeCount instances of the binary, exercises them and
+// returns the violations found. Only failures to run the exercise, such as a
+// missing binary, are returned as errors.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.NodeCount < 1 {
+		return nil, fmt.Errorf("invalid node count %d", cfg.NodeCount)
+	}
+	wcfg := workload.NewConfig()
+	wcfg.Seed = cfg.Seed
+	w, err := workload.New(cfg.Workload, wcfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nw := simnet.New()
+	chk := newChecker()
+	nw.Observe(chk.observe)
+
+	ids := make([]string, cfg.NodeCount)
+	procs := make([]*process, 0, len(ids))
+	for i := range ids {
+		ids[i] = fmt.Sprintf("n%d", i)
+		p, err := start(ctx, cfg, nw, chk, ids[i])
+		if err != nil {
+			nw.Close()
+			stop(procs, nil)
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+
+	exercise(ctx, cfg, nw, chk, w, ids)
+	nw.Close()
+	stop(procs, chk)
+	return chk.report(), nil
+}
+
+// process is a running node binary.
+type process struct {
+	id  string
+	cmd *exec.Cmd
+
+	// Closed once all of the node's output has been checked.
+	tapped chan struct{}
+}
+
+// start starts the node binary for id, checking its output before the
+// network routes it.
+func start(ctx context.Context, cfg Config, nw *simnet.Network, chk *checker, id string) (*process, error) {
+	cmd := exec.CommandContext(ctx, cfg.Bin, cfg.Args...)
+	cmd.Stderr = cfg.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", id, err)
+	}
+
+	p := &process{id: id, cmd: cmd, tapped: make(chan struct{})}
+	r, w := io.Pipe()
+	go func() {
+		defer close(p.tapped)
+		chk.tap(id, stdout, w)
+		w.Close()
+	}()
+	nw.AttachNode(id, stdin, r)
+	return p, nil
+}
+
+// exercise initializes the nodes and sends them the workload's requests.
+// Requests that go unanswered are violations; the exercise carries on.
+func exercise(ctx context.Context, cfg Config, nw *simnet.Network, chk *checker, w workload.Workload, ids []string) {
+	rpc := func(c *simnet.Client, node string, body any) (maelstrom.Message, error) {
+		ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+		chk.request()
+		resp, err := c.RPC(ctx, node, body)
+		if errors.Is(err, context.DeadlineExceeded) {
+			chk.violate(node, RuleReply, fmt.Sprintf("no reply to %s within %s", typeOf(body), cfg.Timeout), "")
+		} else if err == nil || maelstrom.ErrorCode(err) >= 0 {
+			chk.reply()
+		}
+		return resp, err
+	}
+
+	c0 := nw.NewClient("c0")
+	for _, id := range ids {
+		resp, err := rpc(c0, id, maelstrom.InitMessageBody{
+			MessageBody: maelstrom.MessageBody{Type: "init"},
+			NodeID:      id,
+			NodeIDs:     ids,
+		})
+		if err == nil && resp.Type() != "init_ok" {
+			chk.violate(id, RuleInit, fmt.Sprintf("replied to init with %q, want \"init_ok\"", resp.Type()), string(resp.Body))
+		} else if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			chk.violate(id, RuleInit, fmt.Sprintf("replied to init with %s", err), "")
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Setup fails only if a request goes unanswered or is rejected, which
+	// is reported above or is up to the node.
+	client := &client{c: c0, rpc: rpc}
+	w.Setup(ctx, client, ids)
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Ops && ctx.Err() == nil; i++ {
+		client.c = nw.NewClient(fmt.Sprintf("c%d", i+1))
+		w.Invoke(ctx, client, ids[i%len(ids)], w.Generate(rng))
+	}
+}
+
+// client adapts exercise's checked RPCs to workload.Client.
+type client struct {
+	c   *simnet.Client
+	rpc func(c *simnet.Client, node string, body any) (maelstrom.Message, error)
+}
+
+func (c *client) RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	return c.rpc(c.c, dest, body)
+}
+
+// stop waits for the nodes to exit now that their STDIN is closed, killing
+// those that don't within DefaultExitTimeout. Unclean exits are reported to
+// chk, if set.
+func stop(procs []*process, chk *checker) {
+	for _, p := range procs {
+		// Wait closes STDOUT, so the rest of the output must be checked
+		// first.
+		select {
+		case <-p.tapped:
+			if err := p.cmd.Wait(); err != nil && chk != nil {
+				chk.violate(p.id, RuleExit, fmt.Sprintf("exited with %s after STDIN closed", err), "")
+			}
+		case <-time.After(DefaultExitTimeout):
+			p.cmd.Process.Kill()
+			<-p.tapped
+			p.cmd.Wait()
+			if chk != nil {
+				chk.violate(p.id, RuleExit, fmt.Sprintf("still running %s after STDIN closed", DefaultExitTimeout), "")
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package conform_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/conform"
+)
+
+// TestMain runs the test binary as a node when started by a conformance run:
+// a well-behaved echo node, or one that breaks the protocol.
+func TestMain(m *testing.M) {
+	switch os.Getenv("CONFORM_TEST_NODE") {
+	case "":
+		os.Exit(m.Run())
+	case "bad":
+		runBadNode()
+		return
+	}
+
+	n := maelstrom.NewNode()
+	n.Handle("echo", func(msg maelstrom.Message) error {
+		var body map[string]any
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		body["type"] = "echo_ok"
+		return n.Reply(msg, body)
+	})
+	if err := n.Run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// runBadNode answers init correctly but answers each echo with debug output,
+// a request reusing a msg_id, a second reply and a malformed error.
+func runBadNode() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg struct {
+			Src  string
+			Body struct {
+				Type  string `json:"type"`
+				MsgID int    `json:"msg_id"`
+			}
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			os.Exit(1)
+		}
+
+		id := msg.Body.MsgID
+		switch msg.Body.Type {
+		case "init":
+			fmt.Printf(`{"src":"n0","dest":%q,"body":{"type":"init_ok","in_reply_to":%d}}`+"\n", msg.Src, id)
+		case "echo":
+			fmt.Println("received echo")
+			fmt.Println(`{"src":"n0","dest":"n0","body":{"type":"ping","msg_id":1}}`)
+			fmt.Printf(`{"src":"n0","dest":%q,"body":{"type":"echo_ok","in_reply_to":%d}}`+"\n", msg.Src, id)
+			fmt.Printf(`{"src":"n0","dest":%q,"body":{"type":"echo_ok","in_reply_to":%d}}`+"\n", msg.Src, id)
+			fmt.Printf(`{"src":"n0","dest":%q,"body":{"type":"error","code":"crash"}}`+"\n", msg.Src)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("CONFORM_TEST_NODE", "good")
+
+	cfg := conform.NewConfig()
+	cfg.Bin = os.Args[0]
+	cfg.NodeCount = 2
+	cfg.Ops = 5
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := conform.Run(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	} else if !r.OK() {
+		t.Fatalf("unexpected violations: %v", r.Violations)
+	} else if got, want := r.Requests, 7; got != want {
+		t.Fatalf("requests=%d, want %d", got, want)
+	} else if got, want := r.Replies, 7; got != want {
+		t.Fatalf("replies=%d, want %d", got, want)
+	}
+}
+
+// Ensure each way the bad node breaks the protocol is reported.
+func TestRun_Violations(t *testing.T) {
+	t.Setenv("CONFORM_TEST_NODE", "bad")
+
+	cfg := conform.NewConfig()
+	cfg.Bin = os.Args[0]
+	cfg.Ops = 2
+	cfg.Timeout = 200 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := conform.Run(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := make(map[string]int)
+	for _, v := range r.Violations {
+		if v.Node != "n0" {
+			t.Errorf("unexpected node: %s", v)
+		}
+		rules[v.Rule]++
+	}
+	for rule, want := range map[string]int{
+		conform.RuleJSON:      2,
+		conform.RuleMsgID:     1,
+		conform.RuleInReplyTo: 4,
+		conform.RuleError:     2,
+	} {
+		if got := rules[rule]; got != want {
+			t.Errorf("%s violations=%d, want %d: %v", rule, got, want, r.Violations)
+		}
+	}
+	if len(rules) != 4 {
+		t.Fatalf("unexpected violations: %v", r.Violations)
+	}
+}
+
+func TestRun_ErrMissingBinary(t *testing.T) {
+	cfg := conform.NewConfig()
+	cfg.Bin = "/no/such/binary"
+	if _, err := conform.Run(context.Background(), cfg); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,125 @@
+package crdt
+
+import "encoding/json"
+
+// GCounter is a grow-only counter. Each node increments its own entry and the
+// value is the sum of all entries.
+type GCounter struct {
+	counts map[string]uint64
+}
+
+// NewGCounter returns an empty counter.
+func NewGCounter() *GCounter {
+	return &GCounter{}
+}
+
+// Inc adds n to node's entry.
+func (c *GCounter) Inc(node string, n uint64) {
+	if n == 0 {
+		return
+	}
+	if c.counts == nil {
+		c.counts = make(map[string]uint64)
+	}
+	c.counts[node] += n
+}
+
+// Get returns node's entry.
+func (c *GCounter) Get(node string) uint64 {
+	return c.counts[node]
+}
+
+// Value returns the total count.
+func (c *GCounter) Value() uint64 {
+	var sum uint64
+	for _, n := range c.counts {
+		sum += n
+	}
+	return sum
+}
+
+// Merge takes the maximum of each node's entry.
+func (c *GCounter) Merge(other *GCounter) {
+	for node, n := range other.counts {
+		if n > c.counts[node] {
+			if c.counts == nil {
+				c.counts = make(map[string]uint64)
+			}
+			c.counts[node] = n
+		}
+	}
+}
+
+// Delta returns the entries that are ahead of known.
+func (c *GCounter) Delta(known *GCounter) *GCounter {
+	delta := NewGCounter()
+	for node, n := range c.counts {
+		if n > known.counts[node] {
+			delta.Inc(node, n)
+		}
+	}
+	return delta
+}
+
+// Clone returns a deep copy of the counter.
+func (c *GCounter) Clone() *GCounter {
+	other := NewGCounter()
+	other.Merge(c)
+	return other
+}
+
+// MarshalJSON encodes the counter as an object of node IDs to counts.
+func (c GCounter) MarshalJSON() ([]byte, error) {
+	if c.counts == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c.counts)
+}
+
+// UnmarshalJSON decodes a counter encoded by MarshalJSON.
+func (c *GCounter) UnmarshalJSON(data []byte) error {
+	c.counts = nil
+	return json.Unmarshal(data, &c.counts)
+}
+
+// PNCounter is a counter that supports decrements. It pairs a G-Counter of
+// increments with one of decrements.
+type PNCounter struct {
+	P GCounter `json:"p"`
+	N GCounter `json:"n"`
+}
+
+// NewPNCounter returns an empty counter.
+func NewPNCounter() *PNCounter {
+	return &PNCounter{}
+}
+
+// Inc adds n on behalf of node.
+func (c *PNCounter) Inc(node string, n uint64) { c.P.Inc(node, n) }
+
+// Dec subtracts n on behalf of node.
+func (c *PNCounter) Dec(node string, n uint64) { c.N.Inc(node, n) }
+
+// Value returns increments minus decrements.
+func (c *PNCounter) Value() int64 {
+	return int64(c.P.Value()) - int64(c.N.Value())
+}
+
+// Merge merges increments & decrements independently.
+func (c *PNCounter) Merge(other *PNCounter) {
+	c.P.Merge(&other.P)
+	c.N.Merge(&other.N)
+}
+
+// Delta returns the increments & decrements that known has not seen.
+func (c *PNCounter) Delta(known *PNCounter) *PNCounter {
+	return &PNCounter{
+		P: *c.P.Delta(&known.P),
+		N: *c.N.Delta(&known.N),
+	}
+}
+
+// Clone returns a deep copy of the counter.
+func (c *PNCounter) Clone() *PNCounter {
+	return &PNCounter{P: *c.P.Clone(), N: *c.N.Clone()}
+}
@@ -0,0 +1,41 @@
+// Package crdt implements state-based conflict-free replicated data types.
+//
+// Every type provides Merge, which is commutative, associative and
+// idempotent, so replicas converge no matter how often or in what order
+// states are exchanged. Delta(known) extracts the part of a state that a
+// peer with state known is missing, such that known.Merge(s.Delta(known))
+// equals known.Merge(s), which keeps gossip small. Types encode to compact,
+// deterministic JSON so they can be embedded directly in message bodies.
+//
+// Replicas are identified by node ID. Each node must only mutate its own
+// replica, and the zero value of each type is an empty state ready to use.
+// Types are not safe for concurrent use.
+package crdt
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// sortByJSON sorts values by their JSON encoding. This gives a stable order
+// for element types that have no natural ordering.
+func sortByJSON[T any](values []T) {
+	keys := make([][]byte, len(values))
+	for i, v := range values {
+		keys[i], _ = json.Marshal(v)
+	}
+	sort.Sort(byKey[T]{values, keys})
+}
+
+type byKey[T any] struct {
+	values []T
+	keys   [][]byte
+}
+
+func (s byKey[T]) Len() int           { return len(s.values) }
+func (s byKey[T]) Less(i, j int) bool { return bytes.Compare(s.keys[i], s.keys[j]) < 0 }
+func (s byKey[T]) Swap(i, j int) {
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
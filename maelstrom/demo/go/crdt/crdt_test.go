@@ -0,0 +1,238 @@
+package crdt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/crdt"
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+)
+
+// state is the interface shared by every CRDT type.
+type state[S any] interface {
+	Merge(S)
+	Delta(S) S
+	Clone() S
+}
+
+var nodes = []string{"n0", "n1", "n2"}
+
+// checkLaws builds three replicas from random operations interleaved with
+// random merges, then verifies the semilattice laws, the delta property and
+// a JSON round trip for each seed.
+func checkLaws[S state[S]](t *testing.T, newState func() S, op func(rnd *rand.Rand, s S, node string)) {
+	t.Helper()
+
+	for seed := int64(0); seed < 200; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		replicas := []S{newState(), newState(), newState()}
+		for i := 0; i < 30; i++ {
+			j := rnd.Intn(len(replicas))
+			if rnd.Intn(4) == 0 {
+				replicas[j].Merge(replicas[rnd.Intn(len(replicas))])
+			} else {
+				op(rnd, replicas[j], nodes[j])
+			}
+		}
+		a, b, c := replicas[0], replicas[1], replicas[2]
+
+		merge := func(x, y S) S {
+			x = x.Clone()
+			x.Merge(y)
+			return x
+		}
+
+		if x, y := merge(a, b), merge(b, a); !equal(t, x, y) {
+			t.Fatalf("seed %d: merge is not commutative: %s != %s", seed, encode(t, x), encode(t, y))
+		}
+		if x, y := merge(merge(a, b), c), merge(a, merge(b, c)); !equal(t, x, y) {
+			t.Fatalf("seed %d: merge is not associative: %s != %s", seed, encode(t, x), encode(t, y))
+		}
+		if x := merge(a, a); !equal(t, x, a) {
+			t.Fatalf("seed %d: merge is not idempotent: %s != %s", seed, encode(t, x), encode(t, a))
+		}
+		if x, y := merge(b, a.Delta(b)), merge(b, a); !equal(t, x, y) {
+			t.Fatalf("seed %d: delta does not match full merge: %s != %s", seed, encode(t, x), encode(t, y))
+		}
+
+		decoded := newState()
+		if err := json.Unmarshal(encode(t, a), decoded); err != nil {
+			t.Fatal(err)
+		} else if !equal(t, decoded, a) {
+			t.Fatalf("seed %d: JSON round trip: %s != %s", seed, encode(t, decoded), encode(t, a))
+		}
+	}
+}
+
+func encode(tb testing.TB, v any) []byte {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return buf
+}
+
+// equal compares states by their deterministic JSON encoding.
+func equal(tb testing.TB, a, b any) bool {
+	return bytes.Equal(encode(tb, a), encode(tb, b))
+}
+
+// clocks hands out hybrid timestamps which are unique per node but often
+// tie across nodes.
+type clocks map[string]int32
+
+func (c clocks) now(rnd *rand.Rand, node string) hlc.Timestamp {
+	c[node]++
+	return hlc.Timestamp{Wall: rnd.Int63n(3), Logical: c[node]}
+}
+
+func TestGCounter(t *testing.T) {
+	checkLaws(t, crdt.NewGCounter, func(rnd *rand.Rand, s *crdt.GCounter, node string) {
+		s.Inc(node, uint64(rnd.Intn(5)))
+	})
+
+	a, b := crdt.NewGCounter(), crdt.NewGCounter()
+	a.Inc("n0", 3)
+	b.Inc("n1", 4)
+	b.Merge(a)
+	if v := b.Value(); v != 7 {
+		t.Fatalf("value=%d, want 7", v)
+	} else if buf := encode(t, b); string(buf) != `{"n0":3,"n1":4}` {
+		t.Fatalf("json=%s", buf)
+	}
+}
+
+func TestPNCounter(t *testing.T) {
+	checkLaws(t, crdt.NewPNCounter, func(rnd *rand.Rand, s *crdt.PNCounter, node string) {
+		if rnd.Intn(2) == 0 {
+			s.Inc(node, uint64(rnd.Intn(5)))
+		} else {
+			s.Dec(node, uint64(rnd.Intn(5)))
+		}
+	})
+
+	c := crdt.NewPNCounter()
+	c.Inc("n0", 2)
+	c.Dec("n1", 5)
+	if v := c.Value(); v != -3 {
+		t.Fatalf("value=%d, want -3", v)
+	}
+}
+
+func TestGSet(t *testing.T) {
+	checkLaws(t, crdt.NewGSet[int], func(rnd *rand.Rand, s *crdt.GSet[int], node string) {
+		s.Add(rnd.Intn(10))
+	})
+}
+
+func TestTwoPSet(t *testing.T) {
+	checkLaws(t, crdt.NewTwoPSet[int], func(rnd *rand.Rand, s *crdt.TwoPSet[int], node string) {
+		if e := rnd.Intn(10); rnd.Intn(3) == 0 {
+			s.Remove(e)
+		} else {
+			s.Add(e)
+		}
+	})
+
+	s := crdt.NewTwoPSet[string]()
+	s.Add("x")
+	if !s.Remove("x") {
+		t.Fatal("expected remove to succeed")
+	}
+	s.Add("x")
+	if s.Contains("x") {
+		t.Fatal("removed element must not be re-added")
+	} else if s.Remove("y") {
+		t.Fatal("expected remove of missing element to fail")
+	}
+}
+
+func TestORSet(t *testing.T) {
+	checkLaws(t, crdt.NewORSet[int], func(rnd *rand.Rand, s *crdt.ORSet[int], node string) {
+		if e := rnd.Intn(10); rnd.Intn(3) == 0 {
+			s.Remove(e)
+		} else {
+			s.Add(node, e)
+		}
+	})
+
+	// A concurrent add wins over a remove.
+	a, b := crdt.NewORSet[string](), crdt.NewORSet[string]()
+	a.Add("n0", "x")
+	b.Merge(a)
+	b.Remove("x")
+	a.Add("n0", "x")
+	a.Merge(b)
+	if !a.Contains("x") {
+		t.Fatal("expected concurrent add to win")
+	}
+
+	// An observed remove wins.
+	b.Merge(a)
+	b.Remove("x")
+	a.Merge(b)
+	if a.Contains("x") {
+		t.Fatal("expected observed remove to win")
+	}
+}
+
+func TestLWWRegister(t *testing.T) {
+	c := clocks{}
+	checkLaws(t, crdt.NewLWWRegister[int], func(rnd *rand.Rand, s *crdt.LWWRegister[int], node string) {
+		s.Set(rnd.Intn(100), c.now(rnd, node), node)
+	})
+
+	r := crdt.NewLWWRegister[string]()
+	if _, ok := r.Get(); ok {
+		t.Fatal("expected unset register")
+	}
+	r.Set("new", hlc.Timestamp{Wall: 2}, "n0")
+	r.Set("old", hlc.Timestamp{Wall: 1}, "n1")
+	if v, _ := r.Get(); v != "new" {
+		t.Fatalf("value=%q, want new", v)
+	}
+}
+
+func TestLWWMap(t *testing.T) {
+	c := clocks{}
+	checkLaws(t, crdt.NewLWWMap[int, string], func(rnd *rand.Rand, s *crdt.LWWMap[int, string], node string) {
+		if k := rnd.Intn(5); rnd.Intn(3) == 0 {
+			s.Delete(k, c.now(rnd, node), node)
+		} else {
+			s.Set(k, node, c.now(rnd, node), node)
+		}
+	})
+
+	m := crdt.NewLWWMap[string, int]()
+	m.Set("a", 1, hlc.Timestamp{Wall: 1}, "n0")
+	m.Set("b", 2, hlc.Timestamp{Wall: 1}, "n0")
+	m.Delete("a", hlc.Timestamp{Wall: 2}, "n1")
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("expected a to be deleted")
+	} else if keys := m.Keys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("keys=%v", keys)
+	}
+}
+
+func TestMVRegister(t *testing.T) {
+	checkLaws(t, crdt.NewMVRegister[int], func(rnd *rand.Rand, s *crdt.MVRegister[int], node string) {
+		s.Set(node, rnd.Intn(100))
+	})
+
+	// Concurrent writes are kept until a later write observes them.
+	a, b := crdt.NewMVRegister[string](), crdt.NewMVRegister[string]()
+	a.Set("n0", "x")
+	b.Set("n1", "y")
+	a.Merge(b)
+	if got := a.Values(); !reflect.DeepEqual(got, []string{"x", "y"}) {
+		t.Fatalf("values=%v", got)
+	}
+	a.Set("n0", "z")
+	b.Merge(a)
+	if got := b.Values(); !reflect.DeepEqual(got, []string{"z"}) {
+		t.Fatalf("values=%v", got)
+	}
+}
@@ -0,0 +1,125 @@
+package crdt
+
+import (
+	"encoding/json"
+
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+)
+
+// LWWMap is a map of last-writer-wins registers. Deletes are writes of a
+// tombstone, so a delete only wins over writes with older timestamps.
+type LWWMap[K comparable, V any] struct {
+	entries map[K]*lwwMapEntry[K, V]
+}
+
+type lwwMapEntry[K comparable, V any] struct {
+	Key     K             `json:"k"`
+	Val     V             `json:"v,omitempty"`
+	Time    hlc.Timestamp `json:"t"`
+	Node    string        `json:"n"`
+	Deleted bool          `json:"d,omitempty"`
+}
+
+// NewLWWMap returns an empty map.
+func NewLWWMap[K comparable, V any]() *LWWMap[K, V] {
+	return &LWWMap[K, V]{}
+}
+
+// Set writes v to key at ts on behalf of node.
+func (m *LWWMap[K, V]) Set(key K, v V, ts hlc.Timestamp, node string) {
+	m.put(&lwwMapEntry[K, V]{Key: key, Val: v, Time: ts, Node: node})
+}
+
+// Delete removes key at ts on behalf of node.
+func (m *LWWMap[K, V]) Delete(key K, ts hlc.Timestamp, node string) {
+	m.put(&lwwMapEntry[K, V]{Key: key, Time: ts, Node: node, Deleted: true})
+}
+
+// Get returns the value for key. Returns false if the key is unset or
+// deleted.
+func (m *LWWMap[K, V]) Get(key K) (V, bool) {
+	e := m.entries[key]
+	if e == nil || e.Deleted {
+		var zero V
+		return zero, false
+	}
+	return e.Val, true
+}
+
+// Keys returns the live keys ordered by their JSON encoding.
+func (m *LWWMap[K, V]) Keys() []K {
+	var keys []K
+	for k, e := range m.entries {
+		if !e.Deleted {
+			keys = append(keys, k)
+		}
+	}
+	sortByJSON(keys)
+	return keys
+}
+
+// Len returns the number of live keys.
+func (m *LWWMap[K, V]) Len() int {
+	return len(m.Keys())
+}
+
+// Merge keeps the newest write for each key.
+func (m *LWWMap[K, V]) Merge(other *LWWMap[K, V]) {
+	for _, e := range other.entries {
+		m.put(e)
+	}
+}
+
+// Delta returns the keys whose newest write known has not seen.
+func (m *LWWMap[K, V]) Delta(known *LWWMap[K, V]) *LWWMap[K, V] {
+	delta := NewLWWMap[K, V]()
+	for k, e := range m.entries {
+		if prev := known.entries[k]; prev == nil || lwwNewer(e.Time, e.Node, prev.Time, prev.Node) {
+			delta.put(e)
+		}
+	}
+	return delta
+}
+
+// Clone returns a copy of the map. Values are copied shallowly.
+func (m *LWWMap[K, V]) Clone() *LWWMap[K, V] {
+	other := NewLWWMap[K, V]()
+	other.Merge(m)
+	return other
+}
+
+// put stores a copy of e if it is newer than the current entry for its key.
+func (m *LWWMap[K, V]) put(e *lwwMapEntry[K, V]) {
+	if prev := m.entries[e.Key]; prev != nil && !lwwNewer(e.Time, e.Node, prev.Time, prev.Node) {
+		return
+	}
+	if m.entries == nil {
+		m.entries = make(map[K]*lwwMapEntry[K, V])
+	}
+	copied := *e
+	m.entries[e.Key] = &copied
+}
+
+// MarshalJSON encodes the entries, including tombstones, as an array sorted
+// by key.
+func (m LWWMap[K, V]) MarshalJSON() ([]byte, error) {
+	entries := make([]*lwwMapEntry[K, V], 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	sortByJSON(entries)
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes a map encoded by MarshalJSON.
+func (m *LWWMap[K, V]) UnmarshalJSON(data []byte) error {
+	var entries []*lwwMapEntry[K, V]
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	m.entries = nil
+	for _, e := range entries {
+		m.put(e)
+	}
+	return nil
+}
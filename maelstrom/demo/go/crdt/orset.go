@@ -0,0 +1,173 @@
+package crdt
+
+import (
+	"encoding/json"
+
+	"github.com/jepsen-io/maelstrom/demo/go/vclock"
+)
+
+// ORSet is an observed-remove set with add-wins semantics. Each add is
+// tagged with a unique dot (node, counter); a remove only cancels the dots it
+// has observed, so an add concurrent with a remove survives.
+//
+// Removed elements leave no tombstones. Instead, a causal context records
+// every dot the replica has seen, which is enough to tell a removed element
+// from one that hasn't arrived yet.
+type ORSet[T comparable] struct {
+	entries map[T]vclock.Clock // live dots per element, one per node
+	ctx     vclock.Clock       // every dot seen
+}
+
+// NewORSet returns an empty set.
+func NewORSet[T comparable]() *ORSet[T] {
+	return &ORSet[T]{}
+}
+
+// Add adds e on behalf of node.
+func (s *ORSet[T]) Add(node string, e T) {
+	s.init()
+	s.entries[e] = vclock.Clock{node: s.ctx.Tick(node)}
+}
+
+// Remove removes e, cancelling every add observed so far.
+func (s *ORSet[T]) Remove(e T) {
+	delete(s.entries, e)
+}
+
+// Contains returns true if e is in the set.
+func (s *ORSet[T]) Contains(e T) bool {
+	_, ok := s.entries[e]
+	return ok
+}
+
+// Len returns the number of elements.
+func (s *ORSet[T]) Len() int {
+	return len(s.entries)
+}
+
+// Elements returns the elements ordered by their JSON encoding.
+func (s *ORSet[T]) Elements() []T {
+	elems := make([]T, 0, len(s.entries))
+	for e := range s.entries {
+		elems = append(elems, e)
+	}
+	sortByJSON(elems)
+	return elems
+}
+
+// Merge keeps dots present in both replicas, plus dots present in one that
+// the other has not yet seen. Dots that one replica has seen but no longer
+// holds were removed.
+func (s *ORSet[T]) Merge(other *ORSet[T]) {
+	s.init()
+
+	for e := range other.entries {
+		if _, ok := s.entries[e]; !ok {
+			s.entries[e] = nil
+		}
+	}
+
+	for e, dots := range s.entries {
+		theirs := other.entries[e]
+		merged := vclock.New()
+		for node, c := range dots {
+			if theirs[node] == c || c > other.ctx.Get(node) {
+				merged[node] = c
+			}
+		}
+		for node, c := range theirs {
+			if dots[node] == c || c > s.ctx.Get(node) {
+				merged[node] = c
+			}
+		}
+
+		if len(merged) == 0 {
+			delete(s.entries, e)
+		} else {
+			s.entries[e] = merged
+		}
+	}
+	s.ctx.Merge(other.ctx)
+}
+
+// Delta returns the elements known has not seen added, the current state of
+// the elements known holds, and this replica's causal context so that
+// removals carry over.
+func (s *ORSet[T]) Delta(known *ORSet[T]) *ORSet[T] {
+	delta := NewORSet[T]()
+	delta.init()
+	delta.ctx.Merge(s.ctx)
+
+	for e, dots := range s.entries {
+		include := known.Contains(e)
+		for node, c := range dots {
+			if c > known.ctx.Get(node) {
+				include = true
+			}
+		}
+		if include {
+			delta.entries[e] = dots.Copy()
+		}
+	}
+	return delta
+}
+
+// Clone returns a deep copy of the set.
+func (s *ORSet[T]) Clone() *ORSet[T] {
+	other := NewORSet[T]()
+	other.init()
+	for e, dots := range s.entries {
+		other.entries[e] = dots.Copy()
+	}
+	other.ctx.Merge(s.ctx)
+	return other
+}
+
+func (s *ORSet[T]) init() {
+	if s.entries == nil {
+		s.entries = make(map[T]vclock.Clock)
+	}
+	if s.ctx == nil {
+		s.ctx = vclock.New()
+	}
+}
+
+// orSetJSON is the encoded form of an ORSet.
+type orSetJSON[T comparable] struct {
+	Entries []orSetEntry[T] `json:"e"`
+	Context vclock.Clock    `json:"c"`
+}
+
+type orSetEntry[T comparable] struct {
+	Elem T            `json:"v"`
+	Dots vclock.Clock `json:"d"`
+}
+
+// MarshalJSON encodes the elements, each with its dots, and the causal
+// context.
+func (s ORSet[T]) MarshalJSON() ([]byte, error) {
+	v := orSetJSON[T]{Entries: []orSetEntry[T]{}, Context: s.ctx}
+	if v.Context == nil {
+		v.Context = vclock.New()
+	}
+	for _, e := range (&s).Elements() {
+		v.Entries = append(v.Entries, orSetEntry[T]{Elem: e, Dots: s.entries[e]})
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a set encoded by MarshalJSON.
+func (s *ORSet[T]) UnmarshalJSON(data []byte) error {
+	var v orSetJSON[T]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	s.entries, s.ctx = nil, nil
+	s.init()
+	s.ctx.Merge(v.Context)
+	for _, entry := range v.Entries {
+		s.entries[entry.Elem] = entry.Dots
+	}
+	return nil
+}
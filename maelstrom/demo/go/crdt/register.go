@@ -0,0 +1,167 @@
+package crdt
+
+import (
+	"encoding/json"
+
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+	"github.com/jepsen-io/maelstrom/demo/go/vclock"
+)
+
+// LWWRegister is a last-writer-wins register. Writes are ordered by hybrid
+// logical timestamp, with the writing node's ID breaking ties.
+type LWWRegister[T any] struct {
+	Val  T             `json:"v"`
+	Time hlc.Timestamp `json:"t"`
+	Node string        `json:"n"`
+}
+
+// NewLWWRegister returns an unset register.
+func NewLWWRegister[T any]() *LWWRegister[T] {
+	return &LWWRegister[T]{}
+}
+
+// Set writes v at ts on behalf of node. Has no effect if the register holds
+// a newer write.
+func (r *LWWRegister[T]) Set(v T, ts hlc.Timestamp, node string) {
+	r.Merge(&LWWRegister[T]{Val: v, Time: ts, Node: node})
+}
+
+// Get returns the current value. Returns false if the register is unset.
+func (r *LWWRegister[T]) Get() (T, bool) {
+	return r.Val, !r.Time.IsZero()
+}
+
+// Merge keeps the newer of the two writes.
+func (r *LWWRegister[T]) Merge(other *LWWRegister[T]) {
+	if lwwNewer(other.Time, other.Node, r.Time, r.Node) {
+		*r = *other
+	}
+}
+
+// Delta returns the register if it is newer than known, or an unset
+// register otherwise.
+func (r *LWWRegister[T]) Delta(known *LWWRegister[T]) *LWWRegister[T] {
+	if lwwNewer(r.Time, r.Node, known.Time, known.Node) {
+		return r.Clone()
+	}
+	return NewLWWRegister[T]()
+}
+
+// Clone returns a copy of the register. The value itself is copied shallowly.
+func (r *LWWRegister[T]) Clone() *LWWRegister[T] {
+	other := *r
+	return &other
+}
+
+// lwwNewer reports whether write a should replace write b.
+func lwwNewer(aTime hlc.Timestamp, aNode string, bTime hlc.Timestamp, bNode string) bool {
+	if c := aTime.Compare(bTime); c != 0 {
+		return c > 0
+	}
+	return aNode > bNode
+}
+
+// MVRegister is a multi-value register. Concurrent writes are all kept as
+// siblings until a later write, which has observed them, replaces them.
+type MVRegister[T any] struct {
+	values []mvValue[T]
+}
+
+type mvValue[T any] struct {
+	Val   T            `json:"v"`
+	Clock vclock.Clock `json:"c"`
+}
+
+// NewMVRegister returns an unset register.
+func NewMVRegister[T any]() *MVRegister[T] {
+	return &MVRegister[T]{}
+}
+
+// Set replaces every value this replica has observed with v, on behalf of
+// node.
+func (r *MVRegister[T]) Set(node string, v T) {
+	clock := vclock.New()
+	for _, sibling := range r.values {
+		clock.Merge(sibling.Clock)
+	}
+	clock.Tick(node)
+	r.values = []mvValue[T]{{Val: v, Clock: clock}}
+}
+
+// Values returns the current siblings in a deterministic order. Returns a
+// single value unless there have been concurrent writes.
+func (r *MVRegister[T]) Values() []T {
+	values := make([]T, len(r.values))
+	for i, sibling := range r.values {
+		values[i] = sibling.Val
+	}
+	return values
+}
+
+// Merge keeps every sibling that is not causally before another.
+func (r *MVRegister[T]) Merge(other *MVRegister[T]) {
+	var values []mvValue[T]
+	candidates := append(append([]mvValue[T]{}, r.values...), other.values...)
+	for i, a := range candidates {
+		keep := true
+		for j, b := range candidates {
+			switch a.Clock.Compare(b.Clock) {
+			case vclock.Before:
+				keep = false
+			case vclock.Equal:
+				keep = keep && i <= j // keep the first of identical writes
+			}
+		}
+		if keep {
+			values = append(values, mvValue[T]{Val: a.Val, Clock: a.Clock.Copy()})
+		}
+	}
+	sortByJSON(values)
+	r.values = values
+}
+
+// Delta returns the siblings that known has not seen or superseded.
+func (r *MVRegister[T]) Delta(known *MVRegister[T]) *MVRegister[T] {
+	delta := NewMVRegister[T]()
+	for _, a := range r.values {
+		seen := false
+		for _, b := range known.values {
+			if o := a.Clock.Compare(b.Clock); o == vclock.Before || o == vclock.Equal {
+				seen = true
+			}
+		}
+		if !seen {
+			delta.values = append(delta.values, mvValue[T]{Val: a.Val, Clock: a.Clock.Copy()})
+		}
+	}
+	return delta
+}
+
+// Clone returns a copy of the register. Values are copied shallowly.
+func (r *MVRegister[T]) Clone() *MVRegister[T] {
+	other := NewMVRegister[T]()
+	for _, sibling := range r.values {
+		other.values = append(other.values, mvValue[T]{Val: sibling.Val, Clock: sibling.Clock.Copy()})
+	}
+	return other
+}
+
+// MarshalJSON encodes the siblings, each with its clock.
+func (r MVRegister[T]) MarshalJSON() ([]byte, error) {
+	if r.values == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r.values)
+}
+
+// UnmarshalJSON decodes a register encoded by MarshalJSON.
+func (r *MVRegister[T]) UnmarshalJSON(data []byte) error {
+	r.values = nil
+	if err := json.Unmarshal(data, &r.values); err != nil {
+		return err
+	}
+	if len(r.values) == 0 {
+		r.values = nil
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package crdt
+
+import "encoding/json"
+
+// GSet is a grow-only set.
+type GSet[T comparable] struct {
+	elems map[T]struct{}
+}
+
+// NewGSet returns an empty set.
+func NewGSet[T comparable]() *GSet[T] {
+	return &GSet[T]{}
+}
+
+// Add adds e to the set.
+func (s *GSet[T]) Add(e T) {
+	if s.elems == nil {
+		s.elems = make(map[T]struct{})
+	}
+	s.elems[e] = struct{}{}
+}
+
+// Contains returns true if e is in the set.
+func (s *GSet[T]) Contains(e T) bool {
+	_, ok := s.elems[e]
+	return ok
+}
+
+// Len returns the number of elements.
+func (s *GSet[T]) Len() int {
+	return len(s.elems)
+}
+
+// Elements returns the elements ordered by their JSON encoding.
+func (s *GSet[T]) Elements() []T {
+	elems := make([]T, 0, len(s.elems))
+	for e := range s.elems {
+		elems = append(elems, e)
+	}
+	sortByJSON(elems)
+	return elems
+}
+
+// Merge takes the union of both sets.
+func (s *GSet[T]) Merge(other *GSet[T]) {
+	for e := range other.elems {
+		s.Add(e)
+	}
+}
+
+// Delta returns the elements known does not contain.
+func (s *GSet[T]) Delta(known *GSet[T]) *GSet[T] {
+	delta := NewGSet[T]()
+	for e := range s.elems {
+		if !known.Contains(e) {
+			delta.Add(e)
+		}
+	}
+	return delta
+}
+
+// Clone returns a copy of the set.
+func (s *GSet[T]) Clone() *GSet[T] {
+	other := NewGSet[T]()
+	other.Merge(s)
+	return other
+}
+
+// MarshalJSON encodes the set as a sorted array.
+func (s GSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal((&s).Elements())
+}
+
+// UnmarshalJSON decodes a set encoded by MarshalJSON.
+func (s *GSet[T]) UnmarshalJSON(data []byte) error {
+	var elems []T
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+	s.elems = nil
+	for _, e := range elems {
+		s.Add(e)
+	}
+	return nil
+}
+
+// TwoPSet is a two-phase set: elements can be added and then removed, but a
+// removed element can never be added again.
+type TwoPSet[T comparable] struct {
+	Added   GSet[T] `json:"a"`
+	Removed GSet[T] `json:"r"`
+}
+
+// NewTwoPSet returns an empty set.
+func NewTwoPSet[T comparable]() *TwoPSet[T] {
+	return &TwoPSet[T]{}
+}
+
+// Add adds e to the set. Has no effect if e was ever removed.
+func (s *TwoPSet[T]) Add(e T) {
+	s.Added.Add(e)
+}
+
+// Remove removes e from the set. Returns false if e is not in the set, in
+// which case nothing is recorded.
+func (s *TwoPSet[T]) Remove(e T) bool {
+	if !s.Contains(e) {
+		return false
+	}
+	s.Removed.Add(e)
+	return true
+}
+
+// Contains returns true if e has been added and not removed.
+func (s *TwoPSet[T]) Contains(e T) bool {
+	return s.Added.Contains(e) && !s.Removed.Contains(e)
+}
+
+// Elements returns the live elements ordered by their JSON encoding.
+func (s *TwoPSet[T]) Elements() []T {
+	var elems []T
+	for _, e := range s.Added.Elements() {
+		if !s.Removed.Contains(e) {
+			elems = append(elems, e)
+		}
+	}
+	return elems
+}
+
+// Merge merges additions & removals independently.
+func (s *TwoPSet[T]) Merge(other *TwoPSet[T]) {
+	s.Added.Merge(&other.Added)
+	s.Removed.Merge(&other.Removed)
+}
+
+// Delta returns the additions & removals that known has not seen.
+func (s *TwoPSet[T]) Delta(known *TwoPSet[T]) *TwoPSet[T] {
+	return &TwoPSet[T]{
+		Added:   *s.Added.Delta(&known.Added),
+		Removed: *s.Removed.Delta(&known.Removed),
+	}
+}
+
+// Clone returns a copy of the set.
+func (s *TwoPSet[T]) Clone() *TwoPSet[T] {
+	return &TwoPSet[T]{Added: *s.Added.Clone(), Removed: *s.Removed.Clone()}
+}
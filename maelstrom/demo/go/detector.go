@@ -0,0 +1,281 @@
+package maelstrom
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Failure detector defaults.
+const (
+	DefaultHeartbeatInterval = 500 * time.Millisecond
+	DefaultPhiThreshold      = 8.0
+	DefaultDetectorWindow    = 100
+	DefaultMinStdDev         = 100 * time.Millisecond
+)
+
+// FailureDetector is a phi-accrual failure detector. It tracks the
+// inter-arrival times of messages from each peer and computes a suspicion
+// level, phi, from how overdue the next message is. A peer is suspected once
+// phi exceeds Threshold.
+//
+// Any message received from a peer counts as a heartbeat, so heartbeats are
+// only sent explicitly to peers that the node has not otherwise sent a
+// message to within the heartbeat interval.
+type FailureDetector struct {
+	mu        sync.Mutex
+	node      *Node
+	peers     []string // monitored peers; nil monitors the whole cluster
+	windows   map[string]*arrivalWindow
+	lastSent  map[string]time.Time
+	suspected map[string]bool
+	onChange  []func(peer string, suspected bool)
+
+	// Interval between heartbeats sent to each peer.
+	HeartbeatInterval time.Duration
+
+	// Suspicion level above which a peer is considered failed.
+	Threshold float64
+
+	// Number of inter-arrival samples kept per peer.
+	WindowSize int
+
+	// Lower bound on the standard deviation of inter-arrival times. This
+	// prevents very regular traffic from making the detector hypersensitive.
+	MinStdDev time.Duration
+}
+
+// NewFailureDetector returns a new failure detector for n and registers its
+// "heartbeat" handler. This must be called before Run().
+func NewFailureDetector(n *Node) *FailureDetector {
+	d := &FailureDetector{
+		node:      n,
+		windows:   make(map[string]*arrivalWindow),
+		lastSent:  make(map[string]time.Time),
+		suspected: make(map[string]bool),
+
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		Threshold:         DefaultPhiThreshold,
+		WindowSize:        DefaultDetectorWindow,
+		MinStdDev:         DefaultMinStdDev,
+	}
+
+	n.Handle("heartbeat", func(msg Message) error { return nil })
+	n.observe(d.observe)
+	return d
+}
+
+// SetPeers restricts the detector to monitoring & heartbeating the given
+// peers, e.g. a node's neighbors in the broadcast topology. By default every
+// other node in the cluster is monitored.
+func (d *FailureDetector) SetPeers(peers []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.peers = append([]string{}, peers...)
+}
+
+// OnChange registers fn to be called whenever a peer becomes suspected or
+// recovers. Callbacks are invoked from the detector's timer.
+func (d *FailureDetector) OnChange(fn func(peer string, suspected bool)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onChange = append(d.onChange, fn)
+}
+
+// Start sends heartbeats & re-evaluates peers every heartbeat interval until
+// ctx is canceled.
+func (d *FailureDetector) Start(ctx context.Context) {
+	Every(ctx, d.node.Clock(), d.HeartbeatInterval, func() {
+		d.heartbeat()
+		d.check()
+	})
+}
+
+// Heartbeat records the arrival of a message from peer. The detector calls
+// this automatically for every message the node receives.
+func (d *FailureDetector) Heartbeat(peer string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window(peer).add(d.node.Clock().Now())
+}
+
+// Phi returns the current suspicion level for peer. Returns zero for peers
+// that have never been monitored.
+func (d *FailureDetector) Phi(peer string) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := d.windows[peer]
+	if w == nil {
+		return 0
+	}
+	return w.phi(d.node.Clock().Now(), d.MinStdDev)
+}
+
+// Suspected returns true if peer is currently suspected to have failed or to
+// be unreachable.
+func (d *FailureDetector) Suspected(peer string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.suspected[peer]
+}
+
+// Available returns the peers that are not currently suspected, preserving
+// order. Gossip & forwarding logic can use this to route around partitioned
+// nodes.
+func (d *FailureDetector) Available(peers []string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	available := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		if !d.suspected[peer] {
+			available = append(available, peer)
+		}
+	}
+	return available
+}
+
+// observe treats every message from a monitored peer as a heartbeat and
+// tracks outbound traffic so that explicit heartbeats can be skipped.
+func (d *FailureDetector) observe(dir string, msg Message) {
+	switch dir {
+	case RecordIn:
+		if d.node.isNodeID(msg.Src) && msg.Src != d.node.ID() {
+			d.Heartbeat(msg.Src)
+		}
+	case RecordOut:
+		d.mu.Lock()
+		d.lastSent[msg.Dest] = d.node.Clock().Now()
+		d.mu.Unlock()
+	}
+}
+
+// heartbeat sends a heartbeat to each monitored peer that has not been sent
+// any other message within the heartbeat interval.
+func (d *FailureDetector) heartbeat() {
+	now := d.node.Clock().Now()
+
+	d.mu.Lock()
+	var dests []string
+	for _, peer := range d.monitored() {
+		if now.Sub(d.lastSent[peer]) >= d.HeartbeatInterval {
+			dests = append(dests, peer)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, peer := range dests {
+		_ = d.node.Send(peer, MessageBody{Type: "heartbeat"})
+	}
+}
+
+// check re-evaluates every monitored peer and notifies subscribers of
+// changes in suspicion.
+func (d *FailureDetector) check() {
+	now := d.node.Clock().Now()
+
+	type change struct {
+		peer      string
+		suspected bool
+	}
+	var changes []change
+
+	d.mu.Lock()
+	for _, peer := range d.monitored() {
+		suspected := d.window(peer).phi(now, d.MinStdDev) > d.Threshold
+		if suspected != d.suspected[peer] {
+			d.suspected[peer] = suspected
+			changes = append(changes, change{peer, suspected})
+		}
+	}
+	fns := d.onChange
+	d.mu.Unlock()
+
+	for _, c := range changes {
+		for _, fn := range fns {
+			fn(c.peer, c.suspected)
+		}
+	}
+}
+
+// monitored returns the sorted list of monitored peers. Must hold lock.
+func (d *FailureDetector) monitored() []string {
+	peers := d.peers
+	if peers == nil {
+		for _, id := range d.node.NodeIDs() {
+			if id != d.node.ID() {
+				peers = append(peers, id)
+			}
+		}
+	}
+	peers = append([]string{}, peers...)
+	sort.Strings(peers)
+	return peers
+}
+
+// window returns the arrival window for peer, creating one if necessary. A
+// new window treats the current time as the first arrival so that peers that
+// are unreachable from the start are eventually suspected. Must hold lock.
+func (d *FailureDetector) window(peer string) *arrivalWindow {
+	w := d.windows[peer]
+	if w == nil {
+		w = &arrivalWindow{
+			size:     d.WindowSize,
+			estimate: d.HeartbeatInterval,
+			last:     d.node.Clock().Now(),
+		}
+		d.windows[peer] = w
+	}
+	return w
+}
+
+// arrivalWindow holds recent inter-arrival times for a single peer.
+type arrivalWindow struct {
+	size      int
+	estimate  time.Duration // used until the first interval is observed
+	last      time.Time
+	intervals []time.Duration
+}
+
+func (w *arrivalWindow) add(now time.Time) {
+	if d := now.Sub(w.last); d > 0 {
+		w.intervals = append(w.intervals, d)
+		if len(w.intervals) > w.size {
+			w.intervals = w.intervals[1:]
+		}
+	}
+	w.last = now
+}
+
+// phi returns the suspicion level at now using a logistic approximation of
+// the normal distribution's CDF, as in Akka's implementation.
+func (w *arrivalWindow) phi(now time.Time, minStdDev time.Duration) float64 {
+	mean, stdDev := float64(w.estimate), float64(w.estimate)/4
+	if n := len(w.intervals); n > 0 {
+		var sum float64
+		for _, d := range w.intervals {
+			sum += float64(d)
+		}
+		mean = sum / float64(n)
+
+		var variance float64
+		for _, d := range w.intervals {
+			variance += (float64(d) - mean) * (float64(d) - mean)
+		}
+		stdDev = math.Sqrt(variance / float64(n))
+	}
+	if stdDev < float64(minStdDev) {
+		stdDev = float64(minStdDev)
+	}
+
+	elapsed := float64(now.Sub(w.last))
+	y := (elapsed - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
@@ -0,0 +1,109 @@
+package maelstrom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Ensure the detector heartbeats peers, suspects silent ones and notices
+// when they recover.
+func TestFailureDetector(t *testing.T) {
+	n, stdin, stdout := newNode(t)
+	clock := maelstrom.NewFakeClock(epoch)
+	n.SetClock(clock)
+
+	d := maelstrom.NewFailureDetector(n)
+	d.HeartbeatInterval = time.Second
+	changes := make(chan bool, 10)
+	d.OnChange(func(peer string, suspected bool) {
+		if peer != "n2" {
+			t.Errorf("unexpected peer: %s", peer)
+		}
+		changes <- suspected
+	})
+	initNode(t, n, "n1", []string{"n1", "n2"}, stdin, stdout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Start(ctx)
+	clock.BlockUntil(1)
+
+	// Peer is heartbeated while idle.
+	clock.Add(time.Second)
+	if line, err := stdout.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	} else if got, want := line, `{"src":"n1","dest":"n2","body":{"type":"heartbeat"}}`+"\n"; got != want {
+		t.Fatalf("heartbeat=%s, want %s", got, want)
+	}
+	if d.Suspected("n2") {
+		t.Fatal("peer suspected too early")
+	}
+
+	// Peer stays silent so it is eventually suspected.
+	go func() {
+		for {
+			if _, err := stdout.ReadString('\n'); err != nil {
+				return
+			}
+		}
+	}()
+	if !waitChange(t, clock, changes) {
+		t.Fatal("expected suspicion")
+	}
+	if got := d.Available([]string{"n2"}); len(got) != 0 {
+		t.Fatalf("available=%v, want none", got)
+	}
+
+	// Any message from the peer counts as a heartbeat.
+	if _, err := stdin.Write([]byte(`{"src":"n2","dest":"n1","body":{"type":"heartbeat"}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	for d.Phi("n2") > d.Threshold {
+		time.Sleep(time.Millisecond)
+	}
+	if waitChange(t, clock, changes) {
+		t.Fatal("expected recovery")
+	}
+}
+
+// waitChange advances the clock a tick at a time until a change notification
+// arrives, giving the detector's goroutine time to handle each tick.
+func waitChange(tb testing.TB, clock *maelstrom.FakeClock, changes chan bool) bool {
+	tb.Helper()
+	for i := 0; i < 100; i++ {
+		clock.Add(time.Second)
+		select {
+		case suspected := <-changes:
+			return suspected
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	tb.Fatal("timeout waiting for change notification")
+	return false
+}
+
+// Ensure phi grows as a regular heartbeat becomes overdue.
+func TestFailureDetector_Phi(t *testing.T) {
+	n := maelstrom.NewNode()
+	clock := maelstrom.NewFakeClock(epoch)
+	n.SetClock(clock)
+	n.Init("n1", []string{"n1", "n2"})
+
+	d := maelstrom.NewFailureDetector(n)
+	for i := 0; i < 20; i++ {
+		clock.Add(500 * time.Millisecond)
+		d.Heartbeat("n2")
+	}
+
+	clock.Add(500 * time.Millisecond)
+	if phi := d.Phi("n2"); phi > 1 {
+		t.Fatalf("phi=%f after on-time interval", phi)
+	}
+	clock.Add(2 * time.Second)
+	if phi := d.Phi("n2"); phi < d.Threshold {
+		t.Fatalf("phi=%f after overdue interval", phi)
+	}
+}
@@ -0,0 +1,398 @@
+// Package edn reads & writes Extensible Data Notation, the format Jepsen and
+// Maelstrom use for histories and results in store/*/.
+//
+// Values decode to Go types as follows: nil to nil, booleans to bool,
+// integers to int64, floats to float64, strings to string, characters to
+// Char, keywords to Keyword, symbols to Symbol, vectors & lists to []any,
+// sets to Set, maps to map[any]any and tagged literals to Tagged. Map keys
+// must be comparable, so maps keyed by vectors, maps or sets are rejected.
+package edn
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Keyword is an EDN keyword without its leading colon, e.g. ":ok" is "ok".
+type Keyword string
+
+// String returns the keyword in EDN notation.
+func (k Keyword) String() string { return ":" + string(k) }
+
+// Symbol is an EDN symbol.
+type Symbol string
+
+// Char is an EDN character literal such as \a or \newline.
+type Char rune
+
+// Set is an EDN set. Elements are kept in the order they were read.
+type Set []any
+
+// Tagged is a tagged literal such as #inst "2023-01-01" or a Clojure record
+// like #jepsen.history.Op{...}.
+type Tagged struct {
+	Tag   Symbol
+	Value any
+}
+
+// Decoder reads a stream of EDN values.
+type Decoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+// NewDecoder returns a decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), line: 1}
+}
+
+// Decode reads the next top-level value. Returns io.EOF when the stream has
+// no more values.
+func (d *Decoder) Decode() (any, error) {
+	if err := d.skip(); err != nil {
+		return nil, err
+	}
+	v, err := d.value()
+	if err == io.EOF {
+		return nil, d.errorf("unexpected end of input")
+	}
+	return v, err
+}
+
+// Unmarshal decodes a single value from data.
+func Unmarshal(data []byte) (any, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	v, err := d.Decode()
+	if err == io.EOF {
+		return nil, d.errorf("no value")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := d.skip(); err != io.EOF {
+		if err == nil {
+			return nil, d.errorf("unexpected data after value")
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// errorf returns an error annotated with the current line.
+func (d *Decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("edn: line %d: %s", d.line, fmt.Sprintf(format, args...))
+}
+
+func (d *Decoder) read() (rune, error) {
+	r, _, err := d.r.ReadRune()
+	if r == '\n' {
+		d.line++
+	}
+	return r, err
+}
+
+func (d *Decoder) unread(r rune) {
+	_ = d.r.UnreadRune()
+	if r == '\n' {
+		d.line--
+	}
+}
+
+func (d *Decoder) peek() (rune, error) {
+	r, err := d.read()
+	if err == nil {
+		d.unread(r)
+	}
+	return r, err
+}
+
+// skip consumes whitespace, commas, comments & discarded (#_) values.
+func (d *Decoder) skip() error {
+	for {
+		r, err := d.peek()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case unicode.IsSpace(r) || r == ',':
+			_, _ = d.read()
+		case r == ';':
+			for r != '\n' {
+				if r, err = d.read(); err != nil {
+					return err
+				}
+			}
+		case r == '#':
+			if b, _ := d.r.Peek(2); len(b) < 2 || b[1] != '_' {
+				return nil
+			}
+			_, _ = d.r.Discard(2)
+			if err := d.skip(); err != nil {
+				return err
+			} else if _, err := d.value(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// value reads a value starting at the current, non-whitespace, position.
+func (d *Decoder) value() (any, error) {
+	r, err := d.read()
+	if err != nil {
+		return nil, err
+	}
+
+	switch r {
+	case '(':
+		return d.seq(')')
+	case '[':
+		return d.seq(']')
+	case '{':
+		return d.mapValue()
+	case '"':
+		return d.str()
+	case '\\':
+		return d.char()
+	case ':':
+		tok, err := d.token()
+		if err != nil {
+			return nil, err
+		} else if tok == "" {
+			return nil, d.errorf("empty keyword")
+		}
+		return Keyword(tok), nil
+	case '#':
+		return d.dispatch()
+	case ')', ']', '}':
+		return nil, d.errorf("unexpected %q", r)
+	}
+
+	d.unread(r)
+	tok, err := d.token()
+	if err != nil {
+		return nil, err
+	}
+	return d.atom(tok)
+}
+
+// seq reads the elements of a list or vector up to the closing delimiter.
+func (d *Decoder) seq(end rune) ([]any, error) {
+	a := []any{}
+	for {
+		if err := d.skip(); err != nil {
+			return nil, err
+		}
+		if r, err := d.peek(); err != nil {
+			return nil, err
+		} else if r == end {
+			_, _ = d.read()
+			return a, nil
+		}
+
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+func (d *Decoder) mapValue() (map[any]any, error) {
+	elems, err := d.seq('}')
+	if err != nil {
+		return nil, err
+	} else if len(elems)%2 != 0 {
+		return nil, d.errorf("map has an odd number of forms")
+	}
+
+	m := make(map[any]any, len(elems)/2)
+	for i := 0; i < len(elems); i += 2 {
+		if !comparable(elems[i]) {
+			return nil, d.errorf("unsupported map key %v", elems[i])
+		}
+		m[elems[i]] = elems[i+1]
+	}
+	return m, nil
+}
+
+// dispatch reads the value following a '#': a set or a tagged literal.
+func (d *Decoder) dispatch() (any, error) {
+	r, err := d.read()
+	if err != nil {
+		return nil, err
+	}
+	if r == '{' {
+		elems, err := d.seq('}')
+		return Set(elems), err
+	}
+
+	d.unread(r)
+	tag, err := d.token()
+	if err != nil {
+		return nil, err
+	} else if tag == "" {
+		return nil, d.errorf("invalid dispatch character %q", r)
+	}
+
+	// Clojure records are written without a space, e.g. #my.Record{...}.
+	if err := d.skip(); err != nil {
+		return nil, err
+	}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	return Tagged{Tag: Symbol(tag), Value: v}, nil
+}
+
+func (d *Decoder) str() (string, error) {
+	var sb strings.Builder
+	for {
+		r, err := d.read()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if r, err = d.read(); err != nil {
+				return "", err
+			}
+			switch r {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			case 'r':
+				sb.WriteRune('\r')
+			case '"', '\\':
+				sb.WriteRune(r)
+			case 'u':
+				var hex [4]rune
+				for i := range hex {
+					if hex[i], err = d.read(); err != nil {
+						return "", err
+					}
+				}
+				n, err := strconv.ParseUint(string(hex[:]), 16, 32)
+				if err != nil {
+					return "", d.errorf("invalid unicode escape %q", string(hex[:]))
+				}
+				sb.WriteRune(rune(n))
+			default:
+				return "", d.errorf("invalid escape %q", r)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+var charNames = map[string]Char{
+	"newline": '\n',
+	"return":  '\r',
+	"space":   ' ',
+	"tab":     '\t',
+}
+
+func (d *Decoder) char() (Char, error) {
+	r, err := d.read()
+	if err != nil {
+		return 0, err
+	}
+	tok, err := d.token()
+	if err != nil {
+		return 0, err
+	} else if tok == "" {
+		return Char(r), nil
+	}
+
+	name := string(r) + tok
+	if c, ok := charNames[name]; ok {
+		return c, nil
+	} else if r == 'u' && len(tok) == 4 {
+		if n, err := strconv.ParseUint(tok, 16, 32); err == nil {
+			return Char(n), nil
+		}
+	}
+	return 0, d.errorf("invalid character \\%s", name)
+}
+
+// token reads runes up to the next delimiter.
+func (d *Decoder) token() (string, error) {
+	var sb strings.Builder
+	for {
+		r, err := d.read()
+		if err == io.EOF {
+			return sb.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+
+		if unicode.IsSpace(r) || strings.ContainsRune(`,()[]{}"; `, r) {
+			d.unread(r)
+			return sb.String(), nil
+		}
+		sb.WriteRune(r)
+	}
+}
+
+// atom parses a bare token as a number, literal or symbol.
+func (d *Decoder) atom(tok string) (any, error) {
+	switch tok {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, d.errorf("unexpected character")
+	}
+
+	if c := tok[0]; c >= '0' && c <= '9' || (c == '-' || c == '+') && len(tok) > 1 && tok[1] >= '0' && tok[1] <= '9' {
+		return d.number(tok)
+	}
+	return Symbol(tok), nil
+}
+
+func (d *Decoder) number(tok string) (any, error) {
+	if strings.HasSuffix(tok, "N") {
+		tok = tok[:len(tok)-1]
+	} else if strings.HasSuffix(tok, "M") {
+		tok = tok[:len(tok)-1]
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			return f, nil
+		}
+		return nil, d.errorf("invalid number %q", tok)
+	}
+
+	if i, err := strconv.ParseInt(tok, 10, 64); err == nil {
+		return i, nil
+	} else if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		return f, nil
+	}
+	return nil, d.errorf("invalid number %q", tok)
+}
+
+// comparable reports whether v can be used as a Go map key.
+func comparable(v any) bool {
+	switch v := v.(type) {
+	case []any, map[any]any, Set:
+		return false
+	case Tagged:
+		return comparable(v.Value)
+	}
+	return true
+}
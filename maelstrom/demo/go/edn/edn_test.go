@@ -0,0 +1,133 @@
+package edn_test
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+)
+
+func TestUnmarshal(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want any
+	}{
+		{`nil`, nil},
+		{`true`, true},
+		{`-12`, int64(-12)},
+		{`42N`, int64(42)},
+		{`1.5`, 1.5},
+		{`2.5M`, 2.5},
+		{`"a\"b\né"`, "a\"b\né"},
+		{`\a`, edn.Char('a')},
+		{`\newline`, edn.Char('\n')},
+		{`:ok`, edn.Keyword("ok")},
+		{`:jepsen.history/op`, edn.Keyword("jepsen.history/op")},
+		{`foo/bar`, edn.Symbol("foo/bar")},
+		{`-`, edn.Symbol("-")},
+		{`[1 (2, 3) []]`, []any{int64(1), []any{int64(2), int64(3)}, []any{}}},
+		{`#{:a :b}`, edn.Set{edn.Keyword("a"), edn.Keyword("b")}},
+		{`{:type :ok, :value [0 nil]}`, map[any]any{
+			edn.Keyword("type"):  edn.Keyword("ok"),
+			edn.Keyword("value"): []any{int64(0), nil},
+		}},
+		{`#inst "2023-01-01"`, edn.Tagged{Tag: "inst", Value: "2023-01-01"}},
+		{`#jepsen.history.Op{:index 0}`, edn.Tagged{Tag: "jepsen.history.Op", Value: map[any]any{edn.Keyword("index"): int64(0)}}},
+		{"; comment\n [1 #_ 2 #_[3] 4] ", []any{int64(1), int64(4)}},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := edn.Unmarshal([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Error(t *testing.T) {
+	for _, tt := range []struct {
+		in, err string
+	}{
+		{``, `edn: line 1: no value`},
+		{`[1 2`, `edn: line 1: unexpected end of input`},
+		{"\n]", `edn: line 2: unexpected ']'`},
+		{`{:a}`, `edn: line 1: map has an odd number of forms`},
+		{`{[1] 2}`, `edn: line 1: unsupported map key [1]`},
+		{`"\q"`, `edn: line 1: invalid escape 'q'`},
+		{`1 2`, `edn: line 1: unexpected data after value`},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			if _, err := edn.Unmarshal([]byte(tt.in)); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// Ensure a stream of top-level values, as in history.edn, decodes in order.
+func TestDecoder_Decode(t *testing.T) {
+	dec := edn.NewDecoder(strings.NewReader("{:index 0}\n{:index 1}\n"))
+	for i := int64(0); i < 2; i++ {
+		v, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		} else if got := v.(map[any]any)[edn.Keyword("index")]; got != i {
+			t.Fatalf("index=%v, want %d", got, i)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	for _, tt := range []struct {
+		in   any
+		want string
+	}{
+		{nil, `nil`},
+		{[]any{true, int64(-12), 3, uint8(4), 1.5, 2.0}, `[true -12 3 4 1.5 2.0]`},
+		{"a\"b\né", `"a\"b\né"`},
+		{edn.Char('\n'), `\newline`},
+		{edn.Keyword("ok"), `:ok`},
+		{edn.Set{edn.Keyword("a")}, `#{:a}`},
+		{edn.Tagged{Tag: "inst", Value: "2023-01-01"}, `#inst "2023-01-01"`},
+		{map[string]any{"type": "echo_ok", "msg_id": json.Number("9223372036854775807")}, `{:msg_id 9223372036854775807, :type "echo_ok"}`},
+		{map[any]any{"k": []int{1, 2}}, `{"k" [1 2]}`},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			if got, err := edn.Marshal(tt.in); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := edn.Marshal(struct{}{}); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
+
+// Ensure decoded values encode back to equivalent EDN.
+func TestMarshal_RoundTrip(t *testing.T) {
+	in := `{:index 3, :process :nemesis, :value [[:r 1 nil] #{\a "b"}], :time 1.5}`
+	v, err := edn.Unmarshal([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := edn.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := edn.Unmarshal(buf); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, v) {
+		t.Fatalf("got %#v, want %#v", got, v)
+	}
+}
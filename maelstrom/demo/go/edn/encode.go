@@ -0,0 +1,194 @@
+package edn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Marshal returns the EDN encoding of v, the inverse of Unmarshal. In
+// addition to the types Unmarshal returns, it encodes any integer type,
+// float32, json.Number, and other slices & maps by reflection. Go maps with
+// string keys, such as decoded JSON objects, are written with keyword keys.
+// Map entries are sorted so output is deterministic.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		return encodeFloat(buf, v)
+	case float32:
+		return encodeFloat(buf, float64(v))
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			buf.WriteString(v.String())
+		} else if f, err := v.Float64(); err == nil {
+			return encodeFloat(buf, f)
+		} else {
+			return fmt.Errorf("edn: invalid number %q", v)
+		}
+	case string:
+		encodeString(buf, v)
+	case Keyword:
+		buf.WriteString(v.String())
+	case Symbol:
+		buf.WriteString(string(v))
+	case Char:
+		encodeChar(buf, v)
+	case Set:
+		return encodeSeq(buf, "#{", "}", len(v), func(i int) any { return v[i] })
+	case Tagged:
+		buf.WriteString("#" + string(v.Tag) + " ")
+		return encode(buf, v.Value)
+	case []any:
+		return encodeSeq(buf, "[", "]", len(v), func(i int) any { return v[i] })
+	case map[any]any:
+		return encodeMap(buf, reflect.ValueOf(v))
+	case map[string]any:
+		return encodeMap(buf, reflect.ValueOf(v))
+	default:
+		return encodeReflect(buf, v)
+	}
+	return nil
+}
+
+func encodeReflect(buf *bytes.Buffer, v any) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.String:
+		encodeString(buf, rv.String())
+	case reflect.Slice, reflect.Array:
+		return encodeSeq(buf, "[", "]", rv.Len(), func(i int) any { return rv.Index(i).Interface() })
+	case reflect.Map:
+		return encodeMap(buf, rv)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			buf.WriteString("nil")
+			return nil
+		}
+		return encode(buf, rv.Elem().Interface())
+	default:
+		return fmt.Errorf("edn: unsupported type %T", v)
+	}
+	return nil
+}
+
+func encodeFloat(buf *bytes.Buffer, f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("edn: unsupported float %v", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !bytes.ContainsAny([]byte(s), ".eE") {
+		s += ".0" // keep it a float
+	}
+	buf.WriteString(s)
+	return nil
+}
+
+func encodeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+func encodeChar(buf *bytes.Buffer, c Char) {
+	for name, r := range charNames {
+		if r == c {
+			buf.WriteString(`\` + name)
+			return
+		}
+	}
+	if c > ' ' && c < utf8.RuneSelf {
+		buf.WriteString(`\` + string(rune(c)))
+		return
+	}
+	fmt.Fprintf(buf, `\u%04x`, rune(c))
+}
+
+func encodeSeq(buf *bytes.Buffer, open, close string, n int, elem func(i int) any) error {
+	buf.WriteString(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		if err := encode(buf, elem(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(close)
+	return nil
+}
+
+func encodeMap(buf *bytes.Buffer, rv reflect.Value) error {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, rv.Len())
+	keywords := rv.Type().Key().Kind() == reflect.String
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key().Interface()
+		if keywords {
+			k = Keyword(iter.Key().String())
+		}
+
+		kb, err := Marshal(k)
+		if err != nil {
+			return err
+		}
+		vb, err := Marshal(iter.Value().Interface())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{kb, vb})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+
+	buf.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Write(e.key)
+		buf.WriteByte(' ')
+		buf.Write(e.value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
@@ -0,0 +1,302 @@
+// Package election implements lease-based leader election on top of a
+// linearizable key/value store such as Maelstrom's lin-kv service.
+//
+// The current lease is stored under a single key and is only ever changed
+// with compare-and-swap. Each change of leadership increments the lease's
+// term, which doubles as a fencing token: callers attach it to their writes so
+// that a deposed leader's late writes can be rejected.
+//
+// Leases do not rely on synchronized clocks. A candidate only takes over once
+// it has observed the same lease value, unchanged, for a full TTL on its own
+// clock, whereas a leader stops acting as leader a margin before TTL has
+// elapsed since it started its last successful renewal.
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Election defaults.
+const (
+	DefaultTTL = 2 * time.Second
+
+	// Fraction of the TTL that a leader gives up early to allow for clock
+	// rate differences between nodes.
+	DefaultSafetyMargin = 0.1
+)
+
+// Lease represents the value stored in the KV store under the election key.
+type Lease struct {
+	// ID of the node holding the lease. Empty if the lease was released.
+	Leader string `json:"leader"`
+
+	// Incremented each time leadership changes hands. Used as a fencing token.
+	Term int `json:"term"`
+
+	// Incremented on every renewal so observers can tell the lease is alive.
+	Seq int `json:"seq"`
+}
+
+// Leadership describes the outcome of an election as seen by a node.
+type Leadership struct {
+	Leader   string
+	Term     int
+	IsLeader bool
+}
+
+// Election campaigns for leadership of a single key.
+type Election struct {
+	node *maelstrom.Node
+	kv   *maelstrom.KV
+	key  string
+
+	mu          sync.Mutex
+	current     string    // raw lease value last read from the KV store
+	lease       Lease     // decoded form of current
+	observedAt  time.Time // local time at which current was first observed
+	leaderUntil time.Time // local time at which our leadership lapses
+	notified    Leadership
+	onChange    []func(Leadership)
+
+	// Duration of a lease. Leaders renew every RenewInterval.
+	TTL time.Duration
+
+	// How often the leader renews its lease. Defaults to TTL/3.
+	RenewInterval time.Duration
+
+	// How often followers check the lease. Defaults to TTL/4.
+	PollInterval time.Duration
+
+	// Timeout for each KV request. Defaults to RenewInterval.
+	RequestTimeout time.Duration
+
+	// Fraction of the TTL that the leader gives up early.
+	SafetyMargin float64
+}
+
+// New returns a new election for key using kv, which must be linearizable.
+func New(node *maelstrom.Node, kv *maelstrom.KV, key string) *Election {
+	return &Election{
+		node:         node,
+		kv:           kv,
+		key:          key,
+		TTL:          DefaultTTL,
+		SafetyMargin: DefaultSafetyMargin,
+	}
+}
+
+// OnChange registers fn to be called whenever the observed leader or term
+// changes, or when this node gains or loses leadership.
+func (e *Election) OnChange(fn func(Leadership)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onChange = append(e.onChange, fn)
+}
+
+// IsLeader returns true if this node currently holds an unexpired lease.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isLeader(e.node.Clock().Now())
+}
+
+// isLeader must be called with the lock held.
+func (e *Election) isLeader(now time.Time) bool {
+	return e.lease.Leader == e.node.ID() && now.Before(e.leaderUntil)
+}
+
+// Leader returns the last observed leader and term. The leader may be blank
+// if no lease has been observed or the lease was released.
+func (e *Election) Leader() (leader string, term int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lease.Leader, e.lease.Term
+}
+
+// Token returns the fencing token for this node's current leadership. Returns
+// false if this node is not the leader.
+func (e *Election) Token() (int, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.isLeader(e.node.Clock().Now()) {
+		return 0, false
+	}
+	return e.lease.Term, true
+}
+
+// Run campaigns for leadership until ctx is canceled. A leader renews its
+// lease periodically and steps down if renewal fails. On return, a held
+// lease is released so that another node can take over without waiting.
+func (e *Election) Run(ctx context.Context) error {
+	clock := e.node.Clock()
+	for {
+		e.step(ctx)
+		e.notify()
+
+		interval := e.pollInterval()
+		if e.IsLeader() {
+			interval = e.renewInterval()
+		}
+
+		select {
+		case <-ctx.Done():
+			e.release()
+			return ctx.Err()
+		case <-clock.After(interval):
+		}
+	}
+}
+
+// step performs a single round of the election: renewing our lease if we
+// hold it or acquiring it if it has expired.
+func (e *Election) step(ctx context.Context) {
+	clock := e.node.Clock()
+
+	ctx, cancel := context.WithTimeout(ctx, e.requestTimeout())
+	defer cancel()
+
+	start := clock.Now()
+	current, err := e.read(ctx)
+	if err != nil {
+		log.Printf("election %s: read: %s", e.key, err)
+		return
+	}
+
+	e.mu.Lock()
+	if current != e.current {
+		e.current, e.observedAt = current, clock.Now()
+		e.lease = Lease{}
+		if current != "" {
+			if err := json.Unmarshal([]byte(current), &e.lease); err != nil {
+				log.Printf("election %s: malformed lease %q: %s", e.key, current, err)
+			}
+		}
+	}
+	lease, observedAt := e.lease, e.observedAt
+	e.mu.Unlock()
+
+	var next Lease
+	switch {
+	case lease.Leader == e.node.ID():
+		next = Lease{Leader: lease.Leader, Term: lease.Term, Seq: lease.Seq + 1}
+	case current == "" || lease.Leader == "" || clock.Since(observedAt) >= e.TTL:
+		next = Lease{Leader: e.node.ID(), Term: lease.Term + 1}
+	default:
+		return // lease held by another node
+	}
+
+	buf, err := json.Marshal(next)
+	if err != nil {
+		log.Printf("election %s: marshal lease: %s", e.key, err)
+		return
+	}
+	var from any = current
+	if current == "" {
+		from = nil
+	}
+	if err := e.kv.CompareAndSwap(ctx, e.key, from, string(buf), current == ""); err != nil {
+		// Lost the race or the KV store is unreachable. Leadership lapses on
+		// its own once leaderUntil passes.
+		log.Printf("election %s: cas: %s", e.key, err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.current, e.lease, e.observedAt = string(buf), next, clock.Now()
+	e.leaderUntil = start.Add(e.TTL - time.Duration(float64(e.TTL)*e.SafetyMargin))
+}
+
+// read returns the raw lease value. Returns a blank string if no lease exists.
+func (e *Election) read(ctx context.Context) (string, error) {
+	v, err := e.kv.Read(ctx, e.key)
+	if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected lease value: %#v", v)
+	}
+	return s, nil
+}
+
+// release gives up the lease, if held, keeping the term so that fencing
+// tokens stay monotonic.
+func (e *Election) release() {
+	e.mu.Lock()
+	held := e.isLeader(e.node.Clock().Now())
+	current, lease := e.current, e.lease
+	e.leaderUntil = time.Time{}
+	e.mu.Unlock()
+
+	if held {
+		ctx, cancel := context.WithTimeout(context.Background(), e.requestTimeout())
+		defer cancel()
+
+		released := Lease{Term: lease.Term}
+		buf, err := json.Marshal(released)
+		if err == nil {
+			err = e.kv.CompareAndSwap(ctx, e.key, current, string(buf), false)
+		}
+		if err != nil {
+			log.Printf("election %s: release: %s", e.key, err)
+		} else {
+			e.mu.Lock()
+			e.current, e.lease, e.observedAt = string(buf), released, e.node.Clock().Now()
+			e.mu.Unlock()
+		}
+	}
+	e.notify()
+}
+
+// notify calls change callbacks if the leadership state has changed.
+func (e *Election) notify() {
+	e.mu.Lock()
+	l := Leadership{
+		Leader:   e.lease.Leader,
+		Term:     e.lease.Term,
+		IsLeader: e.isLeader(e.node.Clock().Now()),
+	}
+	if l == e.notified {
+		e.mu.Unlock()
+		return
+	}
+	e.notified = l
+	fns := e.onChange
+	e.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(l)
+	}
+}
+
+func (e *Election) renewInterval() time.Duration {
+	if e.RenewInterval > 0 {
+		return e.RenewInterval
+	}
+	return e.TTL / 3
+}
+
+func (e *Election) pollInterval() time.Duration {
+	if e.PollInterval > 0 {
+		return e.PollInterval
+	}
+	return e.TTL / 4
+}
+
+func (e *Election) requestTimeout() time.Duration {
+	if e.RequestTimeout > 0 {
+		return e.RequestTimeout
+	}
+	return e.renewInterval()
+}
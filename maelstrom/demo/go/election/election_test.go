@@ -0,0 +1,135 @@
+package election_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/election"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+const testTTL = 300 * time.Millisecond
+
+// Ensure exactly one node leads and leadership moves, with a higher fencing
+// token, when the leader loses access to the KV store.
+func TestElection_Failover(t *testing.T) {
+	nw, elections, cancels := newCluster(t, 3)
+	defer nw.Close()
+
+	leader, term := waitLeader(t, elections, "")
+	token, ok := elections[leader].Token()
+	if !ok || token != term {
+		t.Fatalf("token=%d/%v, want %d", token, ok, term)
+	}
+
+	nw.Block(leader, maelstrom.LinKV)
+	next, nextTerm := waitLeader(t, elections, leader)
+	if nextTerm <= term {
+		t.Fatalf("term=%d, want > %d", nextTerm, term)
+	} else if elections[leader].IsLeader() {
+		t.Fatal("old leader did not step down")
+	} else if _, ok := elections[leader].Token(); ok {
+		t.Fatal("old leader still has a fencing token")
+	}
+
+	// Once healed, the deposed node follows the new leader.
+	nw.Heal()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if l, _ := elections[leader].Leader(); l == next {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("deposed node sees leader %q, want %q", l, next)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// Ensure a leader that stops campaigning releases its lease promptly.
+func TestElection_Release(t *testing.T) {
+	nw, elections, cancels := newCluster(t, 2)
+	defer nw.Close()
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	leader, _ := waitLeader(t, elections, "")
+
+	var mu sync.Mutex
+	var changes []election.Leadership
+	elections[leader].OnChange(func(l election.Leadership) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, l)
+	})
+
+	cancels[leader]()
+	waitLeader(t, elections, leader)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) == 0 || changes[0].IsLeader {
+		t.Fatalf("expected step-down notification, got %+v", changes)
+	}
+}
+
+// newCluster starts n nodes each campaigning for the same key.
+func newCluster(tb testing.TB, n int) (*simnet.Network, map[string]*election.Election, map[string]context.CancelFunc) {
+	tb.Helper()
+
+	nw := simnet.New()
+	elections := make(map[string]*election.Election)
+	for id, node := range nw.NewNodes(n) {
+		e := election.New(node, maelstrom.NewLinKV(node), "leader")
+		e.TTL = testTTL
+		elections[id] = e
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		tb.Fatal(err)
+	}
+
+	cancels := make(map[string]context.CancelFunc)
+	for id, e := range elections {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[id] = cancel
+		go e.Run(ctx)
+	}
+	return nw, elections, cancels
+}
+
+// waitLeader waits until a single node other than exclude is leader and
+// verifies that no two nodes ever claim leadership at the same time.
+func waitLeader(tb testing.TB, elections map[string]*election.Election, exclude string) (string, int) {
+	tb.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var leaders []string
+		for id, e := range elections {
+			if e.IsLeader() {
+				leaders = append(leaders, id)
+			}
+		}
+		if len(leaders) > 1 {
+			tb.Fatalf("multiple leaders: %v", leaders)
+		} else if len(leaders) == 1 && leaders[0] != exclude {
+			_, term := elections[leaders[0]].Leader()
+			return leaders[0], term
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	tb.Fatal("timeout waiting for leader")
+	return "", 0
+}
@@ -0,0 +1,71 @@
+package maelstrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultForwardTimeout is the default time Forward waits for a reply.
+const DefaultForwardTimeout = 5 * time.Second
+
+// Forward sends req to dest on behalf of its original sender and relays
+// dest's reply, including RPC errors, back to the sender with the correct
+// "in_reply_to". If dest does not reply within ForwardTimeout, the sender
+// receives a Timeout error instead and any late reply is dropped.
+//
+// Forward returns once the request has been sent, so a handler can simply
+// return n.Forward(msg, owner).
+func (n *Node) Forward(req Message, dest string) error {
+	var reqBody MessageBody
+	if err := json.Unmarshal(req.Body, &reqBody); err != nil {
+		return err
+	}
+
+	var body map[string]any
+	if err := unmarshalNumbers(req.Body, &body); err != nil {
+		return err
+	}
+	delete(body, "msg_id")
+
+	// Messages that don't expect a reply are simply passed on.
+	if reqBody.MsgID == 0 {
+		return n.Send(dest, body)
+	}
+
+	var mu sync.Mutex
+	var timer Timer
+	msgID, err := n.rpc(dest, body, func(reply Message) error {
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+
+		return n.Reply(req, json.RawMessage(reply.Body))
+	})
+	if err != nil {
+		n.cancelRPC(msgID)
+		return err
+	}
+
+	timeout := n.ForwardTimeout
+	if timeout <= 0 {
+		timeout = DefaultForwardTimeout
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	timer = n.clock.AfterFunc(timeout, func() {
+		if !n.cancelRPC(msgID) {
+			return // reply won the race
+		}
+		text := fmt.Sprintf("timed out waiting for %s", dest)
+		if err := n.Reply(req, NewRPCError(Timeout, text)); err != nil {
+			log.Printf("forward error: %s", err)
+		}
+	})
+	return nil
+}
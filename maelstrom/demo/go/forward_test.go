@@ -0,0 +1,86 @@
+package maelstrom_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// Ensure forwarded requests are answered by the owner, including errors,
+// and that unanswered requests time out.
+func TestNode_Forward(t *testing.T) {
+	nw := simnet.New()
+	defer nw.Close()
+
+	n1, n2 := nw.NewNode("n1"), nw.NewNode("n2")
+	n1.ForwardTimeout = 100 * time.Millisecond
+	n1.Handle("read", func(msg maelstrom.Message) error {
+		return n1.Forward(msg, "n2")
+	})
+	n2.Handle("read", func(msg maelstrom.Message) error {
+		var body struct {
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		switch body.Key {
+		case "missing":
+			return maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "not found")
+		case "slow":
+			return nil // never reply
+		}
+		return n2.Reply(msg, map[string]any{"type": "read_ok", "value": body.Key + "@" + n2.ID()})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c := nw.NewClient("c1")
+
+	t.Run("OK", func(t *testing.T) {
+		msg, err := c.RPC(ctx, "n1", map[string]any{"type": "read", "key": "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var body struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			t.Fatal(err)
+		} else if msg.Src != "n1" || body.Type != "read_ok" || body.Value != "x@n2" {
+			t.Fatalf("unexpected reply: %s %s", msg.Src, msg.Body)
+		}
+	})
+
+	t.Run("RPCError", func(t *testing.T) {
+		_, err := c.RPC(ctx, "n1", map[string]any{"type": "read", "key": "missing"})
+		if code := maelstrom.ErrorCode(err); code != maelstrom.KeyDoesNotExist {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		msg, err := c.RPC(ctx, "n1", map[string]any{"type": "read", "key": "slow"})
+		if code := maelstrom.ErrorCode(err); code != maelstrom.Timeout {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Timeout is error code zero, so it must be present explicitly.
+		var body map[string]any
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			t.Fatal(err)
+		} else if body["type"] != "error" || body["code"] != float64(maelstrom.Timeout) {
+			t.Fatalf("unexpected reply: %s", msg.Body)
+		}
+	})
+}
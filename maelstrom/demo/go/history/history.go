@@ -0,0 +1,243 @@
+// Package history represents the client histories checked by Jepsen and
+// Maelstrom. Histories are read from the history.edn files Maelstrom writes to
+// store/<test>/<time>/ or recorded in-process with a Recorder.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+)
+
+// Type is the type of an operation.
+type Type string
+
+// Operation types. An invocation is followed by exactly one completion: ok if
+// the operation took place, fail if it certainly did not and info if the
+// outcome is unknown.
+const (
+	Invoke Type = "invoke"
+	OK     Type = "ok"
+	Fail   Type = "fail"
+	Info   Type = "info"
+)
+
+// NemesisProcess is the process of operations performed by the nemesis.
+const NemesisProcess = -1
+
+// Op is a single invocation or completion.
+type Op struct {
+	Index   int
+	Type    Type
+	Process int
+	F       string
+	Value   any
+	Error   any
+
+	// Time since the start of the test, in nanoseconds.
+	Time int64
+}
+
+// String returns a short description of the op.
+func (op Op) String() string {
+	return fmt.Sprintf("%d %s %s %v", op.Process, op.Type, op.F, op.Value)
+}
+
+// IsClient returns true if the op was performed by a client process.
+func (op Op) IsClient() bool {
+	return op.Process != NemesisProcess
+}
+
+// Pair is an invocation together with its completion.
+type Pair struct {
+	Invoke   Op
+	Complete Op
+}
+
+// Pairs matches each client invocation with the next completion from the same
+// process. Invocations that never complete are given an info completion at
+// the end of time.
+func Pairs(ops []Op) []Pair {
+	var pairs []Pair
+	pending := make(map[int]int) // process -> index into pairs
+
+	for _, op := range ops {
+		if !op.IsClient() {
+			continue
+		}
+
+		if op.Type == Invoke {
+			pending[op.Process] = len(pairs)
+			pairs = append(pairs, Pair{Invoke: op})
+			continue
+		}
+
+		if i, ok := pending[op.Process]; ok {
+			pairs[i].Complete = op
+			delete(pending, op.Process)
+		}
+	}
+
+	for _, i := range pending {
+		invoke := pairs[i].Invoke
+		pairs[i].Complete = Op{
+			Index:   -1,
+			Type:    Info,
+			Process: invoke.Process,
+			F:       invoke.F,
+			Time:    math.MaxInt64,
+		}
+	}
+	return pairs
+}
+
+// ReadFile reads a history from an EDN file such as history.edn.
+func ReadFile(path string) ([]Op, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a history from EDN. The ops may be top-level maps, one per line,
+// or elements of a single top-level vector. Ops are returned in index order.
+func Read(r io.Reader) ([]Op, error) {
+	var ops []Op
+	dec := edn.NewDecoder(r)
+	for {
+		v, err := dec.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		vs := []any{v}
+		if a, ok := v.([]any); ok {
+			vs = a
+		}
+		for _, v := range vs {
+			op, err := FromEDN(v)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Index < ops[j].Index })
+	return ops, nil
+}
+
+// FromEDN converts a decoded EDN op map, or record, into an Op.
+func FromEDN(v any) (Op, error) {
+	if t, ok := v.(edn.Tagged); ok {
+		v = t.Value
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return Op{}, fmt.Errorf("history: op is not a map: %v", v)
+	}
+
+	op := Op{
+		Index: int(intField(m, "index")),
+		Time:  intField(m, "time"),
+		Value: m[edn.Keyword("value")],
+		Error: m[edn.Keyword("error")],
+	}
+
+	typ, ok := m[edn.Keyword("type")].(edn.Keyword)
+	if !ok {
+		return Op{}, fmt.Errorf("history: op %d has no type", op.Index)
+	}
+	op.Type = Type(typ)
+
+	switch f := m[edn.Keyword("f")].(type) {
+	case edn.Keyword:
+		op.F = string(f)
+	case string:
+		op.F = f
+	}
+
+	switch p := m[edn.Keyword("process")].(type) {
+	case int64:
+		op.Process = int(p)
+	case edn.Keyword:
+		op.Process = NemesisProcess
+	default:
+		return Op{}, fmt.Errorf("history: op %d has invalid process %v", op.Index, p)
+	}
+	return op, nil
+}
+
+// WriteFile writes a history to an EDN file such as history.edn.
+func WriteFile(path string, ops []Op) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := Write(f, ops); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes a history as EDN, one op per line, in the format Maelstrom
+// uses for history.edn.
+func Write(w io.Writer, ops []Op) error {
+	bw := bufio.NewWriter(w)
+	for _, op := range ops {
+		buf, err := op.MarshalEDN()
+		if err != nil {
+			return fmt.Errorf("history: op %d: %w", op.Index, err)
+		}
+		bw.Write(buf)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// MarshalEDN returns the op as an EDN map, with keys in Maelstrom's order.
+func (op Op) MarshalEDN() ([]byte, error) {
+	var process any = op.Process
+	if !op.IsClient() {
+		process = edn.Keyword("nemesis")
+	}
+
+	keys := []string{"index", "time", "type", "process", "f", "value"}
+	values := []any{op.Index, op.Time, edn.Keyword(op.Type), process, edn.Keyword(op.F), op.Value}
+	if op.Error != nil {
+		keys, values = append(keys, "error"), append(values, op.Error)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		v, err := edn.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(":" + key + " ")
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func intField(m map[any]any, key string) int64 {
+	i, _ := m[edn.Keyword(key)].(int64)
+	return i
+}
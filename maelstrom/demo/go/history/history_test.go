@@ -0,0 +1,106 @@
+package history_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+func TestRead(t *testing.T) {
+	ops, err := history.Read(strings.NewReader(`[
+#jepsen.history.Op{:index 1, :time 20, :type :ok, :process 0, :f :read, :value [0 3]}
+{:index 0, :time 10, :type :invoke, :process 0, :f :read, :value [0 nil]}
+{:index 2, :time 30, :type :info, :process :nemesis, :f :kill, :value ["n1"]}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []history.Op{
+		{Index: 0, Time: 10, Type: history.Invoke, Process: 0, F: "read", Value: []any{int64(0), nil}},
+		{Index: 1, Time: 20, Type: history.OK, Process: 0, F: "read", Value: []any{int64(0), int64(3)}},
+		{Index: 2, Time: 30, Type: history.Info, Process: history.NemesisProcess, F: "kill", Value: []any{"n1"}},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("ops=%#v, want %#v", ops, want)
+	}
+
+	if _, err := history.Read(strings.NewReader(`{:index 0, :process 0}`)); err == nil || err.Error() != `history: op 0 has no type` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPairs(t *testing.T) {
+	ops := []history.Op{
+		{Index: 0, Type: history.Invoke, Process: 0, F: "write", Value: 1},
+		{Index: 1, Type: history.Invoke, Process: 1, F: "read"},
+		{Index: 2, Type: history.Info, Process: history.NemesisProcess, F: "start"},
+		{Index: 3, Type: history.OK, Process: 1, F: "read", Value: 1},
+	}
+	pairs := history.Pairs(ops)
+	if got, want := len(pairs), 2; got != want {
+		t.Fatalf("len=%d, want %d", got, want)
+	} else if got, want := pairs[1].Complete, ops[3]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("complete=%v, want %v", got, want)
+	} else if c := pairs[0].Complete; c.Type != history.Info || c.Time != math.MaxInt64 {
+		t.Fatalf("unexpected completion of unfinished op: %v", c)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	clock := maelstrom.NewFakeClock(time.Unix(0, 0))
+	r := history.NewRecorder(clock)
+
+	invoke := r.Invoke(3, "write", 5)
+	clock.Add(2 * time.Millisecond)
+	r.Complete(invoke, history.OK, 5)
+	r.Fail(r.Invoke(4, "read", nil), history.Fail, 11)
+	r.Nemesis("kill", []any{"n1"})
+
+	want := []history.Op{
+		{Index: 0, Type: history.Invoke, Process: 3, F: "write", Value: 5},
+		{Index: 1, Type: history.OK, Process: 3, F: "write", Value: 5, Time: int64(2 * time.Millisecond)},
+		{Index: 2, Type: history.Invoke, Process: 4, F: "read", Time: int64(2 * time.Millisecond)},
+		{Index: 3, Type: history.Fail, Process: 4, F: "read", Error: 11, Time: int64(2 * time.Millisecond)},
+		{Index: 4, Type: history.Info, Process: history.NemesisProcess, F: "kill", Value: []any{"n1"}, Time: int64(2 * time.Millisecond)},
+	}
+	if got := r.Ops(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ops=%v, want %v", got, want)
+	}
+}
+
+// Ensure written histories read back as they were recorded.
+func TestWrite(t *testing.T) {
+	ops := []history.Op{
+		{Index: 0, Time: 10, Type: history.Invoke, Process: 0, F: "read", Value: []any{int64(0), nil}},
+		{Index: 1, Time: 20, Type: history.Fail, Process: 0, F: "read", Value: []any{int64(0), nil}, Error: int64(11)},
+		{Index: 2, Time: 30, Type: history.Info, Process: history.NemesisProcess, F: "kill", Value: []any{"n1"}},
+	}
+
+	var buf strings.Builder
+	if err := history.Write(&buf, ops); err != nil {
+		t.Fatal(err)
+	} else if got, want := strings.SplitN(buf.String(), "\n", 2)[0], `{:index 0, :time 10, :type :invoke, :process 0, :f :read, :value [0 nil]}`; got != want {
+		t.Fatalf("first line=%s, want %s", got, want)
+	}
+
+	if got, err := history.Read(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, ops) {
+		t.Fatalf("ops=%v, want %v", got, ops)
+	}
+}
+
+// Ensure the Keyword type round trips through op strings for reports.
+func TestOp_String(t *testing.T) {
+	op := history.Op{Type: history.OK, Process: 1, F: "read", Value: []any{int64(0), edn.Keyword("x")}}
+	if got, want := op.String(), "1 ok read [0 :x]"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Recorder records a history in-process, e.g. from clients of a simulated
+// network, so it can be checked without running Maelstrom.
+type Recorder struct {
+	mu    sync.Mutex
+	clock maelstrom.Clock
+	start time.Time
+	ops   []Op
+}
+
+// NewRecorder returns a recorder that timestamps ops with clock, relative to
+// the time the recorder was created. A nil clock uses real time.
+func NewRecorder(clock maelstrom.Clock) *Recorder {
+	if clock == nil {
+		clock = maelstrom.NewRealClock()
+	}
+	return &Recorder{clock: clock, start: clock.Now()}
+}
+
+// Invoke records the invocation of f by process and returns it.
+func (r *Recorder) Invoke(process int, f string, value any) Op {
+	return r.record(Op{Type: Invoke, Process: process, F: f, Value: value})
+}
+
+// Complete records the completion of invoke with the given type & value.
+func (r *Recorder) Complete(invoke Op, typ Type, value any) Op {
+	return r.record(Op{Type: typ, Process: invoke.Process, F: invoke.F, Value: value})
+}
+
+// Fail records the completion of invoke with the given type & error, such
+// as an RPC error code.
+func (r *Recorder) Fail(invoke Op, typ Type, err any) Op {
+	return r.record(Op{Type: typ, Process: invoke.Process, F: invoke.F, Value: invoke.Value, Error: err})
+}
+
+// Nemesis records a fault injected by the nemesis, or its outcome, as an
+// info op. Checkers ignore these ops, but they mark when faults happened.
+func (r *Recorder) Nemesis(f string, value any) Op {
+	return r.record(Op{Type: Info, Process: NemesisProcess, F: f, Value: value})
+}
+
+// Ops returns a copy of the recorded history.
+func (r *Recorder) Ops() []Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+func (r *Recorder) record(op Op) Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op.Index = len(r.ops)
+	op.Time = r.clock.Since(r.start).Nanoseconds()
+	r.ops = append(r.ops, op)
+	return op
+}
@@ -0,0 +1,69 @@
+package maelstrom
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+)
+
+// EnableHLC attaches a hybrid logical clock to the node. Once enabled, every
+// message sent to another node in the cluster is stamped with an "hlc" field
+// and the clock advances on every stamped message received. Messages to &
+// from clients and services are left untouched. This should be called before
+// Run().
+func (n *Node) EnableHLC() *hlc.Clock {
+	n.hlc = hlc.New(func() int64 { return n.clock.Now().UnixNano() })
+	return n.hlc
+}
+
+// HLC returns the node's hybrid logical clock. Returns nil if EnableHLC() has
+// not been called. Handlers can use HLC().Now() for LWW versions & snapshot
+// timestamps that respect causality across nodes.
+func (n *Node) HLC() *hlc.Clock {
+	return n.hlc
+}
+
+// stampHLC adds the current HLC timestamp to a marshaled message body if the
+// clock is enabled and the destination is a node in the cluster.
+func (n *Node) stampHLC(dest string, body []byte) ([]byte, error) {
+	if n.hlc == nil || !n.isNodeID(dest) {
+		return body, nil
+	}
+
+	// Numbers are kept as json.Number so large integers survive re-encoding.
+	var b map[string]any
+	if err := unmarshalNumbers(body, &b); err != nil {
+		return nil, err
+	}
+	b["hlc"] = n.hlc.Now()
+	return json.Marshal(b)
+}
+
+// observeHLC advances the clock with the timestamp on a received message
+// body, if present.
+func (n *Node) observeHLC(body []byte) {
+	if n.hlc == nil {
+		return
+	}
+
+	var b struct {
+		HLC *hlc.Timestamp `json:"hlc"`
+	}
+	if err := json.Unmarshal(body, &b); err != nil || b.HLC == nil {
+		return
+	}
+	if _, err := n.hlc.Update(*b.HLC); err != nil {
+		log.Printf("hlc error: %s", err)
+	}
+}
+
+// isNodeID returns true if id is a node in the cluster.
+func (n *Node) isNodeID(id string) bool {
+	for _, nodeID := range n.nodeIDs {
+		if nodeID == id {
+			return true
+		}
+	}
+	return false
+}
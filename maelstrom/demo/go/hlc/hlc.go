@@ -0,0 +1,142 @@
+// Package hlc implements hybrid logical clocks.
+//
+// A hybrid logical clock combines a physical timestamp with a logical counter
+// so that timestamps stay close to wall-clock time while still respecting
+// causality: if event a happened before event b, a's timestamp is less than
+// b's, even when the physical clocks of the nodes involved are skewed.
+package hlc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClockOffset is returned when a remote timestamp is further ahead of the
+// local physical clock than the configured maximum offset.
+var ErrClockOffset = errors.New("hlc: remote timestamp exceeds max clock offset")
+
+// Timestamp represents a point in hybrid logical time.
+type Timestamp struct {
+	// Physical time, in nanoseconds since the Unix epoch.
+	Wall int64
+
+	// Logical counter used to order events with the same wall time.
+	Logical int32
+}
+
+// IsZero returns true if t is the zero timestamp.
+func (t Timestamp) IsZero() bool {
+	return t == Timestamp{}
+}
+
+// Compare returns -1 if t is before u, 1 if t is after u and 0 if equal.
+func (t Timestamp) Compare(u Timestamp) int {
+	switch {
+	case t.Wall < u.Wall:
+		return -1
+	case t.Wall > u.Wall:
+		return 1
+	case t.Logical < u.Logical:
+		return -1
+	case t.Logical > u.Logical:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Before returns true if t is strictly before u.
+func (t Timestamp) Before(u Timestamp) bool { return t.Compare(u) < 0 }
+
+// After returns true if t is strictly after u.
+func (t Timestamp) After(u Timestamp) bool { return t.Compare(u) > 0 }
+
+// String returns a string representation of the timestamp.
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%d.%d", t.Wall, t.Logical)
+}
+
+// MarshalJSON encodes the timestamp as a compact [wall, logical] array.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int64{t.Wall, int64(t.Logical)})
+}
+
+// UnmarshalJSON decodes a timestamp from a [wall, logical] array.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var a [2]int64
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("unmarshal hlc timestamp: %w", err)
+	}
+	t.Wall, t.Logical = a[0], int32(a[1])
+	return nil
+}
+
+// Clock represents a hybrid logical clock.
+type Clock struct {
+	mu       sync.Mutex
+	physical func() int64
+	last     Timestamp
+
+	// MaxOffset, if non-zero, is the maximum amount (in nanoseconds) that a
+	// remote timestamp may be ahead of the local physical clock. Timestamps
+	// beyond this are rejected by Update().
+	MaxOffset int64
+}
+
+// New returns a new clock that reads physical time, in nanoseconds, from
+// physical.
+func New(physical func() int64) *Clock {
+	return &Clock{physical: physical}
+}
+
+// Now returns a new timestamp for a local or send event. Each call returns a
+// timestamp strictly greater than any previously returned or observed.
+func (c *Clock) Now() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pt := c.physical(); pt > c.last.Wall {
+		c.last = Timestamp{Wall: pt}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update advances the clock on receipt of a remote timestamp and returns a
+// timestamp for the receive event which is greater than both the remote
+// timestamp and any previous local timestamp.
+func (c *Clock) Update(remote Timestamp) (Timestamp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pt := c.physical()
+	if c.MaxOffset > 0 && remote.Wall-pt > c.MaxOffset {
+		return c.last, ErrClockOffset
+	}
+
+	switch {
+	case pt > c.last.Wall && pt > remote.Wall:
+		c.last = Timestamp{Wall: pt}
+	case remote.Wall > c.last.Wall:
+		c.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	case c.last.Wall > remote.Wall:
+		c.last.Logical++
+	default:
+		if remote.Logical > c.last.Logical {
+			c.last.Logical = remote.Logical
+		}
+		c.last.Logical++
+	}
+	return c.last, nil
+}
+
+// Last returns the most recent timestamp issued or observed by the clock
+// without advancing it.
+func (c *Clock) Last() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
@@ -0,0 +1,109 @@
+package hlc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+)
+
+func TestClock_Now(t *testing.T) {
+	var pt int64 = 100
+	c := hlc.New(func() int64 { return pt })
+
+	if got, want := c.Now(), (hlc.Timestamp{Wall: 100}); got != want {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	// Physical clock stalls, so the logical counter breaks the tie.
+	if got, want := c.Now(), (hlc.Timestamp{Wall: 100, Logical: 1}); got != want {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	// Physical clock moves backwards, which must not move the clock backwards.
+	pt = 50
+	if got, want := c.Now(), (hlc.Timestamp{Wall: 100, Logical: 2}); got != want {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+
+	pt = 200
+	if got, want := c.Now(), (hlc.Timestamp{Wall: 200}); got != want {
+		t.Fatalf("now=%s, want %s", got, want)
+	}
+}
+
+func TestClock_Update(t *testing.T) {
+	t.Run("RemoteAhead", func(t *testing.T) {
+		c := hlc.New(func() int64 { return 100 })
+		ts, err := c.Update(hlc.Timestamp{Wall: 500, Logical: 3})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := ts, (hlc.Timestamp{Wall: 500, Logical: 4}); got != want {
+			t.Fatalf("ts=%s, want %s", got, want)
+		}
+
+		// Subsequent local events stay ahead of the observed timestamp.
+		if got, want := c.Now(), (hlc.Timestamp{Wall: 500, Logical: 5}); got != want {
+			t.Fatalf("now=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("PhysicalAhead", func(t *testing.T) {
+		c := hlc.New(func() int64 { return 1000 })
+		ts, err := c.Update(hlc.Timestamp{Wall: 500, Logical: 3})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := ts, (hlc.Timestamp{Wall: 1000}); got != want {
+			t.Fatalf("ts=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("SameWall", func(t *testing.T) {
+		c := hlc.New(func() int64 { return 100 })
+		c.Now()
+		c.Now()
+		ts, err := c.Update(hlc.Timestamp{Wall: 100, Logical: 7})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := ts, (hlc.Timestamp{Wall: 100, Logical: 8}); got != want {
+			t.Fatalf("ts=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("ErrClockOffset", func(t *testing.T) {
+		c := hlc.New(func() int64 { return 100 })
+		c.MaxOffset = 50
+		if _, err := c.Update(hlc.Timestamp{Wall: 200}); err != hlc.ErrClockOffset {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got := c.Last(); !got.IsZero() {
+			t.Fatalf("clock advanced to %s", got)
+		}
+	})
+}
+
+func TestTimestamp_Compare(t *testing.T) {
+	a := hlc.Timestamp{Wall: 1, Logical: 5}
+	b := hlc.Timestamp{Wall: 2}
+	c := hlc.Timestamp{Wall: 2, Logical: 1}
+	if !a.Before(b) || !b.Before(c) || !c.After(a) {
+		t.Fatal("unexpected ordering")
+	} else if a.Compare(a) != 0 {
+		t.Fatal("expected equal")
+	}
+}
+
+func TestTimestamp_JSON(t *testing.T) {
+	buf, err := json.Marshal(hlc.Timestamp{Wall: 1700000000000000000, Logical: 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := string(buf), `[1700000000000000000,2]`; got != want {
+		t.Fatalf("json=%s, want %s", got, want)
+	}
+
+	var ts hlc.Timestamp
+	if err := json.Unmarshal(buf, &ts); err != nil {
+		t.Fatal(err)
+	} else if got, want := ts, (hlc.Timestamp{Wall: 1700000000000000000, Logical: 2}); got != want {
+		t.Fatalf("ts=%s, want %s", got, want)
+	}
+}
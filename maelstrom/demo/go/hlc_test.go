@@ -0,0 +1,73 @@
+package maelstrom_test
+
+import (
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
+)
+
+// Ensure messages between nodes carry HLC timestamps and advance the clock.
+func TestNode_EnableHLC(t *testing.T) {
+	n, stdin, stdout := newNode(t)
+	n.SetClock(maelstrom.NewFakeClock(time.Unix(0, 100)))
+	n.EnableHLC()
+
+	received := make(chan struct{})
+	n.Handle("ping", func(msg maelstrom.Message) error {
+		close(received)
+		return n.Send("n2", map[string]any{"type": "pong"})
+	})
+	n.Handle("echo", func(msg maelstrom.Message) error {
+		return n.Reply(msg, map[string]any{"type": "echo_ok"})
+	})
+	initNode(t, n, "n1", []string{"n1", "n2"}, stdin, stdout)
+
+	// A message from a peer that is ahead advances the local clock.
+	if _, err := stdin.Write([]byte(`{"src":"n2","dest":"n1","body":{"type":"ping","hlc":[500,3]}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	<-received
+
+	if line, err := stdout.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	} else if got, want := line, `{"src":"n1","dest":"n2","body":{"hlc":[500,5],"type":"pong"}}`+"\n"; got != want {
+		t.Fatalf("response=%s, want %s", got, want)
+	}
+
+	// Replies to clients are not stamped.
+	if _, err := stdin.Write([]byte(`{"src":"c1","dest":"n1","body":{"type":"echo","msg_id":1}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := stdout.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	} else if got, want := line, `{"src":"n1","dest":"c1","body":{"in_reply_to":1,"type":"echo_ok"}}`+"\n"; got != want {
+		t.Fatalf("response=%s, want %s", got, want)
+	}
+
+	if got, want := n.HLC().Last(), (hlc.Timestamp{Wall: 500, Logical: 5}); got != want {
+		t.Fatalf("last=%s, want %s", got, want)
+	}
+}
+
+// Ensure stamping a body with an HLC timestamp keeps large integers intact.
+func TestNode_EnableHLC_LargeIntegers(t *testing.T) {
+	n, stdin, stdout := newNode(t)
+	n.SetClock(maelstrom.NewFakeClock(time.Unix(0, 100)))
+	n.EnableHLC()
+
+	n.Handle("ping", func(msg maelstrom.Message) error {
+		return n.Send("n2", map[string]any{"type": "pong", "value": uint64(1<<64 - 1)})
+	})
+	initNode(t, n, "n1", []string{"n1", "n2"}, stdin, stdout)
+
+	if _, err := stdin.Write([]byte(`{"src":"c1","dest":"n1","body":{"type":"ping"}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := stdout.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	} else if got, want := line, `{"src":"n1","dest":"n2","body":{"hlc":[100,0],"type":"pong","value":18446744073709551615}}`+"\n"; got != want {
+		t.Fatalf("response=%s, want %s", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+package isolation
+
+import "fmt"
+
+// Edge kinds, in order of preference when a pair of transactions is linked
+// by several.
+const (
+	wwEdge = 1 << iota
+	wrEdge
+	rwEdge
+	realtimeEdge
+
+	allEdges = wwEdge | wrEdge | rwEdge | realtimeEdge
+)
+
+var edgeKinds = []struct {
+	kind int
+	name string
+}{
+	{wwEdge, "ww"},
+	{wrEdge, "wr"},
+	{rwEdge, "rw"},
+	{realtimeEdge, "realtime"},
+}
+
+type edge struct {
+	kinds int
+	why   map[int]string // first explanation of each kind
+}
+
+// graph is a dependency graph between transactions. Nodes & successors are
+// kept in insertion order so results are deterministic.
+type graph struct {
+	nodes []*Txn
+	succ  map[*Txn][]*Txn
+	edges map[[2]*Txn]*edge
+}
+
+func newGraph() *graph {
+	return &graph{
+		succ:  make(map[*Txn][]*Txn),
+		edges: make(map[[2]*Txn]*edge),
+	}
+}
+
+// link adds an edge of the given kind from a to b.
+func (g *graph) link(a, b *Txn, kind int, why string) {
+	if a == b {
+		return
+	}
+	for _, t := range []*Txn{a, b} {
+		if _, ok := g.succ[t]; !ok {
+			g.succ[t] = nil
+			g.nodes = append(g.nodes, t)
+		}
+	}
+
+	e := g.edges[[2]*Txn{a, b}]
+	if e == nil {
+		e = &edge{why: make(map[int]string)}
+		g.edges[[2]*Txn{a, b}] = e
+		g.succ[a] = append(g.succ[a], b)
+	}
+	if e.kinds&kind == 0 {
+		e.kinds |= kind
+		e.why[kind] = why
+	}
+}
+
+// sccs returns the strongly connected components with more than one node,
+// using Tarjan's algorithm.
+func (g *graph) sccs() [][]*Txn {
+	index := make(map[*Txn]int)
+	low := make(map[*Txn]int)
+	onStack := make(map[*Txn]bool)
+	var stack []*Txn
+	var sccs [][]*Txn
+
+	var visit func(t *Txn)
+	visit = func(t *Txn) {
+		index[t] = len(index)
+		low[t] = index[t]
+		stack = append(stack, t)
+		onStack[t] = true
+
+		for _, u := range g.succ[t] {
+			if _, ok := index[u]; !ok {
+				visit(u)
+				if low[u] < low[t] {
+					low[t] = low[u]
+				}
+			} else if onStack[u] && index[u] < low[t] {
+				low[t] = index[u]
+			}
+		}
+
+		if low[t] == index[t] {
+			var scc []*Txn
+			for {
+				u := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[u] = false
+				scc = append(scc, u)
+				if u == t {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				sccs = append(sccs, scc)
+			}
+		}
+	}
+
+	for _, t := range g.nodes {
+		if _, ok := index[t]; !ok {
+			visit(t)
+		}
+	}
+	return sccs
+}
+
+// path returns the shortest path from src to dst using only edges of the
+// given kinds between members of scc.
+func (g *graph) path(src, dst *Txn, kinds int, scc map[*Txn]bool) []*Txn {
+	parent := map[*Txn]*Txn{src: nil}
+	queue := []*Txn{src}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if t == dst {
+			var path []*Txn
+			for ; t != nil; t = parent[t] {
+				path = append([]*Txn{t}, path...)
+			}
+			return path
+		}
+
+		for _, u := range g.succ[t] {
+			if _, seen := parent[u]; seen || !scc[u] || g.edges[[2]*Txn{t, u}].kinds&kinds == 0 {
+				continue
+			}
+			parent[u] = t
+			queue = append(queue, u)
+		}
+	}
+	return nil
+}
+
+// step describes the edge from a to b, preferring the first of the allowed
+// kinds.
+func (g *graph) step(a, b *Txn, kinds int) Step {
+	e := g.edges[[2]*Txn{a, b}]
+	for _, k := range edgeKinds {
+		if e.kinds&kinds&k.kind != 0 {
+			return Step{From: a, To: b, Kind: k.name, Explanation: e.why[k.kind]}
+		}
+	}
+	panic(fmt.Sprintf("isolation: no edge from T%d to T%d", a.Index, b.Index))
+}
+
+// cycleSearches are tried in order on each strongly connected component:
+// cycles through an edge of the first kind, closed by a path of the second.
+var cycleSearches = []struct{ start, path int }{
+	{wwEdge, wwEdge},                   // G0
+	{wrEdge, wwEdge | wrEdge},          // G1c
+	{rwEdge, wwEdge | wrEdge},          // G-single
+	{rwEdge, wwEdge | wrEdge | rwEdge}, // G2
+	{realtimeEdge, allEdges},           // *-realtime
+}
+
+// cycles reports one cycle of each anomaly type found in each strongly
+// connected component of the graph.
+func (a *analysis) cycles() {
+	g := a.graph
+	for _, scc := range g.sccs() {
+		members := make(map[*Txn]bool, len(scc))
+		for _, t := range scc {
+			members[t] = true
+		}
+
+		found := make(map[string]bool)
+		for _, search := range cycleSearches {
+			a.search(scc, members, search.start, search.path, found)
+		}
+	}
+}
+
+// search looks for a cycle through an edge of kind start, closed by a path
+// of the given kinds, whose type hasn't been found yet.
+func (a *analysis) search(scc []*Txn, members map[*Txn]bool, start, kinds int, found map[string]bool) {
+	g := a.graph
+	for _, t := range scc {
+		for _, u := range g.succ[t] {
+			if !members[u] || g.edges[[2]*Txn{t, u}].kinds&start == 0 {
+				continue
+			}
+			path := g.path(u, t, kinds, members)
+			if path == nil {
+				continue
+			}
+
+			cycle := []Step{g.step(t, u, start)}
+			for i := 0; i+1 < len(path); i++ {
+				cycle = append(cycle, g.step(path[i], path[i+1], kinds))
+			}
+			cycle = rotate(cycle)
+			if typ := classify(cycle); !found[typ] {
+				found[typ] = true
+				a.anomalies = append(a.anomalies, Anomaly{
+					Type:        typ,
+					Cycle:       cycle,
+					Explanation: fmt.Sprintf("cycle of %d transactions", len(cycle)),
+				})
+				return
+			}
+		}
+	}
+}
+
+// rotate returns the cycle starting from the transaction with the lowest
+// index, so reports don't depend on graph traversal order.
+func rotate(cycle []Step) []Step {
+	first := 0
+	for i, s := range cycle {
+		if s.From.Index < cycle[first].From.Index {
+			first = i
+		}
+	}
+	return append(cycle[first:], cycle[:first]...)
+}
+
+// classify names a cycle by the kinds of its edges.
+func classify(cycle []Step) string {
+	var wr, rw, realtime int
+	for _, s := range cycle {
+		switch s.Kind {
+		case "wr":
+			wr++
+		case "rw":
+			rw++
+		case "realtime":
+			realtime++
+		}
+	}
+
+	typ := G2
+	switch {
+	case rw == 0 && wr == 0:
+		typ = G0
+	case rw == 0:
+		typ = G1c
+	case rw == 1:
+		typ = GSingle
+	}
+	if realtime > 0 {
+		typ += "-realtime"
+	}
+	return typ
+}
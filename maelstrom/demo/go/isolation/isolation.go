@@ -0,0 +1,382 @@
+// Package isolation checks transactional histories for the anomalies of
+// Adya's isolation levels, in the style of Elle.
+//
+// Transactions from txn-rw-register and txn-list-append histories are linked
+// by write-write (ww), write-read (wr) and read-write anti-dependency (rw)
+// edges inferred from the values they read & wrote, plus optional realtime
+// edges between transactions that did not overlap. Cycles in this graph are
+// classified as G0 (ww only), G1c (ww & wr), G-single (exactly one rw) or G2
+// (several rw). G1a (aborted read) and G1b (intermediate read) are detected
+// directly from reads. Like Elle, the checker relies on every value written
+// to a key being unique.
+package isolation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Anomaly types. Cycles that include realtime edges have a "-realtime"
+// suffix, e.g. "G-single-realtime".
+const (
+	G0      = "G0"
+	G1a     = "G1a"
+	G1b     = "G1b"
+	G1c     = "G1c"
+	GSingle = "G-single"
+	G2      = "G2"
+
+	// Internal is a read that disagrees with an earlier write in the same
+	// transaction.
+	Internal = "internal"
+
+	// IncompatibleOrder is a pair of list reads where neither is a prefix of
+	// the other.
+	IncompatibleOrder = "incompatible-order"
+
+	// DuplicateElements is a list read containing the same element twice.
+	DuplicateElements = "duplicate-elements"
+)
+
+// Consistency models for Result.Valid.
+const (
+	ReadUncommitted    = "read-uncommitted"
+	ReadCommitted      = "read-committed"
+	Serializable       = "serializable"
+	StrictSerializable = "strict-serializable"
+)
+
+// MicroOp is a single read, write or append within a transaction. F is "r",
+// "w" or "append".
+type MicroOp struct {
+	F     string
+	Key   any
+	Value any
+}
+
+// String returns the micro-op in Maelstrom's notation, e.g. [r 1 nil].
+func (m MicroOp) String() string {
+	return fmt.Sprintf("[%s %v %s]", m.F, m.Key, formatValue(m.Value))
+}
+
+// Txn is a transaction of a history.
+type Txn struct {
+	// Index of the invocation in the history.
+	Index   int
+	Process int
+
+	// OK, Info or Fail. The micro-ops of ok transactions come from the
+	// completion, so they include the values read; the others come from the
+	// invocation.
+	Type history.Type
+	Ops  []MicroOp
+
+	Call, Return int64
+}
+
+// String returns a short description such as "T4 (process 1) [[r 1 2]]".
+func (t *Txn) String() string {
+	ops := make([]string, len(t.Ops))
+	for i, op := range t.Ops {
+		ops[i] = op.String()
+	}
+	return fmt.Sprintf("T%d (process %d) [%s]", t.Index, t.Process, strings.Join(ops, " "))
+}
+
+// Txns converts a history of "txn" operations into transactions.
+func Txns(ops []history.Op) ([]*Txn, error) {
+	var txns []*Txn
+	for _, p := range history.Pairs(ops) {
+		if p.Invoke.F != "txn" {
+			continue
+		}
+
+		value := p.Invoke.Value
+		if p.Complete.Type == history.OK {
+			value = p.Complete.Value
+		}
+		mops, err := microOps(value)
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", p.Invoke.Index, err)
+		}
+
+		ret := p.Complete.Time
+		if p.Complete.Type != history.OK {
+			ret = math.MaxInt64
+		}
+		txns = append(txns, &Txn{
+			Index:   p.Invoke.Index,
+			Process: p.Invoke.Process,
+			Type:    p.Complete.Type,
+			Ops:     mops,
+			Call:    p.Invoke.Time,
+			Return:  ret,
+		})
+	}
+	return txns, nil
+}
+
+func microOps(value any) ([]MicroOp, error) {
+	a, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("transaction is not a list: %v", value)
+	}
+
+	mops := make([]MicroOp, len(a))
+	for i, v := range a {
+		m, ok := v.([]any)
+		if !ok || len(m) != 3 {
+			return nil, fmt.Errorf("invalid micro-op: %v", v)
+		}
+
+		var f string
+		switch x := m[0].(type) {
+		case edn.Keyword:
+			f = string(x)
+		case string:
+			f = x
+		}
+		switch f {
+		case "r", "w", "append":
+		default:
+			return nil, fmt.Errorf("invalid micro-op function: %v", m[0])
+		}
+		mops[i] = MicroOp{F: f, Key: normalize(m[1]), Value: normalize(m[2])}
+	}
+	return mops, nil
+}
+
+// Options configures a check.
+type Options struct {
+	// Realtime adds edges from each transaction to those invoked after it
+	// completed, which is required to check strict serializability.
+	Realtime bool
+}
+
+// Anomaly is a single anomaly found in a history.
+type Anomaly struct {
+	Type string
+
+	// Cycle is the cycle of dependencies for G0, G1c, G-single & G2.
+	Cycle []Step
+
+	// Txns are the transactions involved in non-cycle anomalies, e.g. the
+	// reader & the aborted writer of a G1a.
+	Txns []*Txn
+
+	// Explanation describes the anomaly in terms of the values involved.
+	Explanation string
+}
+
+// Step is an edge of a cycle: From must precede To because of Explanation.
+type Step struct {
+	From, To    *Txn
+	Kind        string // "ww", "wr", "rw" or "realtime"
+	Explanation string
+}
+
+// String renders the anomaly with the offending transactions.
+func (a Anomaly) String() string {
+	var sb strings.Builder
+	sb.WriteString(a.Type)
+	sb.WriteString(":")
+	if a.Explanation != "" {
+		sb.WriteString(" " + a.Explanation)
+	}
+	sb.WriteString("\n")
+
+	if len(a.Cycle) > 0 {
+		for _, s := range a.Cycle {
+			fmt.Fprintf(&sb, "  T%d -%s-> T%d: %s\n", s.From.Index, s.Kind, s.To.Index, s.Explanation)
+		}
+		sb.WriteString("  where\n")
+		for _, s := range a.Cycle {
+			fmt.Fprintf(&sb, "    %s\n", s.From)
+		}
+		return sb.String()
+	}
+	for _, t := range a.Txns {
+		fmt.Fprintf(&sb, "  %s\n", t)
+	}
+	return sb.String()
+}
+
+// Result is the result of a check.
+type Result struct {
+	Txns      int
+	Anomalies []Anomaly
+}
+
+// Types returns the sorted, distinct types of anomalies found.
+func (r Result) Types() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, a := range r.Anomalies {
+		if !seen[a.Type] {
+			seen[a.Type] = true
+			types = append(types, a.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Valid reports whether the history satisfies the given consistency model,
+// i.e. none of the anomalies found are proscribed by it.
+func (r Result) Valid(model string) bool {
+	for _, a := range r.Anomalies {
+		if proscribed(model, a.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+func proscribed(model, typ string) bool {
+	base := strings.TrimSuffix(typ, "-realtime")
+	realtime := base != typ
+
+	switch model {
+	case ReadUncommitted:
+		return base == G0 && !realtime
+	case ReadCommitted:
+		switch base {
+		case G0, G1a, G1b, G1c:
+			return !realtime
+		}
+		return base == Internal
+	case Serializable:
+		return !realtime
+	}
+	return true
+}
+
+// Check checks a txn-rw-register or txn-list-append history. Histories that
+// contain appends are checked as list-append.
+func Check(ops []history.Op, opts Options) (Result, error) {
+	txns, err := Txns(ops)
+	if err != nil {
+		return Result{}, err
+	}
+	return CheckTxns(txns, opts), nil
+}
+
+// CheckTxns checks transactions as returned by Txns.
+func CheckTxns(txns []*Txn, opts Options) Result {
+	a := &analysis{graph: newGraph()}
+	if isListAppend(txns) {
+		a.listAppend(txns)
+	} else {
+		a.rwRegister(txns)
+	}
+	if opts.Realtime {
+		a.realtime(txns)
+	}
+	a.cycles()
+
+	return Result{Txns: len(txns), Anomalies: a.anomalies}
+}
+
+func isListAppend(txns []*Txn) bool {
+	for _, t := range txns {
+		for _, m := range t.Ops {
+			if m.F == "append" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// analysis accumulates the dependency graph & anomalies of a history.
+type analysis struct {
+	graph     *graph
+	anomalies []Anomaly
+}
+
+func (a *analysis) anomaly(typ, explanation string, txns ...*Txn) {
+	a.anomalies = append(a.anomalies, Anomaly{Type: typ, Txns: txns, Explanation: explanation})
+}
+
+// realtime links each ok transaction to those invoked after it returned.
+// Only the frontier of most recently completed transactions is linked, which
+// preserves reachability with far fewer edges.
+func (a *analysis) realtime(txns []*Txn) {
+	type event struct {
+		t    *Txn
+		ret  bool
+		time int64
+	}
+	var events []event
+	for _, t := range txns {
+		if t.Type == history.OK {
+			events = append(events, event{t, false, t.Call}, event{t, true, t.Return})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time != events[j].time {
+			return events[i].time < events[j].time
+		}
+		return !events[i].ret && events[j].ret
+	})
+
+	frontier := make(map[*Txn]bool)
+	preds := make(map[*Txn][]*Txn)
+	for _, ev := range events {
+		if !ev.ret {
+			for p := range frontier {
+				preds[ev.t] = append(preds[ev.t], p)
+			}
+			continue
+		}
+
+		for _, p := range preds[ev.t] {
+			delete(frontier, p)
+			a.graph.link(p, ev.t, realtimeEdge, fmt.Sprintf("T%d completed before T%d began", p.Index, ev.t.Index))
+		}
+		frontier[ev.t] = true
+	}
+}
+
+// normalize converts integral numbers to int64 so keys & values compare
+// equal regardless of whether they came from EDN or JSON.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case float64:
+		if x == math.Trunc(x) {
+			return int64(x)
+		}
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+	case []any:
+		a := make([]any, len(x))
+		for i := range x {
+			a[i] = normalize(x[i])
+		}
+		return a
+	}
+	return v
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "nil"
+	} else if a, ok := v.([]any); ok {
+		s := make([]string, len(a))
+		for i := range a {
+			s[i] = formatValue(a[i])
+		}
+		return "[" + strings.Join(s, " ") + "]"
+	}
+	return fmt.Sprint(v)
+}
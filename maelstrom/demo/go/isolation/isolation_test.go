@@ -0,0 +1,148 @@
+package isolation_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/isolation"
+)
+
+// txn returns an ok transaction that ran from call to ret.
+func txn(index int, call, ret int64, ops ...isolation.MicroOp) *isolation.Txn {
+	return &isolation.Txn{Index: index, Process: index, Type: history.OK, Ops: ops, Call: call, Return: ret}
+}
+
+func r(k, v any) isolation.MicroOp        { return isolation.MicroOp{F: "r", Key: k, Value: v} }
+func w(k, v any) isolation.MicroOp        { return isolation.MicroOp{F: "w", Key: k, Value: v} }
+func appendOp(k, v any) isolation.MicroOp { return isolation.MicroOp{F: "append", Key: k, Value: v} }
+
+func TestCheckTxns_RWRegister(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		txns []*isolation.Txn
+		want []string
+	}{
+		{"Valid", []*isolation.Txn{
+			txn(0, 0, 10, w("x", 1), w("y", 1)),
+			txn(1, 20, 30, r("x", 1), r("y", 1), w("x", 2)),
+			txn(2, 40, 50, r("x", 2)),
+		}, nil},
+		{"G1c", []*isolation.Txn{
+			txn(0, 0, 10, w("x", 1), r("y", 2)),
+			txn(1, 0, 10, w("y", 2), r("x", 1)),
+		}, []string{isolation.G1c}},
+		{"GSingle", []*isolation.Txn{
+			txn(0, 0, 10, w("x", 1), w("y", 1)),
+			txn(1, 0, 10, r("x", nil), r("y", 1)),
+		}, []string{isolation.GSingle}},
+		{"G2", []*isolation.Txn{
+			txn(0, 0, 10, r("x", nil), w("y", 1)),
+			txn(1, 0, 10, r("y", nil), w("x", 2)),
+		}, []string{isolation.G2}},
+		{"G1b", []*isolation.Txn{
+			txn(0, 0, 10, w("x", 1), w("x", 2)),
+			txn(1, 0, 10, r("x", 1)),
+		}, []string{isolation.G1b}},
+		{"Internal", []*isolation.Txn{
+			txn(0, 0, 10, w("x", 1), r("x", 2)),
+		}, []string{isolation.Internal}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := isolation.CheckTxns(tt.txns, isolation.Options{})
+			if got := res.Types(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("types=%v, want %v\n%v", got, tt.want, res.Anomalies)
+			}
+		})
+	}
+}
+
+func TestCheckTxns_ListAppend(t *testing.T) {
+	t.Run("G0", func(t *testing.T) {
+		res := isolation.CheckTxns([]*isolation.Txn{
+			txn(0, 0, 10, appendOp("x", 1), appendOp("y", 2)),
+			txn(1, 0, 10, appendOp("x", 3), appendOp("y", 4)),
+			txn(2, 20, 30, r("x", []any{1, 3}), r("y", []any{4, 2})),
+		}, isolation.Options{})
+		if got, want := res.Types(), []string{isolation.G0}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("types=%v, want %v", got, want)
+		}
+
+		want := "G0: cycle of 2 transactions\n" +
+			"  T0 -ww-> T1: T0 appended 1, then T1 appended 3\n" +
+			"  T1 -ww-> T0: T1 appended 4, then T0 appended 2\n" +
+			"  where\n" +
+			"    T0 (process 0) [[append x 1] [append y 2]]\n" +
+			"    T1 (process 1) [[append x 3] [append y 4]]\n"
+		if got := res.Anomalies[0].String(); got != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("GSingle", func(t *testing.T) {
+		res := isolation.CheckTxns([]*isolation.Txn{
+			txn(0, 0, 10, appendOp("x", 1), appendOp("y", 1)),
+			txn(1, 0, 10, r("x", nil), r("y", []any{1})),
+			txn(2, 20, 30, r("x", []any{1})),
+		}, isolation.Options{})
+		if got, want := res.Types(), []string{isolation.GSingle}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("types=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("IncompatibleOrder", func(t *testing.T) {
+		res := isolation.CheckTxns([]*isolation.Txn{
+			txn(0, 0, 10, appendOp("x", 1)),
+			txn(1, 0, 10, appendOp("x", 2)),
+			txn(2, 20, 30, r("x", []any{1, 2})),
+			txn(3, 20, 30, r("x", []any{2, 1})),
+		}, isolation.Options{})
+		if got, want := res.Types(), []string{isolation.IncompatibleOrder}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("types=%v, want %v", got, want)
+		}
+	})
+}
+
+// Ensure stale reads are only anomalies when realtime order is checked.
+func TestCheckTxns_Realtime(t *testing.T) {
+	txns := []*isolation.Txn{
+		txn(0, 0, 10, w("x", 1)),
+		txn(1, 20, 30, r("x", nil)),
+	}
+
+	if res := isolation.CheckTxns(txns, isolation.Options{}); !res.Valid(isolation.Serializable) {
+		t.Fatalf("unexpected anomalies: %v", res.Anomalies)
+	}
+
+	res := isolation.CheckTxns(txns, isolation.Options{Realtime: true})
+	if got, want := res.Types(), []string{"G-single-realtime"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("types=%v, want %v", got, want)
+	} else if !res.Valid(isolation.Serializable) || res.Valid(isolation.StrictSerializable) {
+		t.Fatal("expected serializable but not strict serializable")
+	}
+}
+
+// Ensure Maelstrom histories are parsed and aborted reads are detected.
+func TestCheck_G1a(t *testing.T) {
+	ops, err := history.Read(strings.NewReader(`
+{:type :invoke, :f :txn, :value [[:w 1 5]], :time 0, :process 0, :index 0}
+{:type :fail, :f :txn, :value [[:w 1 5]], :time 10, :process 0, :index 1}
+{:type :invoke, :f :txn, :value [[:r 1 nil]], :time 20, :process 1, :index 2}
+{:type :ok, :f :txn, :value [[:r 1 5]], :time 30, :process 1, :index 3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := isolation.Check(ops, isolation.Options{})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := res.Types(), []string{isolation.G1a}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("types=%v, want %v", got, want)
+	} else if res.Valid(isolation.ReadCommitted) || !res.Valid(isolation.ReadUncommitted) {
+		t.Fatal("expected read uncommitted but not read committed")
+	} else if got, want := res.Anomalies[0].Explanation, "T2 read 1 = 5 written by aborted T0"; got != want {
+		t.Fatalf("explanation=%q, want %q", got, want)
+	}
+}
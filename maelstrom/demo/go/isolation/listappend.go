@@ -0,0 +1,186 @@
+package isolation
+
+import (
+	"fmt"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// listRead is a read of a list, less any elements the reading transaction
+// appended itself.
+type listRead struct {
+	t     *Txn
+	key   any
+	elems []any
+	ids   []string // ident of each element
+}
+
+// listAppend infers dependencies for a txn-list-append history. Reads reveal
+// the order in which elements were appended, so the version order of each
+// key is the longest list read from it.
+func (a *analysis) listAppend(txns []*Txn) {
+	appenders := make(map[version]*Txn)
+	aborted := make(map[version]*Txn)
+	ownAppends := make(map[*Txn]map[string][]any)
+
+	for _, t := range txns {
+		for _, m := range t.Ops {
+			if m.F != "append" {
+				continue
+			}
+			v := versionOf(m.Key, m.Value)
+			if t.Type == history.Fail {
+				aborted[v] = t
+				continue
+			}
+			appenders[v] = t
+			if ownAppends[t] == nil {
+				ownAppends[t] = make(map[string][]any)
+			}
+			ownAppends[t][v.key] = append(ownAppends[t][v.key], m.Value)
+		}
+	}
+
+	reads := a.listReads(txns)
+
+	// The version order of each key is its longest read. Every other read
+	// must be a prefix of it.
+	longest := make(map[string]listRead)
+	var keys []string
+	for _, r := range reads {
+		k := ident(r.key)
+		prev, ok := longest[k]
+		if !ok {
+			keys = append(keys, k)
+		}
+		switch {
+		case isPrefix(r.ids, prev.ids):
+		case isPrefix(prev.ids, r.ids):
+			longest[k] = r
+		default:
+			a.anomaly(IncompatibleOrder, fmt.Sprintf("reads of %v disagree: %s vs %s", r.key, formatValue(prev.elems), formatValue(r.elems)), prev.t, r.t)
+		}
+	}
+
+	// ww: each appender precedes the appender of the next element.
+	for _, k := range keys {
+		order := longest[k]
+		for i := 0; i+1 < len(order.ids); i++ {
+			w1, w2 := appenders[version{k, order.ids[i]}], appenders[version{k, order.ids[i+1]}]
+			if w1 != nil && w2 != nil {
+				a.graph.link(w1, w2, wwEdge, fmt.Sprintf("T%d appended %s, then T%d appended %s", w1.Index, formatValue(order.elems[i]), w2.Index, formatValue(order.elems[i+1])))
+			}
+		}
+	}
+
+	for _, r := range reads {
+		k := ident(r.key)
+		for i, id := range r.ids {
+			if w := aborted[version{k, id}]; w != nil {
+				a.anomaly(G1a, fmt.Sprintf("T%d read %v = %s containing %s appended by aborted T%d", r.t.Index, r.key, formatTail(r.elems), formatValue(r.elems[i]), w.Index), r.t, w)
+			}
+		}
+
+		// wr: the appender of the last element precedes the reader.
+		if n := len(r.elems); n > 0 {
+			last := r.elems[n-1]
+			if w := appenders[version{k, r.ids[n-1]}]; w != nil && w != r.t {
+				a.graph.link(w, r.t, wrEdge, fmt.Sprintf("T%d appended %s to %v, read by T%d", w.Index, formatValue(last), r.key, r.t.Index))
+				if own := ownAppends[w][k]; ident(own[len(own)-1]) != r.ids[n-1] {
+					a.anomaly(G1b, fmt.Sprintf("T%d read %v = %s, missing later appends of T%d", r.t.Index, r.key, formatTail(r.elems), w.Index), r.t, w)
+				}
+			}
+		}
+
+		// rw: the reader precedes the appender of the next element.
+		if order := longest[k]; len(r.ids) < len(order.ids) {
+			next := order.ids[len(r.ids)]
+			if w := appenders[version{k, next}]; w != nil && w != r.t {
+				a.graph.link(r.t, w, rwEdge, fmt.Sprintf("T%d read %v = %s, T%d appended %s", r.t.Index, r.key, formatTail(r.elems), w.Index, formatValue(order.elems[len(r.ids)])))
+			}
+		}
+	}
+}
+
+// listReads returns the reads of ok transactions. Reads that follow the
+// transaction's own appends must end with them; those elements are removed.
+func (a *analysis) listReads(txns []*Txn) []listRead {
+	var reads []listRead
+	for _, t := range txns {
+		if t.Type != history.OK {
+			continue
+		}
+
+		appended := make(map[string][]string) // idents of own appends
+		appendedValues := make(map[string][]any)
+		for _, m := range t.Ops {
+			k := ident(m.Key)
+			if m.F == "append" {
+				appended[k] = append(appended[k], ident(m.Value))
+				appendedValues[k] = append(appendedValues[k], m.Value)
+				continue
+			} else if m.F != "r" {
+				continue
+			}
+
+			elems, ok := m.Value.([]any)
+			if !ok && m.Value != nil {
+				a.anomaly(Internal, fmt.Sprintf("T%d read %v = %s, which is not a list", t.Index, m.Key, formatValue(m.Value)), t)
+				continue
+			}
+
+			ids := make([]string, len(elems))
+			for i, x := range elems {
+				ids[i] = ident(x)
+			}
+			if i := duplicate(ids); i >= 0 {
+				a.anomaly(DuplicateElements, fmt.Sprintf("T%d read %v = %s containing %s twice", t.Index, m.Key, formatValue(elems), formatValue(elems[i])), t)
+				continue
+			}
+
+			own := appended[k]
+			if len(ids) < len(own) || !isPrefix(own, ids[len(ids)-len(own):]) {
+				a.anomaly(Internal, fmt.Sprintf("T%d read %v = %s after appending %s", t.Index, m.Key, formatTail(elems), formatTail(appendedValues[k])), t)
+				continue
+			}
+			n := len(elems) - len(own)
+			reads = append(reads, listRead{t: t, key: m.Key, elems: elems[:n], ids: ids[:n]})
+		}
+	}
+	return reads
+}
+
+// isPrefix reports whether a is a prefix of b.
+func isPrefix(a, b []string) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTail formats a list, eliding all but its last few elements.
+func formatTail(elems []any) string {
+	const n = 3
+	if len(elems) <= n {
+		return formatValue(elems)
+	}
+	s := formatValue(elems[len(elems)-n:])
+	return "[... " + s[1:]
+}
+
+// duplicate returns the index of the first repeated element, or -1.
+func duplicate(ids []string) int {
+	seen := make(map[string]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			return i
+		}
+		seen[id] = true
+	}
+	return -1
+}
@@ -0,0 +1,167 @@
+package isolation
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// version identifies a value written to a key.
+type version struct {
+	key, value string
+}
+
+func versionOf(key, value any) version {
+	return version{ident(key), ident(value)}
+}
+
+// ident returns a comparable identity for a key or value.
+func ident(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case int64:
+		return "i" + strconv.FormatInt(x, 10)
+	case string:
+		return "s" + x
+	}
+	return fmt.Sprintf("%T %v", v, v)
+}
+
+// rwRegister infers dependencies for a txn-rw-register history. The version
+// order of each key is only partially known: the initial nil precedes every
+// write and a transaction that reads a value and then writes the same key
+// orders the two.
+func (a *analysis) rwRegister(txns []*Txn) {
+	writers := make(map[version]*Txn)
+	aborted := make(map[version]*Txn)
+	intermediate := make(map[version]*Txn)
+	ambiguous := make(map[version]bool)
+	writes := make(map[version]MicroOp) // for explanations
+
+	// Versions that follow each version, including the initial nil, in the
+	// order they were inferred.
+	next := make(map[version][]version)
+	var order []version
+	link := func(v1, v2 version) {
+		for _, v := range next[v1] {
+			if v == v2 {
+				return
+			}
+		}
+		if next[v1] == nil {
+			order = append(order, v1)
+		}
+		next[v1] = append(next[v1], v2)
+	}
+
+	for _, t := range txns {
+		last := make(map[string]int) // last write to each key
+		for i, m := range t.Ops {
+			if m.F == "w" {
+				last[ident(m.Key)] = i
+			}
+		}
+
+		for i, m := range t.Ops {
+			if m.F != "w" {
+				continue
+			}
+			v := versionOf(m.Key, m.Value)
+			writes[v] = m
+
+			if t.Type == history.Fail {
+				aborted[v] = t
+				continue
+			} else if w := writers[v]; w != nil && w != t {
+				ambiguous[v] = true
+			}
+			writers[v] = t
+			link(versionOf(m.Key, nil), v)
+			if last[v.key] != i {
+				intermediate[v] = t
+			}
+		}
+	}
+
+	// A read followed by a write of the same key orders the two versions,
+	// as does a sequence of writes within a transaction.
+	for _, t := range txns {
+		if t.Type == history.Fail {
+			continue
+		}
+		prev := make(map[string]version)
+		for _, m := range t.Ops {
+			v := versionOf(m.Key, m.Value)
+			if m.F == "w" {
+				if p, ok := prev[v.key]; ok && p != v {
+					link(p, v)
+				}
+				prev[v.key] = v
+			} else if t.Type == history.OK {
+				if _, ok := prev[v.key]; !ok {
+					prev[v.key] = v
+				}
+			}
+		}
+	}
+
+	// ww: the writer of a version precedes the writers of later versions.
+	for _, v1 := range order {
+		w1 := writers[v1]
+		if w1 == nil || ambiguous[v1] {
+			continue
+		}
+		for _, v2 := range next[v1] {
+			if w2 := writers[v2]; w2 != nil && !ambiguous[v2] {
+				m := writes[v1]
+				a.graph.link(w1, w2, wwEdge, fmt.Sprintf("T%d wrote %v = %s, T%d overwrote it with %s",
+					w1.Index, m.Key, formatValue(m.Value), w2.Index, formatValue(writes[v2].Value)))
+			}
+		}
+	}
+
+	for _, t := range txns {
+		if t.Type != history.OK {
+			continue
+		}
+
+		own := make(map[string]any)
+		for _, m := range t.Ops {
+			v := versionOf(m.Key, m.Value)
+			if m.F == "w" {
+				own[v.key] = m.Value
+				continue
+			}
+
+			if w, ok := own[v.key]; ok {
+				if ident(w) != v.value {
+					a.anomaly(Internal, fmt.Sprintf("T%d read %v = %s after writing %s", t.Index, m.Key, formatValue(m.Value), formatValue(w)), t)
+				}
+				continue
+			}
+
+			if m.Value != nil {
+				if w := aborted[v]; w != nil {
+					a.anomaly(G1a, fmt.Sprintf("T%d read %v = %s written by aborted T%d", t.Index, m.Key, formatValue(m.Value), w.Index), t, w)
+				} else if w := intermediate[v]; w != nil && w != t {
+					a.anomaly(G1b, fmt.Sprintf("T%d read %v = %s, an intermediate write of T%d", t.Index, m.Key, formatValue(m.Value), w.Index), t, w)
+				}
+				if w := writers[v]; w != nil && !ambiguous[v] {
+					a.graph.link(w, t, wrEdge, fmt.Sprintf("T%d wrote %v = %s, read by T%d", w.Index, m.Key, formatValue(m.Value), t.Index))
+				}
+			}
+
+			// rw: the reader precedes the writers of later versions, except
+			// the later writes of a transaction whose intermediate value it
+			// read, which are reported as G1b.
+			for _, v2 := range next[v] {
+				if w := writers[v2]; w != nil && !ambiguous[v2] && w != writers[v] {
+					a.graph.link(t, w, rwEdge, fmt.Sprintf("T%d read %v = %s, T%d overwrote it with %s",
+						t.Index, m.Key, formatValue(m.Value), w.Index, formatValue(writes[v2].Value)))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Package lamport reconstructs the flow of messages between nodes from their
+// logs or recordings and renders it as a Lamport diagram: one lane per node,
+// with an arrow from each send to the matching receive.
+//
+// Events are placed by Lamport timestamp rather than wall-clock time, so
+// causality is preserved even when node clocks are skewed or logged to the
+// second. Nodes that didn't log, such as Maelstrom clients & services, get
+// lanes whose events are inferred from the nodes they talk to.
+package lamport
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Kind is whether an event sent or received a message.
+type Kind string
+
+const (
+	Send    Kind = "send"
+	Receive Kind = "recv"
+)
+
+// Event is a message sent or received by a node.
+type Event struct {
+	Node string
+	Kind Kind
+	Msg  maelstrom.Message
+
+	// Fields of the message body.
+	Type      string
+	MsgID     int
+	InReplyTo int
+
+	// Time the event was logged, if known.
+	Time time.Time
+
+	// Clock is the event's Lamport timestamp, assigned by Build.
+	Clock int
+
+	// Inferred is true for events on nodes that weren't logged.
+	Inferred bool
+}
+
+func (e *Event) init() *Event {
+	var body maelstrom.MessageBody
+	if err := json.Unmarshal(e.Msg.Body, &body); err == nil {
+		e.Type, e.MsgID, e.InReplyTo = body.Type, body.MsgID, body.InReplyTo
+	}
+	return e
+}
+
+// Peer returns the node at the other end of the message.
+func (e *Event) Peer() string {
+	if e.Kind == Send {
+		return e.Msg.Dest
+	}
+	return e.Msg.Src
+}
+
+// Message is a send and its matching receive. Send is nil if a logged node
+// received a message its sender didn't log, Recv is nil if a message to a
+// logged node was never received, e.g. because it was dropped.
+type Message struct {
+	Send *Event
+	Recv *Event
+}
+
+// Event returns the send, or the receive if the send is unknown.
+func (m Message) Event() *Event {
+	if m.Send != nil {
+		return m.Send
+	}
+	return m.Recv
+}
+
+// Time returns the earliest known time of the message.
+func (m Message) Time() time.Time {
+	if m.Send != nil && !m.Send.Time.IsZero() {
+		return m.Send.Time
+	} else if m.Recv != nil {
+		return m.Recv.Time
+	}
+	return time.Time{}
+}
+
+// Diagram is a set of messages between lanes, one lane per node.
+type Diagram struct {
+	Lanes    []string
+	Messages []Message
+
+	// Start is the earliest known event time.
+	Start time.Time
+}
+
+// Build matches sends with receives and assigns Lamport timestamps. Events
+// of each node must be in the order the node logged them.
+func Build(events []*Event) *Diagram {
+	d := &Diagram{}
+
+	// Events of each node, in order.
+	logged := make(map[string][]*Event)
+	var nodes []string
+	for _, e := range events {
+		if _, ok := logged[e.Node]; !ok {
+			nodes = append(nodes, e.Node)
+		}
+		logged[e.Node] = append(logged[e.Node], e)
+		if !e.Time.IsZero() && (d.Start.IsZero() || e.Time.Before(d.Start)) {
+			d.Start = e.Time
+		}
+	}
+
+	// Messages are matched by their headers; identical messages are
+	// received in the order they were sent.
+	type key struct {
+		src, dest, typ   string
+		msgID, inReplyTo int
+	}
+	keyOf := func(e *Event) key {
+		return key{e.Msg.Src, e.Msg.Dest, e.Type, e.MsgID, e.InReplyTo}
+	}
+	sends := make(map[key][]int) // key -> indices into d.Messages
+	for _, node := range nodes {
+		for _, e := range logged[node] {
+			if e.Kind == Send {
+				sends[keyOf(e)] = append(sends[keyOf(e)], len(d.Messages))
+				d.Messages = append(d.Messages, Message{Send: e})
+			}
+		}
+	}
+	for _, node := range nodes {
+		for _, e := range logged[node] {
+			if e.Kind != Receive {
+				continue
+			}
+			k := keyOf(e)
+			if q := sends[k]; len(q) > 0 {
+				d.Messages[q[0]].Recv = e
+				sends[k] = q[1:]
+			} else {
+				d.Messages = append(d.Messages, Message{Recv: e})
+			}
+		}
+	}
+
+	// Infer the other end of messages to & from nodes that weren't logged.
+	for i, m := range d.Messages {
+		if e := m.Event(); logged[e.Peer()] == nil {
+			inferred := &Event{Node: e.Peer(), Msg: e.Msg, Type: e.Type, MsgID: e.MsgID, InReplyTo: e.InReplyTo, Inferred: true}
+			if m.Send == nil {
+				inferred.Kind = Send
+				d.Messages[i].Send = inferred
+			} else {
+				inferred.Kind = Receive
+				d.Messages[i].Recv = inferred
+			}
+		}
+	}
+
+	d.assignClocks(nodes, logged)
+	sort.SliceStable(d.Messages, func(i, j int) bool {
+		return d.Messages[i].Event().Clock < d.Messages[j].Event().Clock
+	})
+	d.Lanes = lanes(d.Messages)
+	return d
+}
+
+// assignClocks assigns Lamport timestamps: each event follows the previous
+// event of its node and each receive follows its send. Inferred events are
+// placed next to the event at the other end.
+func (d *Diagram) assignClocks(nodes []string, logged map[string][]*Event) {
+	sendOf := make(map[*Event]*Event)
+	for _, m := range d.Messages {
+		if m.Send != nil && m.Recv != nil && !m.Send.Inferred {
+			sendOf[m.Recv] = m.Send
+		}
+	}
+
+	remaining := 0
+	for _, node := range nodes {
+		remaining += len(logged[node])
+	}
+
+	done := make(map[*Event]bool)
+	next := make(map[string]int) // index of each node's next event
+	for remaining > 0 {
+		progress := false
+		for _, node := range nodes {
+			for ; next[node] < len(logged[node]); next[node]++ {
+				e := logged[node][next[node]]
+				if send := sendOf[e]; send != nil && !done[send] {
+					break
+				}
+				tick(logged[node], next[node], sendOf[e])
+				done[e] = true
+				remaining--
+				progress = true
+			}
+		}
+
+		// Mismatched messages can form a cycle. Break it by ignoring the
+		// send of the first blocked receive.
+		if !progress {
+			for _, node := range nodes {
+				if i := next[node]; i < len(logged[node]) {
+					tick(logged[node], i, nil)
+					done[logged[node][i]] = true
+					next[node]++
+					remaining--
+					break
+				}
+			}
+		}
+	}
+
+	for _, m := range d.Messages {
+		if m.Send != nil && m.Send.Inferred {
+			if m.Send.Clock = m.Recv.Clock - 1; m.Send.Clock < 0 {
+				m.Send.Clock = 0
+			}
+		} else if m.Recv != nil && m.Recv.Inferred {
+			m.Recv.Clock = m.Send.Clock + 1
+		}
+	}
+}
+
+// tick assigns the Lamport timestamp of the i'th event of a node.
+func tick(node []*Event, i int, send *Event) {
+	e := node[i]
+	e.Clock = 1
+	if i > 0 {
+		e.Clock = node[i-1].Clock + 1
+	}
+	if send != nil && send.Clock+1 > e.Clock {
+		e.Clock = send.Clock + 1
+	}
+}
+
+// lanes returns the nodes of the messages: clients first, then servers, then
+// services, each in natural order.
+func lanes(messages []Message) []string {
+	seen := make(map[string]bool)
+	var a []string
+	for _, m := range messages {
+		for _, e := range []*Event{m.Send, m.Recv} {
+			if e != nil && !seen[e.Node] {
+				seen[e.Node] = true
+				a = append(a, e.Node)
+			}
+		}
+	}
+
+	sort.Slice(a, func(i, j int) bool {
+		ci, ni, si := laneOrder(a[i])
+		cj, nj, sj := laneOrder(a[j])
+		if ci != cj {
+			return ci < cj
+		} else if si != sj {
+			return si < sj
+		}
+		return ni < nj
+	})
+	return a
+}
+
+// laneOrder returns the category of a node ID, its numeric suffix and its
+// prefix, e.g. (1, 10, "n") for "n10".
+func laneOrder(id string) (category, n int, prefix string) {
+	prefix = strings.TrimRight(id, "0123456789")
+	n, err := strconv.Atoi(id[len(prefix):])
+	switch {
+	case err != nil:
+		return 2, 0, id
+	case prefix == "c":
+		return 0, n, prefix
+	}
+	return 1, n, prefix
+}
+
+// Filter selects the messages to show. Zero fields match everything.
+type Filter struct {
+	// Nodes whose messages are shown, sent or received.
+	Nodes []string
+
+	// Message types to show. A type also matches its "_ok" reply.
+	Types []string
+
+	// Window of time to show, relative to the diagram's start.
+	From, To time.Duration
+}
+
+// Filter returns the diagram with only the matching messages.
+func (d *Diagram) Filter(f Filter) *Diagram {
+	nodes := make(map[string]bool)
+	for _, n := range f.Nodes {
+		nodes[n] = true
+	}
+	types := make(map[string]bool)
+	for _, t := range f.Types {
+		types[t], types[t+"_ok"] = true, true
+	}
+
+	other := &Diagram{Start: d.Start}
+	for _, m := range d.Messages {
+		e := m.Event()
+		if len(nodes) > 0 && !nodes[e.Msg.Src] && !nodes[e.Msg.Dest] {
+			continue
+		} else if len(types) > 0 && !types[e.Type] {
+			continue
+		}
+		if t := m.Time(); !t.IsZero() {
+			if f.From > 0 && t.Sub(d.Start) < f.From {
+				continue
+			} else if f.To > 0 && t.Sub(d.Start) >= f.To {
+				continue
+			}
+		}
+		other.Messages = append(other.Messages, m)
+	}
+	other.Lanes = lanes(other.Messages)
+	return other
+}
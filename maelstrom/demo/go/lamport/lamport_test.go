@@ -0,0 +1,151 @@
+package lamport_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/lamport"
+)
+
+const n0Log = `2025/12/21 15:34:53 Received {c1 n0 {"type":"init","node_id":"n0","node_ids":["n0","n1"],"msg_id":1}}
+2025/12/21 15:34:53 Node n0 initialized
+2025/12/21 15:34:53 Sent {"src":"n0","dest":"c1","body":{"in_reply_to":1,"type":"init_ok"}}
+2025/12/21 15:34:54 Received {c1 n0 {"type":"broadcast","message":7,"msg_id":2}}
+2025/12/21 15:34:54 Sent {"src":"n0","dest":"n1","body":{"message":7,"msg_id":1,"type":"gossip"}}
+2025/12/21 15:34:54 Sent {"src":"n0","dest":"c1","body":{"in_reply_to":2,"type":"broadcast_ok"}}
+2025/12/21 15:34:55 Received {n1 n0 {"in_reply_to":1,"type":"gossip_ok"}}
+2025/12/21 15:34:56 Sent {"src":"n0","dest":"n1","body":{"message":8,"msg_id":2,"type":"gossip"}}
+`
+
+const n1Log = `2025/12/21 15:34:53 Received {c2 n1 {"type":"init","node_id":"n1","node_ids":["n0","n1"],"msg_id":1}}
+2025/12/21 15:34:53 Sent {"src":"n1","dest":"c2","body":{"in_reply_to":1,"type":"init_ok"}}
+2025/12/21 15:34:55 Received {n0 n1 {"message":7,"msg_id":1,"type":"gossip"}}
+2025/12/21 15:34:55 Sent {"src":"n1","dest":"n0","body":{"in_reply_to":1,"type":"gossip_ok"}}
+`
+
+func build(t *testing.T) *lamport.Diagram {
+	t.Helper()
+	var events []*lamport.Event
+	for _, node := range []struct{ id, log string }{{"n0", n0Log}, {"n1", n1Log}} {
+		a, err := lamport.ReadLog(node.id, strings.NewReader(node.log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, a...)
+	}
+	return lamport.Build(events)
+}
+
+// describe returns "src@clock -type-> dest@clock" for each message.
+func describe(d *lamport.Diagram) []string {
+	var a []string
+	for _, m := range d.Messages {
+		s := "?"
+		if m.Send != nil {
+			s = fmt.Sprintf("%s@%d", m.Send.Node, m.Send.Clock)
+		}
+		r := "?"
+		if m.Recv != nil {
+			r = fmt.Sprintf("%s@%d", m.Recv.Node, m.Recv.Clock)
+		}
+		a = append(a, fmt.Sprintf("%s -%s-> %s", s, m.Event().Type, r))
+	}
+	return a
+}
+
+func TestBuild(t *testing.T) {
+	d := build(t)
+
+	if got, want := d.Lanes, []string{"c1", "c2", "n0", "n1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("lanes=%v, want %v", got, want)
+	} else if got, want := d.Start, time.Date(2025, 12, 21, 15, 34, 53, 0, time.Local); !got.Equal(want) {
+		t.Fatalf("start=%s, want %s", got, want)
+	}
+
+	// The gossip reply is received after n1 received the gossip, even though
+	// n0's log is read first, and the last gossip was never received.
+	if got, want := describe(d), []string{
+		"c1@0 -init-> n0@1",
+		"c2@0 -init-> n1@1",
+		"n0@2 -init_ok-> c1@3",
+		"n1@2 -init_ok-> c2@3",
+		"c1@2 -broadcast-> n0@3",
+		"n0@4 -gossip-> n1@5",
+		"n0@5 -broadcast_ok-> c1@6",
+		"n1@6 -gossip_ok-> n0@7",
+		"n0@8 -gossip-> ?",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("messages:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiagram_Filter(t *testing.T) {
+	d := build(t)
+
+	t.Run("Types", func(t *testing.T) {
+		if got, want := describe(d.Filter(lamport.Filter{Types: []string{"gossip"}})), []string{
+			"n0@4 -gossip-> n1@5",
+			"n1@6 -gossip_ok-> n0@7",
+			"n0@8 -gossip-> ?",
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("messages=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Nodes", func(t *testing.T) {
+		other := d.Filter(lamport.Filter{Nodes: []string{"c2"}})
+		if got, want := other.Lanes, []string{"c2", "n1"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("lanes=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		other := d.Filter(lamport.Filter{From: 2 * time.Second, To: 3 * time.Second})
+		if got, want := describe(other), []string{"n1@6 -gossip_ok-> n0@7"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("messages=%v, want %v", got, want)
+		}
+	})
+}
+
+// Ensure recordings are read with their precise timestamps.
+func TestReadRecording(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	events, err := lamport.ReadRecording("n0", []maelstrom.RecordEntry{
+		{Time: start, Dir: maelstrom.RecordIn, Msg: []byte(`{"src":"c1","dest":"n0","body":{"type":"echo","msg_id":1}}`)},
+		{Time: start.Add(time.Millisecond), Dir: maelstrom.RecordOut, Msg: []byte(`{"src":"n0","dest":"c1","body":{"type":"echo_ok","in_reply_to":1}}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := lamport.Build(events)
+	if got, want := describe(d), []string{"c1@0 -echo-> n0@1", "n0@2 -echo_ok-> c1@3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("messages=%v, want %v", got, want)
+	} else if got, want := d.Messages[1].Time().Sub(d.Start), time.Millisecond; got != want {
+		t.Fatalf("reply time=%s, want %s", got, want)
+	}
+}
+
+func TestDiagram_WriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := build(t).WriteSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := buf.String()
+	for _, s := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"`,
+		`>gossip #1</text>`,
+		`<title>n0 → n1 {&#34;message&#34;:8,&#34;msg_id&#34;:2,&#34;type&#34;:&#34;gossip&#34;}</title>`,
+		`>×</text>`,
+	} {
+		if !strings.Contains(svg, s) {
+			t.Fatalf("expected %q in:\n%s", s, svg)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package lamport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// logLine matches the lines Node logs for each message it receives & sends,
+// with the standard log package's date & time prefix.
+var logLine = regexp.MustCompile(`^(?:(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d(?:\.\d+)?) )?(Received|Sent) (.*)$`)
+
+// ReadLog reads the events of a node from its log, as written to STDERR by
+// Node.Run & Node.Send. Other lines are ignored. Log timestamps are only as
+// precise as the log flags the node was run with.
+func ReadLog(node string, r io.Reader) ([]*Event, error) {
+	var events []*Event
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		m := logLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		e := &Event{Node: node}
+		if m[1] != "" {
+			t, err := time.ParseInLocation("2006/01/02 15:04:05", m[1], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			e.Time = t
+		}
+
+		var err error
+		switch m[2] {
+		case "Received":
+			e.Kind = Receive
+			e.Msg, err = parseReceived(m[3])
+		case "Sent":
+			e.Kind = Send
+			err = json.Unmarshal([]byte(m[3]), &e.Msg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		events = append(events, e.init())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// parseReceived parses a message logged with "%s", i.e. "{src dest body}".
+func parseReceived(s string) (maelstrom.Message, error) {
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return maelstrom.Message{}, fmt.Errorf("malformed message: %s", s)
+	}
+	fields := strings.SplitN(s[1:len(s)-1], " ", 3)
+	if len(fields) != 3 {
+		return maelstrom.Message{}, fmt.Errorf("malformed message: %s", s)
+	}
+	return maelstrom.Message{Src: fields[0], Dest: fields[1], Body: json.RawMessage(fields[2])}, nil
+}
+
+// ReadRecording returns the events of a node from its recording. Unlike
+// logs, recordings carry nanosecond timestamps.
+func ReadRecording(node string, entries []maelstrom.RecordEntry) ([]*Event, error) {
+	events := make([]*Event, 0, len(entries))
+	for i, entry := range entries {
+		e := &Event{Node: node, Time: entry.Time, Kind: Receive}
+		if entry.Dir == maelstrom.RecordOut {
+			e.Kind = Send
+		}
+		if err := json.Unmarshal(entry.Msg, &e.Msg); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		events = append(events, e.init())
+	}
+	return events, nil
+}
+
+// ReadFiles reads node logs (*.log) and recordings (*.jsonl). Each file
+// holds the events of the node it is named after, e.g. "n1.log". A directory
+// is read as all such files within it, or within its node-logs directory for
+// a Maelstrom run.
+func ReadFiles(paths ...string) ([]*Event, error) {
+	var files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		if fi, err := os.Stat(filepath.Join(path, "node-logs")); err == nil && fi.IsDir() {
+			path = filepath.Join(path, "node-logs")
+		}
+		for _, pattern := range []string{"*.log", "*.jsonl"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(matches)
+			files = append(files, matches...)
+		}
+	}
+
+	var events []*Event
+	for _, path := range files {
+		a, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, a...)
+	}
+	return events, nil
+}
+
+func readFile(path string) ([]*Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ext := filepath.Ext(path)
+	node := strings.TrimSuffix(filepath.Base(path), ext)
+	if ext != ".jsonl" {
+		return ReadLog(node, f)
+	}
+
+	entries, err := maelstrom.ReadRecording(f)
+	if err != nil {
+		return nil, err
+	}
+	return ReadRecording(node, entries)
+}
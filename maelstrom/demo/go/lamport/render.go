@@ -0,0 +1,169 @@
+package lamport
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+)
+
+// Layout of rendered diagrams, in pixels.
+const (
+	margin     = 20
+	laneWidth  = 140
+	headerSize = 40
+	rowHeight  = 28
+)
+
+// Message colors.
+const (
+	requestColor = "#1f77b4"
+	replyColor   = "#2ca02c"
+	errorColor   = "#d62728"
+)
+
+// layout positions lanes & Lamport timestamps. Timestamps without events
+// are skipped so filtered diagrams stay compact.
+type layout struct {
+	lanes map[string]int
+	rows  map[int]int
+}
+
+func (d *Diagram) layout() layout {
+	l := layout{lanes: make(map[string]int), rows: make(map[int]int)}
+	for i, lane := range d.Lanes {
+		l.lanes[lane] = i
+	}
+
+	var clocks []int
+	for _, m := range d.Messages {
+		for _, e := range []*Event{m.Send, m.Recv} {
+			if e == nil {
+				continue
+			} else if _, ok := l.rows[e.Clock]; !ok {
+				l.rows[e.Clock] = 0
+				clocks = append(clocks, e.Clock)
+			}
+		}
+	}
+	sort.Ints(clocks)
+	for i, c := range clocks {
+		l.rows[c] = i
+	}
+	return l
+}
+
+func (l layout) x(lane string) int { return margin + l.lanes[lane]*laneWidth + laneWidth/2 }
+func (l layout) y(clock int) int   { return headerSize + l.rows[clock]*rowHeight + rowHeight/2 }
+
+func (l layout) width() int  { return 2*margin + len(l.lanes)*laneWidth }
+func (l layout) height() int { return headerSize + len(l.rows)*rowHeight + margin }
+
+// WriteSVG renders the diagram as an SVG image. Hovering over a message
+// shows its body.
+func (d *Diagram) WriteSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	d.writeSVG(bw)
+	return bw.Flush()
+}
+
+func (d *Diagram) writeSVG(w *bufio.Writer) {
+	l := d.layout()
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", l.width(), l.height())
+	w.WriteString("<defs>\n")
+	for _, color := range []string{requestColor, replyColor, errorColor} {
+		fmt.Fprintf(w, `<marker id="arrow%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="%s"/></marker>`+"\n", color[1:], color)
+	}
+	w.WriteString("</defs>\n")
+
+	for _, lane := range d.Lanes {
+		x := l.x(lane)
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle" font-size="13" font-weight="bold">%s</text>`+"\n", x, headerSize-16, html.EscapeString(lane))
+		fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#ccc"/>`+"\n", x, headerSize-8, x, l.height()-margin)
+	}
+
+	for _, m := range d.Messages {
+		d.writeMessage(w, l, m)
+	}
+	w.WriteString("</svg>\n")
+}
+
+func (d *Diagram) writeMessage(w *bufio.Writer, l layout, m Message) {
+	e := m.Event()
+	color, dash := requestColor, ""
+	if e.Type == "error" {
+		color = errorColor
+	} else if e.InReplyTo != 0 {
+		color = replyColor
+	}
+
+	var x1, y1, x2, y2 int
+	switch {
+	case m.Recv == nil: // lost: stop halfway
+		x1, y1 = l.x(e.Msg.Src), l.y(e.Clock)
+		x2, y2 = (x1+l.x(e.Msg.Dest))/2, y1+rowHeight/2
+		color, dash = errorColor, ` stroke-dasharray="4,3"`
+	case m.Send == nil: // sender didn't log it
+		x2, y2 = l.x(e.Msg.Dest), l.y(e.Clock)
+		x1, y1 = l.x(e.Msg.Src), y2-rowHeight/2
+		dash = ` stroke-dasharray="4,3"`
+	default:
+		x1, y1 = l.x(m.Send.Node), l.y(m.Send.Clock)
+		x2, y2 = l.x(m.Recv.Node), l.y(m.Recv.Clock)
+	}
+
+	label := e.Type
+	if e.MsgID != 0 {
+		label = fmt.Sprintf("%s #%d", label, e.MsgID)
+	}
+
+	w.WriteString("<g>")
+	fmt.Fprintf(w, "<title>%s → %s %s</title>", html.EscapeString(e.Msg.Src), html.EscapeString(e.Msg.Dest), html.EscapeString(string(e.Msg.Body)))
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="1.5"%s marker-end="url(#arrow%s)"/>`, x1, y1, x2, y2, color, dash, color[1:])
+	for _, ev := range []*Event{m.Send, m.Recv} {
+		if ev != nil && !ev.Inferred {
+			fmt.Fprintf(w, `<circle cx="%d" cy="%d" r="3" fill="#333"/>`, l.x(ev.Node), l.y(ev.Clock))
+		}
+	}
+	if m.Recv == nil {
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle" fill="%s" font-size="14">×</text>`, x2, y2+5, errorColor)
+	}
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle" fill="%s">%s</text>`, (x1+x2)/2, (y1+y2)/2-3, color, html.EscapeString(label))
+	w.WriteString("</g>\n")
+}
+
+// WriteHTML renders the diagram as an HTML page holding the SVG image and a
+// table of every message.
+func (d *Diagram) WriteHTML(w io.Writer, title string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+table { border-collapse: collapse; font-size: 12px; margin-top: 20px; }
+th, td { border-bottom: 1px solid #eee; padding: 2px 8px; text-align: left; vertical-align: top; }
+td.body { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+`, html.EscapeString(title), html.EscapeString(title))
+	d.writeSVG(bw)
+
+	bw.WriteString("<table>\n<tr><th>Clock</th><th>Time</th><th>From</th><th>To</th><th>Type</th><th>Body</th></tr>\n")
+	for _, m := range d.Messages {
+		e := m.Event()
+		t := ""
+		if mt := m.Time(); !mt.IsZero() {
+			t = mt.Sub(d.Start).String()
+		}
+		fmt.Fprintf(bw, `<tr><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td class="body">%s</td></tr>`+"\n",
+			e.Clock, t, html.EscapeString(e.Msg.Src), html.EscapeString(e.Msg.Dest), html.EscapeString(e.Type), html.EscapeString(string(e.Msg.Body)))
+	}
+	bw.WriteString("</table>\n</body>\n</html>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,87 @@
+package linearizable
+
+import (
+	"fmt"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// FromHistory converts a history into operations. convert returns the input
+// and output of each completed invocation, or an error if it is malformed.
+// Failed operations did not happen and are dropped. Operations with an info
+// completion are given an Unknown output and dropped entirely if convert
+// returns a nil input for them, e.g. for reads, which have no effect.
+func FromHistory(ops []history.Op, convert func(p history.Pair) (input, output any, err error)) ([]Operation, error) {
+	var a []Operation
+	for _, p := range history.Pairs(ops) {
+		if p.Complete.Type == history.Fail {
+			continue
+		}
+
+		input, output, err := convert(p)
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", p.Invoke.Index, err)
+		} else if input == nil {
+			continue
+		}
+		if p.Complete.Type == history.Info {
+			output = Unknown
+		}
+
+		a = append(a, Operation{
+			ClientID: p.Invoke.Process,
+			Input:    input,
+			Output:   output,
+			Call:     p.Invoke.Time,
+			Return:   p.Complete.Time,
+		})
+	}
+	return a, nil
+}
+
+// KVOperations converts a Maelstrom lin-kv history, whose values are
+// [key value] tuples and [key [from to]] for cas, for the KV model.
+func KVOperations(ops []history.Op) ([]Operation, error) {
+	return FromHistory(ops, func(p history.Pair) (any, any, error) {
+		value := p.Invoke.Value
+		if p.Complete.Type == history.OK {
+			value = p.Complete.Value
+		}
+		tuple, ok := value.([]any)
+		if !ok || len(tuple) != 2 {
+			return nil, nil, fmt.Errorf("expected [key value], got %v", value)
+		}
+		key, v := tuple[0], tuple[1]
+
+		switch p.Invoke.F {
+		case "read":
+			if p.Complete.Type != history.OK {
+				return nil, nil, nil
+			}
+			return RegisterInput{Op: "read", Key: key}, v, nil
+		case "write":
+			return RegisterInput{Op: "write", Key: key, Value: v}, nil, nil
+		case "cas":
+			fromTo, ok := v.([]any)
+			if !ok || len(fromTo) != 2 {
+				return nil, nil, fmt.Errorf("expected [from to], got %v", v)
+			}
+			return RegisterInput{Op: "cas", Key: key, From: fromTo[0], Value: fromTo[1]}, nil, nil
+		}
+		return nil, nil, fmt.Errorf("unknown function %q", p.Invoke.F)
+	})
+}
+
+// QueueOperations converts a history of "enqueue" & "dequeue" operations,
+// whose values are the value enqueued or dequeued, for the Queue model.
+func QueueOperations(ops []history.Op) ([]Operation, error) {
+	return FromHistory(ops, func(p history.Pair) (any, any, error) {
+		switch p.Invoke.F {
+		case "enqueue":
+			return QueueInput{Op: "enqueue", Value: p.Invoke.Value}, nil, nil
+		case "dequeue":
+			return QueueInput{Op: "dequeue"}, p.Complete.Value, nil
+		}
+		return nil, nil, fmt.Errorf("unknown function %q", p.Invoke.F)
+	})
+}
@@ -0,0 +1,381 @@
+// Package linearizable checks histories for linearizability.
+//
+// The checker follows Porcupine: each partition of the history, e.g. each
+// key of a key/value store, is checked independently with the Wing & Gong
+// search, memoizing (linearized set, state) pairs as described by Lowe so
+// that equivalent branches are only explored once. Operations whose outcome
+// is unknown may take effect at any point after their call, or never.
+//
+// When a history is not linearizable the checker reports a Counterexample
+// trimmed to the operations concurrent with the first point the search could
+// not get past, together with the model state just before them.
+package linearizable
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Unknown is the output of an operation whose outcome is unknown, e.g. one
+// that timed out. Models must accept it in place of any output.
+var Unknown = unknown{}
+
+type unknown struct{}
+
+func (unknown) String() string { return "?" }
+
+// Operation is a single operation of a history.
+type Operation struct {
+	ClientID int
+	Input    any
+	Output   any
+
+	// Times of the call & return. Operations with an Unknown output may
+	// return at math.MaxInt64.
+	Call   int64
+	Return int64
+}
+
+// Model describes the sequential specification of an object.
+type Model struct {
+	// Name of the model used in reports.
+	Name string
+
+	// Partition returns the key of the independent partition op belongs to,
+	// e.g. the key of a key/value store. Optional.
+	Partition func(op Operation) any
+
+	// Init returns the initial state.
+	Init func() any
+
+	// Step applies input to state and reports whether it could have
+	// produced output, returning the new state. Step must not modify state.
+	Step func(state, input, output any) (bool, any)
+
+	// Equal reports whether two states are equal. Defaults to comparing
+	// with reflect.DeepEqual after normalizing numbers.
+	Equal func(a, b any) bool
+
+	// DescribeOperation & DescribeState render reports. Optional.
+	DescribeOperation func(input, output any) string
+	DescribeState     func(state any) string
+}
+
+// Result is the result of a check.
+type Result struct {
+	OK bool
+
+	// Set when OK is false.
+	Counterexample *Counterexample
+}
+
+// Check checks whether ops are linearizable with respect to model. Returns
+// ctx.Err() if ctx is canceled before the check completes.
+func Check(ctx context.Context, model Model, ops []Operation) (Result, error) {
+	for _, p := range partition(model, ops) {
+		c, err := checkPartition(ctx, model, p.ops)
+		if err != nil {
+			return Result{}, err
+		} else if c != nil {
+			c.Key = p.key
+			return Result{Counterexample: c}, nil
+		}
+	}
+	return Result{OK: true}, nil
+}
+
+type part struct {
+	key any
+	ops []Operation
+}
+
+// partition splits ops by model.Partition, ordering partitions by first call.
+func partition(model Model, ops []Operation) []part {
+	if model.Partition == nil {
+		return []part{{ops: ops}}
+	}
+
+	var parts []part
+	index := make(map[any]int)
+	for _, op := range ops {
+		key := normalize(model.Partition(op))
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			key = fmt.Sprint(key)
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(parts)
+			index[key] = i
+			parts = append(parts, part{key: key})
+		}
+		parts[i].ops = append(parts[i].ops, op)
+	}
+	return parts
+}
+
+// entry is a call or return event in the doubly linked list of events.
+type entry struct {
+	id         int
+	ret        bool
+	match      *entry // the return of a call
+	prev, next *entry
+}
+
+// lift removes a call and its return from the list.
+func lift(e *entry) {
+	e.prev.next = e.next
+	if e.next != nil {
+		e.next.prev = e.prev
+	}
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+// unlift reinserts a call and its return, undoing lift.
+func unlift(e *entry) {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	if e.next != nil {
+		e.next.prev = e
+	}
+}
+
+// events builds the list of call & return events ordered by time. Calls sort
+// before returns at the same time so touching operations are concurrent.
+func events(ops []Operation) *entry {
+	type event struct {
+		id   int
+		ret  bool
+		time int64
+	}
+	evs := make([]event, 0, 2*len(ops))
+	for i, op := range ops {
+		ret := op.Return
+		if op.Output == Unknown {
+			ret = math.MaxInt64
+		}
+		evs = append(evs, event{i, false, op.Call}, event{i, true, ret})
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		if evs[i].time != evs[j].time {
+			return evs[i].time < evs[j].time
+		}
+		return !evs[i].ret && evs[j].ret
+	})
+
+	head := &entry{id: -1}
+	calls := make([]*entry, len(ops))
+	prev := head
+	for _, ev := range evs {
+		e := &entry{id: ev.id, ret: ev.ret, prev: prev}
+		if ev.ret {
+			calls[ev.id].match = e
+		} else {
+			calls[ev.id] = e
+		}
+		prev.next = e
+		prev = e
+	}
+	return head
+}
+
+type frame struct {
+	call  *entry
+	state any
+}
+
+type cacheEntry struct {
+	linearized bitset
+	state      any
+}
+
+func checkPartition(ctx context.Context, model Model, ops []Operation) (*Counterexample, error) {
+	equal := model.Equal
+	if equal == nil {
+		equal = defaultEqual
+	}
+
+	head := events(ops)
+	linearized := newBitset(len(ops))
+	cache := make(map[uint64][]cacheEntry)
+	var stack []frame
+	var best *Counterexample
+
+	state := model.Init()
+	for e, steps := head.next, 0; head.next != nil; steps++ {
+		if steps%1024 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !e.ret {
+			op := ops[e.id]
+			if ok, next := model.Step(state, op.Input, op.Output); ok {
+				candidate := linearized.clone().set(e.id)
+				if !cached(cache, candidate, next, equal) {
+					h := candidate.hash()
+					cache[h] = append(cache[h], cacheEntry{candidate, next})
+					stack = append(stack, frame{e, state})
+					state, linearized = next, candidate
+					lift(e)
+					e = head.next
+					continue
+				}
+			}
+			e = e.next
+			continue
+		}
+
+		// Operations with unknown outcomes return at the end of time, so
+		// reaching one means every remaining operation may never happen.
+		if ops[e.id].Output == Unknown {
+			return nil, nil
+		}
+
+		// No pending call can be linearized before this return.
+		if best == nil || len(stack) > len(best.Linearized) {
+			best = counterexample(model, ops, head, stack, state, e)
+		}
+		if len(stack) == 0 {
+			return best, nil
+		}
+
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = f.state
+		linearized = linearized.clone().clear(f.call.id)
+		unlift(f.call)
+		e = f.call.next
+	}
+	return nil, nil
+}
+
+func cached(cache map[uint64][]cacheEntry, linearized bitset, state any, equal func(a, b any) bool) bool {
+	for _, c := range cache[linearized.hash()] {
+		if c.linearized.equal(linearized) && equal(c.state, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// Counterexample describes where a partition stopped being linearizable.
+type Counterexample struct {
+	// Key of the partition, if the model is partitioned.
+	Key any
+
+	// Linearized is the longest linearizable prefix found and States the
+	// model state after each of its operations.
+	Linearized []Operation
+	States     []any
+
+	// Stuck is the operation that returned before any remaining operation
+	// could be linearized, and Pending the operations that were called
+	// before it returned but could not be linearized next.
+	Stuck   Operation
+	Pending []Operation
+
+	model Model
+}
+
+func counterexample(model Model, ops []Operation, head *entry, stack []frame, state any, stuck *entry) *Counterexample {
+	c := &Counterexample{Stuck: ops[stuck.id], model: model}
+	for i, f := range stack {
+		c.Linearized = append(c.Linearized, ops[f.call.id])
+		if i+1 < len(stack) {
+			c.States = append(c.States, stack[i+1].state)
+		} else {
+			c.States = append(c.States, state)
+		}
+	}
+	for e := head.next; e != stuck; e = e.next {
+		if !e.ret && e.id != stuck.id {
+			c.Pending = append(c.Pending, ops[e.id])
+		}
+	}
+	return c
+}
+
+// Window returns the linearized operations that overlap the failure, i.e.
+// that returned after the earliest call among Stuck & Pending, along with
+// the model state before them. This is the minimal context needed to see
+// the violation.
+func (c *Counterexample) Window() (state any, ops []Operation) {
+	start := c.Stuck.Call
+	for _, op := range c.Pending {
+		if op.Call < start {
+			start = op.Call
+		}
+	}
+
+	state = c.model.Init()
+	i := 0
+	for ; i < len(c.Linearized) && c.Linearized[i].Return < start; i++ {
+		state = c.States[i]
+	}
+	return state, c.Linearized[i:]
+}
+
+// String renders the minimal counterexample.
+func (c *Counterexample) String() string {
+	var sb strings.Builder
+	name := c.model.Name
+	if name == "" {
+		name = "model"
+	}
+	fmt.Fprintf(&sb, "%s: history is not linearizable", name)
+	if c.Key != nil {
+		fmt.Fprintf(&sb, " for key %v", c.Key)
+	}
+	sb.WriteString("\n")
+
+	state, window := c.Window()
+	if skipped := len(c.Linearized) - len(window); skipped > 0 {
+		fmt.Fprintf(&sb, "after %d earlier operations, ", skipped)
+	}
+	fmt.Fprintf(&sb, "state is %s\n", c.describeState(state))
+
+	offset := len(c.Linearized) - len(window)
+	for i, op := range window {
+		fmt.Fprintf(&sb, "  linearized  %s => %s\n", c.describeOp(op), c.describeState(c.States[offset+i]))
+	}
+
+	fmt.Fprintf(&sb, "  cannot linearize %s before it returned\n", c.describeOp(c.Stuck))
+	for _, op := range c.Pending {
+		fmt.Fprintf(&sb, "  pending     %s\n", c.describeOp(op))
+	}
+	return sb.String()
+}
+
+func (c *Counterexample) describeOp(op Operation) string {
+	desc := fmt.Sprintf("%v -> %v", op.Input, op.Output)
+	if c.model.DescribeOperation != nil {
+		desc = c.model.DescribeOperation(op.Input, op.Output)
+	}
+	ret := "?"
+	if op.Output != Unknown {
+		ret = fmt.Sprint(op.Return)
+	}
+	return fmt.Sprintf("[client %d, %d-%s] %s", op.ClientID, op.Call, ret, desc)
+}
+
+func (c *Counterexample) describeState(state any) string {
+	if c.model.DescribeState != nil {
+		return c.model.DescribeState(state)
+	}
+	return fmt.Sprint(state)
+}
+
+func defaultEqual(a, b any) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
@@ -0,0 +1,169 @@
+package linearizable_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/linearizable"
+)
+
+func read(client int, call, ret int64, v any) linearizable.Operation {
+	return linearizable.Operation{ClientID: client, Input: linearizable.RegisterInput{Op: "read"}, Output: v, Call: call, Return: ret}
+}
+
+func write(client int, call, ret int64, v any) linearizable.Operation {
+	return linearizable.Operation{ClientID: client, Input: linearizable.RegisterInput{Op: "write", Value: v}, Call: call, Return: ret}
+}
+
+func check(t *testing.T, model linearizable.Model, ops []linearizable.Operation) linearizable.Result {
+	t.Helper()
+	res, err := linearizable.Check(context.Background(), model, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestCheck_Register(t *testing.T) {
+	t.Run("Concurrent", func(t *testing.T) {
+		// The read overlaps both writes so it may see either.
+		ops := []linearizable.Operation{
+			write(0, 0, 10, 1),
+			write(1, 5, 20, 2),
+			read(2, 8, 12, 1),
+			read(2, 25, 30, 2),
+		}
+		if res := check(t, linearizable.Register(), ops); !res.OK {
+			t.Fatalf("expected linearizable:\n%s", res.Counterexample)
+		}
+	})
+
+	t.Run("StaleRead", func(t *testing.T) {
+		ops := []linearizable.Operation{
+			write(0, 0, 10, 1),
+			write(0, 20, 30, 2),
+			read(1, 40, 50, 1),
+		}
+		res := check(t, linearizable.Register(), ops)
+		if res.OK {
+			t.Fatal("expected violation")
+		}
+		c := res.Counterexample
+		if got, want := len(c.Linearized), 2; got != want {
+			t.Fatalf("linearized=%d, want %d", got, want)
+		} else if c.Stuck != ops[2] {
+			t.Fatalf("stuck=%v, want %v", c.Stuck, ops[2])
+		}
+
+		// Only the stuck read is in the window; earlier writes are summarized
+		// as the state before it.
+		if state, window := c.Window(); state != 2 || len(window) != 0 {
+			t.Fatalf("window=%v %v", state, window)
+		}
+		if got, want := c.String(), "register: history is not linearizable\n"+
+			"after 2 earlier operations, state is 2\n"+
+			"  cannot linearize [client 1, 40-50] read 1 before it returned\n"; got != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		// A timed out write may take effect late, or never.
+		ops := []linearizable.Operation{
+			write(0, 0, 10, 1),
+			write(1, 5, 0, 2),
+			read(2, 20, 30, 1),
+			read(2, 40, 50, 2),
+		}
+		ops[1].Output = linearizable.Unknown
+		if res := check(t, linearizable.Register(), ops); !res.OK {
+			t.Fatalf("expected linearizable:\n%s", res.Counterexample)
+		}
+
+		// But it can't take effect twice.
+		ops = append(ops, read(2, 60, 70, 1))
+		if res := check(t, linearizable.Register(), ops); res.OK {
+			t.Fatal("expected violation")
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := linearizable.Check(ctx, linearizable.Register(), []linearizable.Operation{write(0, 0, 1, 1)}); err != context.Canceled {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// Ensure lin-kv histories are read from EDN, partitioned by key and that
+// the counterexample names the failing key.
+func TestCheck_KV(t *testing.T) {
+	ops, err := history.Read(strings.NewReader(`
+{:type :invoke, :f :write, :value [0 1], :time 0, :process 0, :index 0}
+{:type :ok, :f :write, :value [0 1], :time 10, :process 0, :index 1}
+{:type :invoke, :f :cas, :value [1 [nil 3]], :time 11, :process 0, :index 2}
+{:type :fail, :f :cas, :value [1 [nil 3]], :time 12, :process 0, :index 3, :error [22 "mismatch"]}
+{:type :invoke, :f :cas, :value [0 [1 2]], :time 20, :process 1, :index 4}
+{:type :info, :f :cas, :value [0 [1 2]], :time 30, :process 1, :index 5}
+{:type :invoke, :f :read, :value [1 nil], :time 31, :process 2, :index 6}
+{:type :ok, :f :read, :value [1 nil], :time 32, :process 2, :index 7}
+{:type :invoke, :f :read, :value [0 nil], :time 40, :process 0, :index 8}
+{:type :ok, :f :read, :value [0 2], :time 50, :process 0, :index 9}
+{:type :invoke, :f :read, :value [0 nil], :time 60, :process 0, :index 10}
+{:type :ok, :f :read, :value [0 1], :time 70, :process 0, :index 11}
+{:type :info, :f :start-partition, :value nil, :time 80, :process :nemesis, :index 12}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := linearizable.KVOperations(ops)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(kv), 5; got != want {
+		t.Fatalf("len=%d, want %d", got, want)
+	}
+
+	if res := check(t, linearizable.KV(), kv[:4]); !res.OK {
+		t.Fatalf("expected linearizable:\n%s", res.Counterexample)
+	}
+
+	res := check(t, linearizable.KV(), kv)
+	if res.OK {
+		t.Fatal("expected violation")
+	} else if got := res.Counterexample.Key; got != int64(0) {
+		t.Fatalf("key=%v, want 0", got)
+	}
+}
+
+func TestCheck_Queue(t *testing.T) {
+	op := func(client int, call, ret int64, f string, v any) linearizable.Operation {
+		if f == "enqueue" {
+			return linearizable.Operation{ClientID: client, Input: linearizable.QueueInput{Op: f, Value: v}, Call: call, Return: ret}
+		}
+		return linearizable.Operation{ClientID: client, Input: linearizable.QueueInput{Op: f}, Output: v, Call: call, Return: ret}
+	}
+
+	// Concurrent enqueues may be dequeued in either order.
+	ops := []linearizable.Operation{
+		op(0, 0, 10, "enqueue", 1),
+		op(1, 0, 10, "enqueue", 2),
+		op(2, 20, 30, "dequeue", 2),
+		op(2, 40, 50, "dequeue", 1),
+		op(2, 60, 70, "dequeue", nil),
+	}
+	if res := check(t, linearizable.Queue(), ops); !res.OK {
+		t.Fatalf("expected linearizable:\n%s", res.Counterexample)
+	}
+
+	// Sequential enqueues may not.
+	ops[1].Call, ops[1].Return = 11, 15
+	res := check(t, linearizable.Queue(), ops)
+	if res.OK {
+		t.Fatal("expected violation")
+	} else if !strings.Contains(res.Counterexample.String(), "cannot linearize [client 2, 20-30] dequeue 2") {
+		t.Fatalf("unexpected counterexample:\n%s", res.Counterexample)
+	}
+}
@@ -0,0 +1,165 @@
+package linearizable
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// RegisterInput is an operation on a register or on a key of a key/value
+// store. Op is "read", "write" or "cas". Reads output the value read.
+type RegisterInput struct {
+	Op    string
+	Key   any
+	Value any // value written, or the new value of a cas
+	From  any // expected value of a cas
+}
+
+// Register returns the model of a single register that starts out nil.
+// Operation keys are ignored.
+func Register() Model {
+	return Model{
+		Name:              "register",
+		Init:              func() any { return nil },
+		Step:              registerStep,
+		DescribeOperation: describeRegister,
+	}
+}
+
+// KV returns the model of a key/value store of independent registers, such
+// as Maelstrom's lin-kv, partitioned by key.
+func KV() Model {
+	m := Register()
+	m.Name = "kv"
+	m.Partition = func(op Operation) any { return op.Input.(RegisterInput).Key }
+	return m
+}
+
+func registerStep(state, input, output any) (bool, any) {
+	in := input.(RegisterInput)
+	switch in.Op {
+	case "read":
+		return output == Unknown || defaultEqual(output, state), state
+	case "write":
+		return true, in.Value
+	case "cas":
+		// A cas with an unknown outcome that doesn't match had no effect.
+		if !defaultEqual(in.From, state) {
+			return output == Unknown, state
+		}
+		return true, in.Value
+	}
+	return false, state
+}
+
+func describeRegister(input, output any) string {
+	in := input.(RegisterInput)
+	switch in.Op {
+	case "read":
+		return fmt.Sprintf("read %v", output)
+	case "write":
+		return fmt.Sprintf("write %v", in.Value)
+	case "cas":
+		return fmt.Sprintf("cas %v -> %v", in.From, in.Value)
+	}
+	return in.Op
+}
+
+// QueueInput is an operation on a FIFO queue. Op is "enqueue" or "dequeue".
+// Dequeues output the value removed, or nil if the queue was empty.
+type QueueInput struct {
+	Op    string
+	Value any
+}
+
+// Queue returns the model of a FIFO queue that starts out empty.
+func Queue() Model {
+	return Model{
+		Name: "queue",
+		Init: func() any { return []any(nil) },
+		Step: func(state, input, output any) (bool, any) {
+			q, in := state.([]any), input.(QueueInput)
+			switch in.Op {
+			case "enqueue":
+				return true, append(q[:len(q):len(q)], in.Value)
+			case "dequeue":
+				if len(q) == 0 {
+					return output == Unknown || output == nil, q
+				}
+				return output == Unknown || defaultEqual(output, q[0]), q[1:]
+			}
+			return false, q
+		},
+		Equal: func(a, b any) bool {
+			x, y := a.([]any), b.([]any)
+			if len(x) != len(y) {
+				return false
+			}
+			for i := range x {
+				if !defaultEqual(x[i], y[i]) {
+					return false
+				}
+			}
+			return true
+		},
+		DescribeOperation: func(input, output any) string {
+			in := input.(QueueInput)
+			if in.Op == "dequeue" {
+				return fmt.Sprintf("dequeue %v", output)
+			}
+			return fmt.Sprintf("enqueue %v", in.Value)
+		},
+		DescribeState: func(state any) string {
+			var sb strings.Builder
+			sb.WriteString("[")
+			for i, v := range state.([]any) {
+				if i > 0 {
+					sb.WriteString(" ")
+				}
+				fmt.Fprint(&sb, v)
+			}
+			sb.WriteString("]")
+			return sb.String()
+		},
+	}
+}
+
+// normalize converts numbers to int64 where they are integral, so values
+// from EDN, JSON & Go literals compare equal.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+	case []any:
+		a := make([]any, len(v))
+		for i := range v {
+			a[i] = normalize(v[i])
+		}
+		return a
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		a := make([]any, rv.Len())
+		for i := range a {
+			a[i] = normalize(rv.Index(i).Interface())
+		}
+		return a
+	}
+	return v
+}
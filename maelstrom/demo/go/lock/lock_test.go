@@ -0,0 +1,170 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/lock"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+const testTTL = 300 * time.Millisecond
+
+// Ensure a mutex excludes other nodes, is reentrant for its holder, and hands
+// out increasing fencing tokens.
+func TestMutex(t *testing.T) {
+	nw, nodes := newCluster(t, 2)
+	defer nw.Close()
+	m1, m2 := newMutex(nodes["n1"]), newMutex(nodes["n2"])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := m1.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if token != 1 {
+		t.Fatalf("token=%d, want 1", token)
+	}
+
+	// Reentrant acquisitions keep the same token.
+	if again, err := m1.Lock(ctx); err != nil {
+		t.Fatal(err)
+	} else if again != token {
+		t.Fatalf("reentrant token=%d, want %d", again, token)
+	}
+
+	if _, ok, err := m2.TryLock(ctx); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected lock to be held by n1")
+	}
+
+	// The lock remains held until every acquisition is released.
+	if err := m1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	} else if _, ok, err := m2.TryLock(ctx); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected lock to still be held by n1")
+	}
+	if err := m1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if token, err := m2.Lock(ctx); err != nil {
+		t.Fatal(err)
+	} else if token != 2 {
+		t.Fatalf("token=%d, want 2", token)
+	}
+	if err := m1.Unlock(ctx); !errors.Is(err, lock.ErrNotHeld) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a holder that keeps its lock alive is not evicted and that a holder
+// which disappears is evicted after its TTL.
+func TestMutex_CrashRecovery(t *testing.T) {
+	nw, nodes := newCluster(t, 2)
+	defer nw.Close()
+	m1, m2 := newMutex(nodes["n1"]), newMutex(nodes["n2"])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := m1.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keepalives prevent eviction while n1 can reach the store.
+	tryCtx, tryCancel := context.WithTimeout(ctx, 3*testTTL)
+	if _, err := m2.Lock(tryCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tryCancel()
+
+	nw.Block("n1", maelstrom.LinKV)
+	token, err := m2.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if token != 2 {
+		t.Fatalf("token=%d, want 2", token)
+	}
+
+	// n1 eventually notices that it has lost the lock.
+	nw.Heal()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := m1.Token(); !ok {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("n1 still believes it holds the lock")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Ensure a semaphore admits up to its capacity.
+func TestSemaphore(t *testing.T) {
+	nw, nodes := newCluster(t, 3)
+	defer nw.Close()
+
+	sems := make(map[string]*lock.Semaphore)
+	for id, node := range nodes {
+		s := lock.NewSemaphore(node, maelstrom.NewLinKV(node), "sem", 2)
+		s.TTL = testTTL
+		sems[id] = s
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t1, err := sems["n1"].Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := sems["n2"].Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if t2 <= t1 {
+		t.Fatalf("token=%d, want > %d", t2, t1)
+	}
+
+	if _, ok, err := sems["n3"].TryAcquire(ctx); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected semaphore to be full")
+	}
+
+	if err := sems["n1"].Release(ctx); err != nil {
+		t.Fatal(err)
+	} else if t3, err := sems["n3"].Acquire(ctx); err != nil {
+		t.Fatal(err)
+	} else if t3 <= t2 {
+		t.Fatalf("token=%d, want > %d", t3, t2)
+	}
+}
+
+// newCluster starts a simulated network with n nodes.
+func newCluster(tb testing.TB, n int) (*simnet.Network, map[string]*maelstrom.Node) {
+	tb.Helper()
+
+	nw := simnet.New()
+	nodes := nw.NewNodes(n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return nw, nodes
+}
+
+func newMutex(node *maelstrom.Node) *lock.Mutex {
+	m := lock.NewMutex(node, maelstrom.NewLinKV(node), "lock")
+	m.TTL = testTTL
+	return m
+}
@@ -0,0 +1,36 @@
+package lock
+
+import (
+	"context"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Mutex is a distributed mutual exclusion lock: a semaphore with a capacity
+// of one. Locking is reentrant per node, so goroutines on the same node that
+// need to exclude each other should also hold a local sync.Mutex. A
+// Simulation cannot run handlers that hold one across RPCs.
+type Mutex struct {
+	*Semaphore
+}
+
+// NewMutex returns a mutex stored under key.
+func NewMutex(node *maelstrom.Node, kv *maelstrom.KV, key string) *Mutex {
+	return &Mutex{Semaphore: NewSemaphore(node, kv, key, 1)}
+}
+
+// Lock blocks until the lock is held or ctx is done. Returns the fencing
+// token for this ownership.
+func (m *Mutex) Lock(ctx context.Context) (int, error) {
+	return m.Acquire(ctx)
+}
+
+// TryLock makes a single attempt to take the lock.
+func (m *Mutex) TryLock(ctx context.Context) (int, bool, error) {
+	return m.TryAcquire(ctx)
+}
+
+// Unlock releases one level of ownership of the lock.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	return m.Release(ctx)
+}
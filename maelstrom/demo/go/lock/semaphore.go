@@ -0,0 +1,321 @@
+// Package lock implements distributed locks and counting semaphores on top of
+// a linearizable key/value store such as Maelstrom's lin-kv service.
+//
+// All state for a lock lives under a single key and is only changed with
+// compare-and-swap. Holders are identified by node ID, so acquisitions from
+// the same node are reentrant. Every new acquisition is assigned a fencing
+// token which increases monotonically for the key; callers should attach it
+// to writes that the lock protects.
+//
+// Holders keep their ownership alive by periodically bumping a sequence
+// number. If a holder crashes, other nodes notice that its entry has not
+// changed for a full TTL, as measured on their own clocks, and evict it.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Defaults for locks & semaphores.
+const (
+	DefaultTTL        = 2 * time.Second
+	DefaultMinBackoff = 10 * time.Millisecond
+	DefaultMaxBackoff = 500 * time.Millisecond
+)
+
+// ErrNotHeld is returned when releasing a lock that this node does not hold,
+// for example because it was evicted after failing to keep it alive.
+var ErrNotHeld = errors.New("lock: not held")
+
+// state is the value stored in the KV store for a semaphore.
+type state struct {
+	// Last fencing token handed out for the key.
+	Token int `json:"token"`
+
+	// Current holders, by node ID.
+	Holders map[string]holder `json:"holders,omitempty"`
+}
+
+// holder represents a single node's ownership of a semaphore.
+type holder struct {
+	Token int `json:"token"` // fencing token of the acquisition
+	Seq   int `json:"seq"`   // bumped by keepalives
+	Count int `json:"count"` // reentrant acquisitions
+}
+
+// observation records when a holder's entry was last seen to change.
+type observation struct {
+	seq int
+	at  time.Time
+}
+
+// Semaphore is a distributed counting semaphore which allows up to Capacity
+// nodes to hold it at once.
+type Semaphore struct {
+	node     *maelstrom.Node
+	kv       *maelstrom.KV
+	key      string
+	capacity int
+
+	mu        sync.Mutex
+	observed  map[string]observation
+	held      bool
+	token     int
+	keepalive context.CancelFunc
+
+	// Time after which a holder that has not refreshed its entry is evicted.
+	TTL time.Duration
+
+	// Bounds for the exponential backoff between acquisition attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewSemaphore returns a semaphore stored under key which admits up to
+// capacity holders.
+func NewSemaphore(node *maelstrom.Node, kv *maelstrom.KV, key string, capacity int) *Semaphore {
+	if capacity < 1 {
+		panic("lock: semaphore capacity must be positive")
+	}
+	return &Semaphore{
+		node:     node,
+		kv:       kv,
+		key:      key,
+		capacity: capacity,
+		observed: make(map[string]observation),
+
+		TTL:        DefaultTTL,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// Acquire blocks until this node holds the semaphore or ctx is done. Returns
+// the fencing token for the acquisition. Acquiring a semaphore that this node
+// already holds increments its hold count and returns the same token.
+func (s *Semaphore) Acquire(ctx context.Context) (int, error) {
+	backoff := s.MinBackoff
+	for {
+		token, ok, err := s.TryAcquire(ctx)
+		if ok {
+			return token, nil
+		} else if err != nil && ctx.Err() == nil {
+			log.Printf("lock %s: acquire: %s", s.key, err)
+		}
+
+		// Sleep with full jitter so that contending nodes spread out. The
+		// clock's Sleep, unlike a timer channel, also yields to a simulation.
+		d := time.Duration(s.node.Rand().Int63n(int64(backoff) + 1))
+		s.node.Clock().Sleep(d)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if backoff *= 2; backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
+
+// TryAcquire makes a single attempt to acquire the semaphore. Returns false
+// if the semaphore is full or the attempt lost a race with another node.
+func (s *Semaphore) TryAcquire(ctx context.Context) (token int, ok bool, err error) {
+	raw, st, err := s.read(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	me := s.node.ID()
+
+	if h, ok := st.Holders[me]; ok {
+		h.Count++
+		st.Holders[me] = h
+	} else {
+		s.evict(&st)
+		if len(st.Holders) >= s.capacity {
+			return 0, false, nil
+		}
+		st.Token++
+		st.Holders[me] = holder{Token: st.Token, Count: 1}
+	}
+
+	if err := s.cas(ctx, raw, st); maelstrom.ErrorCode(err) == maelstrom.PreconditionFailed {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	s.hold(st.Holders[me].Token)
+	return st.Holders[me].Token, true, nil
+}
+
+// Release decrements this node's hold count and gives up the semaphore once
+// it reaches zero. Returns ErrNotHeld if this node is not a holder.
+func (s *Semaphore) Release(ctx context.Context) error {
+	me := s.node.ID()
+	for {
+		raw, st, err := s.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		h, ok := st.Holders[me]
+		if !ok {
+			s.unhold()
+			return ErrNotHeld
+		}
+		if h.Count--; h.Count > 0 {
+			st.Holders[me] = h
+		} else {
+			delete(st.Holders, me)
+		}
+
+		if err := s.cas(ctx, raw, st); maelstrom.ErrorCode(err) == maelstrom.PreconditionFailed {
+			continue // another node changed the state; retry against the new value
+		} else if err != nil {
+			return err
+		}
+
+		if h.Count == 0 {
+			s.unhold()
+		}
+		return nil
+	}
+}
+
+// Token returns the fencing token of this node's current acquisition. Returns
+// false if this node does not believe it holds the semaphore.
+func (s *Semaphore) Token() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, s.held
+}
+
+// hold records a successful acquisition and starts the keepalive loop.
+func (s *Semaphore) hold(token int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = token
+	if s.held {
+		return
+	}
+	s.held = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.keepalive = cancel
+	maelstrom.Every(ctx, s.node.Clock(), s.TTL/3, func() { s.keepaliveOnce(ctx) })
+}
+
+// unhold records that the semaphore is no longer held and stops keepalives.
+func (s *Semaphore) unhold() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.held, s.token = false, 0
+	if s.keepalive != nil {
+		s.keepalive()
+		s.keepalive = nil
+	}
+}
+
+// keepaliveOnce bumps this node's sequence number so that other nodes do not
+// evict it. hold runs it every TTL/3 until the entry disappears.
+func (s *Semaphore) keepaliveOnce(ctx context.Context) {
+	if err := s.refresh(ctx); errors.Is(err, ErrNotHeld) {
+		log.Printf("lock %s: lost ownership", s.key)
+		s.unhold()
+	} else if err != nil && ctx.Err() == nil {
+		log.Printf("lock %s: keepalive: %s", s.key, err)
+	}
+}
+
+func (s *Semaphore) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.TTL/3)
+	defer cancel()
+
+	raw, st, err := s.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	me := s.node.ID()
+	h, ok := st.Holders[me]
+	if !ok {
+		return ErrNotHeld
+	}
+	h.Seq++
+	st.Holders[me] = h
+	return s.cas(ctx, raw, st)
+}
+
+// evict removes holders whose entries have not changed for a full TTL.
+func (s *Semaphore) evict(st *state) {
+	now := s.node.Clock().Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, h := range st.Holders {
+		if o, ok := s.observed[id]; ok && o.seq == h.Seq && now.Sub(o.at) >= s.TTL {
+			delete(st.Holders, id)
+		}
+	}
+}
+
+// read returns the raw & decoded state. A missing key is returned as a blank
+// raw value and empty state. Holder observations are updated as a side effect.
+func (s *Semaphore) read(ctx context.Context) (string, state, error) {
+	st := state{Holders: make(map[string]holder)}
+
+	v, err := s.kv.Read(ctx, s.key)
+	if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+		return "", st, nil
+	} else if err != nil {
+		return "", st, err
+	}
+
+	raw, ok := v.(string)
+	if !ok {
+		return "", st, fmt.Errorf("unexpected lock value: %#v", v)
+	} else if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		return "", st, fmt.Errorf("unmarshal lock value: %w", err)
+	}
+	if st.Holders == nil {
+		st.Holders = make(map[string]holder)
+	}
+
+	now := s.node.Clock().Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, h := range st.Holders {
+		if o, ok := s.observed[id]; !ok || o.seq != h.Seq {
+			s.observed[id] = observation{seq: h.Seq, at: now}
+		}
+	}
+	for id := range s.observed {
+		if _, ok := st.Holders[id]; !ok {
+			delete(s.observed, id)
+		}
+	}
+	return raw, st, nil
+}
+
+// cas replaces the raw value with the encoded state.
+func (s *Semaphore) cas(ctx context.Context, raw string, st state) error {
+	buf, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	var from any = raw
+	if raw == "" {
+		from = nil
+	}
+	return s.kv.CompareAndSwap(ctx, s.key, from, string(buf), raw == "")
+}
@@ -0,0 +1,181 @@
+package nemesis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Partition modes, as in Jepsen.
+const (
+	// Halves splits the nodes into two halves.
+	Halves = "halves"
+
+	// One isolates a single node from the rest.
+	One = "one"
+
+	// Bridge splits the nodes into two halves, except for one node that can
+	// talk to both.
+	Bridge = "bridge"
+
+	// MajoritiesRing arranges the nodes in a ring where each node can talk
+	// to a majority made of its neighbors, so every node sees a different
+	// majority.
+	MajoritiesRing = "majorities-ring"
+)
+
+// Grudge maps each node to the nodes it cannot communicate with. Grudges
+// are symmetric.
+type Grudge map[string][]string
+
+// Pairs returns each pair of nodes that cannot communicate, once.
+func (g Grudge) Pairs() [][2]string {
+	var pairs [][2]string
+	for _, a := range g.nodes() {
+		for _, b := range g[a] {
+			if a < b {
+				pairs = append(pairs, [2]string{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+func (g Grudge) nodes() []string {
+	nodes := make([]string, 0, len(g))
+	for n := range g {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// edn returns the grudge as recorded in histories, e.g.
+// {"n1" ["n2" "n3"], ...}.
+func (g Grudge) edn() map[any]any {
+	m := make(map[any]any, len(g))
+	for n, others := range g {
+		a := make([]any, len(others))
+		for i := range others {
+			a[i] = others[i]
+		}
+		m[n] = a
+	}
+	return m
+}
+
+// Groups returns a grudge in which nodes can only talk to nodes in the same
+// group. Nodes in no group can talk to everyone.
+func Groups(groups ...[]string) Grudge {
+	g := make(Grudge)
+	for i := range groups {
+		for j := range groups {
+			if i == j {
+				continue
+			}
+			for _, a := range groups[i] {
+				g[a] = append(g[a], groups[j]...)
+			}
+		}
+	}
+	for n := range g {
+		sort.Strings(g[n])
+	}
+	return g
+}
+
+// HalvesGrudge splits nodes into a first half (the smaller, for an odd
+// number of nodes) and a second half.
+func HalvesGrudge(nodes []string) Grudge {
+	mid := len(nodes) / 2
+	return Groups(nodes[:mid], nodes[mid:])
+}
+
+// OneGrudge isolates the first node.
+func OneGrudge(nodes []string) Grudge {
+	if len(nodes) == 0 {
+		return Grudge{}
+	}
+	return Groups(nodes[:1], nodes[1:])
+}
+
+// BridgeGrudge splits nodes into halves with the middle node in both.
+func BridgeGrudge(nodes []string) Grudge {
+	g := HalvesGrudge(nodes)
+	if len(nodes) < 3 {
+		return g
+	}
+
+	bridge := nodes[len(nodes)/2]
+	delete(g, bridge)
+	for n := range g {
+		g[n] = remove(g[n], bridge)
+		if len(g[n]) == 0 {
+			delete(g, n)
+		}
+	}
+	return g
+}
+
+// MajoritiesRingGrudge arranges nodes in a ring, in order, and lets each
+// node talk only to the nodes close enough to form a majority with it.
+func MajoritiesRingGrudge(nodes []string) Grudge {
+	n := len(nodes)
+	majority := n/2 + 1
+	radius := majority / 2 // neighbors on each side
+
+	g := make(Grudge)
+	for i, a := range nodes {
+		for j, b := range nodes {
+			d := i - j
+			if d < 0 {
+				d = -d
+			}
+			if n-d < d {
+				d = n - d
+			}
+			if d > radius {
+				g[a] = append(g[a], b)
+			}
+		}
+	}
+	for a := range g {
+		sort.Strings(g[a])
+	}
+	return g
+}
+
+// ParseGrudge returns the grudge for a partition mode over nodes, which the
+// caller may shuffle first. Besides the modes, explicit groups can be given
+// as comma-separated node lists separated by "|", e.g. "n1,n2|n3,n4,n5".
+func ParseGrudge(mode string, nodes []string) (Grudge, error) {
+	switch mode {
+	case Halves:
+		return HalvesGrudge(nodes), nil
+	case One:
+		return OneGrudge(nodes), nil
+	case Bridge:
+		return BridgeGrudge(nodes), nil
+	case MajoritiesRing:
+		return MajoritiesRingGrudge(nodes), nil
+	}
+
+	if !strings.Contains(mode, "|") {
+		return nil, fmt.Errorf("unknown partition %q", mode)
+	}
+	var groups [][]string
+	for _, s := range strings.Split(mode, "|") {
+		groups = append(groups, strings.Split(s, ","))
+	}
+	return Groups(groups...), nil
+}
+
+func remove(a []string, s string) []string {
+	var b []string
+	for _, x := range a {
+		if x != s {
+			b = append(b, x)
+		}
+	}
+	return b
+}
@@ -0,0 +1,295 @@
+// Package nemesis injects faults into a running cluster, as Jepsen's nemesis
+// does: network partitions, paused & crashed processes and skewed clocks.
+// Faults follow a schedule, either read from a file or generated at random,
+// and each one is recorded in the history as info ops of the nemesis
+// process, so failures line up with the anomalies they cause.
+package nemesis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Cluster is a set of nodes faults can be injected into.
+type Cluster interface {
+	// Nodes returns the IDs of all nodes.
+	Nodes() []string
+
+	// Block drops all messages between a and b. Heal removes all blocks.
+	// *simnet.Network implements both.
+	Block(a, b string)
+	Heal()
+
+	// Pause stops a node's process, e.g. with SIGSTOP, until Resume.
+	Pause(node string) error
+	Resume(node string) error
+
+	// Kill crashes a node. Restart starts it again & initializes it.
+	Kill(node string) error
+	Restart(ctx context.Context, node string) error
+
+	// SkewClock offsets the node's clock from real time. A zero offset
+	// resets it.
+	SkewClock(node string, offset time.Duration) error
+}
+
+// Nemesis applies events to a cluster and records them in a history. It
+// tracks the faults in effect so they can be undone with Recover.
+type Nemesis struct {
+	cluster Cluster
+	rec     *history.Recorder
+	rng     *rand.Rand
+
+	partitioned bool
+	paused      map[string]bool
+	killed      map[string]bool
+	skewed      map[string]bool
+}
+
+// New returns a nemesis for c that records its operations with rec. Random
+// partitions & targets are chosen with a generator seeded by seed.
+func New(c Cluster, rec *history.Recorder, seed int64) *Nemesis {
+	return &Nemesis{
+		cluster: c,
+		rec:     rec,
+		rng:     rand.New(rand.NewSource(seed)),
+		paused:  make(map[string]bool),
+		killed:  make(map[string]bool),
+		skewed:  make(map[string]bool),
+	}
+}
+
+// Run applies events at their times, measured from when Run was called,
+// until they are exhausted or ctx is done. Failed events are recorded and
+// don't stop the schedule; the first error is returned at the end.
+func (n *Nemesis) Run(ctx context.Context, events []Event) error {
+	start := time.Now()
+	var firstErr error
+	for _, e := range events {
+		select {
+		case <-ctx.Done():
+			return firstErr
+		case <-time.After(time.Until(start.Add(e.At))):
+		}
+		if err := n.Apply(ctx, e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Recover undoes every fault in effect: it heals the network, resumes
+// paused nodes, restarts crashed ones and resets skewed clocks.
+func (n *Nemesis) Recover(ctx context.Context) error {
+	var firstErr error
+	for _, e := range []Event{{F: StopPartition}, {F: Resume}, {F: Start}, {F: ResetClock}} {
+		if !n.affected(e.F) {
+			continue
+		}
+		if err := n.Apply(ctx, e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// affected returns true if undoing f would change anything.
+func (n *Nemesis) affected(f string) bool {
+	switch f {
+	case StopPartition:
+		return n.partitioned
+	case Resume:
+		return len(n.paused) > 0
+	case Start:
+		return len(n.killed) > 0
+	case ResetClock:
+		return len(n.skewed) > 0
+	}
+	return false
+}
+
+// Apply performs a single event, recording an op when it begins and another
+// with its outcome: the grudge of a partition, the nodes affected or the
+// error.
+func (n *Nemesis) Apply(ctx context.Context, e Event) error {
+	n.rec.Nemesis(e.F, e.value())
+	value, err := n.apply(ctx, e)
+	if err != nil {
+		n.rec.Nemesis(e.F, err.Error())
+		return fmt.Errorf("nemesis %s: %w", e.F, err)
+	}
+	n.rec.Nemesis(e.F, value)
+	return nil
+}
+
+// value returns the event's arguments as recorded in the history.
+func (e Event) value() any {
+	switch {
+	case e.F == SkewClock:
+		return []any{e.Arg, e.Offset.String()}
+	case e.Arg != "":
+		return e.Arg
+	}
+	return nil
+}
+
+func (n *Nemesis) apply(ctx context.Context, e Event) (any, error) {
+	switch e.F {
+	case StartPartition:
+		nodes := append([]string{}, n.cluster.Nodes()...)
+		n.rng.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+		g, err := ParseGrudge(e.Arg, nodes)
+		if err != nil {
+			return nil, err
+		}
+		n.cluster.Heal()
+		for _, p := range g.Pairs() {
+			n.cluster.Block(p[0], p[1])
+		}
+		n.partitioned = true
+		return []any{edn.Keyword("isolated"), g.edn()}, nil
+
+	case StopPartition:
+		n.cluster.Heal()
+		n.partitioned = false
+		return edn.Keyword("network-healed"), nil
+
+	case Pause:
+		return n.each(e.Arg, nil, func(node string) error {
+			if err := n.cluster.Pause(node); err != nil {
+				return err
+			}
+			n.paused[node] = true
+			return nil
+		})
+
+	case Resume:
+		return n.each(e.Arg, n.paused, func(node string) error {
+			if err := n.cluster.Resume(node); err != nil {
+				return err
+			}
+			delete(n.paused, node)
+			return nil
+		})
+
+	case Kill:
+		return n.each(e.Arg, nil, func(node string) error {
+			if err := n.cluster.Kill(node); err != nil {
+				return err
+			}
+			n.killed[node] = true
+			delete(n.paused, node)
+			return nil
+		})
+
+	case Start:
+		return n.each(e.Arg, n.killed, func(node string) error {
+			if err := n.cluster.Restart(ctx, node); err != nil {
+				return err
+			}
+			delete(n.killed, node)
+			return nil
+		})
+
+	case SkewClock:
+		return n.each(e.Arg, nil, func(node string) error {
+			if err := n.cluster.SkewClock(node, e.Offset); err != nil {
+				return err
+			}
+			if e.Offset == 0 {
+				delete(n.skewed, node)
+			} else {
+				n.skewed[node] = true
+			}
+			return nil
+		})
+
+	case ResetClock:
+		return n.each(e.Arg, n.skewed, func(node string) error {
+			if err := n.cluster.SkewClock(node, 0); err != nil {
+				return err
+			}
+			delete(n.skewed, node)
+			return nil
+		})
+	}
+	return nil, fmt.Errorf("unknown operation %q", e.F)
+}
+
+// each calls fn for each node selected by target and returns the nodes
+// affected. A blank target selects the nodes in current, sorted.
+func (n *Nemesis) each(target string, current map[string]bool, fn func(node string) error) (any, error) {
+	var nodes []string
+	if target == "" {
+		for node := range current {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+	} else {
+		var err error
+		if nodes, err = n.targets(target); err != nil {
+			return nil, err
+		}
+	}
+
+	done := make([]any, 0, len(nodes))
+	for _, node := range nodes {
+		if err := fn(node); err != nil {
+			return nil, fmt.Errorf("%s: %w", node, err)
+		}
+		done = append(done, node)
+	}
+	return done, nil
+}
+
+// targets returns the nodes selected by target: one of the Target
+// constants or a comma-separated list of node IDs.
+func (n *Nemesis) targets(target string) ([]string, error) {
+	nodes := append([]string{}, n.cluster.Nodes()...)
+	n.rng.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+
+	var count int
+	switch target {
+	case TargetOne:
+		count = 1
+	case TargetMinority:
+		count = (len(nodes) - 1) / 2
+		if count == 0 {
+			count = 1
+		}
+	case TargetMajority:
+		count = len(nodes)/2 + 1
+	case TargetAll:
+		count = len(nodes)
+	default:
+		selected := strings.Split(target, ",")
+		for _, node := range selected {
+			if !contains(nodes, node) {
+				return nil, fmt.Errorf("unknown node %q", node)
+			}
+		}
+		return selected, nil
+	}
+	if count > len(nodes) {
+		count = len(nodes)
+	}
+	selected := nodes[:count]
+	sort.Strings(selected)
+	return selected, nil
+}
+
+func contains(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
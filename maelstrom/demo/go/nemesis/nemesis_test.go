@@ -0,0 +1,278 @@
+package nemesis_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/nemesis"
+)
+
+func nodeIDs(n int) []string {
+	a := make([]string, n)
+	for i := range a {
+		a[i] = fmt.Sprintf("n%d", i)
+	}
+	return a
+}
+
+// reachable returns the number of nodes each node can talk to, including
+// itself.
+func reachable(g nemesis.Grudge, nodes []string) map[string]int {
+	m := make(map[string]int)
+	for _, n := range nodes {
+		m[n] = len(nodes) - len(g[n])
+	}
+	return m
+}
+
+func TestGrudges(t *testing.T) {
+	nodes := nodeIDs(5)
+
+	t.Run("Halves", func(t *testing.T) {
+		g := nemesis.HalvesGrudge(nodes)
+		if got, want := g["n0"], []string{"n2", "n3", "n4"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("n0=%v, want %v", got, want)
+		} else if got, want := len(g.Pairs()), 6; got != want {
+			t.Fatalf("pairs=%d, want %d", got, want)
+		}
+	})
+
+	t.Run("One", func(t *testing.T) {
+		g := nemesis.OneGrudge(nodes)
+		if got, want := reachable(g, nodes), map[string]int{"n0": 1, "n1": 4, "n2": 4, "n3": 4, "n4": 4}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("reachable=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("Bridge", func(t *testing.T) {
+		g := nemesis.BridgeGrudge(nodes)
+		if got, want := reachable(g, nodes), map[string]int{"n0": 3, "n1": 3, "n2": 5, "n3": 3, "n4": 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("reachable=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("MajoritiesRing", func(t *testing.T) {
+		for _, n := range []int{4, 5, 6, 7, 25} {
+			nodes := nodeIDs(n)
+			g := nemesis.MajoritiesRingGrudge(nodes)
+			majorities := make(map[string]bool)
+			for _, node := range nodes {
+				if r := reachable(g, nodes)[node]; r < n/2+1 || r == n {
+					t.Fatalf("%d nodes: %s reaches %d", n, node, r)
+				}
+				majorities[strings.Join(g[node], ",")] = true
+			}
+			if len(majorities) != n {
+				t.Fatalf("%d nodes: only %d distinct majorities", n, len(majorities))
+			}
+		}
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		g, err := nemesis.ParseGrudge("n0,n1|n2,n3,n4", nodes)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(g, nemesis.HalvesGrudge(nodes[:])) {
+			t.Fatalf("grudge=%v", g)
+		}
+		if _, err := nemesis.ParseGrudge("quarters", nodes); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+const schedule = `# a schedule
+5s   start-partition majorities-ring
+15s  stop-partition
+1s   pause one       # out of order
+20s  kill n1,n2
+25s  start
+30s  skew-clock minority -500ms
+35s  reset-clock
+`
+
+func TestParseSchedule(t *testing.T) {
+	events, err := nemesis.ParseSchedule(strings.NewReader(schedule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nemesis.Event{
+		{At: 1 * time.Second, F: nemesis.Pause, Arg: nemesis.TargetOne},
+		{At: 5 * time.Second, F: nemesis.StartPartition, Arg: nemesis.MajoritiesRing},
+		{At: 15 * time.Second, F: nemesis.StopPartition},
+		{At: 20 * time.Second, F: nemesis.Kill, Arg: "n1,n2"},
+		{At: 25 * time.Second, F: nemesis.Start},
+		{At: 30 * time.Second, F: nemesis.SkewClock, Arg: nemesis.TargetMinority, Offset: -500 * time.Millisecond},
+		{At: 35 * time.Second, F: nemesis.ResetClock},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events=%v, want %v", events, want)
+	}
+
+	// Written schedules read back the same.
+	var buf strings.Builder
+	if err := nemesis.WriteSchedule(&buf, events); err != nil {
+		t.Fatal(err)
+	} else if other, err := nemesis.ParseSchedule(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other, events) {
+		t.Fatalf("events=%v, want %v", other, events)
+	}
+
+	for _, s := range []string{"5s", "5s explode", "5s kill", "5s stop-partition now", "5s skew-clock n1 soon", "-1s heal"} {
+		if _, err := nemesis.ParseSchedule(strings.NewReader(s)); err == nil {
+			t.Fatalf("%q: expected error", s)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	events, err := nemesis.Generate([]string{nemesis.PartitionFault, nemesis.ClockFault}, 10*time.Second, 60*time.Second, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(events), 6; got != want {
+		t.Fatalf("events=%d, want %d: %v", got, want, events)
+	}
+	for i := 0; i < len(events); i += 2 {
+		start, stop := events[i], events[i+1]
+		if start.At != time.Duration(10+20*i/2)*time.Second || stop.At != start.At+10*time.Second {
+			t.Fatalf("unexpected times: %v %v", start, stop)
+		} else if f := start.F + "/" + stop.F; f != "start-partition/stop-partition" && f != "skew-clock/reset-clock" {
+			t.Fatalf("unexpected pair %s", f)
+		}
+	}
+
+	if _, err := nemesis.Generate([]string{"flood"}, time.Second, time.Minute, rand.New(rand.NewSource(1))); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// cluster records the faults applied to it.
+type cluster struct {
+	nodes  []string
+	blocks [][2]string
+	calls  []string
+}
+
+func (c *cluster) Nodes() []string   { return c.nodes }
+func (c *cluster) Block(a, b string) { c.blocks = append(c.blocks, [2]string{a, b}) }
+func (c *cluster) Heal()             { c.blocks = nil; c.calls = append(c.calls, "heal") }
+
+func (c *cluster) Pause(node string) error  { return c.call("pause", node) }
+func (c *cluster) Resume(node string) error { return c.call("resume", node) }
+func (c *cluster) Kill(node string) error   { return c.call("kill", node) }
+func (c *cluster) Restart(ctx context.Context, node string) error {
+	return c.call("restart", node)
+}
+func (c *cluster) SkewClock(node string, offset time.Duration) error {
+	return c.call("skew", node, offset)
+}
+
+func (c *cluster) call(args ...any) error {
+	c.calls = append(c.calls, strings.TrimSpace(fmt.Sprintln(args...)))
+	return nil
+}
+
+func TestNemesis(t *testing.T) {
+	c := &cluster{nodes: nodeIDs(5)}
+	rec := history.NewRecorder(nil)
+	n := nemesis.New(c, rec, 1)
+	ctx := context.Background()
+
+	for _, e := range []nemesis.Event{
+		{F: nemesis.StartPartition, Arg: nemesis.Halves},
+		{F: nemesis.Pause, Arg: "n1,n2"},
+		{F: nemesis.Resume, Arg: "n1"},
+		{F: nemesis.Kill, Arg: "n3"},
+		{F: nemesis.SkewClock, Arg: "n4", Offset: time.Second},
+	} {
+		if err := n.Apply(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := len(c.blocks), 6; got != want {
+		t.Fatalf("blocks=%d, want %d", got, want)
+	}
+
+	// Recovery undoes whatever is still in effect.
+	c.calls = nil
+	if err := n.Recover(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, want := c.calls, []string{"heal", "resume n2", "restart n3", "skew n4 0s"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls=%v, want %v", got, want)
+	} else if err := n.Recover(ctx); err != nil || len(c.calls) != 4 {
+		t.Fatalf("second recovery: calls=%v, err=%v", c.calls, err)
+	}
+
+	if err := n.Apply(ctx, nemesis.Event{F: nemesis.Kill, Arg: "n9"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Every operation is recorded by the nemesis process when it begins &
+	// with its outcome, and ignored by client pairs.
+	ops := rec.Ops()
+	if len(history.Pairs(ops)) != 0 {
+		t.Fatal("expected no client ops")
+	}
+	var got []string
+	for _, op := range ops {
+		if op.Process != history.NemesisProcess || op.Type != history.Info {
+			t.Fatalf("unexpected op %v", op)
+		}
+		if op.F == nemesis.StartPartition {
+			if v, ok := op.Value.([]any); ok && v[0] == edn.Keyword("isolated") {
+				continue
+			}
+		}
+		got = append(got, fmt.Sprintf("%s %v", op.F, op.Value))
+	}
+	if want := []string{
+		"start-partition halves",
+		"pause n1,n2", "pause [n1 n2]",
+		"resume n1", "resume [n1]",
+		"kill n3", "kill [n3]",
+		"skew-clock [n4 1s]", "skew-clock [n4]",
+		"stop-partition <nil>", "stop-partition :network-healed",
+		"resume <nil>", "resume [n2]",
+		"start <nil>", "start [n3]",
+		"reset-clock <nil>", "reset-clock [n4]",
+		"kill n9", `kill unknown node "n9"`,
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ops:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// Ensure scheduled events are applied at their times.
+func TestNemesis_Run(t *testing.T) {
+	c := &cluster{nodes: nodeIDs(3)}
+	n := nemesis.New(c, history.NewRecorder(nil), 1)
+
+	start := time.Now()
+	if err := n.Run(context.Background(), []nemesis.Event{
+		{At: 20 * time.Millisecond, F: nemesis.Pause, Arg: "n0"},
+		{At: 40 * time.Millisecond, F: nemesis.Resume},
+	}); err != nil {
+		t.Fatal(err)
+	} else if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("finished after %s", elapsed)
+	} else if got, want := c.calls, []string{"pause n0", "resume n0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls=%v, want %v", got, want)
+	}
+
+	// Cancellation stops the schedule.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.calls = nil
+	if err := n.Run(ctx, []nemesis.Event{{At: time.Hour, F: nemesis.Kill, Arg: "n0"}}); err != nil {
+		t.Fatal(err)
+	} else if len(c.calls) != 0 {
+		t.Fatalf("calls=%v", c.calls)
+	}
+}
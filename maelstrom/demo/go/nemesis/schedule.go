@@ -0,0 +1,223 @@
+package nemesis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Nemesis operations, used both in schedules and as the :f of the ops
+// recorded in histories.
+const (
+	StartPartition = "start-partition"
+	StopPartition  = "stop-partition"
+	Pause          = "pause"
+	Resume         = "resume"
+	Kill           = "kill"
+	Start          = "start"
+	SkewClock      = "skew-clock"
+	ResetClock     = "reset-clock"
+)
+
+// Faults that Generate can schedule, as in Maelstrom's --nemesis option.
+const (
+	PartitionFault = "partition"
+	PauseFault     = "pause"
+	KillFault      = "kill"
+	ClockFault     = "clock"
+)
+
+// Targets select the nodes a fault applies to. Lists of node IDs separated
+// by commas may be given instead.
+const (
+	TargetOne      = "one"
+	TargetMinority = "minority"
+	TargetMajority = "majority"
+	TargetAll      = "all"
+)
+
+// maxSkew bounds the clock offsets Generate picks.
+const maxSkew = 2 * time.Second
+
+// Event is a nemesis operation at a time relative to the start of a test.
+type Event struct {
+	At time.Duration
+	F  string
+
+	// Arg is the partition mode of start-partition and the target of other
+	// operations. Blank targets of resume, start & reset-clock mean every
+	// node affected so far.
+	Arg string
+
+	// Offset is the clock offset of skew-clock.
+	Offset time.Duration
+}
+
+// String returns the event as a line of a schedule file.
+func (e Event) String() string {
+	s := e.At.String() + " " + e.F
+	if e.Arg != "" {
+		s += " " + e.Arg
+	}
+	if e.F == SkewClock {
+		s += " " + e.Offset.String()
+	}
+	return s
+}
+
+// ParseSchedule reads a schedule with one event per line: the time since
+// the start of the test, the operation and its arguments.
+//
+//	# at  f                args
+//	5s    start-partition  majorities-ring
+//	15s   stop-partition
+//	20s   pause            one
+//	25s   resume
+//	30s   kill             n1,n2
+//	35s   start
+//	40s   skew-clock       minority -500ms
+//	50s   reset-clock
+//
+// Blank lines & comments starting with "#" are ignored. Events are sorted by
+// time, keeping the order of events at the same time.
+func ParseSchedule(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		e, err := parseEvent(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+	return events, nil
+}
+
+// ReadSchedule reads a schedule file. See ParseSchedule.
+func ReadSchedule(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events, err := ParseSchedule(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return events, nil
+}
+
+func parseEvent(fields []string) (Event, error) {
+	if len(fields) < 2 {
+		return Event{}, fmt.Errorf("expected time & operation")
+	}
+	at, err := time.ParseDuration(fields[0])
+	if err != nil {
+		return Event{}, err
+	} else if at < 0 {
+		return Event{}, fmt.Errorf("negative time %s", at)
+	}
+	e := Event{At: at, F: fields[1]}
+	args := fields[2:]
+
+	var min, max int
+	switch e.F {
+	case StartPartition, Pause, Kill:
+		min, max = 1, 1
+	case StopPartition:
+		min, max = 0, 0
+	case Resume, Start, ResetClock:
+		min, max = 0, 1
+	case SkewClock:
+		min, max = 2, 2
+	default:
+		return Event{}, fmt.Errorf("unknown operation %q", e.F)
+	}
+	if len(args) < min || len(args) > max {
+		return Event{}, fmt.Errorf("%s: expected %d to %d arguments, got %d", e.F, min, max, len(args))
+	}
+
+	if len(args) > 0 {
+		e.Arg = args[0]
+	}
+	if e.F == SkewClock {
+		if e.Offset, err = time.ParseDuration(args[1]); err != nil {
+			return Event{}, fmt.Errorf("%s: %w", e.F, err)
+		}
+	}
+	return e, nil
+}
+
+// Generate returns a schedule that, every interval, starts one of the given
+// faults with a random mode or target and stops it an interval later, for
+// the duration of a test.
+func Generate(faults []string, interval, duration time.Duration, rng *rand.Rand) ([]Event, error) {
+	if len(faults) == 0 {
+		return nil, nil
+	} else if interval <= 0 {
+		return nil, fmt.Errorf("invalid nemesis interval %s", interval)
+	}
+	for _, f := range faults {
+		switch f {
+		case PartitionFault, PauseFault, KillFault, ClockFault:
+		default:
+			return nil, fmt.Errorf("unknown fault %q", f)
+		}
+	}
+
+	modes := []string{Halves, One, Bridge, MajoritiesRing}
+	targets := []string{TargetOne, TargetMinority, TargetMajority}
+
+	var events []Event
+	for at := interval; at+interval <= duration; at += 2 * interval {
+		switch faults[rng.Intn(len(faults))] {
+		case PartitionFault:
+			events = append(events,
+				Event{At: at, F: StartPartition, Arg: modes[rng.Intn(len(modes))]},
+				Event{At: at + interval, F: StopPartition})
+		case PauseFault:
+			events = append(events,
+				Event{At: at, F: Pause, Arg: targets[rng.Intn(len(targets))]},
+				Event{At: at + interval, F: Resume})
+		case KillFault:
+			events = append(events,
+				Event{At: at, F: Kill, Arg: targets[rng.Intn(len(targets))]},
+				Event{At: at + interval, F: Start})
+		case ClockFault:
+			offset := time.Duration(rng.Int63n(int64(2*maxSkew))) - maxSkew
+			events = append(events,
+				Event{At: at, F: SkewClock, Arg: targets[rng.Intn(len(targets))], Offset: offset.Round(time.Millisecond)},
+				Event{At: at + interval, F: ResetClock})
+		}
+	}
+	return events, nil
+}
+
+// WriteSchedule writes events in the format read by ParseSchedule.
+func WriteSchedule(w io.Writer, events []Event) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range events {
+		fmt.Fprintln(bw, e)
+	}
+	return bw.Flush()
+}
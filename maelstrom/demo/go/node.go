@@ -2,13 +2,19 @@ package maelstrom
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"os"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
 )
 
 // Node represents a single node in the network.
@@ -22,23 +28,60 @@ type Node struct {
 
 	handlers  map[string]HandlerFunc
 	callbacks map[int]HandlerFunc
+	observers []func(dir string, msg Message)
+
+	clock Clock
+	hlc   *hlc.Clock
+	recMu sync.Mutex
+	sim   *Simulation
+	rand  *rand.Rand
 
 	// Stdin is for reading messages in from the Maelstrom network.
 	Stdin io.Reader
 
 	// Stdin is for writing messages out to the Maelstrom network.
 	Stdout io.Writer
+
+	// Record, if set, receives every message sent or received by the node
+	// as a timestamped JSON line. See RecordEntry and Replay.
+	Record io.Writer
+
+	// ForwardTimeout is the time Forward waits for a reply before answering
+	// the original sender with a timeout. Defaults to DefaultForwardTimeout.
+	ForwardTimeout time.Duration
 }
 
 // NewNode returns a new instance of Node connected to STDIN/STDOUT.
+//
+// If the MAELSTROM_CLOCK_SKEW_FILE environment variable is set, the node's
+// clock is skewed by the offset in that file. If MAELSTROM_SIMULATION is set,
+// the node runs under a simulation seeded by its value instead; see Run.
 func NewNode() *Node {
-	return &Node{
+	var clock Clock = NewRealClock()
+	if path := os.Getenv(ClockSkewFileEnv); path != "" {
+		c := NewSkewedClock(clock, 0)
+		c.WatchFile(path)
+		clock = c
+	}
+
+	n := &Node{
 		handlers:  make(map[string]HandlerFunc),
 		callbacks: make(map[int]HandlerFunc),
+		clock:     clock,
+		rand:      rand.New(newLockedSource(time.Now().UnixNano())),
 
 		Stdin:  os.Stdin,
 		Stdout: os.Stdout,
 	}
+
+	if v := os.Getenv(SimulationEnv); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid %s: %s", SimulationEnv, err)
+		}
+		n.Simulate(NewSimulation(seed))
+	}
+	return n
 }
 
 // Init is used for initializing the node. This is normally called after
@@ -62,6 +105,33 @@ func (n *Node) NodeIDs() []string {
 	return n.nodeIDs
 }
 
+// Clock returns the clock used by the node. Handlers should use it for
+// timestamps, timers & tickers so that time can be controlled in tests.
+func (n *Node) Clock() Clock {
+	return n.clock
+}
+
+// SetClock replaces the node's clock. This should be called before Run().
+func (n *Node) SetClock(clock Clock) {
+	n.clock = clock
+}
+
+// Simulate makes the node run under s: handlers, callbacks, timers & Go run
+// as tasks of s and the node's clock reads its virtual time. This should be
+// called before the node receives any message.
+func (n *Node) Simulate(s *Simulation) {
+	n.sim = s
+	n.clock = s.Clock()
+	n.rand = s.Rand()
+}
+
+// Rand returns a random source for handlers, safe for concurrent use. It is
+// seeded by the simulation when the node is simulated, so handlers should
+// use it rather than the global math/rand functions.
+func (n *Node) Rand() *rand.Rand {
+	return n.rand
+}
+
 // Handle registers a message handler for a given message type. Will panic if
 // registering multiple handlers for the same message type.
 func (n *Node) Handle(typ string, fn HandlerFunc) {
@@ -74,60 +144,25 @@ func (n *Node) Handle(typ string, fn HandlerFunc) {
 // Run executes the main event handling loop. It reads in messages from STDIN
 // and delegates them to the appropriate registered handler. This should be
 // the last function executed by main().
+//
+// If the MAELSTROM_REPLAY environment variable is set, the node replays the
+// recording at that path instead of reading from STDIN. If the node is
+// simulated, STDIN carries SimulationStep lines instead of messages.
 func (n *Node) Run() error {
+	if path := os.Getenv(ReplayEnv); path != "" {
+		return n.replayFile(path)
+	} else if n.sim != nil {
+		return n.runSimulation()
+	}
+	return n.run()
+}
+
+func (n *Node) run() error {
 	scanner := bufio.NewScanner(n.Stdin)
 	for scanner.Scan() {
-		line := scanner.Bytes()
-
-		// Parse next line from STDIN as a JSON-formatted message.
-		var msg Message
-		if err := json.Unmarshal(line, &msg); err != nil {
-			return fmt.Errorf("unmarshal message: %w", err)
-		}
-
-		var body MessageBody
-		if err := json.Unmarshal(msg.Body, &body); err != nil {
-			return fmt.Errorf("unmarshal message body: %w", err)
-		}
-		log.Printf("Received %s", msg)
-
-		// What handler should we use for this message?
-		if body.InReplyTo != 0 {
-			// Extract callback, if replying to a previous message.
-			n.mu.Lock()
-			h := n.callbacks[body.InReplyTo]
-			delete(n.callbacks, body.InReplyTo)
-			n.mu.Unlock()
-
-			// If no callback exists, just log a message and skip.
-			if h == nil {
-				log.Printf("Ignoring reply to %d with no callback", body.InReplyTo)
-				continue
-			}
-
-			// Handle callback in a separate goroutine.
-			n.wg.Add(1)
-			go func() {
-				defer n.wg.Done()
-				n.handleCallback(h, msg)
-			}()
-			continue
-		}
-
-		// If this is not a callback, ensure that a handler is registered.
-		var h HandlerFunc
-		if body.Type == "init" {
-			h = n.handleInitMessage // wraps init message with special handling.
-		} else if h = n.handlers[body.Type]; h == nil {
-			return fmt.Errorf("No handler for %s", line)
+		if err := n.Deliver(scanner.Bytes()); err != nil {
+			return err
 		}
-
-		// Handle message in a separate goroutine.
-		n.wg.Add(1)
-		go func() {
-			defer n.wg.Done()
-			n.handleMessage(h, msg)
-		}()
 	}
 	if err := scanner.Err(); err != nil {
 		return err
@@ -139,6 +174,81 @@ func (n *Node) Run() error {
 	return nil
 }
 
+// Deliver handles a single message line as if it had been read from Stdin.
+// The handler or callback runs in the background; see Go. Run calls Deliver
+// for each line; it is exported so that a test harness or simulation can
+// drive a node without Stdin.
+func (n *Node) Deliver(line []byte) error {
+	// Parse the line as a JSON-formatted message.
+	var msg Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return fmt.Errorf("unmarshal message: %w", err)
+	}
+
+	var body MessageBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return fmt.Errorf("unmarshal message body: %w", err)
+	}
+	log.Printf("Received %s", msg)
+
+	if body.Type == "init" {
+		if err := n.openRecordDir(msg); err != nil {
+			return err
+		}
+	}
+	n.record(RecordIn, line)
+	n.observeHLC(msg.Body)
+	n.notifyObservers(RecordIn, msg)
+
+	// What handler should we use for this message?
+	if body.InReplyTo != 0 {
+		// Extract callback, if replying to a previous message.
+		n.mu.Lock()
+		h := n.callbacks[body.InReplyTo]
+		delete(n.callbacks, body.InReplyTo)
+		n.mu.Unlock()
+
+		// If no callback exists, just log a message and skip.
+		if h == nil {
+			log.Printf("Ignoring reply to %d with no callback", body.InReplyTo)
+			return nil
+		}
+
+		// Handle callback in the background.
+		n.Go(func() { n.handleCallback(h, msg) })
+		return nil
+	}
+
+	// If this is not a callback, ensure that a handler is registered.
+	var h HandlerFunc
+	if body.Type == "init" {
+		h = n.handleInitMessage // wraps init message with special handling.
+	} else if h = n.handlers[body.Type]; h == nil {
+		return fmt.Errorf("No handler for %s", line)
+	}
+
+	// Handle message in the background.
+	n.Go(func() { n.handleMessage(h, msg) })
+	return nil
+}
+
+// Go runs fn in the background: in its own goroutine, which Run waits for
+// before returning, or as a task when the node is simulated. Handlers should
+// start background work with Go rather than the go statement so that it is
+// scheduled deterministically under simulation.
+func (n *Node) Go(fn func()) {
+	if n.sim != nil {
+		n.sim.Go(fn)
+		return
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		fn()
+	}()
+}
+
 // handleCallback sends msg response to a callback function. Logs error, if one occurs.
 func (n *Node) handleCallback(h HandlerFunc, msg Message) {
 	if err := h(msg); err != nil {
@@ -182,6 +292,18 @@ func (n *Node) handleInitMessage(msg Message) error {
 	return n.Reply(msg, MessageBody{Type: "init_ok"})
 }
 
+// observe registers fn to be called with every message sent or received by
+// the node. Must be called before Run().
+func (n *Node) observe(fn func(dir string, msg Message)) {
+	n.observers = append(n.observers, fn)
+}
+
+func (n *Node) notifyObservers(dir string, msg Message) {
+	for _, fn := range n.observers {
+		fn(dir, msg)
+	}
+}
+
 // Reply replies to a request with a response body.
 func (n *Node) Reply(req Message, body any) error {
 	// Extract the message ID from the original message.
@@ -190,11 +312,12 @@ func (n *Node) Reply(req Message, body any) error {
 		return err
 	}
 
-	// We have to marshal/unmarshal to inject our reply message ID.
+	// We have to marshal/unmarshal to inject our reply message ID. Numbers
+	// are kept as json.Number so large integers survive the round trip.
 	b := make(map[string]any)
 	if buf, err := json.Marshal(body); err != nil {
 		return err
-	} else if err := json.Unmarshal(buf, &b); err != nil {
+	} else if err := unmarshalNumbers(buf, &b); err != nil {
 		return err
 	}
 	b["in_reply_to"] = reqBody.MsgID
@@ -202,27 +325,39 @@ func (n *Node) Reply(req Message, body any) error {
 	return n.Send(req.Src, b)
 }
 
+// unmarshalNumbers decodes data into v, keeping numbers as json.Number.
+func unmarshalNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
 // Send sends a message body to a given destination node.
 func (n *Node) Send(dest string, body any) error {
 	bodyJSON, err := json.Marshal(body)
 	if err != nil {
 		return err
+	} else if bodyJSON, err = n.stampHLC(dest, bodyJSON); err != nil {
+		return err
 	}
 
-	buf, err := json.Marshal(Message{
+	msg := Message{
 		Src:  n.id,
 		Dest: dest,
 		Body: bodyJSON,
-	})
+	}
+	buf, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
+	n.notifyObservers(RecordOut, msg)
 
 	// Synchronize access to STDOUT.
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
 	log.Printf("Sent %s", buf)
+	n.record(RecordOut, buf)
 
 	if _, err = n.Stdout.Write(buf); err != nil {
 		return err
@@ -233,6 +368,12 @@ func (n *Node) Send(dest string, body any) error {
 
 // RPC sends an async RPC request. Handler invoked when response message received.
 func (n *Node) RPC(dest string, body any, handler HandlerFunc) error {
+	_, err := n.rpc(dest, body, handler)
+	return err
+}
+
+// rpc sends an async RPC request and returns its message ID.
+func (n *Node) rpc(dest string, body any, handler HandlerFunc) (int, error) {
 	n.mu.Lock()
 
 	// Generate a unique message ID.
@@ -244,32 +385,51 @@ func (n *Node) RPC(dest string, body any, handler HandlerFunc) error {
 
 	n.mu.Unlock()
 
-	// We have to marshal/unmarshal to inject our message ID.
+	// We have to marshal/unmarshal to inject our message ID, keeping large
+	// integers intact as Reply does.
 	b := make(map[string]any)
 	if buf, err := json.Marshal(body); err != nil {
-		return err
-	} else if err := json.Unmarshal(buf, &b); err != nil {
-		return err
+		return msgID, err
+	} else if err := unmarshalNumbers(buf, &b); err != nil {
+		return msgID, err
 	}
 	b["msg_id"] = msgID
 
-	return n.Send(dest, b)
+	return msgID, n.Send(dest, b)
+}
+
+// cancelRPC removes the callback for an outstanding RPC so that a late reply
+// is ignored. Returns false if the reply has already been handled.
+func (n *Node) cancelRPC(msgID int) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, ok := n.callbacks[msgID]
+	delete(n.callbacks, msgID)
+	return ok
 }
 
 // SyncRPC sends a synchronous RPC request. Returns the response message. RPC
 // errors in the message body are converted to *RPCError and are returned.
 func (n *Node) SyncRPC(ctx context.Context, dest string, body any) (Message, error) {
-	respCh := make(chan Message)
-	if err := n.RPC(dest, body, func(m Message) error {
+	if n.sim != nil {
+		return n.syncRPCSimulated(ctx, dest, body)
+	}
+
+	respCh := make(chan Message, 1) // buffered so a late reply never blocks
+	msgID, err := n.rpc(dest, body, func(m Message) error {
 		respCh <- m
 		return nil
-	}); err != nil {
+	})
+	if err != nil {
 		return Message{}, err
 	}
 
-	// Wait for either the context to finish or for the response message to arrive.
+	// Wait for either the context to finish or for the response message to
+	// arrive. A reply that never comes must not leave its callback behind.
 	select {
 	case <-ctx.Done():
+		n.cancelRPC(msgID)
 		return Message{}, ctx.Err()
 
 	case m := <-respCh:
@@ -298,14 +458,15 @@ func (m *Message) Type() string {
 	return body.Type
 }
 
-// RPCError returns the RPC error from the message body.
-// Returns a malformed body as a generic crash error.
+// RPCError returns the RPC error from the message body: an "error" body, or
+// any body with a non-zero code. Returns a malformed body as a generic crash
+// error.
 func (m *Message) RPCError() *RPCError {
 	var body MessageBody
 	if err := json.Unmarshal(m.Body, &body); err != nil {
 		return NewRPCError(Crash, err.Error())
-	} else if body.Code == 0 {
-		return nil // no error
+	} else if body.Type != "error" && body.Code == 0 {
+		return nil // no error; code 0 is Timeout, so only with type "error"
 	}
 	return NewRPCError(body.Code, body.Text)
 }
@@ -337,3 +498,31 @@ type InitMessageBody struct {
 
 // HandlerFunc is the function signature for a message handler.
 type HandlerFunc func(msg Message) error
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
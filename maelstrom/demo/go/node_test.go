@@ -68,6 +68,38 @@ func TestNode_Run(t *testing.T) {
 			t.Fatalf("stdout=%s, want %s", got, want)
 		}
 	})
+
+	t.Run("ReplyLargeIntegers", func(t *testing.T) {
+		var stdout bytes.Buffer
+		n := maelstrom.NewNode()
+		n.Stdin = strings.NewReader(`{"dest":"n1", "body":{"type":"foo", "msg_id":1}}` + "\n")
+		n.Stdout = &stdout
+		n.Handle("foo", func(msg maelstrom.Message) error {
+			return n.Reply(msg, map[string]any{"type": "foo_ok", "value": uint64(1<<63 + 1)})
+		})
+		if err := n.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := stdout.String(), `{"body":{"in_reply_to":1,"type":"foo_ok","value":9223372036854775809}}`+"\n"; got != want {
+			t.Fatalf("stdout=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("RPCLargeIntegers", func(t *testing.T) {
+		var stdout bytes.Buffer
+		n := maelstrom.NewNode()
+		n.Stdin = strings.NewReader(`{"dest":"n1", "body":{"type":"foo"}}` + "\n")
+		n.Stdout = &stdout
+		n.Handle("foo", func(msg maelstrom.Message) error {
+			return n.RPC("n2", map[string]any{"type": "bar", "value": uint64(1<<63 + 1)}, func(msg maelstrom.Message) error { return nil })
+		})
+		if err := n.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := stdout.String(), `{"dest":"n2","body":{"msg_id":1,"type":"bar","value":9223372036854775809}}`+"\n"; got != want {
+			t.Fatalf("stdout=%s, want %s", got, want)
+		}
+	})
 }
 
 // Ensure a node can handle the "init" message.
@@ -331,6 +363,34 @@ func TestNode_SyncRPC(t *testing.T) {
 			t.Fatal("timeout waiting for RPC response")
 		}
 	})
+
+	// Timeout is code 0, so an error body must not be mistaken for success.
+	t.Run("RPCErrorTimeout", func(t *testing.T) {
+		n, stdin, stdout := newNode(t)
+		initNode(t, n, "n1", []string{"n1", "n2"}, stdin, stdout)
+
+		errorCh := make(chan error)
+		go func() {
+			_, err := n.SyncRPC(context.Background(), "n2", map[string]any{"type": "foo"})
+			errorCh <- err
+		}()
+		if _, err := stdout.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := stdin.Write([]byte(`{"src":"n2", "dest":"n1", "body":{"type":"error", "in_reply_to":1, "code":0, "text":"timed out"}}` + "\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-errorCh:
+			if got, want := maelstrom.ErrorCode(err), maelstrom.Timeout; got != want {
+				t.Fatalf("code=%v, want %v (err=%v)", got, want, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for RPC response")
+		}
+	})
 }
 
 // newNode initializes a test node and returns streams to read/write messages.
@@ -0,0 +1,68 @@
+// Package partition assigns keys to nodes. It offers consistent hashing with
+// virtual nodes and rendezvous (highest random weight) hashing. Both are
+// deterministic: every node computes the same placement from the same member
+// list regardless of its order, and only a small fraction of keys move when
+// a member joins or leaves.
+//
+// Members are typically the cluster's node IDs:
+//
+//	ring := partition.NewRing(n.NodeIDs(), partition.DefaultVNodes)
+//	owner := ring.Owner(topic)
+package partition
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Partitioner maps keys to the members responsible for them.
+type Partitioner interface {
+	// Members returns the sorted member list.
+	Members() []string
+
+	// Owner returns the primary member for key, or blank if there are no
+	// members.
+	Owner(key string) string
+
+	// Replicas returns up to n distinct members for key, primary first.
+	Replicas(key string, n int) []string
+}
+
+// hash returns a well-mixed 64-bit hash of s. FNV-1a alone distributes
+// similar strings poorly, so its output is passed through a finalizer.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// normalize returns a sorted copy of members with duplicates removed.
+func normalize(members []string) []string {
+	out := append([]string{}, members...)
+	sort.Strings(out)
+
+	n := 0
+	for i, m := range out {
+		if i == 0 || m != out[n-1] {
+			out[n] = m
+			n++
+		}
+	}
+	return out[:n]
+}
+
+// vnodeKey returns the string hashed for a member's i-th virtual node.
+func vnodeKey(member string, i int) string {
+	return member + "#" + strconv.Itoa(i)
+}
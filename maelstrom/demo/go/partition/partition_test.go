@@ -0,0 +1,132 @@
+package partition_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/partition"
+)
+
+var members = []string{"n0", "n1", "n2", "n3", "n4"}
+
+// partitioners returns each implementation built over the given members.
+func partitioners(members []string) map[string]partition.Partitioner {
+	return map[string]partition.Partitioner{
+		"ring":       partition.NewRing(members, partition.DefaultVNodes),
+		"rendezvous": partition.NewRendezvous(members),
+	}
+}
+
+func keys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+// Ensure placement does not depend on member order or duplicates.
+func TestPartitioner_Deterministic(t *testing.T) {
+	a := partitioners(members)
+	b := partitioners([]string{"n4", "n2", "n0", "n3", "n1", "n2"})
+	for name := range a {
+		for _, key := range keys(1000) {
+			if x, y := a[name].Replicas(key, 3), b[name].Replicas(key, 3); !reflect.DeepEqual(x, y) {
+				t.Fatalf("%s: replicas for %s differ: %v != %v", name, key, x, y)
+			}
+		}
+	}
+}
+
+// Ensure replica sets are distinct members led by the owner.
+func TestPartitioner_Replicas(t *testing.T) {
+	for name, p := range partitioners(members) {
+		for _, key := range keys(100) {
+			replicas := p.Replicas(key, 3)
+			if len(replicas) != 3 {
+				t.Fatalf("%s: replicas=%v, want 3", name, replicas)
+			} else if replicas[0] != p.Owner(key) {
+				t.Fatalf("%s: replicas=%v, owner=%s", name, replicas, p.Owner(key))
+			}
+			seen := make(map[string]bool)
+			for _, m := range replicas {
+				if seen[m] {
+					t.Fatalf("%s: duplicate replica in %v", name, replicas)
+				}
+				seen[m] = true
+			}
+		}
+
+		if got := p.Replicas("k", 10); len(got) != len(members) {
+			t.Fatalf("%s: replicas=%v, want all members", name, got)
+		}
+	}
+
+	for name, p := range partitioners(nil) {
+		if owner := p.Owner("k"); owner != "" {
+			t.Fatalf("%s: owner=%q, want blank", name, owner)
+		} else if replicas := p.Replicas("k", 3); len(replicas) != 0 {
+			t.Fatalf("%s: replicas=%v, want none", name, replicas)
+		}
+	}
+}
+
+// Ensure keys are spread roughly evenly.
+func TestPartitioner_Balance(t *testing.T) {
+	const n = 20000
+	for name, p := range partitioners(members) {
+		counts := make(map[string]int)
+		for _, key := range keys(n) {
+			counts[p.Owner(key)]++
+		}
+
+		fair := n / len(members)
+		for _, m := range members {
+			if c := counts[m]; c < fair*7/10 || c > fair*13/10 {
+				t.Fatalf("%s: %s owns %d keys, fair share is %d", name, m, c, fair)
+			}
+		}
+	}
+}
+
+// Ensure adding a member only moves keys to the new member, and removing it
+// restores the original placement.
+func TestPartitioner_MinimalMovement(t *testing.T) {
+	type mutable interface {
+		partition.Partitioner
+		Add(string)
+		Remove(string)
+	}
+
+	const n = 20000
+	for name, p := range partitioners(members) {
+		p := p.(mutable)
+
+		before := make(map[string]string)
+		for _, key := range keys(n) {
+			before[key] = p.Owner(key)
+		}
+
+		p.Add("n5")
+		moved := 0
+		for key, owner := range before {
+			if o := p.Owner(key); o != owner {
+				if o != "n5" {
+					t.Fatalf("%s: %s moved from %s to %s", name, key, owner, o)
+				}
+				moved++
+			}
+		}
+		if fair := n / 6; moved < fair*7/10 || moved > fair*13/10 {
+			t.Fatalf("%s: moved %d keys, expected about %d", name, moved, fair)
+		}
+
+		p.Remove("n5")
+		for key, owner := range before {
+			if o := p.Owner(key); o != owner {
+				t.Fatalf("%s: %s owned by %s after removal, want %s", name, key, o, owner)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package partition
+
+import "sort"
+
+// Rendezvous implements rendezvous (highest random weight) hashing. Each
+// member is scored against the key and the highest scores win. It needs no
+// virtual nodes and moves the minimum number of keys on membership changes,
+// at the cost of scoring every member on each lookup.
+//
+// Rendezvous is not safe for concurrent modification.
+type Rendezvous struct {
+	members []string
+	hashes  []uint64
+}
+
+var _ Partitioner = (*Rendezvous)(nil)
+
+// NewRendezvous returns a rendezvous partitioner over members.
+func NewRendezvous(members []string) *Rendezvous {
+	r := &Rendezvous{}
+	r.build(members)
+	return r
+}
+
+// Members returns the sorted member list.
+func (r *Rendezvous) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+// Add adds member. Only keys that now belong to member move.
+func (r *Rendezvous) Add(member string) {
+	r.build(append(r.Members(), member))
+}
+
+// Remove removes member. Only keys owned by member move.
+func (r *Rendezvous) Remove(member string) {
+	members := make([]string, 0, len(r.members))
+	for _, m := range r.members {
+		if m != member {
+			members = append(members, m)
+		}
+	}
+	r.build(members)
+}
+
+// Owner returns the member with the highest score for key.
+func (r *Rendezvous) Owner(key string) string {
+	if len(r.members) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	best, bestScore := 0, mix(h^r.hashes[0])
+	for i := 1; i < len(r.members); i++ {
+		if score := mix(h ^ r.hashes[i]); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return r.members[best]
+}
+
+// Replicas returns the n members with the highest scores for key.
+func (r *Rendezvous) Replicas(key string, n int) []string {
+	if n > len(r.members) {
+		n = len(r.members)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	h := hash(key)
+	idx := make([]int, len(r.members))
+	scores := make([]uint64, len(r.members))
+	for i := range r.members {
+		idx[i], scores[i] = i, mix(h^r.hashes[i])
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		if scores[idx[a]] != scores[idx[b]] {
+			return scores[idx[a]] > scores[idx[b]]
+		}
+		return idx[a] < idx[b]
+	})
+
+	replicas := make([]string, n)
+	for i := range replicas {
+		replicas[i] = r.members[idx[i]]
+	}
+	return replicas
+}
+
+func (r *Rendezvous) build(members []string) {
+	r.members = normalize(members)
+	r.hashes = make([]uint64, len(r.members))
+	for i, m := range r.members {
+		r.hashes[i] = hash(m)
+	}
+}
@@ -0,0 +1,113 @@
+package partition
+
+import "sort"
+
+// DefaultVNodes is the default number of virtual nodes per member.
+const DefaultVNodes = 128
+
+// Ring is a consistent hash ring. Each member is placed on the ring at
+// several points (virtual nodes) to even out the load; a key belongs to the
+// member owning the first point clockwise from the key's hash.
+//
+// Ring is not safe for concurrent modification.
+type Ring struct {
+	vnodes  int
+	members []string
+	points  []point
+}
+
+type point struct {
+	hash   uint64
+	member string
+}
+
+var _ Partitioner = (*Ring)(nil)
+
+// NewRing returns a ring of members with vnodes points per member.
+func NewRing(members []string, vnodes int) *Ring {
+	if vnodes < 1 {
+		vnodes = 1
+	}
+	r := &Ring{vnodes: vnodes}
+	r.build(members)
+	return r
+}
+
+// Members returns the sorted member list.
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+// Add adds member to the ring. Only keys that now belong to member move.
+func (r *Ring) Add(member string) {
+	r.build(append(r.Members(), member))
+}
+
+// Remove removes member from the ring. Only keys owned by member move.
+func (r *Ring) Remove(member string) {
+	members := make([]string, 0, len(r.members))
+	for _, m := range r.members {
+		if m != member {
+			members = append(members, m)
+		}
+	}
+	r.build(members)
+}
+
+// Owner returns the member owning key.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	return r.points[r.search(hash(key))].member
+}
+
+// Replicas returns up to n distinct members for key by walking the ring
+// clockwise from the owner.
+func (r *Ring) Replicas(key string, n int) []string {
+	if n > len(r.members) {
+		n = len(r.members)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	replicas := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i, start := 0, r.search(hash(key)); len(replicas) < n; i++ {
+		m := r.points[(start+i)%len(r.points)].member
+		if !seen[m] {
+			seen[m] = true
+			replicas = append(replicas, m)
+		}
+	}
+	return replicas
+}
+
+// search returns the index of the first point at or after h, wrapping
+// around the ring.
+func (r *Ring) search(h uint64) int {
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return i
+}
+
+func (r *Ring) build(members []string) {
+	r.members = normalize(members)
+	r.points = make([]point, 0, len(r.members)*r.vnodes)
+	for _, m := range r.members {
+		for i := 0; i < r.vnodes; i++ {
+			r.points = append(r.points, point{hash: hash(vnodeKey(m, i)), member: m})
+		}
+	}
+
+	// Break hash ties by member so placement never depends on input order.
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].member < r.points[j].member
+	})
+}
@@ -0,0 +1,134 @@
+package raft
+
+import (
+	"encoding/json"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+type requestVoteBody struct {
+	Type         string `json:"type"`
+	Term         int    `json:"term"`
+	CandidateID  string `json:"candidate_id"`
+	LastLogIndex int    `json:"last_log_index"`
+	LastLogTerm  int    `json:"last_log_term"`
+}
+
+type requestVoteOKBody struct {
+	Type        string `json:"type"`
+	Term        int    `json:"term"`
+	VoteGranted bool   `json:"vote_granted"`
+}
+
+// becomeCandidate starts an election for the next term. Must hold lock.
+func (r *Raft) becomeCandidate() {
+	r.role = Candidate
+	r.term++
+	r.votedFor = r.node.ID()
+	r.leader = ""
+	r.votes = map[string]bool{r.node.ID(): true}
+	r.resetDeadline()
+	r.notify()
+
+	if len(r.votes) >= r.quorum() {
+		r.becomeLeader()
+		return
+	}
+
+	term := r.term
+	body := requestVoteBody{
+		Type:         "request_vote",
+		Term:         term,
+		CandidateID:  r.node.ID(),
+		LastLogIndex: r.lastIndex(),
+		LastLogTerm:  r.log[r.lastIndex()].Term,
+	}
+	for _, peer := range r.peers() {
+		r.send(peer, body, func(msg maelstrom.Message) error {
+			return r.handleRequestVoteOK(term, msg)
+		})
+	}
+}
+
+// becomeLeader takes leadership of the current term and appends a no-op so
+// that entries from earlier terms can be committed. Must hold lock.
+func (r *Raft) becomeLeader() {
+	r.role = Leader
+	r.leader = r.node.ID()
+	r.nextIndex = make(map[string]int)
+	r.matchIndex = make(map[string]int)
+	r.acked = make(map[string]int)
+	for _, peer := range r.peers() {
+		r.nextIndex[peer] = r.lastIndex() + 1
+	}
+
+	r.log = append(r.log, Entry{Term: r.term})
+	r.advanceCommit()
+	r.broadcast()
+	r.notify()
+}
+
+// stepDown reverts to follower, adopting term if it is newer. Must hold lock.
+func (r *Raft) stepDown(term int) {
+	if term > r.term {
+		r.term = term
+		r.votedFor = ""
+		r.leader = ""
+	}
+	if r.role != Follower {
+		r.role = Follower
+		r.resetDeadline()
+	}
+	r.notify()
+}
+
+func (r *Raft) handleRequestVote(msg maelstrom.Message) error {
+	var body requestVoteBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if body.Term > r.term {
+		r.stepDown(body.Term)
+	}
+
+	// Only vote for candidates whose log is at least as up-to-date as ours.
+	lastTerm := r.log[r.lastIndex()].Term
+	upToDate := body.LastLogTerm > lastTerm ||
+		(body.LastLogTerm == lastTerm && body.LastLogIndex >= r.lastIndex())
+
+	granted := body.Term == r.term && upToDate &&
+		(r.votedFor == "" || r.votedFor == body.CandidateID)
+	if granted {
+		r.votedFor = body.CandidateID
+		r.resetDeadline()
+	}
+	reply := requestVoteOKBody{Type: "request_vote_ok", Term: r.term, VoteGranted: granted}
+	r.mu.Unlock()
+
+	return r.node.Reply(msg, reply)
+}
+
+func (r *Raft) handleRequestVoteOK(term int, msg maelstrom.Message) error {
+	var body requestVoteOKBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if body.Term > r.term {
+		r.stepDown(body.Term)
+		return nil
+	} else if r.role != Candidate || r.term != term || !body.VoteGranted {
+		return nil
+	}
+
+	r.votes[msg.Src] = true
+	if len(r.votes) >= r.quorum() {
+		r.becomeLeader()
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+// Package raft implements the Raft consensus algorithm on top of a Maelstrom
+// node. It provides leader election, log replication and linearizable reads
+// via the read-index protocol. Applications plug in a StateMachine which
+// receives committed operations in log order on every node.
+//
+// State is kept in memory only, matching Maelstrom workloads where nodes are
+// partitioned & paused but never lose their memory.
+package raft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Raft defaults.
+const (
+	DefaultElectionTimeout   = 1 * time.Second
+	DefaultHeartbeatInterval = 100 * time.Millisecond
+	DefaultTickInterval      = 10 * time.Millisecond
+	DefaultMaxBatch          = 64
+)
+
+// Roles a node can take.
+const (
+	Follower  = "follower"
+	Candidate = "candidate"
+	Leader    = "leader"
+)
+
+var (
+	// ErrNotLeader is returned when an operation requires the local node to
+	// be the leader. Callers can consult Status().Leader to redirect.
+	ErrNotLeader = errors.New("raft: not leader")
+
+	// ErrLeadershipLost is returned when a proposed entry was overwritten by
+	// a different leader. The operation was not applied.
+	ErrLeadershipLost = errors.New("raft: leadership lost before entry was committed")
+)
+
+// StateMachine is the replicated application state. Apply is called with
+// each committed operation, in log order, exactly once per node. The result
+// is returned to the proposer on the leader.
+type StateMachine interface {
+	Apply(op json.RawMessage) (any, error)
+}
+
+// Entry is a single entry in the replicated log. A nil Op is a no-op which
+// is not passed to the state machine.
+type Entry struct {
+	Term int             `json:"term"`
+	Op   json.RawMessage `json:"op,omitempty"`
+}
+
+// Status is a snapshot of a node's Raft state.
+type Status struct {
+	ID          string
+	Role        string
+	Term        int
+	Leader      string // blank if unknown
+	LastIndex   int
+	CommitIndex int
+	LastApplied int
+}
+
+// Raft is a single member of a Raft cluster. The cluster consists of every
+// node in the Maelstrom network.
+type Raft struct {
+	mu      sync.Mutex
+	node    *maelstrom.Node
+	sm      StateMachine
+	changed chan struct{} // closed & replaced on every state change
+
+	role     string
+	term     int
+	votedFor string
+	leader   string
+	log      []Entry // log[0] is a sentinel so indexes start at 1
+	deadline time.Time
+	votes    map[string]bool
+
+	commitIndex int
+	lastApplied int
+	pending     map[int]*proposal
+
+	// Leader state.
+	nextIndex     map[string]int
+	matchIndex    map[string]int
+	seq           int            // broadcast round, used to confirm leadership
+	acked         map[string]int // latest round acknowledged by each peer
+	lastBroadcast time.Time
+
+	// Base election timeout. Each timeout is randomized between one and two
+	// times this value.
+	ElectionTimeout time.Duration
+
+	// Interval between heartbeats sent by the leader.
+	HeartbeatInterval time.Duration
+
+	// Resolution of election & heartbeat timers.
+	TickInterval time.Duration
+
+	// Maximum number of entries sent in a single append.
+	MaxBatch int
+}
+
+// proposal tracks the outcome of an entry proposed on this node.
+type proposal struct {
+	term   int
+	done   bool
+	result any
+	err    error
+}
+
+// New returns a new Raft member for node applying commands to sm, and
+// registers its message handlers. This must be called before Run().
+func New(node *maelstrom.Node, sm StateMachine) *Raft {
+	r := &Raft{
+		node:    node,
+		sm:      sm,
+		changed: make(chan struct{}),
+		role:    Follower,
+		log:     []Entry{{}},
+		pending: make(map[int]*proposal),
+
+		ElectionTimeout:   DefaultElectionTimeout,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		TickInterval:      DefaultTickInterval,
+		MaxBatch:          DefaultMaxBatch,
+	}
+
+	node.Handle("request_vote", r.handleRequestVote)
+	node.Handle("append_entries", r.handleAppendEntries)
+	return r
+}
+
+// Start runs election & heartbeat timers until ctx is canceled. Ticks read the
+// node's ID & peers, so Start must be called once the node is initialized,
+// e.g. from an "init" handler.
+func (r *Raft) Start(ctx context.Context) {
+	maelstrom.Every(ctx, r.node.Clock(), r.TickInterval, r.tick)
+}
+
+// Status returns a snapshot of the node's current state.
+func (r *Raft) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Status{
+		ID:          r.node.ID(),
+		Role:        r.role,
+		Term:        r.term,
+		Leader:      r.leader,
+		LastIndex:   r.lastIndex(),
+		CommitIndex: r.commitIndex,
+		LastApplied: r.lastApplied,
+	}
+}
+
+// IsLeader returns true if this node currently believes it is the leader.
+func (r *Raft) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.role == Leader
+}
+
+// Propose appends op to the log and waits until it has been committed and
+// applied. Returns the state machine's result. Returns ErrNotLeader if this
+// node is not the leader.
+//
+// If ctx is done first the outcome is unknown: the entry may still commit.
+func (r *Raft) Propose(ctx context.Context, op any) (any, error) {
+	buf, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.role != Leader {
+		r.mu.Unlock()
+		return nil, ErrNotLeader
+	}
+	r.log = append(r.log, Entry{Term: r.term, Op: buf})
+	p := &proposal{term: r.term}
+	r.pending[r.lastIndex()] = p
+	r.advanceCommit()
+	r.broadcast()
+	r.mu.Unlock()
+
+	if err := r.wait(ctx, func() bool { return p.done }); err != nil {
+		return nil, err
+	}
+	return p.result, p.err
+}
+
+// Read executes fn against the state machine once the leader has confirmed
+// that it is still the leader and has applied every entry committed before
+// the read began. fn is called with no concurrent Apply, so the result is
+// linearizable. Returns ErrNotLeader if leadership cannot be confirmed.
+func (r *Raft) Read(ctx context.Context, fn func() (any, error)) (any, error) {
+	r.mu.Lock()
+	if r.role != Leader {
+		r.mu.Unlock()
+		return nil, ErrNotLeader
+	}
+	term := r.term
+	r.mu.Unlock()
+
+	deposed := func() bool { return r.role != Leader || r.term != term }
+
+	// A new leader only knows the commit index once an entry from its own
+	// term has committed.
+	if err := r.wait(ctx, func() bool {
+		return deposed() || r.log[r.commitIndex].Term == term
+	}); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if deposed() {
+		r.mu.Unlock()
+		return nil, ErrNotLeader
+	}
+	readIndex := r.commitIndex
+	r.broadcast()
+	seq := r.seq
+	r.mu.Unlock()
+
+	// Wait for a quorum to acknowledge a round sent after the read began.
+	if err := r.wait(ctx, func() bool {
+		if deposed() {
+			return true
+		}
+		acks := 1
+		for _, s := range r.acked {
+			if s >= seq {
+				acks++
+			}
+		}
+		return acks >= r.quorum() && r.lastApplied >= readIndex
+	}); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if deposed() {
+		return nil, ErrNotLeader
+	}
+	return fn()
+}
+
+// tick fires elections & heartbeats when their timers have expired.
+func (r *Raft) tick() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.node.NodeIDs()) == 0 {
+		return // not initialized yet
+	}
+
+	now := r.node.Clock().Now()
+	switch {
+	case r.deadline.IsZero():
+		r.resetDeadline()
+	case r.role == Leader:
+		if now.Sub(r.lastBroadcast) >= r.HeartbeatInterval {
+			r.broadcast()
+		}
+	case now.After(r.deadline):
+		r.becomeCandidate()
+	}
+}
+
+// apply applies committed entries to the state machine and resolves local
+// proposals. Must hold lock.
+func (r *Raft) apply() {
+	for r.lastApplied < r.commitIndex {
+		r.lastApplied++
+		e := r.log[r.lastApplied]
+
+		var result any
+		var err error
+		if e.Op != nil {
+			result, err = r.sm.Apply(e.Op)
+		}
+
+		if p := r.pending[r.lastApplied]; p != nil {
+			if p.term == e.Term {
+				p.result, p.err = result, err
+			} else {
+				p.err = ErrLeadershipLost
+			}
+			p.done = true
+			delete(r.pending, r.lastApplied)
+		}
+	}
+	r.notify()
+}
+
+// wait blocks until cond returns true or ctx is done. cond is evaluated with
+// the lock held each time the state changes.
+func (r *Raft) wait(ctx context.Context, cond func() bool) error {
+	for {
+		r.mu.Lock()
+		if cond() {
+			r.mu.Unlock()
+			return nil
+		}
+		ch := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// notify wakes up waiters. Must hold lock.
+func (r *Raft) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// resetDeadline schedules the next election at a random point between one &
+// two election timeouts from now. Must hold lock.
+func (r *Raft) resetDeadline() {
+	d := r.ElectionTimeout + time.Duration(r.node.Rand().Int63n(int64(r.ElectionTimeout)))
+	r.deadline = r.node.Clock().Now().Add(d)
+}
+
+// lastIndex returns the index of the last log entry. Must hold lock.
+func (r *Raft) lastIndex() int {
+	return len(r.log) - 1
+}
+
+// peers returns every other node in the cluster.
+func (r *Raft) peers() []string {
+	var peers []string
+	for _, id := range r.node.NodeIDs() {
+		if id != r.node.ID() {
+			peers = append(peers, id)
+		}
+	}
+	return peers
+}
+
+// send sends body to peer in the background and passes the reply to fn. The
+// request is abandoned after an election timeout, by when a newer one has
+// superseded it, so unreachable peers don't accumulate pending callbacks.
+func (r *Raft) send(peer string, body any, fn func(msg maelstrom.Message) error) {
+	timeout := r.ElectionTimeout
+	r.node.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		msg, err := r.node.SyncRPC(ctx, peer, body)
+		if err != nil && maelstrom.ErrorCode(err) < 0 {
+			return // no reply
+		}
+		if err := fn(msg); err != nil {
+			log.Printf("raft: reply from %s: %s", peer, err)
+		}
+	})
+}
+
+// quorum returns the number of nodes that constitutes a majority.
+func (r *Raft) quorum() int {
+	return len(r.node.NodeIDs())/2 + 1
+}
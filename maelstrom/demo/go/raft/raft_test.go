@@ -0,0 +1,243 @@
+package raft_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/raft"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// Ensure proposals on the leader are replicated to every node in order and
+// that followers reject client operations.
+func TestRaft_Replication(t *testing.T) {
+	c := newCluster(t, 3)
+	defer c.close()
+
+	leader := c.waitLeader(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 1; i <= 10; i++ {
+		if v, err := c.rafts[leader].Propose(ctx, i); err != nil {
+			t.Fatal(err)
+		} else if v != i {
+			t.Fatalf("result=%v, want %d", v, i)
+		}
+	}
+
+	if v, err := c.rafts[leader].Read(ctx, c.sms[leader].last); err != nil {
+		t.Fatal(err)
+	} else if v != 10 {
+		t.Fatalf("read=%v, want 10", v)
+	}
+
+	for id, r := range c.rafts {
+		if id == leader {
+			continue
+		}
+		if _, err := r.Propose(ctx, 0); !errors.Is(err, raft.ErrNotLeader) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if _, err := r.Read(ctx, c.sms[id].last); !errors.Is(err, raft.ErrNotLeader) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if s := r.Status(); s.Leader != leader {
+			t.Fatalf("%s sees leader %q, want %q", id, s.Leader, leader)
+		}
+	}
+
+	c.waitConverged(t, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+}
+
+// Ensure an isolated leader can neither commit writes nor serve reads, that
+// the majority elects a new leader, and that the old leader's uncommitted
+// entries are discarded once the partition heals.
+func TestRaft_Partition(t *testing.T) {
+	c := newCluster(t, 5)
+	defer c.close()
+
+	old := c.waitLeader(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.rafts[old].Propose(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	oldTerm := c.rafts[old].Status().Term
+
+	c.nw.Isolate(old)
+
+	shortCtx, shortCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer shortCancel()
+	if _, err := c.rafts[old].Propose(shortCtx, 2); err == nil {
+		t.Fatal("expected isolated leader to fail to commit")
+	}
+	if _, err := c.rafts[old].Read(shortCtx, c.sms[old].last); err == nil {
+		t.Fatal("expected isolated leader to fail to read")
+	}
+
+	next := c.waitLeader(t, old)
+	if term := c.rafts[next].Status().Term; term <= oldTerm {
+		t.Fatalf("term=%d, want > %d", term, oldTerm)
+	}
+	if _, err := c.rafts[next].Propose(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	c.nw.Heal()
+	c.waitConverged(t, 1, 3)
+	if s := c.rafts[old].Status(); s.Role != raft.Follower || s.Leader != next {
+		t.Fatalf("old leader status: %+v", s)
+	}
+}
+
+// Ensure a single-node cluster commits on its own.
+func TestRaft_SingleNode(t *testing.T) {
+	c := newCluster(t, 1)
+	defer c.close()
+
+	leader := c.waitLeader(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.rafts[leader].Propose(ctx, 1); err != nil {
+		t.Fatal(err)
+	} else if v, err := c.rafts[leader].Read(ctx, c.sms[leader].last); err != nil {
+		t.Fatal(err)
+	} else if v != 1 {
+		t.Fatalf("read=%v, want 1", v)
+	}
+}
+
+// seqLog is a state machine which appends every integer it applies.
+type seqLog struct {
+	mu     sync.Mutex
+	values []int
+}
+
+func (l *seqLog) Apply(op json.RawMessage) (any, error) {
+	var v int
+	if err := json.Unmarshal(op, &v); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values = append(l.values, v)
+	return v, nil
+}
+
+func (l *seqLog) last() (any, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.values) == 0 {
+		return nil, nil
+	}
+	return l.values[len(l.values)-1], nil
+}
+
+func (l *seqLog) snapshot() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]int{}, l.values...)
+}
+
+type cluster struct {
+	nw     *simnet.Network
+	rafts  map[string]*raft.Raft
+	sms    map[string]*seqLog
+	cancel context.CancelFunc
+}
+
+// newCluster starts n Raft nodes with short timeouts.
+func newCluster(tb testing.TB, n int) *cluster {
+	tb.Helper()
+
+	c := &cluster{
+		nw:    simnet.New(),
+		rafts: make(map[string]*raft.Raft),
+		sms:   make(map[string]*seqLog),
+	}
+	for id, node := range c.nw.NewNodes(n) {
+		c.sms[id] = &seqLog{}
+		r := raft.New(node, c.sms[id])
+		r.ElectionTimeout = 150 * time.Millisecond
+		r.HeartbeatInterval = 30 * time.Millisecond
+		r.TickInterval = 5 * time.Millisecond
+		c.rafts[id] = r
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.nw.Start(ctx); err != nil {
+		tb.Fatal(err)
+	}
+
+	var runCtx context.Context
+	runCtx, c.cancel = context.WithCancel(context.Background())
+	for _, r := range c.rafts {
+		r.Start(runCtx)
+	}
+	return c
+}
+
+func (c *cluster) close() {
+	c.cancel()
+	c.nw.Close()
+}
+
+// waitLeader waits until a node other than exclude is leader and verifies
+// that there is never more than one leader in a term.
+func (c *cluster) waitLeader(tb testing.TB, exclude string) string {
+	tb.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		leaders := make(map[int]string)
+		var found string
+		for id, r := range c.rafts {
+			s := r.Status()
+			if s.Role != raft.Leader {
+				continue
+			} else if other, ok := leaders[s.Term]; ok {
+				tb.Fatalf("multiple leaders in term %d: %s, %s", s.Term, other, id)
+			}
+			leaders[s.Term] = id
+			if id != exclude {
+				found = id
+			}
+		}
+		if found != "" {
+			return found
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	tb.Fatal("timeout waiting for leader")
+	return ""
+}
+
+// waitConverged waits until every state machine has applied exactly want.
+func (c *cluster) waitConverged(tb testing.TB, want ...int) {
+	tb.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		converged := true
+		for _, sm := range c.sms {
+			if !reflect.DeepEqual(sm.snapshot(), want) {
+				converged = false
+			}
+		}
+		if converged {
+			return
+		} else if time.Now().After(deadline) {
+			for id, sm := range c.sms {
+				tb.Logf("%s: %v", id, sm.snapshot())
+			}
+			tb.Fatal("timeout waiting for state machines to converge")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,198 @@
+package raft
+
+import (
+	"encoding/json"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+type appendEntriesBody struct {
+	Type         string  `json:"type"`
+	Term         int     `json:"term"`
+	LeaderID     string  `json:"leader_id"`
+	PrevLogIndex int     `json:"prev_log_index"`
+	PrevLogTerm  int     `json:"prev_log_term"`
+	Entries      []Entry `json:"entries"`
+	LeaderCommit int     `json:"leader_commit"`
+}
+
+type appendEntriesOKBody struct {
+	Type    string `json:"type"`
+	Term    int    `json:"term"`
+	Success bool   `json:"success"`
+
+	// On failure, the index the leader should retry from.
+	ConflictIndex int `json:"conflict_index,omitempty"`
+}
+
+// broadcast starts a new round of appends to every peer. Peers that are up
+// to date receive an empty append, which acts as a heartbeat. Must hold lock.
+func (r *Raft) broadcast() {
+	r.seq++
+	r.lastBroadcast = r.node.Clock().Now()
+	for _, peer := range r.peers() {
+		r.sendAppend(peer)
+	}
+}
+
+// sendAppend sends the next batch of entries to peer. Must hold lock.
+func (r *Raft) sendAppend(peer string) {
+	next := r.nextIndex[peer]
+	if next < 1 {
+		next = 1
+	}
+	end := next + r.MaxBatch
+	if end > len(r.log) {
+		end = len(r.log)
+	}
+
+	body := appendEntriesBody{
+		Type:         "append_entries",
+		Term:         r.term,
+		LeaderID:     r.node.ID(),
+		PrevLogIndex: next - 1,
+		PrevLogTerm:  r.log[next-1].Term,
+		Entries:      append([]Entry{}, r.log[next:end]...),
+		LeaderCommit: r.commitIndex,
+	}
+	term, seq := r.term, r.seq
+	r.send(peer, body, func(msg maelstrom.Message) error {
+		return r.handleAppendEntriesOK(term, seq, body, msg)
+	})
+}
+
+// advanceCommit commits the highest entry from the current term that is
+// stored on a majority of nodes. Must hold lock.
+func (r *Raft) advanceCommit() {
+	for i := r.lastIndex(); i > r.commitIndex; i-- {
+		if r.log[i].Term != r.term {
+			break // earlier terms are committed indirectly
+		}
+
+		n := 1
+		for _, match := range r.matchIndex {
+			if match >= i {
+				n++
+			}
+		}
+		if n >= r.quorum() {
+			r.commitIndex = i
+			r.apply()
+			return
+		}
+	}
+}
+
+func (r *Raft) handleAppendEntries(msg maelstrom.Message) error {
+	var body appendEntriesBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	reply := r.appendEntries(body)
+	r.mu.Unlock()
+
+	return r.node.Reply(msg, reply)
+}
+
+// appendEntries applies an append from the leader to the local log. Must
+// hold lock.
+func (r *Raft) appendEntries(body appendEntriesBody) appendEntriesOKBody {
+	reply := appendEntriesOKBody{Type: "append_entries_ok"}
+	if body.Term < r.term {
+		reply.Term = r.term
+		return reply
+	}
+
+	if body.Term > r.term || r.role != Follower {
+		r.stepDown(body.Term)
+	}
+	if r.leader != body.LeaderID {
+		r.leader = body.LeaderID
+		r.notify()
+	}
+	r.resetDeadline()
+	reply.Term = r.term
+
+	// Find where our log diverges from the leader's.
+	if body.PrevLogIndex > r.lastIndex() {
+		reply.ConflictIndex = r.lastIndex() + 1
+		return reply
+	} else if t := r.log[body.PrevLogIndex].Term; t != body.PrevLogTerm {
+		i := body.PrevLogIndex
+		for i > 1 && r.log[i-1].Term == t {
+			i--
+		}
+		reply.ConflictIndex = i
+		return reply
+	}
+
+	// Truncate conflicting entries and append new ones. Entries that are
+	// already present are left alone since the append may be stale.
+	for i, e := range body.Entries {
+		index := body.PrevLogIndex + 1 + i
+		if index <= r.lastIndex() {
+			if r.log[index].Term == e.Term {
+				continue
+			}
+			r.log = r.log[:index]
+		}
+		r.log = append(r.log, body.Entries[i:]...)
+		break
+	}
+
+	if commit := body.PrevLogIndex + len(body.Entries); body.LeaderCommit > r.commitIndex && commit > r.commitIndex {
+		if body.LeaderCommit < commit {
+			commit = body.LeaderCommit
+		}
+		r.commitIndex = commit
+		r.apply()
+	}
+
+	reply.Success = true
+	return reply
+}
+
+func (r *Raft) handleAppendEntriesOK(term, seq int, req appendEntriesBody, msg maelstrom.Message) error {
+	var body appendEntriesOKBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if body.Term > r.term {
+		r.stepDown(body.Term)
+		return nil
+	} else if r.role != Leader || r.term != term {
+		return nil
+	}
+
+	peer := msg.Src
+	if seq > r.acked[peer] {
+		r.acked[peer] = seq
+		r.notify()
+	}
+
+	if !body.Success {
+		if body.ConflictIndex > 0 && body.ConflictIndex < r.nextIndex[peer] {
+			r.nextIndex[peer] = body.ConflictIndex
+			r.sendAppend(peer)
+		}
+		return nil
+	}
+
+	if match := req.PrevLogIndex + len(req.Entries); match > r.matchIndex[peer] {
+		r.matchIndex[peer] = match
+		r.nextIndex[peer] = match + 1
+		r.advanceCommit()
+	}
+
+	// Keep streaming if the peer is still behind.
+	if r.nextIndex[peer] <= r.lastIndex() && len(req.Entries) > 0 {
+		r.sendAppend(peer)
+	}
+	return nil
+}
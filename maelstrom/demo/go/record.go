@@ -0,0 +1,125 @@
+package maelstrom
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Environment variables used to enable recording & replay without changing
+// the node binary.
+const (
+	// RecordDirEnv names a directory. When set, each node writes its
+	// recording to "<dir>/<node_id>.jsonl" once it has been initialized.
+	RecordDirEnv = "MAELSTROM_RECORD_DIR"
+
+	// ReplayEnv names a recording file. When set, Run() replays the file
+	// instead of reading from STDIN.
+	ReplayEnv = "MAELSTROM_REPLAY"
+)
+
+// Directions of a recorded message.
+const (
+	RecordIn  = "in"
+	RecordOut = "out"
+)
+
+// RecordEntry represents a single message in a node recording.
+type RecordEntry struct {
+	Time time.Time       `json:"time"`
+	Dir  string          `json:"dir"`
+	Msg  json.RawMessage `json:"msg"`
+}
+
+// Type returns the "type" field from the recorded message body.
+// Returns blank string if the message is malformed.
+func (e *RecordEntry) Type() string {
+	var msg Message
+	if err := json.Unmarshal(e.Msg, &msg); err != nil {
+		return ""
+	}
+	return msg.Type()
+}
+
+// record writes line to the node's recording, if enabled. Errors are logged
+// rather than returned so that recording never interrupts the node.
+func (n *Node) record(dir string, line []byte) {
+	n.recMu.Lock()
+	defer n.recMu.Unlock()
+
+	if n.Record == nil {
+		return
+	}
+
+	buf, err := json.Marshal(RecordEntry{
+		Time: n.clock.Now(),
+		Dir:  dir,
+		Msg:  json.RawMessage(line),
+	})
+	if err != nil {
+		log.Printf("record error: %s", err)
+		return
+	}
+	if _, err := n.Record.Write(append(buf, '\n')); err != nil {
+		log.Printf("record error: %s", err)
+	}
+}
+
+// openRecordDir opens a recording file for the node if RecordDirEnv is set
+// and no recording has been configured yet. It is called with the "init"
+// message so the file can be named after the node.
+func (n *Node) openRecordDir(msg Message) error {
+	dir := os.Getenv(RecordDirEnv)
+	if dir == "" {
+		return nil
+	}
+
+	n.recMu.Lock()
+	defer n.recMu.Unlock()
+	if n.Record != nil {
+		return nil
+	}
+
+	var body InitMessageBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return fmt.Errorf("unmarshal init message body: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, body.NodeID+".jsonl"))
+	if err != nil {
+		return err
+	}
+	n.Record = f
+	return nil
+}
+
+// ReadRecording reads all entries from a recording written by a node.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry RecordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("unmarshal record entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
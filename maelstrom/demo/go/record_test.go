@@ -0,0 +1,57 @@
+package maelstrom_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Ensure a node records inbound & outbound messages.
+func TestNode_Record(t *testing.T) {
+	n, stdin, stdout := newNode(t)
+
+	var rec bytes.Buffer
+	n.Record = &rec
+	n.Handle("echo", func(msg maelstrom.Message) error {
+		var body map[string]any
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		body["type"] = "echo_ok"
+		return n.Reply(msg, body)
+	})
+	initNode(t, n, "n1", []string{"n1"}, stdin, stdout)
+
+	if _, err := stdin.Write([]byte(`{"src":"c1","dest":"n1","body":{"type":"echo","msg_id":2}}` + "\n")); err != nil {
+		t.Fatal(err)
+	} else if _, err := stdout.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := maelstrom.ReadRecording(bytes.NewReader(rec.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(entries), 4; got != want {
+		t.Fatalf("len=%d, want %d", got, want)
+	}
+
+	for i, want := range []struct {
+		dir string
+		msg string
+	}{
+		{maelstrom.RecordIn, `{"body":{"type":"init","msg_id":1,"node_id":"n1","node_ids":["n1"]}}`},
+		{maelstrom.RecordOut, `{"src":"n1","body":{"in_reply_to":1,"type":"init_ok"}}`},
+		{maelstrom.RecordIn, `{"src":"c1","dest":"n1","body":{"type":"echo","msg_id":2}}`},
+		{maelstrom.RecordOut, `{"src":"n1","dest":"c1","body":{"in_reply_to":2,"msg_id":2,"type":"echo_ok"}}`},
+	} {
+		if got := entries[i].Dir; got != want.dir {
+			t.Fatalf("entries[%d].Dir=%s, want %s", i, got, want.dir)
+		} else if got := string(entries[i].Msg); got != want.msg {
+			t.Fatalf("entries[%d].Msg=%s, want %s", i, got, want.msg)
+		} else if entries[i].Time.IsZero() {
+			t.Fatalf("entries[%d].Time not set", i)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package maelstrom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReplayIdleTimeout is the default time a replayed node must be quiet
+// after its last input before its STDIN is closed.
+const DefaultReplayIdleTimeout = 1 * time.Second
+
+// ErrReplayNotStopped is returned by Replay.Run when handlers are still
+// running after STDIN has been closed, typically because they are waiting on
+// an RPC reply that does not exist in the recording.
+var ErrReplayNotStopped = errors.New("replay: node did not stop after input was closed")
+
+// Replay feeds a recording back into a fresh node so that a run can be
+// reproduced locally, e.g. under a debugger.
+//
+// Messages the node originally received are delivered in recorded order.
+// Replies to the node's own RPCs (such as those from the KV services) cannot
+// be delivered as-is because message IDs differ between runs. Instead, each
+// outbound request is matched against the recorded request with the same
+// destination & body and the recorded reply is injected with its
+// "in_reply_to" rewritten.
+type Replay struct {
+	entries []RecordEntry
+
+	// If true, inbound messages are delivered with their original spacing.
+	Realtime bool
+
+	// Time the node must be quiet after the last input before STDIN is
+	// closed. Defaults to DefaultReplayIdleTimeout.
+	IdleTimeout time.Duration
+
+	// Output, if set, receives every line written by the node.
+	Output io.Writer
+}
+
+// NewReplay returns a new instance of Replay for a set of recorded entries.
+func NewReplay(entries []RecordEntry) *Replay {
+	return &Replay{
+		entries:     entries,
+		IdleTimeout: DefaultReplayIdleTimeout,
+	}
+}
+
+// replayFile runs the node against the recording at path. Node output is
+// written to the node's original STDOUT.
+func (n *Node) replayFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := ReadRecording(f)
+	if err != nil {
+		return err
+	}
+
+	r := NewReplay(entries)
+	r.Output = n.Stdout
+	return r.Run(n)
+}
+
+// Run attaches the replay to the node's STDIN & STDOUT and executes the
+// node's event loop until the recording is exhausted.
+func (r *Replay) Run(n *Node) error {
+	feed, replies, err := r.plan()
+	if err != nil {
+		return err
+	}
+
+	inr, inw := io.Pipe()
+	w := &replayWriter{
+		output:   r.Output,
+		replies:  replies,
+		notify:   make(chan struct{}, 1),
+		initOK:   make(chan struct{}),
+		activity: time.Now(),
+	}
+	n.Stdin, n.Stdout = inr, w
+
+	runErr := make(chan error, 1)
+	go func() {
+		err := n.run()
+		inr.CloseWithError(io.ErrClosedPipe)
+		runErr <- err
+	}()
+
+	feedDone := make(chan struct{})
+	go func() {
+		defer close(feedDone)
+		r.feed(inw, w, feed)
+		inw.Close()
+	}()
+
+	select {
+	case err := <-runErr:
+		return err
+	case <-feedDone:
+	}
+
+	select {
+	case err := <-runErr:
+		return err
+	case <-time.After(r.idleTimeout()):
+		return ErrReplayNotStopped
+	}
+}
+
+// feed writes recorded inbound messages & injected replies to the node until
+// the recording is exhausted and the node has been idle.
+func (r *Replay) feed(inw io.Writer, w *replayWriter, feed []RecordEntry) {
+	var start time.Time
+	for i := 0; ; {
+		// Injected replies always take priority over new requests.
+		if line := w.nextInjection(); line != nil {
+			if !r.write(inw, w, line) {
+				return
+			}
+			continue
+		}
+
+		if i < len(feed) {
+			if r.Realtime {
+				if i == 0 {
+					start = time.Now()
+				} else if d := feed[i].Time.Sub(feed[0].Time) - time.Since(start); d > 0 {
+					time.Sleep(d)
+				}
+			}
+			if !r.write(inw, w, feed[i].Msg) {
+				return
+			}
+
+			// Maelstrom waits for "init_ok" before sending anything else.
+			if feed[i].Type() == "init" {
+				select {
+				case <-w.initOK:
+				case <-time.After(r.idleTimeout()):
+				}
+			}
+			i++
+			continue
+		}
+
+		// Wait until the node produces output or stays quiet long enough.
+		idle := r.idleTimeout() - time.Since(w.lastActivity())
+		if idle <= 0 {
+			return
+		}
+		select {
+		case <-w.notify:
+		case <-time.After(idle):
+		}
+	}
+}
+
+func (r *Replay) write(inw io.Writer, w *replayWriter, line []byte) bool {
+	w.touch()
+	if _, err := inw.Write(append(append([]byte{}, line...), '\n')); err != nil {
+		return false
+	}
+	return true
+}
+
+func (r *Replay) idleTimeout() time.Duration {
+	if r.IdleTimeout <= 0 {
+		return DefaultReplayIdleTimeout
+	}
+	return r.IdleTimeout
+}
+
+// plan splits the recording into messages to feed in order and recorded
+// replies indexed by the request they answer.
+func (r *Replay) plan() (feed []RecordEntry, replies map[string][]Message, err error) {
+	requests := make(map[int]string)
+	replies = make(map[string][]Message)
+
+	for _, entry := range r.entries {
+		var msg Message
+		if err := json.Unmarshal(entry.Msg, &msg); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal recorded message: %w", err)
+		}
+		var body MessageBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal recorded message body: %w", err)
+		}
+
+		switch {
+		case entry.Dir == RecordOut:
+			if body.MsgID != 0 && body.InReplyTo == 0 {
+				key, err := replayKey(msg)
+				if err != nil {
+					return nil, nil, err
+				}
+				requests[body.MsgID] = key
+			}
+
+		case body.InReplyTo != 0:
+			if key, ok := requests[body.InReplyTo]; ok {
+				replies[key] = append(replies[key], msg)
+			}
+
+		default:
+			feed = append(feed, entry)
+		}
+	}
+	return feed, replies, nil
+}
+
+// replayKey returns a key identifying a request by its destination & body,
+// ignoring its message ID and HLC timestamp, which differ between runs.
+func replayKey(msg Message) (string, error) {
+	var body map[string]any
+	if err := unmarshalNumbers(msg.Body, &body); err != nil {
+		return "", err
+	}
+	delete(body, "msg_id")
+	delete(body, "hlc")
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return msg.Dest + " " + string(buf), nil
+}
+
+// replayWriter acts as the STDOUT of a replayed node. It matches outbound
+// requests against recorded replies and queues them for injection.
+type replayWriter struct {
+	mu       sync.Mutex
+	buf      []byte
+	output   io.Writer
+	replies  map[string][]Message
+	pending  [][]byte
+	notify   chan struct{}
+	initOK   chan struct{}
+	activity time.Time
+}
+
+func (w *replayWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.activity = time.Now()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i]
+		w.buf = w.buf[i+1:]
+
+		if w.output != nil {
+			if _, err := w.output.Write(append(append([]byte{}, line...), '\n')); err != nil {
+				return 0, err
+			}
+		}
+		w.match(line)
+	}
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// match queues the recorded reply for line, if line is a request that was
+// answered in the recording.
+func (w *replayWriter) match(line []byte) {
+	var msg Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		log.Printf("replay: unmarshal output: %s", err)
+		return
+	}
+	var body MessageBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return
+	} else if body.Type == "init_ok" {
+		select {
+		case <-w.initOK:
+		default:
+			close(w.initOK)
+		}
+		return
+	} else if body.MsgID == 0 || body.InReplyTo != 0 {
+		return
+	}
+
+	key, err := replayKey(msg)
+	if err != nil {
+		return
+	}
+	queue := w.replies[key]
+	if len(queue) == 0 {
+		log.Printf("replay: no recorded reply for %s", line)
+		return
+	}
+	reply := queue[0]
+	w.replies[key] = queue[1:]
+
+	// Rewrite the reply so it answers the new message ID.
+	var replyBody map[string]any
+	if err := unmarshalNumbers(reply.Body, &replyBody); err != nil {
+		return
+	}
+	replyBody["in_reply_to"] = body.MsgID
+	if reply.Body, err = json.Marshal(replyBody); err != nil {
+		return
+	}
+
+	buf, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	w.pending = append(w.pending, buf)
+}
+
+func (w *replayWriter) nextInjection() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return nil
+	}
+	line := w.pending[0]
+	w.pending = w.pending[1:]
+	return line
+}
+
+func (w *replayWriter) touch() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.activity = time.Now()
+}
+
+func (w *replayWriter) lastActivity() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.activity
+}
@@ -0,0 +1,130 @@
+package maelstrom_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Ensure a replayed node receives recorded requests & faked KV replies.
+func TestReplay_Run(t *testing.T) {
+	entries, err := maelstrom.ReadRecording(strings.NewReader(strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","dir":"in","msg":{"src":"c0","dest":"n1","body":{"type":"init","msg_id":1,"node_id":"n1","node_ids":["n1"]}}}`,
+		`{"time":"2024-01-01T00:00:00Z","dir":"out","msg":{"src":"n1","dest":"c0","body":{"in_reply_to":1,"type":"init_ok"}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"in","msg":{"src":"c1","dest":"n1","body":{"type":"get","msg_id":1,"key":"x"}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"out","msg":{"src":"n1","dest":"lin-kv","body":{"key":"x","msg_id":17,"type":"read"}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"in","msg":{"src":"lin-kv","dest":"n1","body":{"type":"read_ok","value":42,"in_reply_to":17}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"out","msg":{"src":"n1","dest":"c1","body":{"in_reply_to":1,"type":"get_ok","value":42}}}`,
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := maelstrom.NewNode()
+	kv := maelstrom.NewLinKV(n)
+	n.Handle("get", func(msg maelstrom.Message) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		v, err := kv.ReadInt(ctx, "x")
+		if err != nil {
+			return err
+		}
+		return n.Reply(msg, map[string]any{"type": "get_ok", "value": v})
+	})
+
+	var output bytes.Buffer
+	r := maelstrom.NewReplay(entries)
+	r.IdleTimeout = 100 * time.Millisecond
+	r.Output = &output
+	if err := r.Run(n); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if got, want := len(lines), 3; got != want {
+		t.Fatalf("len=%d, want %d: %q", got, want, lines)
+	} else if got, want := lines[1], `{"src":"n1","dest":"lin-kv","body":{"key":"x","msg_id":1,"type":"read"}}`; got != want {
+		t.Fatalf("lines[1]=%s, want %s", got, want)
+	} else if got, want := lines[2], `{"src":"n1","dest":"c1","body":{"in_reply_to":1,"type":"get_ok","value":42}}`; got != want {
+		t.Fatalf("lines[2]=%s, want %s", got, want)
+	}
+}
+
+// Ensure recorded replies to peer RPCs are matched although the HLC timestamp
+// stamped on each request differs from the recording, and that large integers
+// in the replies survive.
+func TestReplay_Run_HLC(t *testing.T) {
+	entries, err := maelstrom.ReadRecording(strings.NewReader(strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","dir":"in","msg":{"src":"c0","dest":"n1","body":{"type":"init","msg_id":1,"node_id":"n1","node_ids":["n1","n2"]}}}`,
+		`{"time":"2024-01-01T00:00:00Z","dir":"out","msg":{"src":"n1","dest":"c0","body":{"in_reply_to":1,"type":"init_ok"}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"in","msg":{"src":"c1","dest":"n1","body":{"type":"get","msg_id":1}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"out","msg":{"src":"n1","dest":"n2","body":{"hlc":[1704067201000000000,0],"msg_id":3,"type":"peek"}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"in","msg":{"src":"n2","dest":"n1","body":{"hlc":[1704067201000000000,1],"type":"peek_ok","value":18446744073709551615,"in_reply_to":3}}}`,
+		`{"time":"2024-01-01T00:00:01Z","dir":"out","msg":{"src":"n1","dest":"c1","body":{"in_reply_to":1,"type":"get_ok","value":18446744073709551615}}}`,
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := maelstrom.NewNode()
+	n.EnableHLC()
+	n.Handle("get", func(msg maelstrom.Message) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		resp, err := n.SyncRPC(ctx, "n2", map[string]any{"type": "peek"})
+		if err != nil {
+			return err
+		}
+		var body struct{ Value uint64 }
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			return err
+		}
+		return n.Reply(msg, map[string]any{"type": "get_ok", "value": body.Value})
+	})
+
+	var output bytes.Buffer
+	r := maelstrom.NewReplay(entries)
+	r.IdleTimeout = 100 * time.Millisecond
+	r.Output = &output
+	if err := r.Run(n); err != nil {
+		t.Fatal(err)
+	} else if got, want := output.String(), `"type":"get_ok","value":18446744073709551615}}`+"\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("output=%s, want suffix %s", got, want)
+	}
+}
+
+// Ensure replay reports handlers blocked on a reply missing from the recording.
+func TestReplay_Run_ErrNotStopped(t *testing.T) {
+	entries, err := maelstrom.ReadRecording(strings.NewReader(
+		`{"time":"2024-01-01T00:00:00Z","dir":"in","msg":{"src":"c1","dest":"n1","body":{"type":"get","msg_id":1}}}`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := maelstrom.NewNode()
+	release := make(chan struct{})
+	defer close(release)
+	n.Handle("get", func(msg maelstrom.Message) error {
+		<-release
+		return nil
+	})
+
+	r := maelstrom.NewReplay(entries)
+	r.IdleTimeout = 50 * time.Millisecond
+	if err := r.Run(n); err != maelstrom.ErrReplayNotStopped {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
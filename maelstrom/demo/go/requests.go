@@ -0,0 +1,74 @@
+package maelstrom
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Requests allocates message IDs for requests sent by a client process
+// rather than a Node, and routes each reply to the request it answers. It is
+// shared by the clients of the client, simnet & sim packages, which differ
+// only in how they send messages and wait for replies. The zero value is
+// ready to use.
+type Requests struct {
+	mu        sync.Mutex
+	nextMsgID int
+	pending   map[int]func(Message)
+}
+
+// New encodes body as a request from src to dest under a new message ID and
+// registers fn to receive its reply. Large integers in body are kept intact.
+// Returns the request and its message ID, which the caller must pass to
+// Cancel once it stops waiting.
+func (r *Requests) New(src, dest string, body any, fn func(Message)) (Message, int, error) {
+	b := make(map[string]any)
+	if buf, err := json.Marshal(body); err != nil {
+		return Message{}, 0, err
+	} else if err := unmarshalNumbers(buf, &b); err != nil {
+		return Message{}, 0, err
+	}
+
+	r.mu.Lock()
+	r.nextMsgID++
+	msgID := r.nextMsgID
+	if r.pending == nil {
+		r.pending = make(map[int]func(Message))
+	}
+	r.pending[msgID] = fn
+	r.mu.Unlock()
+
+	b["msg_id"] = msgID
+	buf, err := json.Marshal(b)
+	if err != nil {
+		r.Cancel(msgID)
+		return Message{}, 0, err
+	}
+	return Message{Src: src, Dest: dest, Body: buf}, msgID, nil
+}
+
+// Cancel stops waiting for the reply to msgID.
+func (r *Requests) Cancel(msgID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, msgID)
+}
+
+// Deliver passes a reply to its request's function, at most once. Returns
+// false if msg does not answer a pending request.
+func (r *Requests) Deliver(msg Message) bool {
+	var body MessageBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	fn := r.pending[body.InReplyTo]
+	delete(r.pending, body.InReplyTo)
+	r.mu.Unlock()
+
+	if fn == nil {
+		return false
+	}
+	fn(msg)
+	return true
+}
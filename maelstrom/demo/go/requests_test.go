@@ -0,0 +1,48 @@
+package maelstrom_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Ensure requests get fresh message IDs, keep large integers intact and
+// receive their reply exactly once.
+func TestRequests(t *testing.T) {
+	var r maelstrom.Requests
+	var replies []maelstrom.Message
+	req, msgID, err := r.New("c1", "n1", map[string]any{"type": "read", "key": uint64(1<<64 - 1)}, func(msg maelstrom.Message) {
+		replies = append(replies, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := string(req.Body), `{"key":18446744073709551615,"msg_id":1,"type":"read"}`; got != want {
+		t.Fatalf("body=%s, want %s", got, want)
+	} else if req.Src != "c1" || req.Dest != "n1" || msgID != 1 {
+		t.Fatalf("unexpected request: %+v (msg_id %d)", req, msgID)
+	}
+
+	reply := maelstrom.Message{Src: "n1", Dest: "c1", Body: json.RawMessage(`{"type":"read_ok","in_reply_to":1}`)}
+	if !r.Deliver(reply) {
+		t.Fatal("expected reply to be delivered")
+	} else if r.Deliver(reply) {
+		t.Fatal("expected duplicate reply to be dropped")
+	} else if len(replies) != 1 {
+		t.Fatalf("replies=%d, want 1", len(replies))
+	}
+
+	// Replies to canceled requests are dropped.
+	_, msgID, err = r.New("c1", "n1", map[string]any{"type": "read"}, func(msg maelstrom.Message) {
+		t.Fatal("unexpected reply")
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if msgID != 2 {
+		t.Fatalf("msg_id=%d, want 2", msgID)
+	}
+	r.Cancel(msgID)
+	if r.Deliver(maelstrom.Message{Body: json.RawMessage(`{"type":"read_ok","in_reply_to":2}`)}) {
+		t.Fatal("expected reply to canceled request to be dropped")
+	}
+}
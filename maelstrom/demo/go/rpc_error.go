@@ -57,6 +57,18 @@ func ErrorCode(err error) int {
 	}
 }
 
+// IsDefinite returns true if an error code means the operation certainly did
+// not take place. Timeouts, crashes & unknown codes are indefinite.
+func IsDefinite(code int) bool {
+	switch code {
+	case NotSupported, TemporarilyUnavailable, MalformedRequest, Abort,
+		KeyDoesNotExist, KeyAlreadyExists, PreconditionFailed, TxnConflict:
+		return true
+	default:
+		return false
+	}
+}
+
 // RPCError represents a Maelstrom RPC error.
 type RPCError struct {
 	Code int
@@ -88,6 +100,6 @@ func (e *RPCError) MarshalJSON() ([]byte, error) {
 // rpcErrorJSON is a struct for marshaling an RPCError to JSON.
 type rpcErrorJSON struct {
 	Type string `json:"type,omitempty"`
-	Code int    `json:"code,omitempty"`
+	Code int    `json:"code"` // zero is a valid code (Timeout)
 	Text string `json:"text,omitempty"`
 }
@@ -34,3 +34,18 @@ func TestRPCError_Error(t *testing.T) {
 		t.Fatalf("error=%s, want %s", got, want)
 	}
 }
+
+func TestIsDefinite(t *testing.T) {
+	for code, want := range map[int]bool{
+		maelstrom.Timeout:                false,
+		maelstrom.Crash:                  false,
+		maelstrom.TemporarilyUnavailable: true,
+		maelstrom.PreconditionFailed:     true,
+		maelstrom.TxnConflict:            true,
+		1000:                             false,
+	} {
+		if got := maelstrom.IsDefinite(code); got != want {
+			t.Errorf("IsDefinite(%d)=%v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package sim
+
+import (
+	"context"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Client sends requests into the network, like a Maelstrom client process.
+type Client struct {
+	id   string
+	nw   *Network
+	reqs maelstrom.Requests
+}
+
+// ID returns the client's identifier.
+func (c *Client) ID() string { return c.id }
+
+// RPC sends a request to dest and waits for the reply. RPC errors in the reply
+// body are returned as *maelstrom.RPCError. The context's deadline is
+// converted to a virtual timeout, as by a simulated node's SyncRPC. RPC must
+// be called from a task of the network's simulation.
+func (c *Client) RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline).Round(time.Millisecond); timeout <= 0 {
+			return maelstrom.Message{}, context.DeadlineExceeded
+		}
+	}
+
+	w := c.nw.s.NewWaiter()
+	req, msgID, err := c.reqs.New(c.id, dest, body, func(msg maelstrom.Message) { w.Wake(msg) })
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	defer c.reqs.Cancel(msgID)
+	c.nw.Send(req)
+
+	v, ok := w.Wait(timeout)
+	if !ok {
+		return maelstrom.Message{}, context.DeadlineExceeded
+	}
+	msg := v.(maelstrom.Message)
+	if err := msg.RPCError(); err != nil {
+		return msg, err
+	}
+	return msg, nil
+}
+
+// deliver passes a reply to the waiting RPC call, if any.
+func (c *Client) deliver(msg maelstrom.Message) { c.reqs.Deliver(msg) }
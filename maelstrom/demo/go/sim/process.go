@@ -0,0 +1,100 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// process steps a simulated node binary in lock-step with the network: each
+// message is delivered in a SimulationStep at the current virtual time, and
+// the network waits for the node's SimulationIdle line before moving on. The
+// node's own timers are run by stepping it again when the next one is due.
+type process struct {
+	nw      *Network
+	id      string
+	w       io.WriteCloser
+	scanner *bufio.Scanner
+	timer   maelstrom.Timer
+	dead    bool
+}
+
+func newProcess(nw *Network, id string, stdin io.WriteCloser, stdout io.Reader) *process {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &process{nw: nw, id: id, w: stdin, scanner: scanner}
+}
+
+func (p *process) deliver(line []byte) { p.step(line) }
+
+func (p *process) close() error { return p.w.Close() }
+
+// tick runs the node's timers that are due.
+func (p *process) tick() { p.step(nil) }
+
+// step advances the node to the current virtual time, delivering msg if
+// set, and routes every message it sends in response.
+func (p *process) step(msg []byte) {
+	if p.dead {
+		return
+	}
+
+	now := p.nw.s.Now()
+	buf, err := json.Marshal(maelstrom.SimulationStep{
+		Time: now.Sub(maelstrom.SimulationEpoch),
+		Msg:  msg,
+	})
+	if err != nil {
+		log.Printf("sim: marshal step: %s", err)
+		return
+	} else if _, err := p.w.Write(append(buf, '\n')); err != nil {
+		p.fail(err)
+		return
+	}
+
+	for p.scanner.Scan() {
+		line := p.scanner.Bytes()
+		var idle maelstrom.SimulationIdle
+		if err := json.Unmarshal(line, &idle); err == nil && idle.Idle {
+			p.schedule(idle)
+			return
+		}
+		p.nw.route(p.id, line)
+	}
+
+	err = p.scanner.Err()
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	p.fail(err)
+}
+
+// schedule arranges for the node to be stepped when its next timer is due.
+func (p *process) schedule(idle maelstrom.SimulationIdle) {
+	if idle.Next < 0 {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		return
+	}
+
+	d := maelstrom.SimulationEpoch.Add(idle.Next).Sub(p.nw.s.Now())
+	if d < 0 {
+		d = 0
+	}
+	if p.timer == nil {
+		p.timer = p.nw.s.Clock().AfterFunc(d, p.tick)
+	} else {
+		p.timer.Reset(d)
+	}
+}
+
+// fail stops stepping a node whose process has exited or broken the
+// protocol. Messages to it are lost from then on.
+func (p *process) fail(err error) {
+	log.Printf("sim: %s stopped: %s", p.id, err)
+	p.dead = true
+}
@@ -0,0 +1,349 @@
+// Package sim provides a deterministic Maelstrom network for a
+// maelstrom.Simulation. Message deliveries are tasks of the simulation, so
+// the nodes, clients & services attached to a network exchange messages in
+// an order fixed by the simulation's seed, and the same seed replays a run
+// exactly.
+//
+// Nodes are either in-process nodes, which run as tasks of the simulation
+// themselves, or node binaries started with MAELSTROM_SIMULATION set, which
+// the network steps through their STDIN & STDOUT.
+package sim
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// Network routes messages between nodes, clients & services in virtual time.
+type Network struct {
+	mu        sync.Mutex
+	s         *maelstrom.Simulation
+	nodeIDs   []string
+	endpoints map[string]endpoint
+	clients   map[string]*Client
+	services  map[string]simnet.Service
+	blocked   map[link]bool
+	latency   func(src, dest string) time.Duration
+	observers []func(msg maelstrom.Message, dropped bool)
+
+	trace     hash.Hash
+	delivered int
+}
+
+// endpoint receives the messages for a node.
+type endpoint interface {
+	deliver(line []byte)
+	close() error
+}
+
+// link represents a directed connection between two endpoints.
+type link struct{ src, dest string }
+
+// New returns a network running in s and hosting the lin-kv, seq-kv, lww-kv
+// & lin-tso services, as simnet.New does.
+func New(s *maelstrom.Simulation) *Network {
+	nw := &Network{
+		s:         s,
+		endpoints: make(map[string]endpoint),
+		clients:   make(map[string]*Client),
+		services:  make(map[string]simnet.Service),
+		blocked:   make(map[link]bool),
+		trace:     sha256.New(),
+	}
+	nw.AddService(maelstrom.LinKV, simnet.NewKVService())
+	nw.AddService(maelstrom.SeqKV, simnet.NewKVService())
+	nw.AddService(maelstrom.LWWKV, simnet.NewKVService())
+	nw.AddService("lin-tso", simnet.NewTSOService())
+	return nw
+}
+
+// Simulation returns the simulation the network runs in.
+func (nw *Network) Simulation() *maelstrom.Simulation { return nw.s }
+
+// NodeIDs returns the IDs of all nodes in the order they were added.
+func (nw *Network) NodeIDs() []string {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return append([]string{}, nw.nodeIDs...)
+}
+
+// SetLatency sets a function which returns the delay for each message. It is
+// called in delivery order, so it may draw from a seeded random source.
+func (nw *Network) SetLatency(fn func(src, dest string) time.Duration) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.latency = fn
+}
+
+// AddService registers a service under id, replacing any existing service.
+func (nw *Network) AddService(id string, svc simnet.Service) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.services[id] = svc
+}
+
+// Service returns the service registered under id.
+func (nw *Network) Service(id string) simnet.Service {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.services[id]
+}
+
+// Observe registers fn to be called for every message routed by the network.
+// dropped is true if the message was discarded by a partition.
+func (nw *Network) Observe(fn func(msg maelstrom.Message, dropped bool)) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.observers = append(nw.observers, fn)
+}
+
+// NewNode returns a new in-process node attached to the network and running
+// in its simulation. Handlers should be registered before Start() is called.
+func (nw *Network) NewNode(id string) *maelstrom.Node {
+	n := maelstrom.NewNode()
+	n.Simulate(nw.s)
+	n.Stdin = nil
+	n.Stdout = &lineWriter{fn: func(line []byte) { nw.route(id, line) }}
+
+	nw.attach(id, &nodeEndpoint{id: id, node: n})
+	return n
+}
+
+// AttachProcess attaches a node binary started with MAELSTROM_SIMULATION
+// set, which reads SimulationStep lines from stdin and writes messages &
+// SimulationIdle lines to stdout. The network closes stdin on Close().
+func (nw *Network) AttachProcess(id string, stdin io.WriteCloser, stdout io.Reader) {
+	nw.attach(id, newProcess(nw, id, stdin, stdout))
+}
+
+func (nw *Network) attach(id string, ep endpoint) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.nodeIDs = append(nw.nodeIDs, id)
+	nw.endpoints[id] = ep
+}
+
+// NewClient returns a client attached to the network under id, e.g. "c1".
+func (nw *Network) NewClient(id string) *Client {
+	c := &Client{id: id, nw: nw}
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.clients[id] = c
+	return c
+}
+
+// Start initializes every node, in the order they were added, with the full
+// list of node IDs. Returns once every node has replied with "init_ok". Like
+// every call that waits for replies, Start must be called from a task of the
+// network's simulation.
+func (nw *Network) Start(ctx context.Context) error {
+	for _, id := range nw.NodeIDs() {
+		if err := nw.Init(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Init sends the init message to a node and waits for it to reply.
+func (nw *Network) Init(ctx context.Context, id string) error {
+	nw.mu.Lock()
+	nodeIDs := append([]string{}, nw.nodeIDs...)
+	c := nw.clients["c0"]
+	nw.mu.Unlock()
+	if c == nil {
+		c = nw.NewClient("c0")
+	}
+
+	if _, err := c.RPC(ctx, id, maelstrom.InitMessageBody{
+		MessageBody: maelstrom.MessageBody{Type: "init"},
+		NodeID:      id,
+		NodeIDs:     nodeIDs,
+	}); err != nil {
+		return fmt.Errorf("init %s: %w", id, err)
+	}
+	return nil
+}
+
+// Close closes the input of every node binary. Returns the first error.
+func (nw *Network) Close() (err error) {
+	for _, id := range nw.NodeIDs() {
+		nw.mu.Lock()
+		ep := nw.endpoints[id]
+		nw.mu.Unlock()
+
+		if e := ep.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Block drops all messages between a and b in both directions.
+func (nw *Network) Block(a, b string) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.blocked[link{a, b}] = true
+	nw.blocked[link{b, a}] = true
+}
+
+// Heal removes all partitions.
+func (nw *Network) Heal() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.blocked = make(map[link]bool)
+}
+
+// Delivered returns the number of messages delivered so far.
+func (nw *Network) Delivered() int {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.delivered
+}
+
+// Fingerprint returns a hash of every message delivered so far and the
+// virtual time it was delivered at. Two runs with the same fingerprint
+// exchanged exactly the same messages at the same times.
+func (nw *Network) Fingerprint() string {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return hex.EncodeToString(nw.trace.Sum(nil))
+}
+
+// Send routes a message through the network. The message is delivered as a
+// task of the simulation once its latency has elapsed.
+func (nw *Network) Send(msg maelstrom.Message) {
+	nw.mu.Lock()
+	dropped := nw.blocked[link{msg.Src, msg.Dest}]
+	var delay time.Duration
+	if nw.latency != nil && !dropped {
+		delay = nw.latency(msg.Src, msg.Dest)
+	}
+	observers := nw.observers
+	nw.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(msg, dropped)
+	}
+	if !dropped {
+		nw.s.Clock().AfterFunc(delay, func() { nw.deliver(msg) })
+	}
+}
+
+// route sends a line written by node id.
+func (nw *Network) route(id string, line []byte) {
+	var msg maelstrom.Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		log.Printf("sim: malformed output from %s: %q", id, line)
+		return
+	}
+	if msg.Src == "" {
+		msg.Src = id
+	}
+	nw.Send(msg)
+}
+
+// deliver hands msg to its destination.
+func (nw *Network) deliver(msg maelstrom.Message) {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("sim: marshal message: %s", err)
+		return
+	}
+
+	nw.mu.Lock()
+	fmt.Fprintf(nw.trace, "%d %s\n", nw.s.Now().Sub(maelstrom.SimulationEpoch), buf)
+	nw.delivered++
+	ep, svc, c := nw.endpoints[msg.Dest], nw.services[msg.Dest], nw.clients[msg.Dest]
+	nw.mu.Unlock()
+
+	switch {
+	case ep != nil:
+		ep.deliver(buf)
+
+	case svc != nil:
+		if body := svc.Handle(msg); body != nil {
+			reply, err := replyTo(msg, body)
+			if err != nil {
+				log.Printf("sim: %s reply: %s", msg.Dest, err)
+				return
+			}
+			nw.Send(reply)
+		}
+
+	case c != nil:
+		c.deliver(msg)
+
+	default:
+		log.Printf("sim: no route to %q", msg.Dest)
+	}
+}
+
+// replyTo builds a reply message to req with the given body.
+func replyTo(req maelstrom.Message, body any) (maelstrom.Message, error) {
+	var reqBody maelstrom.MessageBody
+	if err := json.Unmarshal(req.Body, &reqBody); err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	b := make(map[string]any)
+	if buf, err := json.Marshal(body); err != nil {
+		return maelstrom.Message{}, err
+	} else if err := json.Unmarshal(buf, &b); err != nil {
+		return maelstrom.Message{}, err
+	}
+	b["in_reply_to"] = reqBody.MsgID
+
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	return maelstrom.Message{Src: req.Dest, Dest: req.Src, Body: buf}, nil
+}
+
+// nodeEndpoint delivers messages to an in-process node.
+type nodeEndpoint struct {
+	id   string
+	node *maelstrom.Node
+}
+
+func (ep *nodeEndpoint) deliver(line []byte) {
+	if err := ep.node.Deliver(line); err != nil {
+		log.Printf("sim: %s: %s", ep.id, err)
+	}
+}
+
+func (ep *nodeEndpoint) close() error { return nil }
+
+// lineWriter calls fn with each complete line written to it. Nodes write a
+// message and its newline separately.
+type lineWriter struct {
+	buf []byte
+	fn  func(line []byte)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := w.buf[:i]
+		w.buf = w.buf[i+1:]
+		w.fn(line)
+	}
+}
@@ -0,0 +1,172 @@
+package sim_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/sim"
+)
+
+// Ensure a seed replays the same run exactly, while other seeds vary latency
+// and ordering.
+func TestNetwork(t *testing.T) {
+	type result struct {
+		Fingerprint string
+		Values      []any
+	}
+	run := func(seed int64) result {
+		s := maelstrom.NewSimulation(seed)
+		defer s.Close()
+		nw := sim.New(s)
+		rng := rand.New(rand.NewSource(seed))
+		nw.SetLatency(func(src, dest string) time.Duration {
+			return time.Duration(rng.Intn(100)) * time.Millisecond
+		})
+
+		// Each node gossips its counter to a random peer every second and
+		// keeps the largest value it has seen.
+		for _, id := range []string{"n1", "n2", "n3"} {
+			n := nw.NewNode(id)
+			var value int
+			n.Handle("init", func(msg maelstrom.Message) error {
+				maelstrom.Every(context.Background(), n.Clock(), time.Second, func() {
+					peer := n.NodeIDs()[n.Rand().Intn(len(n.NodeIDs()))]
+					n.Go(func() {
+						ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+						defer cancel()
+						n.SyncRPC(ctx, peer, map[string]any{"type": "gossip", "value": value})
+					})
+				})
+				return nil
+			})
+			n.Handle("gossip", func(msg maelstrom.Message) error {
+				var body struct{ Value int }
+				if err := json.Unmarshal(msg.Body, &body); err != nil {
+					return err
+				} else if body.Value > value {
+					value = body.Value
+				}
+				return n.Reply(msg, map[string]any{"type": "gossip_ok"})
+			})
+			n.Handle("set", func(msg maelstrom.Message) error {
+				var body struct{ Value int }
+				if err := json.Unmarshal(msg.Body, &body); err != nil {
+					return err
+				}
+				value = body.Value
+				return n.Reply(msg, map[string]any{"type": "set_ok"})
+			})
+			n.Handle("read", func(msg maelstrom.Message) error {
+				return n.Reply(msg, map[string]any{"type": "read_ok", "value": value})
+			})
+		}
+
+		var r result
+		if err := s.Run(func() {
+			ctx := context.Background()
+			if err := nw.Start(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			c := nw.NewClient("c1")
+			if _, err := c.RPC(ctx, "n1", map[string]any{"type": "set", "value": 42}); err != nil {
+				t.Error(err)
+			}
+			s.Clock().Sleep(30 * time.Second)
+
+			for _, id := range nw.NodeIDs() {
+				resp, err := c.RPC(ctx, id, map[string]any{"type": "read"})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				var body map[string]any
+				json.Unmarshal(resp.Body, &body)
+				r.Values = append(r.Values, body["value"])
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		r.Fingerprint = nw.Fingerprint()
+		return r
+	}
+
+	a := run(1)
+	if got, want := a.Values, []any{42.0, 42.0, 42.0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("values=%v, want %v", got, want)
+	} else if got := run(1); !reflect.DeepEqual(got, a) {
+		t.Fatalf("seed 1 replayed as %v, want %v", got, a)
+	} else if run(2).Fingerprint == a.Fingerprint {
+		t.Fatal("expected another seed to produce another run")
+	}
+}
+
+// Ensure node binaries are stepped in lock-step with the network, including
+// their timers, and that RPC timeouts elapse in virtual time.
+func TestNetwork_AttachProcess(t *testing.T) {
+	s := maelstrom.NewSimulation(1)
+	defer s.Close()
+	nw := sim.New(s)
+	nw.SetLatency(func(src, dest string) time.Duration { return 10 * time.Millisecond })
+
+	// The node runs its own simulation, stepped through its STDIN, as a
+	// node binary started with MAELSTROM_SIMULATION does.
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	n := maelstrom.NewNode()
+	n.Simulate(maelstrom.NewSimulation(1))
+	n.Stdin, n.Stdout = inr, outw
+	n.Handle("sleep", func(msg maelstrom.Message) error {
+		n.Clock().Sleep(time.Hour)
+		return n.Reply(msg, map[string]any{"type": "sleep_ok", "at": n.Clock().Now().Sub(maelstrom.SimulationEpoch)})
+	})
+	done := make(chan error, 1)
+	go func() { done <- n.Run() }()
+	nw.AttachProcess("n1", inw, bufio.NewReader(outr))
+
+	start := time.Now()
+	if err := s.Run(func() {
+		ctx := context.Background()
+		if err := nw.Start(ctx); err != nil {
+			t.Error(err)
+			return
+		}
+		c := nw.NewClient("c1")
+
+		shortCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		if _, err := c.RPC(shortCtx, "n1", map[string]any{"type": "sleep"}); err != context.DeadlineExceeded {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		resp, err := c.RPC(ctx, "n1", map[string]any{"type": "sleep"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var body struct{ At time.Duration }
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			t.Error(err)
+		} else if got, want := body.At, time.Hour+time.Minute+30*time.Millisecond; got != want {
+			t.Errorf("at=%s, want %s", got, want)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("simulation took %s of real time", d)
+	}
+
+	if err := nw.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
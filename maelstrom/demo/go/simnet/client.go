@@ -0,0 +1,56 @@
+package simnet
+
+import (
+	"context"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Client sends requests into the network, like a Maelstrom client process.
+type Client struct {
+	id   string
+	nw   *Network
+	reqs maelstrom.Requests
+}
+
+// ID returns the client's identifier.
+func (c *Client) ID() string { return c.id }
+
+// RPC sends a request to dest and waits for the reply. RPC errors in the reply
+// body are returned as *maelstrom.RPCError.
+func (c *Client) RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	ch := make(chan maelstrom.Message, 1)
+	req, msgID, err := c.reqs.New(c.id, dest, body, func(msg maelstrom.Message) { ch <- msg })
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	defer c.reqs.Cancel(msgID)
+	c.nw.Send(req)
+
+	select {
+	case <-ctx.Done():
+		return maelstrom.Message{}, ctx.Err()
+	case msg := <-ch:
+		if err := msg.RPCError(); err != nil {
+			return msg, err
+		}
+		return msg, nil
+	}
+}
+
+// deliver passes a reply to the waiting RPC call, if any.
+func (c *Client) deliver(msg maelstrom.Message) { c.reqs.Deliver(msg) }
+
+// IsClient returns true for client IDs such as "c1", as Maelstrom assigns to
+// its client processes.
+func IsClient(id string) bool {
+	if len(id) < 2 || id[0] != 'c' {
+		return false
+	}
+	for _, r := range id[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package simnet
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// KVService is an in-memory, linearizable key/value store which implements
+// the lin-kv, seq-kv & lww-kv protocols.
+type KVService struct {
+	mu   sync.Mutex
+	data map[string]any
+}
+
+// NewKVService returns a new, empty KV service.
+func NewKVService() *KVService {
+	return &KVService{data: make(map[string]any)}
+}
+
+// Get returns the current value of a key.
+func (s *KVService) Get(key any) (any, bool) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[string(k)]
+	return v, ok
+}
+
+// Handle executes a read, write or cas request.
+func (s *KVService) Handle(req maelstrom.Message) any {
+	var body struct {
+		maelstrom.MessageBody
+		Key               any  `json:"key"`
+		Value             any  `json:"value"`
+		From              any  `json:"from"`
+		To                any  `json:"to"`
+		CreateIfNotExists bool `json:"create_if_not_exists"`
+	}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+	}
+
+	// Keys may be any JSON value; normalize them to their JSON encoding.
+	k, err := json.Marshal(body.Key)
+	if err != nil {
+		return maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+	}
+	key := string(k)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch body.Type {
+	case "read":
+		v, ok := s.data[key]
+		if !ok {
+			return maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+		}
+		return map[string]any{"type": "read_ok", "value": v}
+
+	case "write":
+		s.data[key] = body.Value
+		return maelstrom.MessageBody{Type: "write_ok"}
+
+	case "cas":
+		v, ok := s.data[key]
+		if !ok && !body.CreateIfNotExists {
+			return maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+		} else if ok && !reflect.DeepEqual(v, body.From) {
+			return maelstrom.NewRPCError(maelstrom.PreconditionFailed, "current value does not match")
+		}
+		s.data[key] = body.To
+		return maelstrom.MessageBody{Type: "cas_ok"}
+
+	default:
+		return maelstrom.NewRPCError(maelstrom.NotSupported, "unsupported request type "+body.Type)
+	}
+}
+
+// TSOService is a linearizable timestamp oracle implementing lin-tso.
+type TSOService struct {
+	mu sync.Mutex
+	ts int
+}
+
+// NewTSOService returns a new timestamp oracle starting at zero.
+func NewTSOService() *TSOService {
+	return &TSOService{}
+}
+
+// Handle returns the next timestamp for a "ts" request.
+func (s *TSOService) Handle(req maelstrom.Message) any {
+	if req.Type() != "ts" {
+		return maelstrom.NewRPCError(maelstrom.NotSupported, "unsupported request type "+req.Type())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ts++
+	return map[string]any{"type": "ts_ok", "ts": s.ts}
+}
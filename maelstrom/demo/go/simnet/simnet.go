@@ -0,0 +1,480 @@
+// Package simnet provides an in-memory Maelstrom network for testing nodes
+// without the Maelstrom binary. Nodes, clients & services exchange JSON
+// messages through the network, which can add latency and partition links.
+package simnet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Service represents a Maelstrom-provided service, such as lin-kv, hosted by
+// the network. Handle returns the reply body for a request or nil to send no
+// reply. The network fills in "in_reply_to".
+type Service interface {
+	Handle(req maelstrom.Message) any
+}
+
+// Network routes messages between nodes, clients & services.
+type Network struct {
+	mu        sync.Mutex
+	nodeIDs   []string
+	nodes     map[string]*maelstrom.Node
+	endpoints map[string]*endpoint
+	clients   map[string]*Client
+	services  map[string]Service
+	blocked   map[link]bool
+	latency   func(src, dest string) time.Duration
+	observers []func(msg maelstrom.Message, dropped bool)
+
+	wg     sync.WaitGroup
+	runErr []error
+}
+
+// link represents a directed connection between two endpoints.
+type link struct{ src, dest string }
+
+// New returns a new network hosting the lin-kv, seq-kv, lww-kv & lin-tso
+// services. The in-memory KV stores are all linearizable, which is a valid
+// (if generous) implementation of each consistency model.
+func New() *Network {
+	nw := &Network{
+		nodes:     make(map[string]*maelstrom.Node),
+		endpoints: make(map[string]*endpoint),
+		clients:   make(map[string]*Client),
+		services:  make(map[string]Service),
+		blocked:   make(map[link]bool),
+	}
+	nw.AddService(maelstrom.LinKV, NewKVService())
+	nw.AddService(maelstrom.SeqKV, NewKVService())
+	nw.AddService(maelstrom.LWWKV, NewKVService())
+	nw.AddService("lin-tso", NewTSOService())
+	return nw
+}
+
+// NodeIDs returns the IDs of all nodes in the order they were added.
+func (nw *Network) NodeIDs() []string {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return append([]string{}, nw.nodeIDs...)
+}
+
+// SetLatency sets a function which returns the delay for each message.
+func (nw *Network) SetLatency(fn func(src, dest string) time.Duration) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.latency = fn
+}
+
+// AddService registers a service under id, replacing any existing service.
+func (nw *Network) AddService(id string, svc Service) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.services[id] = svc
+}
+
+// Service returns the service registered under id.
+func (nw *Network) Service(id string) Service {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.services[id]
+}
+
+// Observe registers fn to be called for every message routed by the network.
+// dropped is true if the message was discarded by a partition.
+func (nw *Network) Observe(fn func(msg maelstrom.Message, dropped bool)) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.observers = append(nw.observers, fn)
+}
+
+// NewNode returns a new in-process node attached to the network. Handlers
+// should be registered before Start() is called.
+func (nw *Network) NewNode(id string) *maelstrom.Node {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+
+	n := maelstrom.NewNode()
+	n.Stdin, n.Stdout = inr, outw
+
+	nw.mu.Lock()
+	nw.nodes[id] = n
+	nw.mu.Unlock()
+
+	nw.AttachNode(id, inw, outr)
+	return n
+}
+
+// NewNodes returns n new in-process nodes named "n1" through "n<n>", by ID,
+// as Maelstrom names them.
+func (nw *Network) NewNodes(n int) map[string]*maelstrom.Node {
+	nodes := make(map[string]*maelstrom.Node, n)
+	for i := 1; i <= n; i++ {
+		id := fmt.Sprintf("n%d", i)
+		nodes[id] = nw.NewNode(id)
+	}
+	return nodes
+}
+
+// Node returns the in-process node with the given ID, if any.
+func (nw *Network) Node(id string) *maelstrom.Node {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.nodes[id]
+}
+
+// AttachNode attaches a node that reads messages from stdin and writes them
+// to stdout, such as a subprocess. The network closes stdin on Close().
+func (nw *Network) AttachNode(id string, stdin io.WriteCloser, stdout io.Reader) {
+	ep := &endpoint{
+		id:     id,
+		w:      stdin,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	nw.mu.Lock()
+	if !contains(nw.nodeIDs, id) {
+		nw.nodeIDs = append(nw.nodeIDs, id)
+	}
+	nw.endpoints[id] = ep
+	nw.mu.Unlock()
+
+	go ep.writeLoop()
+	go nw.readLoop(id, stdout)
+}
+
+// DetachNode closes the input of a node and drops messages sent to it until
+// it is attached again, e.g. while a crashed process restarts.
+func (nw *Network) DetachNode(id string) {
+	nw.mu.Lock()
+	ep := nw.endpoints[id]
+	delete(nw.endpoints, id)
+	nw.mu.Unlock()
+
+	if ep != nil {
+		ep.close()
+	}
+}
+
+// NewClient returns a client attached to the network under id, e.g. "c1".
+func (nw *Network) NewClient(id string) *Client {
+	c := &Client{id: id, nw: nw}
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.clients[id] = c
+	return c
+}
+
+// Start runs every in-process node and initializes all nodes with the full
+// list of node IDs. Returns once every node has replied with "init_ok".
+func (nw *Network) Start(ctx context.Context) error {
+	nw.mu.Lock()
+	nodes := make(map[string]*maelstrom.Node, len(nw.nodes))
+	for id, n := range nw.nodes {
+		nodes[id] = n
+	}
+	nodeIDs := append([]string{}, nw.nodeIDs...)
+	nw.mu.Unlock()
+
+	for id, n := range nodes {
+		id, n := id, n
+		nw.wg.Add(1)
+		go func() {
+			defer nw.wg.Done()
+			if err := n.Run(); err != nil {
+				nw.mu.Lock()
+				nw.runErr = append(nw.runErr, fmt.Errorf("%s: %w", id, err))
+				nw.mu.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range nodeIDs {
+		if err := nw.Init(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Init sends the init message to a node and waits for it to reply, e.g.
+// after it was restarted. Start() initializes every node.
+func (nw *Network) Init(ctx context.Context, id string) error {
+	nw.mu.Lock()
+	nodeIDs := append([]string{}, nw.nodeIDs...)
+	c := nw.clients["c0"]
+	nw.mu.Unlock()
+	if c == nil {
+		c = nw.NewClient("c0")
+	}
+
+	if _, err := c.RPC(ctx, id, maelstrom.InitMessageBody{
+		MessageBody: maelstrom.MessageBody{Type: "init"},
+		NodeID:      id,
+		NodeIDs:     nodeIDs,
+	}); err != nil {
+		return fmt.Errorf("init %s: %w", id, err)
+	}
+	return nil
+}
+
+// Close closes the input of every node and waits for in-process nodes to
+// stop. Returns the first error returned by a node's Run().
+func (nw *Network) Close() error {
+	nw.mu.Lock()
+	endpoints := make([]*endpoint, 0, len(nw.endpoints))
+	for _, ep := range nw.endpoints {
+		endpoints = append(endpoints, ep)
+	}
+	nw.mu.Unlock()
+
+	for _, ep := range endpoints {
+		ep.close()
+	}
+	nw.wg.Wait()
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if len(nw.runErr) > 0 {
+		return nw.runErr[0]
+	}
+	return nil
+}
+
+// Block drops all messages between a and b in both directions.
+func (nw *Network) Block(a, b string) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.blocked[link{a, b}] = true
+	nw.blocked[link{b, a}] = true
+}
+
+// Partition splits the network so that endpoints can only communicate with
+// endpoints in the same group. Endpoints & services that are not listed in
+// any group remain reachable from everywhere.
+func (nw *Network) Partition(groups ...[]string) {
+	for i := range groups {
+		for j := range groups {
+			if i == j {
+				continue
+			}
+			for _, a := range groups[i] {
+				for _, b := range groups[j] {
+					nw.Block(a, b)
+				}
+			}
+		}
+	}
+}
+
+// Isolate cuts id off from every other node & service. Clients can still
+// reach it.
+func (nw *Network) Isolate(id string) {
+	nw.mu.Lock()
+	peers := append([]string{}, nw.nodeIDs...)
+	for svc := range nw.services {
+		peers = append(peers, svc)
+	}
+	nw.mu.Unlock()
+
+	for _, peer := range peers {
+		if peer != id {
+			nw.Block(id, peer)
+		}
+	}
+}
+
+// Heal removes all partitions.
+func (nw *Network) Heal() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.blocked = make(map[link]bool)
+}
+
+// Send routes a message through the network.
+func (nw *Network) Send(msg maelstrom.Message) {
+	nw.mu.Lock()
+	dropped := nw.blocked[link{msg.Src, msg.Dest}]
+	var delay time.Duration
+	if nw.latency != nil && !dropped {
+		delay = nw.latency(msg.Src, msg.Dest)
+	}
+	observers := nw.observers
+	nw.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(msg, dropped)
+	}
+	if dropped {
+		return
+	}
+
+	if delay > 0 {
+		time.AfterFunc(delay, func() { nw.deliver(msg) })
+		return
+	}
+	nw.deliver(msg)
+}
+
+// deliver hands msg to its destination.
+func (nw *Network) deliver(msg maelstrom.Message) {
+	nw.mu.Lock()
+	ep, svc, c := nw.endpoints[msg.Dest], nw.services[msg.Dest], nw.clients[msg.Dest]
+	nw.mu.Unlock()
+
+	switch {
+	case ep != nil:
+		buf, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("simnet: marshal message: %s", err)
+			return
+		}
+		ep.enqueue(buf)
+
+	case svc != nil:
+		if body := svc.Handle(msg); body != nil {
+			reply, err := replyTo(msg, body)
+			if err != nil {
+				log.Printf("simnet: %s reply: %s", msg.Dest, err)
+				return
+			}
+			nw.Send(reply)
+		}
+
+	case c != nil:
+		c.deliver(msg)
+
+	case nw.isNode(msg.Dest):
+		// detached: the message is lost
+
+	default:
+		log.Printf("simnet: no route to %q", msg.Dest)
+	}
+}
+
+func (nw *Network) isNode(id string) bool {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return contains(nw.nodeIDs, id)
+}
+
+func contains(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readLoop routes every message written by a node.
+func (nw *Network) readLoop(id string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg maelstrom.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("simnet: malformed output from %s: %q", id, scanner.Text())
+			continue
+		}
+		if msg.Src == "" {
+			msg.Src = id
+		}
+		nw.Send(msg)
+	}
+}
+
+// replyTo builds a reply message to req with the given body.
+func replyTo(req maelstrom.Message, body any) (maelstrom.Message, error) {
+	var reqBody maelstrom.MessageBody
+	if err := json.Unmarshal(req.Body, &reqBody); err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	b := make(map[string]any)
+	if buf, err := json.Marshal(body); err != nil {
+		return maelstrom.Message{}, err
+	} else if err := json.Unmarshal(buf, &b); err != nil {
+		return maelstrom.Message{}, err
+	}
+	b["in_reply_to"] = reqBody.MsgID
+
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	return maelstrom.Message{Src: req.Dest, Dest: req.Src, Body: buf}, nil
+}
+
+// endpoint buffers messages for a node so that a slow reader never blocks
+// the rest of the network.
+type endpoint struct {
+	id string
+	w  io.WriteCloser
+
+	mu      sync.Mutex
+	queue   [][]byte
+	closed  bool
+	notify  chan struct{}
+	done    chan struct{}
+	closeMu sync.Once
+}
+
+func (ep *endpoint) enqueue(line []byte) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return
+	}
+	ep.queue = append(ep.queue, line)
+
+	select {
+	case ep.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (ep *endpoint) writeLoop() {
+	defer close(ep.done)
+	for {
+		ep.mu.Lock()
+		queue, closed := ep.queue, ep.closed
+		ep.queue = nil
+		ep.mu.Unlock()
+
+		for _, line := range queue {
+			if _, err := ep.w.Write(append(line, '\n')); err != nil {
+				return
+			}
+		}
+		if closed {
+			return
+		}
+		<-ep.notify
+	}
+}
+
+func (ep *endpoint) close() {
+	ep.closeMu.Do(func() {
+		ep.mu.Lock()
+		ep.closed = true
+		ep.mu.Unlock()
+
+		select {
+		case ep.notify <- struct{}{}:
+		default:
+		}
+		<-ep.done
+		ep.w.Close()
+	})
+}
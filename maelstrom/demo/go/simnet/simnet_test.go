@@ -0,0 +1,211 @@
+package simnet_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+)
+
+// Ensure nodes can be driven by clients and talk to each other & services.
+func TestNetwork(t *testing.T) {
+	nw := simnet.New()
+	for _, id := range []string{"n1", "n2"} {
+		n := nw.NewNode(id)
+		kv := maelstrom.NewLinKV(n)
+
+		// "incr" forwards to the other node, which increments a shared counter.
+		n.Handle("incr", func(msg maelstrom.Message) error {
+			peer := "n1"
+			if n.ID() == "n1" {
+				peer = "n2"
+			}
+			resp, err := n.SyncRPC(context.Background(), peer, map[string]any{"type": "add"})
+			if err != nil {
+				return err
+			}
+			return n.Reply(msg, json.RawMessage(resp.Body))
+		})
+		n.Handle("add", func(msg maelstrom.Message) error {
+			ctx := context.Background()
+			v, err := kv.ReadInt(ctx, "counter")
+			if err != nil && maelstrom.ErrorCode(err) != maelstrom.KeyDoesNotExist {
+				return err
+			}
+			if err := kv.Write(ctx, "counter", v+1); err != nil {
+				return err
+			}
+			return n.Reply(msg, map[string]any{"type": "incr_ok", "value": v + 1})
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	c := nw.NewClient("c1")
+	for i, dest := range []string{"n1", "n2"} {
+		resp, err := c.RPC(ctx, dest, map[string]any{"type": "incr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Value int `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			t.Fatal(err)
+		} else if got, want := body.Value, i+1; got != want {
+			t.Fatalf("value=%d, want %d", got, want)
+		}
+	}
+
+	if v, ok := nw.Service(maelstrom.LinKV).(*simnet.KVService).Get("counter"); !ok || v != float64(2) {
+		t.Fatalf("counter=%v", v)
+	}
+}
+
+// Ensure partitions drop messages until healed.
+func TestNetwork_Partition(t *testing.T) {
+	nw := simnet.New()
+	n := nw.NewNode("n1")
+	n.Handle("ping", func(msg maelstrom.Message) error {
+		return n.Reply(msg, map[string]any{"type": "pong"})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	var dropped int
+	nw.Observe(func(msg maelstrom.Message, d bool) {
+		if d {
+			dropped++
+		}
+	})
+
+	c := nw.NewClient("c1")
+	nw.Partition([]string{"c1"}, []string{"n1"})
+
+	shortCtx, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer shortCancel()
+	if _, err := c.RPC(shortCtx, "n1", map[string]any{"type": "ping"}); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	} else if dropped != 1 {
+		t.Fatalf("dropped=%d, want 1", dropped)
+	}
+
+	nw.Heal()
+	if _, err := c.RPC(ctx, "n1", map[string]any{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a detached node loses messages until it is reattached & initialized
+// again, as when a process crashes & restarts.
+func TestNetwork_DetachNode(t *testing.T) {
+	nw := simnet.New()
+	newNode := func() *maelstrom.Node {
+		n := maelstrom.NewNode()
+		n.Handle("ping", func(msg maelstrom.Message) error {
+			return n.Reply(msg, map[string]any{"type": "pong", "node": n.ID()})
+		})
+		return n
+	}
+	attach := func(n *maelstrom.Node) {
+		inr, inw := io.Pipe()
+		outr, outw := io.Pipe()
+		n.Stdin, n.Stdout = inr, outw
+		nw.AttachNode("n1", inw, outr)
+		go n.Run()
+	}
+	attach(newNode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	c := nw.NewClient("c1")
+	nw.DetachNode("n1")
+	shortCtx, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer shortCancel()
+	if _, err := c.RPC(shortCtx, "n1", map[string]any{"type": "ping"}); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attach(newNode())
+	if err := nw.Init(ctx, "n1"); err != nil {
+		t.Fatal(err)
+	} else if got, want := nw.NodeIDs(), []string{"n1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("node IDs=%v, want %v", got, want)
+	}
+	resp, err := c.RPC(ctx, "n1", map[string]any{"type": "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Node string `json:"node"`
+	}
+	if err := json.Unmarshal(resp.Body, &body); err != nil {
+		t.Fatal(err)
+	} else if body.Node != "n1" {
+		t.Fatalf("node=%q, want n1", body.Node)
+	}
+}
+
+// Ensure the KV service reports errors with Maelstrom error codes.
+func TestKVService(t *testing.T) {
+	nw := simnet.New()
+	c := nw.NewClient("c1")
+	ctx := context.Background()
+
+	if _, err := c.RPC(ctx, maelstrom.LinKV, map[string]any{"type": "read", "key": "x"}); maelstrom.ErrorCode(err) != maelstrom.KeyDoesNotExist {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.RPC(ctx, maelstrom.LinKV, map[string]any{"type": "cas", "key": "x", "from": 1, "to": 2, "create_if_not_exists": true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RPC(ctx, maelstrom.LinKV, map[string]any{"type": "cas", "key": "x", "from": 1, "to": 3}); maelstrom.ErrorCode(err) != maelstrom.PreconditionFailed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp, err := c.RPC(ctx, maelstrom.LinKV, map[string]any{"type": "read", "key": "x"}); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(resp.Body), `{"in_reply_to":4,"type":"read_ok","value":2}`; got != want {
+		t.Fatalf("body=%s, want %s", got, want)
+	}
+}
+
+func TestIsClient(t *testing.T) {
+	for id, want := range map[string]bool{"c1": true, "c42": true, "c": false, "n1": false, "cx": false, "lin-kv": false} {
+		if got := simnet.IsClient(id); got != want {
+			t.Errorf("IsClient(%q)=%v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestNetwork_NewNodes(t *testing.T) {
+	nw := simnet.New()
+	defer nw.Close()
+
+	nodes := nw.NewNodes(12)
+	if got, want := len(nodes), 12; got != want {
+		t.Fatalf("len=%d, want %d", got, want)
+	}
+	for _, id := range []string{"n1", "n9", "n10", "n12"} {
+		if nodes[id] == nil || nw.Node(id) != nodes[id] {
+			t.Fatalf("missing node %s", id)
+		}
+	}
+}
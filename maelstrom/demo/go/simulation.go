@@ -0,0 +1,627 @@
+package maelstrom
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SimulationEnv, if set to a seed, makes NewNode() return a node that runs
+// under a deterministic Simulation driven through STDIN & STDOUT, as by
+// `distsys run -simulate`. See Node.Run.
+const SimulationEnv = "MAELSTROM_SIMULATION"
+
+// SimulationEpoch is the virtual time at which every simulation starts.
+var SimulationEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultStallTimeout is the default real time a simulation task may run
+// without yielding before the simulation gives up on it.
+const DefaultStallTimeout = 10 * time.Second
+
+var (
+	// ErrSimulationDeadlock is returned by Simulation.Run when every task is
+	// waiting and no timer or message is pending to wake any of them.
+	ErrSimulationDeadlock = errors.New("simulation: all tasks are blocked")
+
+	// ErrSimulationStalled is returned when a task blocks on something the
+	// simulation does not control, such as a channel, a mutex held by a
+	// waiting task or a real timer.
+	ErrSimulationStalled = errors.New("simulation: task blocked outside the simulation")
+)
+
+// Simulation runs work deterministically. Every handler, RPC callback, timer
+// and message delivery of the nodes attached to it is a task, and tasks run
+// one at a time, in virtual time order. Tasks scheduled for the same instant
+// run in an order chosen by the seed, so a seed identifies one interleaving
+// and replays it exactly.
+//
+// Tasks hand control back to the simulation when they finish or wait on a
+// simulated primitive: Sleep & AfterFunc of the simulation's clock, SyncRPC
+// and Waiter. Tasks must not block on anything else, including the channels
+// of the clock's timers & tickers; use Every for periodic work and Node.Go
+// for background work.
+type Simulation struct {
+	mu      sync.Mutex
+	rng     *rand.Rand // orders simultaneous events
+	rand    *rand.Rand
+	now     time.Time
+	seq     uint64
+	events  simEvents
+	current *simTask
+	parked  map[*simTask]bool
+	yield   chan struct{}
+	stall   *time.Timer
+	stopped bool
+	steps   int
+
+	// StallTimeout is the real time a task may run before yielding.
+	// Defaults to DefaultStallTimeout.
+	StallTimeout time.Duration
+}
+
+// NewSimulation returns a simulation at SimulationEpoch whose scheduling is
+// determined by seed.
+func NewSimulation(seed int64) *Simulation {
+	return &Simulation{
+		rng:          rand.New(rand.NewSource(seed)),
+		rand:         rand.New(newLockedSource(seed + 1)),
+		now:          SimulationEpoch,
+		parked:       make(map[*simTask]bool),
+		yield:        make(chan struct{}, 1),
+		StallTimeout: DefaultStallTimeout,
+	}
+}
+
+// Clock returns a clock that reads the simulation's virtual time.
+func (s *Simulation) Clock() Clock { return simClock{s} }
+
+// Now returns the current virtual time.
+func (s *Simulation) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Rand returns a random source seeded by the simulation's seed. Draws are
+// only repeatable when made from tasks.
+func (s *Simulation) Rand() *rand.Rand { return s.rand }
+
+// Steps returns the number of times a task has run so far.
+func (s *Simulation) Steps() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.steps
+}
+
+// Go runs fn as a new task at the current virtual time.
+func (s *Simulation) Go(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedule(s.now, &simEvent{task: fn})
+}
+
+// Run runs main as a task, along with every task it causes, until main
+// returns. Tasks still pending at that point are abandoned; see Close.
+func (s *Simulation) Run(main func()) error {
+	var done bool
+	s.Go(func() {
+		main()
+		s.mu.Lock()
+		done = true
+		s.mu.Unlock()
+	})
+
+	for {
+		s.mu.Lock()
+		if done {
+			s.mu.Unlock()
+			return nil
+		}
+		ev := s.pop()
+		s.mu.Unlock()
+
+		if ev == nil {
+			return ErrSimulationDeadlock
+		} else if err := s.exec(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// RunUntil runs every task due at or before t and then sets the virtual time
+// to t. It is used to drive a simulation step by step from outside.
+func (s *Simulation) RunUntil(t time.Time) error {
+	for {
+		s.mu.Lock()
+		if len(s.events) == 0 || s.events[0].at.After(t) {
+			if t.After(s.now) {
+				s.now = t
+			}
+			s.mu.Unlock()
+			return nil
+		}
+		ev := s.pop()
+		s.mu.Unlock()
+
+		if err := s.exec(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// Next returns the time of the next pending task or timer, if any.
+func (s *Simulation) Next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return time.Time{}, false
+	}
+	return s.events[0].at, true
+}
+
+// Close stops the simulation. Waiting tasks are unwound with
+// runtime.Goexit, running their deferred calls, and pending ones are
+// discarded.
+func (s *Simulation) Close() {
+	s.mu.Lock()
+	s.stopped = true
+	s.events = nil
+	parked := make([]*simTask, 0, len(s.parked))
+	for t := range s.parked {
+		parked = append(parked, t)
+	}
+	s.parked = make(map[*simTask]bool)
+	s.mu.Unlock()
+
+	for _, t := range parked {
+		t.wake <- struct{}{}
+	}
+}
+
+// SimulationStep is a line written to the STDIN of a simulated node binary.
+// The node runs until Time, delivers Msg, if any, runs any tasks that causes
+// and then writes a SimulationIdle line.
+type SimulationStep struct {
+	// Virtual time since SimulationEpoch.
+	Time time.Duration `json:"sim_time"`
+
+	// Optional. Message to deliver at Time.
+	Msg json.RawMessage `json:"msg,omitempty"`
+}
+
+// SimulationIdle is written to STDOUT by a simulated node binary after the
+// messages it sent during a step.
+type SimulationIdle struct {
+	// Always true; marks the line as the end of a step.
+	Idle bool `json:"sim_idle"`
+
+	// Time since SimulationEpoch of the node's next timer, or -1 if none.
+	Next time.Duration `json:"sim_next"`
+}
+
+// runSimulation runs the node one SimulationStep at a time.
+func (n *Node) runSimulation() error {
+	defer n.sim.Close()
+
+	scanner := bufio.NewScanner(n.Stdin)
+	for scanner.Scan() {
+		var step SimulationStep
+		if err := json.Unmarshal(scanner.Bytes(), &step); err != nil {
+			return fmt.Errorf("unmarshal simulation step: %w", err)
+		}
+
+		t := SimulationEpoch.Add(step.Time)
+		if err := n.sim.RunUntil(t); err != nil {
+			return err
+		}
+		if len(step.Msg) > 0 {
+			if err := n.Deliver(step.Msg); err != nil {
+				return err
+			} else if err := n.sim.RunUntil(t); err != nil {
+				return err
+			}
+		}
+
+		idle := SimulationIdle{Idle: true, Next: -1}
+		if next, ok := n.sim.Next(); ok {
+			idle.Next = next.Sub(SimulationEpoch)
+		}
+		buf, err := json.Marshal(idle)
+		if err != nil {
+			return err
+		}
+
+		n.mu.Lock()
+		_, err = n.Stdout.Write(append(buf, '\n'))
+		n.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// syncRPCSimulated is SyncRPC for a simulated node. The context's deadline is
+// converted to a virtual timeout, rounded to the millisecond so that it does
+// not depend on how long the real call took; cancellation without a deadline
+// is not observed.
+func (n *Node) syncRPCSimulated(ctx context.Context, dest string, body any) (Message, error) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline).Round(time.Millisecond); timeout <= 0 {
+			return Message{}, context.DeadlineExceeded
+		}
+	}
+
+	w := n.sim.NewWaiter()
+	msgID, err := n.rpc(dest, body, func(m Message) error {
+		w.Wake(m)
+		return nil
+	})
+	if err != nil {
+		return Message{}, err
+	}
+
+	v, ok := w.Wait(timeout)
+	if !ok {
+		n.cancelRPC(msgID)
+		return Message{}, context.DeadlineExceeded
+	}
+	m := v.(Message)
+	if err := m.RPCError(); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// simTask is a goroutine that only runs while the simulation has handed it
+// control.
+type simTask struct {
+	wake chan struct{}
+}
+
+// simEvent is a scheduled unit of work: a new task, a waiting task to
+// resume or a function to run on the scheduler itself, which must not block.
+type simEvent struct {
+	at    time.Time
+	prio  int64
+	seq   uint64
+	index int
+
+	task   func()
+	resume *simTask
+	inline func()
+}
+
+// schedule adds ev at time at. Must hold lock.
+func (s *Simulation) schedule(at time.Time, ev *simEvent) *simEvent {
+	if s.stopped {
+		ev.index = -1
+		return ev
+	}
+	ev.at, ev.prio, ev.seq = at, s.rng.Int63(), s.seq
+	s.seq++
+	heap.Push(&s.events, ev)
+	return ev
+}
+
+// cancel removes ev if it is still pending. Must hold lock.
+func (s *Simulation) cancel(ev *simEvent) bool {
+	if ev.index < 0 {
+		return false
+	}
+	heap.Remove(&s.events, ev.index)
+	return true
+}
+
+// pop removes the next event and advances time to it. Must hold lock.
+func (s *Simulation) pop() *simEvent {
+	if len(s.events) == 0 {
+		return nil
+	}
+	ev := heap.Pop(&s.events).(*simEvent)
+	if ev.at.After(s.now) {
+		s.now = ev.at
+	}
+	return ev
+}
+
+func (s *Simulation) exec(ev *simEvent) error {
+	switch {
+	case ev.inline != nil:
+		ev.inline()
+		return nil
+	case ev.task != nil:
+		return s.resume(s.spawn(ev.task))
+	default:
+		return s.resume(ev.resume)
+	}
+}
+
+// spawn starts a task for fn that waits to be resumed.
+func (s *Simulation) spawn(fn func()) *simTask {
+	t := &simTask{wake: make(chan struct{}, 1)}
+	go func() {
+		<-t.wake
+		defer s.exit(t)
+		fn()
+	}()
+	return t
+}
+
+// resume hands control to t and waits for it to finish or wait.
+func (s *Simulation) resume(t *simTask) error {
+	s.mu.Lock()
+	s.current = t
+	delete(s.parked, t)
+	s.steps++
+	if s.stall == nil {
+		s.stall = time.NewTimer(s.StallTimeout)
+	} else {
+		s.stall.Reset(s.StallTimeout)
+	}
+	stall := s.stall
+	s.mu.Unlock()
+
+	t.wake <- struct{}{}
+	select {
+	case <-s.yield:
+		if !stall.Stop() {
+			<-stall.C
+		}
+		return nil
+	case <-stall.C:
+		return ErrSimulationStalled
+	}
+}
+
+// park returns control to the simulation from the running task t and waits
+// until t is resumed.
+func (s *Simulation) park(t *simTask) {
+	s.mu.Lock()
+	s.current = nil
+	s.parked[t] = true
+	s.mu.Unlock()
+
+	s.yield <- struct{}{}
+	<-t.wake
+
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+	if stopped {
+		runtime.Goexit()
+	}
+}
+
+// exit returns control to the simulation when t finishes.
+func (s *Simulation) exit(t *simTask) {
+	s.mu.Lock()
+	if s.current == t {
+		s.current = nil
+	}
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if !stopped {
+		s.yield <- struct{}{}
+	}
+}
+
+// Waiter parks a task until another task wakes it or a virtual timeout
+// elapses. Simulated nodes wait for RPC replies with a Waiter.
+type Waiter struct {
+	s     *Simulation
+	task  *simTask
+	timer *simEvent
+	ch    chan struct{}
+	done  bool
+	value any
+	ok    bool
+}
+
+// NewWaiter returns a waiter that has not been woken.
+func (s *Simulation) NewWaiter() *Waiter {
+	return &Waiter{s: s, ch: make(chan struct{})}
+}
+
+// Wait waits until Wake is called or timeout elapses in virtual time. A
+// non-positive timeout waits indefinitely. Returns the value passed to Wake
+// and true, or false on timeout.
+//
+// Called from a task, Wait lets other tasks run in the meantime. Called from
+// outside the simulation, it simply blocks while the simulation runs.
+func (w *Waiter) Wait(timeout time.Duration) (any, bool) {
+	s := w.s
+	s.mu.Lock()
+	if !w.done && timeout > 0 {
+		w.timer = s.schedule(s.now.Add(timeout), &simEvent{inline: w.expire})
+	}
+	t := s.current
+	if !w.done && t != nil {
+		w.task = t
+		s.mu.Unlock()
+		s.park(t)
+		s.mu.Lock()
+	} else if !w.done {
+		s.mu.Unlock()
+		<-w.ch
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+	return w.value, w.ok
+}
+
+// Wake wakes the waiter with v. Returns false if it was already woken or
+// timed out.
+func (w *Waiter) Wake(v any) bool {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	if w.done {
+		return false
+	}
+	w.value, w.ok = v, true
+	w.finish()
+	return true
+}
+
+func (w *Waiter) expire() {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	if !w.done {
+		w.finish()
+	}
+}
+
+// finish marks the waiter done and resumes its task. Must hold lock.
+func (w *Waiter) finish() {
+	s := w.s
+	w.done = true
+	close(w.ch)
+	if w.timer != nil {
+		s.cancel(w.timer)
+	}
+	if w.task != nil {
+		s.schedule(s.now, &simEvent{resume: w.task})
+	}
+}
+
+// simClock is the Clock of a Simulation.
+type simClock struct{ s *Simulation }
+
+func (c simClock) Now() time.Time                  { return c.s.Now() }
+func (c simClock) Since(t time.Time) time.Duration { return c.s.Now().Sub(t) }
+
+// Sleep waits for d of virtual time, letting other tasks run.
+func (c simClock) Sleep(d time.Duration) {
+	if d > 0 {
+		c.s.NewWaiter().Wait(d)
+	}
+}
+
+// After returns a channel that receives the virtual time once d has
+// elapsed. Tasks must not block on it; see Simulation.
+func (c simClock) After(d time.Duration) <-chan time.Time { return c.NewTimer(d).C() }
+
+// AfterFunc runs f as a task once d has elapsed.
+func (c simClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &simTimer{s: c.s, fn: f}
+	t.Reset(d)
+	return t
+}
+
+func (c simClock) NewTimer(d time.Duration) Timer {
+	t := &simTimer{s: c.s, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c simClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &simTimer{s: c.s, ch: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return &simTicker{t}
+}
+
+// simTimer implements Timer for the simulation's clock and backs its
+// tickers.
+type simTimer struct {
+	s      *Simulation
+	ev     *simEvent
+	ch     chan time.Time
+	fn     func()
+	period time.Duration
+}
+
+func (t *simTimer) C() <-chan time.Time { return t.ch }
+
+func (t *simTimer) Stop() bool {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	return t.ev != nil && t.s.cancel(t.ev)
+}
+
+func (t *simTimer) Reset(d time.Duration) bool {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := t.ev != nil && s.cancel(t.ev)
+	if t.period > 0 {
+		t.period = d
+	}
+	t.ev = &simEvent{task: t.fn}
+	if t.fn == nil {
+		t.ev = &simEvent{inline: t.fire}
+	}
+	s.schedule(s.now.Add(d), t.ev)
+	return active
+}
+
+// fire delivers a tick and rearms a ticker.
+func (t *simTimer) fire() {
+	s := t.s
+	s.mu.Lock()
+	now := s.now
+	if t.period > 0 {
+		t.ev = s.schedule(now.Add(t.period), &simEvent{inline: t.fire})
+	}
+	s.mu.Unlock()
+
+	// Drop the tick if the receiver is not keeping up, like time.Ticker.
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
+
+// simTicker adapts a periodic simTimer to the Ticker interface.
+type simTicker struct{ t *simTimer }
+
+func (t *simTicker) C() <-chan time.Time   { return t.t.ch }
+func (t *simTicker) Stop()                 { t.t.Stop() }
+func (t *simTicker) Reset(d time.Duration) { t.t.Reset(d) }
+
+// simEvents is a priority queue of events ordered by time, then by a random
+// priority so that simultaneous events run in an order chosen by the seed.
+type simEvents []*simEvent
+
+func (q simEvents) Len() int { return len(q) }
+
+func (q simEvents) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	} else if q[i].prio != q[j].prio {
+		return q[i].prio < q[j].prio
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q simEvents) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *simEvents) Push(x any) {
+	ev := x.(*simEvent)
+	ev.index = len(*q)
+	*q = append(*q, ev)
+}
+
+func (q *simEvents) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	old[len(old)-1] = nil
+	ev.index = -1
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,156 @@
+package maelstrom_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Ensure a seed fixes the interleaving of tasks and different seeds explore
+// different ones.
+func TestSimulation_Run(t *testing.T) {
+	trace := func(seed int64) []string {
+		s := maelstrom.NewSimulation(seed)
+		defer s.Close()
+
+		var events []string
+		if err := s.Run(func() {
+			done := s.NewWaiter()
+			left := 5
+			for i := 0; i < 5; i++ {
+				i := i
+				s.Go(func() {
+					for j := 0; j < 3; j++ {
+						events = append(events, fmt.Sprintf("%d.%d@%s", i, j, s.Now().Sub(maelstrom.SimulationEpoch)))
+						s.Clock().Sleep(time.Duration(s.Rand().Intn(3)) * time.Second)
+					}
+					if left--; left == 0 {
+						done.Wake(nil)
+					}
+				})
+			}
+			done.Wait(0)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	a := trace(1)
+	if got := trace(1); !reflect.DeepEqual(got, a) {
+		t.Fatalf("seed 1 replayed as %v, want %v", got, a)
+	} else if len(a) != 15 {
+		t.Fatalf("events=%v", a)
+	}
+	for seed := int64(2); reflect.DeepEqual(trace(seed), a); seed++ {
+		if seed == 20 {
+			t.Fatal("every seed produced the same interleaving")
+		}
+	}
+}
+
+// Ensure waiters time out in virtual time and the simulation reports tasks
+// that can never be woken.
+func TestSimulation_Waiter(t *testing.T) {
+	s := maelstrom.NewSimulation(1)
+	defer s.Close()
+
+	if err := s.Run(func() {
+		w := s.NewWaiter()
+		if _, ok := w.Wait(time.Minute); ok {
+			t.Error("expected timeout")
+		} else if got, want := s.Now(), maelstrom.SimulationEpoch.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("now=%s, want %s", got, want)
+		} else if w.Wake(1) {
+			t.Error("expected expired waiter to ignore Wake")
+		}
+
+		w = s.NewWaiter()
+		s.Clock().AfterFunc(time.Second, func() { w.Wake("ok") })
+		if v, ok := w.Wait(time.Minute); !ok || v != "ok" {
+			t.Errorf("wait=%v, %v", v, ok)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Run(func() { s.NewWaiter().Wait(0) }); !errors.Is(err, maelstrom.ErrSimulationDeadlock) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure Every runs periodic work in virtual time until canceled.
+func TestEvery(t *testing.T) {
+	s := maelstrom.NewSimulation(1)
+	defer s.Close()
+
+	var ticks []time.Duration
+	if err := s.Run(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		maelstrom.Every(ctx, s.Clock(), time.Second, func() {
+			ticks = append(ticks, s.Now().Sub(maelstrom.SimulationEpoch))
+		})
+		s.Clock().Sleep(3500 * time.Millisecond)
+		cancel()
+		s.Clock().Sleep(5 * time.Second)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ticks, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ticks=%v, want %v", got, want)
+	}
+}
+
+// Ensure a simulated node is stepped through STDIN and reports when its next
+// timer is due.
+func TestNode_Run_Simulation(t *testing.T) {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	n := maelstrom.NewNode()
+	n.Simulate(maelstrom.NewSimulation(1))
+	n.Stdin, n.Stdout = inr, outw
+	n.Handle("sleep", func(msg maelstrom.Message) error {
+		n.Clock().Sleep(time.Second)
+		return n.Reply(msg, map[string]any{"type": "sleep_ok"})
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- n.Run() }()
+	stdout := bufio.NewReader(outr)
+
+	step := func(line string, want ...string) {
+		t.Helper()
+		if _, err := inw.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range want {
+			if got, err := stdout.ReadString('\n'); err != nil {
+				t.Fatal(err)
+			} else if got != w+"\n" {
+				t.Fatalf("output=%s, want %s", got, w)
+			}
+		}
+	}
+	step(`{"sim_time":0,"msg":{"src":"c1","dest":"n1","body":{"type":"init","msg_id":1,"node_id":"n1","node_ids":["n1"]}}}`,
+		`{"src":"n1","dest":"c1","body":{"in_reply_to":1,"type":"init_ok"}}`,
+		`{"sim_idle":true,"sim_next":-1}`)
+	step(`{"sim_time":500000000,"msg":{"src":"c1","dest":"n1","body":{"type":"sleep","msg_id":2}}}`,
+		`{"sim_idle":true,"sim_next":1500000000}`)
+	step(`{"sim_time":1000000000}`,
+		`{"sim_idle":true,"sim_next":1500000000}`)
+	step(`{"sim_time":1500000000}`,
+		`{"src":"n1","dest":"c1","body":{"in_reply_to":2,"type":"sleep_ok"}}`,
+		`{"sim_idle":true,"sim_next":-1}`)
+
+	inw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
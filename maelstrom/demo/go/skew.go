@@ -0,0 +1,111 @@
+package maelstrom
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClockSkewFileEnv names a file holding a clock offset and optional drift
+// rate, such as "250ms" or "-2s 1.05". When set, NewNode() gives the node a
+// SkewedClock that follows the file, so a test harness can skew the clocks
+// of node binaries while they run. A missing or empty file means no skew.
+const ClockSkewFileEnv = "MAELSTROM_CLOCK_SKEW_FILE"
+
+// skewReloadInterval is how often a watched skew file is checked for changes.
+const skewReloadInterval = 10 * time.Millisecond
+
+// skewFile tracks the file a SkewedClock follows.
+type skewFile struct {
+	mu      sync.Mutex
+	path    string
+	checked time.Time
+	modTime time.Time
+	size    int64
+}
+
+// WatchFile makes the clock follow the offset & rate written to path, in
+// the format of ClockSkewFileEnv. The file is rechecked at most every 10ms.
+func (c *SkewedClock) WatchFile(path string) {
+	c.mu.Lock()
+	c.file = &skewFile{path: path, size: -1}
+	c.mu.Unlock()
+	c.reload()
+}
+
+// reload applies the watched file, if it changed.
+func (c *SkewedClock) reload() {
+	c.mu.Lock()
+	f := c.file
+	c.mu.Unlock()
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := c.base.Now()
+	if now.Sub(f.checked) < skewReloadInterval {
+		return
+	}
+	f.checked = now
+
+	var buf []byte
+	fi, err := os.Stat(f.path)
+	switch {
+	case os.IsNotExist(err):
+		if f.size == 0 && f.modTime.IsZero() {
+			return
+		}
+		f.modTime, f.size = time.Time{}, 0
+	case err != nil:
+		log.Printf("clock skew: %s", err)
+		return
+	case fi.ModTime().Equal(f.modTime) && fi.Size() == f.size:
+		return
+	default:
+		f.modTime, f.size = fi.ModTime(), fi.Size()
+		if buf, err = os.ReadFile(f.path); err != nil {
+			log.Printf("clock skew: %s", err)
+			return
+		}
+	}
+
+	offset, rate, err := ParseClockSkew(string(buf))
+	if err != nil {
+		log.Printf("clock skew: %s: %s", f.path, err)
+		return
+	}
+	c.SetOffset(offset)
+	c.SetRate(rate)
+}
+
+// ParseClockSkew parses an offset and optional drift rate in the format of
+// ClockSkewFileEnv. The blank string is no skew.
+func ParseClockSkew(s string) (offset time.Duration, rate float64, err error) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 0:
+		return 0, 1, nil
+	case 1, 2:
+	default:
+		return 0, 0, fmt.Errorf("invalid clock skew %q", s)
+	}
+
+	if offset, err = time.ParseDuration(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	rate = 1
+	if len(fields) == 2 {
+		if rate, err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return 0, 0, err
+		} else if rate <= 0 {
+			return 0, 0, fmt.Errorf("non-positive clock rate %v", rate)
+		}
+	}
+	return offset, rate, nil
+}
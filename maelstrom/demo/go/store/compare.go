@@ -0,0 +1,176 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tolerance bounds how much worse a run may be than its baseline before a
+// metric counts as regressed. Latency & Msgs are fractions of the baseline
+// value, Errors is an absolute increase in the error rate.
+type Tolerance struct {
+	Latency float64
+	Msgs    float64
+	Errors  float64
+}
+
+// DefaultTolerance allows for the noise of short Maelstrom runs.
+var DefaultTolerance = Tolerance{Latency: 0.10, Msgs: 0.10, Errors: 0.01}
+
+// Comparison is the result of comparing a candidate run with a baseline.
+type Comparison struct {
+	Baseline  Summary
+	Candidate Summary
+	Metrics   []Metric
+}
+
+// Metric compares a single figure. Lower is better for every metric.
+type Metric struct {
+	Name      string
+	Baseline  string
+	Candidate string
+
+	// Change is the relative change, or the absolute change for rates.
+	// It is empty where there's nothing to compute.
+	Change string
+
+	// Checked is false for metrics that are reported but too noisy to
+	// fail a comparison.
+	Checked   bool
+	Regressed bool
+}
+
+// Compare compares a candidate run against a baseline.
+func Compare(baseline, candidate Summary, tol Tolerance) Comparison {
+	c := Comparison{Baseline: baseline, Candidate: candidate}
+
+	c.Metrics = append(c.Metrics, Metric{
+		Name:      "validity",
+		Baseline:  string(baseline.Valid),
+		Candidate: string(candidate.Valid),
+		Checked:   true,
+		Regressed: baseline.Valid == Valid && candidate.Valid != Valid,
+	})
+
+	// A baseline without ok operations has no latencies to compare against.
+	latency := func(name string, base, cand time.Duration, checked bool) {
+		checked = checked && baseline.OK > 0
+		c.Metrics = append(c.Metrics, Metric{
+			Name:      name,
+			Baseline:  base.Round(time.Microsecond).String(),
+			Candidate: cand.Round(time.Microsecond).String(),
+			Change:    relativeChange(float64(base), float64(cand)),
+			Checked:   checked,
+			Regressed: checked && float64(cand) > float64(base)*(1+tol.Latency),
+		})
+	}
+	latency("p50 latency", baseline.Latency.P50, candidate.Latency.P50, true)
+	latency("p95 latency", baseline.Latency.P95, candidate.Latency.P95, true)
+	latency("p99 latency", baseline.Latency.P99, candidate.Latency.P99, true)
+	latency("max latency", baseline.Latency.Max, candidate.Latency.Max, false)
+
+	// Every operation sends messages, so a baseline without any has no
+	// results and its message counts are missing rather than zero.
+	hasResults := baseline.MsgsPerOp > 0
+	msgs := func(name string, base, cand float64) {
+		c.Metrics = append(c.Metrics, Metric{
+			Name:      name,
+			Baseline:  fmt.Sprintf("%.2f", base),
+			Candidate: fmt.Sprintf("%.2f", cand),
+			Change:    relativeChange(base, cand),
+			Checked:   hasResults,
+			Regressed: hasResults && cand > base*(1+tol.Msgs),
+		})
+	}
+	msgs("msgs/op", baseline.MsgsPerOp, candidate.MsgsPerOp)
+	msgs("server msgs/op", baseline.ServerMsgsPerOp, candidate.ServerMsgsPerOp)
+
+	base, cand := baseline.ErrorRate(), candidate.ErrorRate()
+	c.Metrics = append(c.Metrics, Metric{
+		Name:      "error rate",
+		Baseline:  fmt.Sprintf("%.2f%%", 100*base),
+		Candidate: fmt.Sprintf("%.2f%%", 100*cand),
+		Change:    fmt.Sprintf("%+.2fpp", 100*(cand-base)),
+		Checked:   baseline.Ops > 0,
+		Regressed: baseline.Ops > 0 && cand > base+tol.Errors,
+	})
+	return c
+}
+
+// ErrorRate returns the fraction of operations that did not succeed, or zero
+// if there were no operations.
+func (s Summary) ErrorRate() float64 {
+	if s.Ops == 0 {
+		return 0
+	}
+	return 1 - s.Availability
+}
+
+// Regressions returns the metrics that regressed.
+func (c Comparison) Regressions() []Metric {
+	var a []Metric
+	for _, m := range c.Metrics {
+		if m.Regressed {
+			a = append(a, m)
+		}
+	}
+	return a
+}
+
+// Markdown renders the comparison as a Markdown table.
+func (c Comparison) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "| Metric | Baseline | Candidate | Change | |\n")
+	fmt.Fprintf(&sb, "|---|---:|---:|---:|---|\n")
+	for _, m := range c.Metrics {
+		status := ""
+		if m.Regressed {
+			status = "regressed"
+		} else if !m.Checked {
+			status = "not checked"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", m.Name, m.Baseline, m.Candidate, m.Change, status)
+	}
+	return sb.String()
+}
+
+// relativeChange formats the change from base to cand as a percentage.
+func relativeChange(base, cand float64) string {
+	if base == 0 {
+		if cand == 0 {
+			return "+0.0%"
+		}
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", 100*(cand-base)/base)
+}
+
+// ReadSummary summarizes the run in a store directory or reads a summary
+// saved as JSON, such as the output of "distsys report -json".
+func ReadSummary(path string) (Summary, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	if fi.IsDir() {
+		run, err := Open(path)
+		if err != nil {
+			return Summary{}, err
+		}
+		return run.Summary(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Summary{}, err
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Summary{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
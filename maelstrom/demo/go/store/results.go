@@ -0,0 +1,184 @@
+package store
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+)
+
+// Validity is the outcome of a checker: Jepsen reports true, false or
+// :unknown.
+type Validity string
+
+const (
+	Valid   Validity = "valid"
+	Invalid Validity = "invalid"
+	Unknown Validity = "unknown"
+)
+
+// Results are the checker results in results.edn.
+type Results struct {
+	Valid        Validity
+	Stats        Stats
+	Availability Availability
+	Net          Net
+
+	// Raw holds the whole results map, including the results of
+	// workload-specific checkers.
+	Raw map[any]any
+}
+
+// Stats counts operations by completion type.
+type Stats struct {
+	Valid     Validity
+	Count     int64
+	OKCount   int64
+	FailCount int64
+	InfoCount int64
+
+	// ByF holds the stats of each operation function.
+	ByF map[string]Stats
+}
+
+// Availability is the fraction of operations that succeeded.
+type Availability struct {
+	Valid      Validity
+	OKFraction float64
+}
+
+// Net holds network statistics for all messages and for those sent between
+// clients & servers and between servers.
+type Net struct {
+	Valid   Validity
+	All     NetStats
+	Clients NetStats
+	Servers NetStats
+}
+
+// NetStats counts messages. MsgsPerOp is zero where Maelstrom doesn't report
+// it.
+type NetStats struct {
+	SendCount int64
+	RecvCount int64
+	MsgCount  int64
+	MsgsPerOp float64
+}
+
+// ReadResults reads a results.edn file.
+func ReadResults(path string) (*Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ParseResults(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return res, nil
+}
+
+// ParseResults parses the EDN results map written by Maelstrom.
+func ParseResults(data []byte) (*Results, error) {
+	v, err := edn.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("results are %T, not a map", v)
+	}
+
+	net := field(m, "net")
+	return &Results{
+		Valid: validity(m),
+		Stats: stats(field(m, "stats")),
+		Availability: Availability{
+			Valid:      validity(field(m, "availability")),
+			OKFraction: floatField(field(m, "availability"), "ok-fraction"),
+		},
+		Net: Net{
+			Valid:   validity(net),
+			All:     netStats(field(net, "all")),
+			Clients: netStats(field(net, "clients")),
+			Servers: netStats(field(net, "servers")),
+		},
+		Raw: m,
+	}, nil
+}
+
+func stats(m map[any]any) Stats {
+	s := Stats{
+		Valid:     validity(m),
+		Count:     intField(m, "count"),
+		OKCount:   intField(m, "ok-count"),
+		FailCount: intField(m, "fail-count"),
+		InfoCount: intField(m, "info-count"),
+	}
+	if byF, ok := m[edn.Keyword("by-f")].(map[any]any); ok {
+		s.ByF = make(map[string]Stats, len(byF))
+		for f, v := range byF {
+			sub, _ := v.(map[any]any)
+			s.ByF[name(f)] = stats(sub)
+		}
+	}
+	return s
+}
+
+func netStats(m map[any]any) NetStats {
+	return NetStats{
+		SendCount: intField(m, "send-count"),
+		RecvCount: intField(m, "recv-count"),
+		MsgCount:  intField(m, "msg-count"),
+		MsgsPerOp: floatField(m, "msgs-per-op"),
+	}
+}
+
+// validity returns the :valid? entry of a checker result. Missing results
+// are unknown.
+func validity(m map[any]any) Validity {
+	switch m[edn.Keyword("valid?")] {
+	case true:
+		return Valid
+	case false:
+		return Invalid
+	}
+	return Unknown
+}
+
+// field returns a nested map, or nil if there isn't one.
+func field(m map[any]any, key string) map[any]any {
+	v, _ := m[edn.Keyword(key)].(map[any]any)
+	return v
+}
+
+func intField(m map[any]any, key string) int64 {
+	switch v := m[edn.Keyword(key)].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func floatField(m map[any]any, key string) float64 {
+	switch v := m[edn.Keyword(key)].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// name returns a keyword or other value as a string.
+func name(v any) string {
+	switch v := v.(type) {
+	case edn.Keyword:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
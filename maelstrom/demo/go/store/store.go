@@ -0,0 +1,127 @@
+// Package store reads the artifacts Maelstrom writes for each test run to
+// store/<test>/<timestamp>/: the history (history.edn), the checker results
+// (results.edn), the node logs (node-logs/) and the command line recorded in
+// jepsen.log. test.jepsen is a Fressian serialization of the whole test and
+// is not read.
+package store
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Run is a single test run.
+type Run struct {
+	// Dir is the run's directory, with symlinks such as store/latest
+	// resolved.
+	Dir string
+
+	// Name of the test, e.g. "broadcast", and the run's timestamp, taken
+	// from the directory structure.
+	Name      string
+	Timestamp string
+
+	// Command is the command line the test was run with.
+	Command string
+
+	History []history.Op
+
+	// Results are nil if the run did not complete.
+	Results *Results
+
+	// NodeLogs maps node IDs to the paths of their logs.
+	NodeLogs map[string]string
+}
+
+// Open reads the run in dir.
+func Open(dir string) (*Run, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		Dir:       dir,
+		Name:      filepath.Base(filepath.Dir(dir)),
+		Timestamp: filepath.Base(dir),
+		NodeLogs:  make(map[string]string),
+	}
+
+	if run.History, err = history.ReadFile(filepath.Join(dir, "history.edn")); err != nil {
+		return nil, err
+	}
+
+	if run.Results, err = ReadResults(filepath.Join(dir, "results.edn")); errors.Is(err, os.ErrNotExist) {
+		run.Results = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if run.Command, err = readCommand(filepath.Join(dir, "jepsen.log")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	logs, err := filepath.Glob(filepath.Join(dir, "node-logs", "*.log"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range logs {
+		run.NodeLogs[strings.TrimSuffix(filepath.Base(path), ".log")] = path
+	}
+	return run, nil
+}
+
+// List returns the directories of all runs under a store directory, oldest
+// first. The latest & current symlinks are skipped.
+func List(root string) ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []string
+	for _, dir := range dirs {
+		if fi, err := os.Lstat(dir); err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			continue // symlink or stray file
+		} else if _, err := os.Stat(filepath.Join(dir, "history.edn")); err != nil {
+			continue
+		}
+		runs = append(runs, dir)
+	}
+
+	// Timestamps sort chronologically, so order by them across tests.
+	sort.SliceStable(runs, func(i, j int) bool {
+		return filepath.Base(runs[i]) < filepath.Base(runs[j])
+	})
+	return runs, nil
+}
+
+// readCommand returns the command line logged at the start of jepsen.log.
+func readCommand(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.HasSuffix(scanner.Text(), "Command line:") && scanner.Scan() {
+			return scanner.Text(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return "", nil
+}
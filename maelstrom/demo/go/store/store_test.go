@@ -0,0 +1,201 @@
+package store_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/store"
+)
+
+// Ensure a run is read through the latest symlink and summarized.
+func TestOpen(t *testing.T) {
+	run, err := store.Open("testdata/echo/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := run.Name, "echo"; got != want {
+		t.Fatalf("name=%q, want %q", got, want)
+	} else if got, want := run.Timestamp, "20251221T153451.630-0800"; got != want {
+		t.Fatalf("timestamp=%q, want %q", got, want)
+	} else if got, want := run.Command, "lein run test -w echo --bin /root/go/bin/maelstrom-echo --node-count 1 --time-limit 10"; got != want {
+		t.Fatalf("command=%q, want %q", got, want)
+	} else if got, want := len(run.History), 92; got != want {
+		t.Fatalf("len(history)=%d, want %d", got, want)
+	} else if _, ok := run.NodeLogs["n0"]; !ok || len(run.NodeLogs) != 1 {
+		t.Fatalf("unexpected node logs: %v", run.NodeLogs)
+	}
+
+	res := run.Results
+	if res == nil {
+		t.Fatal("expected results")
+	} else if got, want := res.Stats.ByF["echo"].OKCount, int64(46); got != want {
+		t.Fatalf("echo ok-count=%d, want %d", got, want)
+	} else if got, want := res.Net, (store.Net{
+		Valid:   store.Valid,
+		All:     store.NetStats{SendCount: 94, RecvCount: 94, MsgCount: 94, MsgsPerOp: 2.0434783},
+		Clients: store.NetStats{SendCount: 94, RecvCount: 94, MsgCount: 94},
+		Servers: store.NetStats{},
+	}); got != want {
+		t.Fatalf("net=%+v, want %+v", got, want)
+	}
+
+	want := store.Summary{
+		Name:         "echo",
+		Timestamp:    "20251221T153451.630-0800",
+		Valid:        store.Valid,
+		Ops:          46,
+		OK:           46,
+		Availability: 1,
+		MsgsPerOp:    2.0434783,
+		Latency: store.Latency{
+			P50: 2244992 * time.Nanosecond,
+			P95: 2954088 * time.Nanosecond,
+			P99: 16523015 * time.Nanosecond,
+			Max: 16523015 * time.Nanosecond,
+		},
+	}
+	if got := run.Summary(); got != want {
+		t.Fatalf("summary=%+v, want %+v", got, want)
+	}
+}
+
+func TestList(t *testing.T) {
+	runs, err := store.List("testdata")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := runs, []string{"testdata/echo/20251221T153451.630-0800"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("runs=%v, want %v", got, want)
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	t.Run("Unknown", func(t *testing.T) {
+		res, err := store.ParseResults([]byte(`{:valid? :unknown, :stats {:valid? false, :count 3}}`))
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := res.Valid, store.Unknown; got != want {
+			t.Fatalf("valid=%q, want %q", got, want)
+		} else if got, want := res.Stats.Valid, store.Invalid; got != want {
+			t.Fatalf("stats valid=%q, want %q", got, want)
+		} else if got, want := res.Availability.Valid, store.Unknown; got != want {
+			t.Fatalf("availability valid=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("NotMap", func(t *testing.T) {
+		if _, err := store.ParseResults([]byte(`[1 2]`)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestQuantiles(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i))
+	}
+	if got, want := store.Quantiles(latencies), (store.Latency{P50: 50, P95: 95, P99: 99, Max: 100}); got != want {
+		t.Fatalf("quantiles=%+v, want %+v", got, want)
+	} else if got := store.Quantiles(nil); got != (store.Latency{}) {
+		t.Fatalf("quantiles of nothing=%+v", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := store.Summary{
+		Valid:        store.Valid,
+		Ops:          100,
+		OK:           100,
+		Availability: 1,
+		MsgsPerOp:    10,
+		Latency:      store.Latency{P50: 100 * time.Millisecond, P95: 200 * time.Millisecond, P99: 300 * time.Millisecond, Max: time.Second},
+	}
+
+	t.Run("WithinTolerance", func(t *testing.T) {
+		candidate := baseline
+		candidate.Latency.P99 = 320 * time.Millisecond
+		candidate.Latency.Max = 5 * time.Second // not checked
+		candidate.MsgsPerOp = 10.5
+		if c := store.Compare(baseline, candidate, store.DefaultTolerance); len(c.Regressions()) != 0 {
+			t.Fatalf("unexpected regressions:\n%s", c.Markdown())
+		}
+	})
+
+	t.Run("Regressed", func(t *testing.T) {
+		candidate := baseline
+		candidate.Valid = store.Unknown
+		candidate.Latency.P50 = 150 * time.Millisecond
+		candidate.Availability = 0.95
+		candidate.MsgsPerOp = 20
+
+		c := store.Compare(baseline, candidate, store.DefaultTolerance)
+		var names []string
+		for _, m := range c.Regressions() {
+			names = append(names, m.Name)
+		}
+		if got, want := names, []string{"validity", "p50 latency", "msgs/op", "error rate"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("regressions=%v, want %v", got, want)
+		}
+
+		if got, want := c.Markdown(), "| Metric | Baseline | Candidate | Change | |\n"+
+			"|---|---:|---:|---:|---|\n"+
+			"| validity | valid | unknown |  | regressed |\n"+
+			"| p50 latency | 100ms | 150ms | +50.0% | regressed |\n"+
+			"| p95 latency | 200ms | 200ms | +0.0% |  |\n"+
+			"| p99 latency | 300ms | 300ms | +0.0% |  |\n"+
+			"| max latency | 1s | 1s | +0.0% | not checked |\n"+
+			"| msgs/op | 10.00 | 20.00 | +100.0% | regressed |\n"+
+			"| server msgs/op | 0.00 | 0.00 | +0.0% |  |\n"+
+			"| error rate | 0.00% | 5.00% | +5.00pp | regressed |\n"; got != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	// A baseline whose results.edn is missing has no message counts, and one
+	// without operations has no latencies or error rate.
+	t.Run("MissingBaseline", func(t *testing.T) {
+		for _, empty := range []store.Summary{{Valid: store.Unknown}, {Valid: store.Unknown, Ops: 100, OK: 100, Availability: 1, Latency: baseline.Latency}} {
+			c := store.Compare(empty, baseline, store.DefaultTolerance)
+			if got := c.Regressions(); len(got) != 0 {
+				t.Fatalf("unexpected regressions:\n%s", c.Markdown())
+			}
+			for _, m := range c.Metrics {
+				if m.Name == "msgs/op" && m.Checked {
+					t.Fatalf("msgs/op checked:\n%s", c.Markdown())
+				}
+			}
+		}
+
+		if got := (store.Summary{}).ErrorRate(); got != 0 {
+			t.Fatalf("error rate=%v, want 0", got)
+		}
+	})
+}
+
+// Ensure summaries saved by "distsys report -json" can be compared.
+func TestReadSummary(t *testing.T) {
+	want, err := store.ReadSummary("testdata/echo/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := store.ReadSummary(path); err != nil {
+		t.Fatal(err)
+	} else if got != want {
+		t.Fatalf("summary=%+v, want %+v", got, want)
+	}
+}
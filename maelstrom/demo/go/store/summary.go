@@ -0,0 +1,103 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Summary condenses a run into the figures worth tracking across runs.
+type Summary struct {
+	Name      string   `json:"name"`
+	Timestamp string   `json:"timestamp"`
+	Valid     Validity `json:"valid"`
+
+	Ops  int `json:"ops"`
+	OK   int `json:"ok"`
+	Fail int `json:"fail"`
+	Info int `json:"info"`
+
+	// Availability is the fraction of operations that succeeded.
+	Availability float64 `json:"availability"`
+
+	// MsgsPerOp counts all messages, ServerMsgsPerOp only those exchanged
+	// between servers. Both are zero if the run has no results.
+	MsgsPerOp       float64 `json:"msgs_per_op"`
+	ServerMsgsPerOp float64 `json:"server_msgs_per_op"`
+
+	// Latency of ok operations.
+	Latency Latency `json:"latency"`
+}
+
+// Latency holds quantiles of operation latency.
+type Latency struct {
+	P50 time.Duration `json:"p50_ns"`
+	P95 time.Duration `json:"p95_ns"`
+	P99 time.Duration `json:"p99_ns"`
+	Max time.Duration `json:"max_ns"`
+}
+
+// Summary summarizes the run. Counts and latencies are computed from the
+// history; validity and message counts come from the results.
+func (r *Run) Summary() Summary {
+	s := Summary{
+		Name:      r.Name,
+		Timestamp: r.Timestamp,
+		Valid:     Unknown,
+	}
+
+	var latencies []time.Duration
+	for _, p := range history.Pairs(r.History) {
+		s.Ops++
+		switch p.Complete.Type {
+		case history.OK:
+			s.OK++
+			latencies = append(latencies, time.Duration(p.Complete.Time-p.Invoke.Time))
+		case history.Fail:
+			s.Fail++
+		default:
+			s.Info++
+		}
+	}
+	s.Latency = Quantiles(latencies)
+	if s.Ops > 0 {
+		s.Availability = float64(s.OK) / float64(s.Ops)
+	}
+
+	if res := r.Results; res != nil {
+		s.Valid = res.Valid
+		s.MsgsPerOp = res.Net.All.MsgsPerOp
+		s.ServerMsgsPerOp = res.Net.Servers.MsgsPerOp
+		if res.Availability.Valid != Unknown {
+			s.Availability = res.Availability.OKFraction
+		}
+	}
+	return s
+}
+
+// Quantiles returns latency quantiles by the nearest-rank method.
+func Quantiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	q := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		} else if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+		return sorted[i]
+	}
+	return Latency{
+		P50: q(0.50),
+		P95: q(0.95),
+		P99: q(0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,180 @@
+package topology
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// maxAttempts bounds retries for randomized generators.
+const maxAttempts = 1000
+
+// SpanningTree returns a breadth-first spanning tree of g rooted at root.
+// Nodes unreachable from root are left without neighbors.
+func SpanningTree(g Graph, root string) Graph {
+	t := newGraph(g.Nodes())
+	seen := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range g[id] {
+			if !seen[neighbor] {
+				seen[neighbor] = true
+				t.addEdge(id, neighbor)
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return t
+}
+
+// Tree returns a k-ary tree over nodes in the given order. The first node is
+// the root and node i's parent is node (i-1)/k. A tree has the fewest edges
+// of any connected overlay; larger k trades fan-out for a smaller diameter.
+func Tree(nodes []string, k int) Graph {
+	if k < 1 {
+		k = 1
+	}
+	g := newGraph(nodes)
+	for i := 1; i < len(nodes); i++ {
+		g.addEdge(nodes[(i-1)/k], nodes[i])
+	}
+	return g
+}
+
+// Grid returns the square grid Maelstrom supplies by default: nodes fill rows
+// of width ceil(sqrt(n)) in order and each is linked to the nodes above,
+// below, left and right of it.
+func Grid(nodes []string) Graph {
+	width := int(math.Ceil(math.Sqrt(float64(len(nodes)))))
+	g := newGraph(nodes)
+	for i := range nodes {
+		if i%width > 0 {
+			g.addEdge(nodes[i-1], nodes[i])
+		}
+		if i >= width {
+			g.addEdge(nodes[i-width], nodes[i])
+		}
+	}
+	return g
+}
+
+// Star returns a star with the first hubs nodes fully connected as hubs and
+// every other node attached to a single hub, round-robin. Any message reaches
+// every node within three hops.
+func Star(nodes []string, hubs int) Graph {
+	if hubs < 1 {
+		hubs = 1
+	} else if hubs > len(nodes) {
+		hubs = len(nodes)
+	}
+	g := newGraph(nodes)
+	for i := 0; i < hubs; i++ {
+		for j := i + 1; j < hubs; j++ {
+			g.addEdge(nodes[i], nodes[j])
+		}
+	}
+	for i := hubs; i < len(nodes); i++ {
+		g.addEdge(nodes[i%hubs], nodes[i])
+	}
+	return g
+}
+
+// RandomRegular returns a connected random graph in which every node has
+// exactly degree neighbors. len(nodes)*degree must be even and degree must be
+// less than len(nodes).
+func RandomRegular(nodes []string, degree int, seed int64) (Graph, error) {
+	n := len(nodes)
+	if degree < 1 || degree >= n {
+		return nil, fmt.Errorf("degree %d out of range for %d nodes", degree, n)
+	} else if n*degree%2 != 0 {
+		return nil, fmt.Errorf("%d nodes cannot all have odd degree %d", n, degree)
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if g, ok := randomRegular(nodes, degree, rnd); ok && g.Connected() {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("no connected %d-regular graph found", degree)
+}
+
+// randomRegular pairs up node "stubs" at random, giving up if it reaches a
+// state where the remaining stubs can't be paired without self-loops or
+// duplicate edges.
+func randomRegular(nodes []string, degree int, rnd *rand.Rand) (Graph, bool) {
+	g := newGraph(nodes)
+	stubs := make([]string, 0, len(nodes)*degree)
+	for _, id := range nodes {
+		for i := 0; i < degree; i++ {
+			stubs = append(stubs, id)
+		}
+	}
+
+	for len(stubs) > 0 {
+		paired := false
+		for try := 0; try < 100 && !paired; try++ {
+			i, j := rnd.Intn(len(stubs)), rnd.Intn(len(stubs))
+			a, b := stubs[i], stubs[j]
+			if i == j || a == b || g.HasEdge(a, b) {
+				continue
+			}
+			g.addEdge(a, b)
+
+			// Remove both stubs, higher index first.
+			if i < j {
+				i, j = j, i
+			}
+			stubs = append(stubs[:i], stubs[i+1:]...)
+			stubs = append(stubs[:j], stubs[j+1:]...)
+			paired = true
+		}
+		if !paired {
+			return nil, false
+		}
+	}
+	return g, true
+}
+
+// SmallWorld returns a connected Watts-Strogatz small-world graph: a ring in
+// which each node is linked to its k nearest neighbors, with each link
+// rewired to a random node with probability p. A few long-range links give a
+// diameter close to that of a random graph while most links stay local.
+func SmallWorld(nodes []string, k int, p float64, seed int64) (Graph, error) {
+	n := len(nodes)
+	if k < 2 || k%2 != 0 || k >= n {
+		return nil, fmt.Errorf("k must be even and between 2 and %d", n-1)
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		g := newGraph(nodes)
+		for i := 0; i < n; i++ {
+			for j := 1; j <= k/2; j++ {
+				g.addEdge(nodes[i], nodes[(i+j)%n])
+			}
+		}
+
+		for j := 1; j <= k/2; j++ {
+			for i := 0; i < n; i++ {
+				a, b := nodes[i], nodes[(i+j)%n]
+				if rnd.Float64() >= p || !g.HasEdge(a, b) {
+					continue
+				}
+				c := nodes[rnd.Intn(n)]
+				if c == a || g.HasEdge(a, c) {
+					continue
+				}
+				g.removeEdge(a, b)
+				g.addEdge(a, c)
+			}
+		}
+
+		if g.Connected() {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("no connected small-world graph found")
+}
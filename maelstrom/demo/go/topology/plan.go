@@ -0,0 +1,95 @@
+package topology
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Plan computes the overlay described by spec over nodes. supplied is the
+// topology sent by Maelstrom. Specs are a name optionally followed by
+// colon-separated parameters:
+//
+//	maelstrom           the supplied topology (also the blank spec)
+//	spanning            BFS spanning tree of the supplied topology
+//	grid                square grid, as Maelstrom supplies by default
+//	tree:K              K-ary tree (default 2)
+//	star:H              star with H hubs (default 1)
+//	random:D            random D-regular graph (default 3)
+//	small-world:K:P     Watts-Strogatz graph (default 4, 0.2)
+//
+// Randomized overlays are seeded from the node list, so every node computes
+// the same graph.
+func Plan(spec string, nodes []string, supplied Graph) (Graph, error) {
+	parts := strings.Split(spec, ":")
+	name, args := parts[0], parts[1:]
+
+	intArg := func(i, def int) (int, error) {
+		if i >= len(args) || args[i] == "" {
+			return def, nil
+		}
+		return strconv.Atoi(args[i])
+	}
+
+	switch name {
+	case "", "maelstrom":
+		return supplied, nil
+
+	case "spanning":
+		if len(nodes) == 0 {
+			return supplied, nil
+		}
+		return SpanningTree(supplied, nodes[0]), nil
+
+	case "grid":
+		return Grid(nodes), nil
+
+	case "tree":
+		k, err := intArg(0, 2)
+		if err != nil {
+			return nil, fmt.Errorf("topology %q: %w", spec, err)
+		}
+		return Tree(nodes, k), nil
+
+	case "star":
+		hubs, err := intArg(0, 1)
+		if err != nil {
+			return nil, fmt.Errorf("topology %q: %w", spec, err)
+		}
+		return Star(nodes, hubs), nil
+
+	case "random":
+		degree, err := intArg(0, 3)
+		if err != nil {
+			return nil, fmt.Errorf("topology %q: %w", spec, err)
+		}
+		return RandomRegular(nodes, degree, seed(nodes))
+
+	case "small-world":
+		k, err := intArg(0, 4)
+		if err != nil {
+			return nil, fmt.Errorf("topology %q: %w", spec, err)
+		}
+		p := 0.2
+		if len(args) > 1 {
+			if p, err = strconv.ParseFloat(args[1], 64); err != nil {
+				return nil, fmt.Errorf("topology %q: %w", spec, err)
+			}
+		}
+		return SmallWorld(nodes, k, p, seed(nodes))
+
+	default:
+		return nil, fmt.Errorf("unknown topology %q", spec)
+	}
+}
+
+// seed derives a random seed from the node list.
+func seed(nodes []string) int64 {
+	h := fnv.New64a()
+	for _, id := range nodes {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	return int64(h.Sum64())
+}
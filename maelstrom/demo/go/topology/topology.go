@@ -0,0 +1,167 @@
+// Package topology computes overlay networks for gossip & broadcast. Every
+// generator is deterministic for a given node list (and seed), so each node
+// can compute the same overlay locally from Node.NodeIDs() without
+// coordination.
+package topology
+
+import (
+	"sort"
+)
+
+// Graph is an undirected overlay, mapping each node to its sorted neighbors.
+// Every node appears as a key, even if it has no neighbors.
+type Graph map[string][]string
+
+// newGraph returns a graph containing nodes and no edges.
+func newGraph(nodes []string) Graph {
+	g := make(Graph, len(nodes))
+	for _, id := range nodes {
+		g[id] = nil
+	}
+	return g
+}
+
+// FromMaelstrom converts a topology as sent in Maelstrom's "topology" message
+// into a graph. Links are made symmetric.
+func FromMaelstrom(topology map[string][]string) Graph {
+	g := make(Graph, len(topology))
+	for id, neighbors := range topology {
+		if _, ok := g[id]; !ok {
+			g[id] = nil
+		}
+		for _, neighbor := range neighbors {
+			g.addEdge(id, neighbor)
+		}
+	}
+	return g
+}
+
+// Nodes returns the sorted node IDs in the graph.
+func (g Graph) Nodes() []string {
+	nodes := make([]string, 0, len(g))
+	for id := range g {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Neighbors returns a copy of the neighbors of id.
+func (g Graph) Neighbors(id string) []string {
+	return append([]string{}, g[id]...)
+}
+
+// HasEdge returns true if a and b are neighbors.
+func (g Graph) HasEdge(a, b string) bool {
+	for _, id := range g[a] {
+		if id == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Edges returns the number of undirected edges.
+func (g Graph) Edges() int {
+	n := 0
+	for _, neighbors := range g {
+		n += len(neighbors)
+	}
+	return n / 2
+}
+
+// FanOut returns the number of neighbors of each node.
+func (g Graph) FanOut() map[string]int {
+	m := make(map[string]int, len(g))
+	for id, neighbors := range g {
+		m[id] = len(neighbors)
+	}
+	return m
+}
+
+// MaxFanOut returns the largest number of neighbors of any node.
+func (g Graph) MaxFanOut() int {
+	n := 0
+	for _, neighbors := range g {
+		if len(neighbors) > n {
+			n = len(neighbors)
+		}
+	}
+	return n
+}
+
+// Connected returns true if every node can reach every other node.
+func (g Graph) Connected() bool {
+	for _, id := range g.Nodes() {
+		return len(g.distances(id)) == len(g)
+	}
+	return true
+}
+
+// Diameter returns the longest shortest path between any two nodes, in hops.
+// Returns -1 if the graph is disconnected.
+func (g Graph) Diameter() int {
+	diameter := 0
+	for id := range g {
+		dist := g.distances(id)
+		if len(dist) != len(g) {
+			return -1
+		}
+		for _, d := range dist {
+			if d > diameter {
+				diameter = d
+			}
+		}
+	}
+	return diameter
+}
+
+// distances returns the hop count from src to every reachable node.
+func (g Graph) distances(src string) map[string]int {
+	dist := map[string]int{src: 0}
+	queue := []string{src}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range g[id] {
+			if _, ok := dist[neighbor]; !ok {
+				dist[neighbor] = dist[id] + 1
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return dist
+}
+
+// addEdge links a & b, keeping neighbor lists sorted & unique.
+func (g Graph) addEdge(a, b string) {
+	if a == b || g.HasEdge(a, b) {
+		return
+	}
+	g[a] = insertSorted(g[a], b)
+	g[b] = insertSorted(g[b], a)
+}
+
+// removeEdge unlinks a & b.
+func (g Graph) removeEdge(a, b string) {
+	g[a] = remove(g[a], b)
+	g[b] = remove(g[b], a)
+}
+
+func insertSorted(s []string, v string) []string {
+	i := sort.SearchStrings(s, v)
+	s = append(s, "")
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func remove(s []string, v string) []string {
+	out := s[:0]
+	for _, x := range s {
+		if x != v {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package topology_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/topology"
+)
+
+func nodeIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+// checkSymmetric fails if any edge is one-directional or a self-loop.
+func checkSymmetric(tb testing.TB, g topology.Graph) {
+	tb.Helper()
+	for id, neighbors := range g {
+		for _, neighbor := range neighbors {
+			if neighbor == id {
+				tb.Fatalf("self-loop on %s", id)
+			} else if !g.HasEdge(neighbor, id) {
+				tb.Fatalf("edge %s->%s is not symmetric", id, neighbor)
+			}
+		}
+	}
+}
+
+func TestFromMaelstrom(t *testing.T) {
+	g := topology.FromMaelstrom(map[string][]string{
+		"n0": {"n1"},
+		"n1": {},
+		"n2": {"n1", "n1"},
+	})
+	checkSymmetric(t, g)
+	if got, want := g.Neighbors("n1"), []string{"n0", "n2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("neighbors=%v, want %v", got, want)
+	} else if g.Edges() != 2 || g.Diameter() != 2 {
+		t.Fatalf("edges=%d diameter=%d", g.Edges(), g.Diameter())
+	}
+}
+
+func TestGraph_Diameter_Disconnected(t *testing.T) {
+	g := topology.FromMaelstrom(map[string][]string{"n0": {"n1"}, "n2": nil})
+	if g.Connected() {
+		t.Fatal("expected disconnected graph")
+	} else if d := g.Diameter(); d != -1 {
+		t.Fatalf("diameter=%d, want -1", d)
+	}
+}
+
+func TestTree(t *testing.T) {
+	nodes := nodeIDs(25)
+	for _, tt := range []struct {
+		k, diameter, fanOut int
+	}{
+		{1, 24, 2},
+		{2, 8, 3},
+		{4, 5, 5},
+		{24, 2, 24},
+	} {
+		g := topology.Tree(nodes, tt.k)
+		checkSymmetric(t, g)
+		if g.Edges() != len(nodes)-1 {
+			t.Fatalf("k=%d: edges=%d, want %d", tt.k, g.Edges(), len(nodes)-1)
+		} else if d := g.Diameter(); d != tt.diameter {
+			t.Fatalf("k=%d: diameter=%d, want %d", tt.k, d, tt.diameter)
+		} else if f := g.MaxFanOut(); f != tt.fanOut {
+			t.Fatalf("k=%d: fan-out=%d, want %d", tt.k, f, tt.fanOut)
+		}
+	}
+}
+
+func TestSpanningTree(t *testing.T) {
+	grid := make(map[string][]string)
+	nodes := nodeIDs(25)
+	for i, id := range nodes {
+		if i%5 != 4 {
+			grid[id] = append(grid[id], nodes[i+1])
+		}
+		if i+5 < len(nodes) {
+			grid[id] = append(grid[id], nodes[i+5])
+		}
+	}
+
+	g := topology.SpanningTree(topology.FromMaelstrom(grid), "n0")
+	checkSymmetric(t, g)
+	if !g.Connected() || g.Edges() != len(nodes)-1 {
+		t.Fatalf("not a spanning tree: edges=%d connected=%v", g.Edges(), g.Connected())
+	} else if d := g.Diameter(); d != 12 {
+		t.Fatalf("diameter=%d, want 12", d)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	g := topology.Grid(nodeIDs(25))
+	checkSymmetric(t, g)
+	if g.Edges() != 40 || g.Diameter() != 8 || g.MaxFanOut() != 4 {
+		t.Fatalf("edges=%d diameter=%d fan-out=%d, want 40, 8, 4", g.Edges(), g.Diameter(), g.MaxFanOut())
+	} else if got, want := g.Neighbors("n6"), []string{"n1", "n11", "n5", "n7"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("neighbors=%v, want %v", got, want)
+	}
+
+	// Incomplete last row.
+	if g := topology.Grid(nodeIDs(7)); !g.Connected() || g.Edges() != 8 {
+		t.Fatalf("edges=%d connected=%v", g.Edges(), g.Connected())
+	}
+}
+
+func TestStar(t *testing.T) {
+	nodes := nodeIDs(25)
+	g := topology.Star(nodes, 3)
+	checkSymmetric(t, g)
+	if d := g.Diameter(); d != 3 {
+		t.Fatalf("diameter=%d, want 3", d)
+	}
+
+	fanOut := g.FanOut()
+	for i, id := range nodes {
+		if i < 3 && fanOut[id] < 9 {
+			t.Fatalf("hub %s fan-out=%d", id, fanOut[id])
+		} else if i >= 3 && fanOut[id] != 1 {
+			t.Fatalf("leaf %s fan-out=%d, want 1", id, fanOut[id])
+		}
+	}
+
+	if d := topology.Star(nodes, 1).Diameter(); d != 2 {
+		t.Fatalf("single hub diameter=%d, want 2", d)
+	}
+}
+
+func TestRandomRegular(t *testing.T) {
+	nodes := nodeIDs(25)
+	g, err := topology.RandomRegular(nodes, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSymmetric(t, g)
+	if !g.Connected() {
+		t.Fatal("expected connected graph")
+	}
+	for id, f := range g.FanOut() {
+		if f != 4 {
+			t.Fatalf("%s fan-out=%d, want 4", id, f)
+		}
+	}
+
+	// Same seed, same graph.
+	if other, err := topology.RandomRegular(nodes, 4, 1); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(g, other) {
+		t.Fatal("expected deterministic graph")
+	}
+
+	if _, err := topology.RandomRegular(nodes, 3, 1); err == nil {
+		t.Fatal("expected error for odd total degree")
+	}
+}
+
+func TestSmallWorld(t *testing.T) {
+	nodes := nodeIDs(25)
+	g, err := topology.SmallWorld(nodes, 4, 0.2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSymmetric(t, g)
+	if !g.Connected() {
+		t.Fatal("expected connected graph")
+	} else if g.Edges() != 50 {
+		t.Fatalf("edges=%d, want 50", g.Edges())
+	}
+
+	// Without rewiring the graph is a ring lattice.
+	if lattice, err := topology.SmallWorld(nodes, 4, 0, 1); err != nil {
+		t.Fatal(err)
+	} else if d := lattice.Diameter(); d != 6 {
+		t.Fatalf("lattice diameter=%d, want 6", d)
+	} else if d := g.Diameter(); d > 6 {
+		t.Fatalf("rewired diameter=%d, want <= 6", d)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	nodes := nodeIDs(10)
+	supplied := topology.FromMaelstrom(map[string][]string{"n0": {"n1"}})
+
+	for _, tt := range []struct {
+		spec   string
+		fanOut int
+	}{
+		{"", 1},
+		{"maelstrom", 1},
+		{"grid", 4},
+		{"tree", 3},
+		{"tree:3", 4},
+		{"star", 9},
+		{"star:2", 5},
+		{"random:4", 4},
+		{"small-world:4:0.1", 0},
+	} {
+		g, err := topology.Plan(tt.spec, nodes, supplied)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.spec, err)
+		} else if tt.fanOut != 0 && g.MaxFanOut() != tt.fanOut {
+			t.Fatalf("%q: fan-out=%d, want %d", tt.spec, g.MaxFanOut(), tt.fanOut)
+		}
+
+		// Every node must compute the same overlay.
+		if other, _ := topology.Plan(tt.spec, nodes, supplied); !reflect.DeepEqual(g, other) {
+			t.Fatalf("%q: expected deterministic plan", tt.spec)
+		}
+	}
+
+	for _, spec := range []string{"bogus", "tree:x", "small-world:3"} {
+		if _, err := topology.Plan(spec, nodes, supplied); err == nil {
+			t.Fatalf("%q: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package vclock
+
+import "fmt"
+
+// Codec encodes clocks compactly as positional counter arrays, using a fixed
+// node order such as Node.NodeIDs(), which is the same on every node. This
+// avoids repeating node IDs in every message.
+type Codec struct {
+	nodeIDs []string
+	index   map[string]int
+}
+
+// NewCodec returns a codec for a fixed list of node IDs.
+func NewCodec(nodeIDs []string) *Codec {
+	index := make(map[string]int, len(nodeIDs))
+	for i, id := range nodeIDs {
+		index[id] = i
+	}
+	return &Codec{nodeIDs: nodeIDs, index: index}
+}
+
+// Encode returns the counters in c in node order. Trailing zeros are trimmed.
+// Returns an error if c contains a node unknown to the codec.
+func (c *Codec) Encode(clock Clock) ([]uint64, error) {
+	a := make([]uint64, len(c.nodeIDs))
+	for node, n := range clock {
+		i, ok := c.index[node]
+		if !ok {
+			if n == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("vclock: unknown node %q", node)
+		}
+		a[i] = n
+	}
+
+	for len(a) > 0 && a[len(a)-1] == 0 {
+		a = a[:len(a)-1]
+	}
+	return a, nil
+}
+
+// Decode converts a positional counter array back into a clock. Returns an
+// error if the array is longer than the node list.
+func (c *Codec) Decode(a []uint64) (Clock, error) {
+	if len(a) > len(c.nodeIDs) {
+		return nil, fmt.Errorf("vclock: %d counters for %d nodes", len(a), len(c.nodeIDs))
+	}
+
+	clock := New()
+	for i, n := range a {
+		if n > 0 {
+			clock[c.nodeIDs[i]] = n
+		}
+	}
+	return clock, nil
+}
+
+// EncodeDVV encodes a dotted version vector as [node index, counter,
+// context...].
+func (c *Codec) EncodeDVV(d DVV) ([]uint64, error) {
+	i, ok := c.index[d.Dot.Node]
+	if !ok {
+		return nil, fmt.Errorf("vclock: unknown node %q", d.Dot.Node)
+	}
+
+	ctx, err := c.Encode(d.Context)
+	if err != nil {
+		return nil, err
+	}
+	return append([]uint64{uint64(i), d.Dot.Counter}, ctx...), nil
+}
+
+// DecodeDVV decodes a dotted version vector encoded by EncodeDVV.
+func (c *Codec) DecodeDVV(a []uint64) (DVV, error) {
+	if len(a) < 2 {
+		return DVV{}, fmt.Errorf("vclock: short dotted version vector")
+	} else if a[0] >= uint64(len(c.nodeIDs)) {
+		return DVV{}, fmt.Errorf("vclock: node index %d out of range", a[0])
+	}
+
+	ctx, err := c.Decode(a[2:])
+	if err != nil {
+		return DVV{}, err
+	}
+	return DVV{
+		Dot:     Dot{Node: c.nodeIDs[a[0]], Counter: a[1]},
+		Context: ctx,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package vclock_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/vclock"
+)
+
+func TestCodec_Encode(t *testing.T) {
+	codec := vclock.NewCodec([]string{"n0", "n1", "n2", "n3"})
+
+	a, err := codec.Encode(vclock.Clock{"n0": 1, "n2": 7})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := a, []uint64{1, 0, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("encode=%v, want %v", got, want)
+	}
+
+	c, err := codec.Decode(a)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := c, (vclock.Clock{"n0": 1, "n2": 7}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("decode=%s, want %s", got, want)
+	}
+
+	if _, err := codec.Encode(vclock.Clock{"n9": 1}); err == nil || err.Error() != `vclock: unknown node "n9"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := codec.Decode([]uint64{1, 2, 3, 4, 5}); err == nil || err.Error() != `vclock: 5 counters for 4 nodes` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCodec_EncodeDVV(t *testing.T) {
+	codec := vclock.NewCodec([]string{"n0", "n1"})
+	d := vclock.DVV{
+		Dot:     vclock.Dot{Node: "n1", Counter: 4},
+		Context: vclock.Clock{"n0": 2, "n1": 3},
+	}
+
+	a, err := codec.EncodeDVV(d)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := a, []uint64{1, 4, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("encode=%v, want %v", got, want)
+	}
+
+	other, err := codec.DecodeDVV(a)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other, d) {
+		t.Fatalf("decode=%+v, want %+v", other, d)
+	}
+}
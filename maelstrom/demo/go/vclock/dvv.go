@@ -0,0 +1,118 @@
+package vclock
+
+import "fmt"
+
+// Dot identifies a single event: the Counter-th event at Node.
+type Dot struct {
+	Node    string `json:"node"`
+	Counter uint64 `json:"counter"`
+}
+
+// String returns a string representation of the dot.
+func (d Dot) String() string {
+	return fmt.Sprintf("%s:%d", d.Node, d.Counter)
+}
+
+// DVV represents a dotted version vector: the dot of a single update plus the
+// causal context that the update was made with. Unlike a plain vector clock,
+// it distinguishes concurrent writes made through the same node, so siblings
+// are never lost or falsely ordered.
+type DVV struct {
+	Dot     Dot   `json:"dot"`
+	Context Clock `json:"context,omitempty"`
+}
+
+// NewDVV returns a version for a new update at node. The update has seen
+// everything in ctx and supersedes any of siblings that ctx includes. The dot
+// counter is one more than the highest counter for node in ctx or siblings.
+func NewDVV(node string, ctx Clock, siblings []DVV) DVV {
+	counter := ctx.Get(node)
+	for _, s := range siblings {
+		if s.Dot.Node == node && s.Dot.Counter > counter {
+			counter = s.Dot.Counter
+		}
+		if n := s.Context.Get(node); n > counter {
+			counter = n
+		}
+	}
+
+	return DVV{
+		Dot:     Dot{Node: node, Counter: counter + 1},
+		Context: ctx.Copy(),
+	}
+}
+
+// Contains returns true if dot is part of the causal history of d.
+func (d DVV) Contains(dot Dot) bool {
+	return d.Dot == dot || d.Context.Get(dot.Node) >= dot.Counter
+}
+
+// Compare returns the causal ordering of d relative to other.
+func (d DVV) Compare(other DVV) Ordering {
+	switch {
+	case d.Dot == other.Dot:
+		return Equal
+	case d.Contains(other.Dot):
+		return After
+	case other.Contains(d.Dot):
+		return Before
+	default:
+		return Concurrent
+	}
+}
+
+// Descends returns true if d has seen other.
+func (d DVV) Descends(other DVV) bool {
+	o := d.Compare(other)
+	return o == After || o == Equal
+}
+
+// Dominates returns true if d has seen other and is a different update.
+func (d DVV) Dominates(other DVV) bool { return d.Compare(other) == After }
+
+// Concurrent returns true if neither update has seen the other.
+func (d DVV) Concurrent(other DVV) bool { return d.Compare(other) == Concurrent }
+
+// Clock returns the full causal history of d as a vector clock.
+func (d DVV) Clock() Clock {
+	c := d.Context.Copy()
+	if d.Dot.Counter > c[d.Dot.Node] {
+		c[d.Dot.Node] = d.Dot.Counter
+	}
+	return c
+}
+
+// Sync merges two sets of sibling versions, discarding any version that is
+// dominated by another and removing duplicates. The result preserves the
+// order of first appearance.
+func Sync(a, b []DVV) []DVV {
+	all := append(append([]DVV{}, a...), b...)
+
+	var out []DVV
+	for i, v := range all {
+		keep := true
+		for j, w := range all {
+			if i == j {
+				continue
+			}
+			if w.Dominates(v) || (w.Dot == v.Dot && j < i) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Join returns a clock covering the causal history of every sibling. Clients
+// pass this back as the context of their next write.
+func Join(siblings []DVV) Clock {
+	c := New()
+	for _, s := range siblings {
+		c.Merge(s.Clock())
+	}
+	return c
+}
@@ -0,0 +1,46 @@
+package vclock_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/vclock"
+)
+
+// Ensure concurrent writes through the same node are kept as siblings and a
+// later write that has seen both replaces them.
+func TestDVV_Siblings(t *testing.T) {
+	// Two clients read nothing and write concurrently through n1.
+	a := vclock.NewDVV("n1", vclock.New(), nil)
+	siblings := vclock.Sync(nil, []vclock.DVV{a})
+
+	b := vclock.NewDVV("n1", vclock.New(), siblings)
+	siblings = vclock.Sync(siblings, []vclock.DVV{b})
+
+	if got, want := b.Dot, (vclock.Dot{Node: "n1", Counter: 2}); got != want {
+		t.Fatalf("dot=%s, want %s", got, want)
+	} else if !a.Concurrent(b) {
+		t.Fatalf("expected concurrent, got %s", a.Compare(b))
+	} else if got, want := len(siblings), 2; got != want {
+		t.Fatalf("len=%d, want %d", got, want)
+	}
+
+	// A client reads both siblings and writes through n2.
+	c := vclock.NewDVV("n2", vclock.Join(siblings), siblings)
+	if !c.Dominates(a) || !c.Dominates(b) {
+		t.Fatal("expected c to dominate siblings")
+	}
+	if got, want := vclock.Sync(siblings, []vclock.DVV{c}), []vclock.DVV{c}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sync=%+v, want %+v", got, want)
+	}
+	if got, want := c.Clock(), (vclock.Clock{"n1": 2, "n2": 1}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("clock=%s, want %s", got, want)
+	}
+}
+
+func TestSync_Duplicates(t *testing.T) {
+	a := vclock.NewDVV("n1", vclock.New(), nil)
+	if got, want := vclock.Sync([]vclock.DVV{a}, []vclock.DVV{a}), []vclock.DVV{a}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sync=%+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Package vclock implements vector clocks, version vectors and dotted version
+// vectors for tracking causality between nodes. Entries are keyed by node ID,
+// as returned by Node.NodeIDs(), and missing entries are treated as zero.
+package vclock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ordering represents the causal relationship between two clocks.
+type Ordering int
+
+// Possible orderings returned by Compare.
+const (
+	Equal Ordering = iota
+	Before
+	After
+	Concurrent
+)
+
+// String returns the name of the ordering.
+func (o Ordering) String() string {
+	switch o {
+	case Equal:
+		return "Equal"
+	case Before:
+		return "Before"
+	case After:
+		return "After"
+	case Concurrent:
+		return "Concurrent"
+	default:
+		return fmt.Sprintf("Ordering<%d>", int(o))
+	}
+}
+
+// Clock represents a vector clock: a counter of events observed per node.
+type Clock map[string]uint64
+
+// New returns a new, empty clock.
+func New() Clock {
+	return make(Clock)
+}
+
+// Get returns the counter for a node.
+func (c Clock) Get(node string) uint64 {
+	return c[node]
+}
+
+// Tick increments the counter for a node and returns its new value.
+func (c Clock) Tick(node string) uint64 {
+	c[node]++
+	return c[node]
+}
+
+// Copy returns a copy of the clock.
+func (c Clock) Copy() Clock {
+	other := make(Clock, len(c))
+	for node, n := range c {
+		other[node] = n
+	}
+	return other
+}
+
+// Merge updates c in place to the pointwise maximum of c and other.
+func (c Clock) Merge(other Clock) {
+	for node, n := range other {
+		if n > c[node] {
+			c[node] = n
+		}
+	}
+}
+
+// Compare returns the causal ordering of c relative to other.
+func (c Clock) Compare(other Clock) Ordering {
+	var less, greater bool
+	for node, n := range c {
+		if m := other[node]; n > m {
+			greater = true
+		} else if n < m {
+			less = true
+		}
+	}
+	for node, m := range other {
+		if _, ok := c[node]; !ok && m > 0 {
+			less = true
+		}
+	}
+
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	default:
+		return Equal
+	}
+}
+
+// Descends returns true if c has observed every event in other.
+func (c Clock) Descends(other Clock) bool {
+	o := c.Compare(other)
+	return o == After || o == Equal
+}
+
+// Dominates returns true if c has observed every event in other plus at
+// least one more.
+func (c Clock) Dominates(other Clock) bool {
+	return c.Compare(other) == After
+}
+
+// Concurrent returns true if neither clock has observed all of the other's
+// events.
+func (c Clock) Concurrent(other Clock) bool {
+	return c.Compare(other) == Concurrent
+}
+
+// String returns a deterministic representation of the clock.
+func (c Clock) String() string {
+	nodes := make([]string, 0, len(c))
+	for node := range c {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = fmt.Sprintf("%s:%d", node, c[node])
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
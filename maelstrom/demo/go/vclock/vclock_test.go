@@ -0,0 +1,83 @@
+package vclock_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jepsen-io/maelstrom/demo/go/vclock"
+)
+
+func TestClock_Compare(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		a, b vclock.Clock
+		want vclock.Ordering
+	}{
+		{"Empty", vclock.Clock{}, vclock.Clock{}, vclock.Equal},
+		{"Equal", vclock.Clock{"n1": 1, "n2": 2}, vclock.Clock{"n1": 1, "n2": 2}, vclock.Equal},
+		{"ZeroEntries", vclock.Clock{"n1": 1, "n2": 0}, vclock.Clock{"n1": 1}, vclock.Equal},
+		{"Before", vclock.Clock{"n1": 1}, vclock.Clock{"n1": 1, "n2": 1}, vclock.Before},
+		{"After", vclock.Clock{"n1": 2, "n2": 1}, vclock.Clock{"n1": 1}, vclock.After},
+		{"Concurrent", vclock.Clock{"n1": 2}, vclock.Clock{"n2": 1}, vclock.Concurrent},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Compare(tt.b); got != tt.want {
+				t.Fatalf("compare=%s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClock_Merge(t *testing.T) {
+	a := vclock.Clock{"n1": 3, "n2": 1}
+	b := vclock.Clock{"n2": 4, "n3": 2}
+	a.Merge(b)
+	if got, want := a, (vclock.Clock{"n1": 3, "n2": 4, "n3": 2}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("merge=%s, want %s", got, want)
+	}
+	if !a.Dominates(b) || !a.Descends(a) || a.Dominates(a) {
+		t.Fatal("unexpected dominance")
+	}
+}
+
+func TestClock_Tick(t *testing.T) {
+	c := vclock.New()
+	c.Tick("n1")
+	if got, want := c.Tick("n1"), uint64(2); got != want {
+		t.Fatalf("tick=%d, want %d", got, want)
+	}
+
+	other := c.Copy()
+	other.Tick("n2")
+	if !c.Concurrent(vclock.Clock{"n2": 1}) || !other.Dominates(c) {
+		t.Fatal("unexpected ordering")
+	} else if got, want := other.String(), "{n1:2 n2:1}"; got != want {
+		t.Fatalf("string=%s, want %s", got, want)
+	}
+}
+
+func TestVersionVector_Missing(t *testing.T) {
+	local := vclock.VersionVector{"n1": 5, "n2": 3, "n3": 1}
+	peer := vclock.VersionVector{"n1": 2, "n2": 3, "n3": 4}
+
+	if got, want := local.Missing(peer), []vclock.Range{
+		{Replica: "n1", From: 2, To: 5},
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("missing=%+v, want %+v", got, want)
+	}
+	if got, want := peer.Missing(local), []vclock.Range{
+		{Replica: "n3", From: 1, To: 4},
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("missing=%+v, want %+v", got, want)
+	}
+	if !local.Concurrent(peer) {
+		t.Fatal("expected concurrent")
+	}
+
+	local.Merge(peer)
+	if got := peer.Missing(local); len(got) != 0 {
+		t.Fatalf("unexpected delta after merge: %+v", got)
+	} else if !local.Dominates(peer) {
+		t.Fatal("expected merged vector to dominate")
+	}
+}
@@ -0,0 +1,71 @@
+package vclock
+
+import "sort"
+
+// VersionVector tracks, per replica, how many updates originating at that
+// replica have been applied locally. Unlike a Clock, which orders events, a
+// version vector summarizes replica state so peers can compute which updates
+// the other is missing.
+type VersionVector Clock
+
+// NewVersionVector returns a new, empty version vector.
+func NewVersionVector() VersionVector {
+	return make(VersionVector)
+}
+
+// Get returns the number of updates applied from a replica.
+func (v VersionVector) Get(replica string) uint64 { return Clock(v).Get(replica) }
+
+// Tick records a new local update at a replica and returns its sequence number.
+func (v VersionVector) Tick(replica string) uint64 { return Clock(v).Tick(replica) }
+
+// Copy returns a copy of the version vector.
+func (v VersionVector) Copy() VersionVector { return VersionVector(Clock(v).Copy()) }
+
+// Merge updates v in place to the pointwise maximum of v and other.
+func (v VersionVector) Merge(other VersionVector) { Clock(v).Merge(Clock(other)) }
+
+// Compare returns the ordering of v relative to other.
+func (v VersionVector) Compare(other VersionVector) Ordering {
+	return Clock(v).Compare(Clock(other))
+}
+
+// Descends returns true if v includes every update in other.
+func (v VersionVector) Descends(other VersionVector) bool {
+	return Clock(v).Descends(Clock(other))
+}
+
+// Dominates returns true if v includes every update in other plus at least
+// one more.
+func (v VersionVector) Dominates(other VersionVector) bool {
+	return Clock(v).Dominates(Clock(other))
+}
+
+// Concurrent returns true if each vector includes updates the other lacks.
+func (v VersionVector) Concurrent(other VersionVector) bool {
+	return Clock(v).Concurrent(Clock(other))
+}
+
+// Range represents the updates (From, To] from a single replica.
+type Range struct {
+	Replica string `json:"replica"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+}
+
+// Missing returns the ranges of updates that v has applied but peer has not,
+// sorted by replica. Sending only these ranges lets a replica ship a delta
+// instead of its full state.
+func (v VersionVector) Missing(peer VersionVector) []Range {
+	var ranges []Range
+	for replica, n := range v {
+		if m := peer[replica]; n > m {
+			ranges = append(ranges, Range{Replica: replica, From: m, To: n})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Replica < ranges[j].Replica })
+	return ranges
+}
+
+// String returns a deterministic representation of the version vector.
+func (v VersionVector) String() string { return Clock(v).String() }
@@ -0,0 +1,174 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/topology"
+)
+
+// Broadcast is the broadcast workload: clients broadcast unique integers and
+// read back the set of messages each node has seen. Every acknowledged
+// message must appear in the final read of every node.
+type Broadcast struct {
+	topology string
+	mix      mix
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewBroadcast returns a new broadcast workload. Broadcasts & reads are
+// equally likely by default.
+func NewBroadcast(cfg Config) (*Broadcast, error) {
+	m, err := newMix(map[string]float64{"broadcast": 1, "read": 1}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Broadcast{topology: cfg.Topology, mix: m}, nil
+}
+
+// Setup sends every node its neighbors in the configured topology, a grid
+// by default as in Maelstrom.
+func (w *Broadcast) Setup(ctx context.Context, c Client, nodes []string) error {
+	g, err := topology.Plan(w.topology, nodes, topology.Grid(nodes))
+	if err != nil {
+		return err
+	}
+	body := map[string]any{"type": "topology", "topology": g}
+	for _, node := range nodes {
+		if _, err := c.RPC(ctx, node, body); err != nil {
+			return fmt.Errorf("topology %s: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// Generate returns broadcasts of increasing integers and reads.
+func (w *Broadcast) Generate(rng *rand.Rand) Op {
+	if w.mix.next(rng) == "read" {
+		return Op{F: "read"}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v := w.next
+	w.next++
+	return Op{F: "broadcast", Value: v}
+}
+
+func (w *Broadcast) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	switch op.F {
+	case "broadcast":
+		_, err := c.RPC(ctx, node, map[string]any{"type": "broadcast", "message": op.Value})
+		return op.Value, err
+
+	case "read":
+		msg, err := c.RPC(ctx, node, map[string]any{"type": "read"})
+		if err != nil {
+			return nil, err
+		}
+		var body struct {
+			Messages []int `json:"messages"`
+		}
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return nil, err
+		}
+		sort.Ints(body.Messages)
+		return body.Messages, nil
+
+	default:
+		return nil, fmt.Errorf("broadcast: unknown op %q", op.F)
+	}
+}
+
+// Final reads from every node.
+func (w *Broadcast) Final() []Op { return []Op{{F: "read"}} }
+
+// Check verifies that no read returned a message that was never broadcast
+// and that the final reads, which begin after every broadcast completed,
+// contain every acknowledged message.
+func (w *Broadcast) Check(ops []history.Op) Result {
+	attempted := make(map[int64]bool)
+	acked := make(map[int64]bool)
+	var lastWrite int64 // time the last broadcast completed
+	var reads []history.Pair
+	for _, p := range history.Pairs(ops) {
+		switch p.Invoke.F {
+		case "broadcast":
+			v, _ := intValue(p.Invoke.Value)
+			attempted[v] = true
+			if p.Complete.Type == history.OK {
+				acked[v] = true
+			}
+			if p.Complete.Time > lastWrite && p.Complete.Time != math.MaxInt64 {
+				lastWrite = p.Complete.Time
+			}
+		case "read":
+			if p.Complete.Type == history.OK {
+				reads = append(reads, p)
+			}
+		}
+	}
+
+	unexpected := make(map[int64]bool)
+	lost := make(map[int64]bool)
+	finalReads := 0
+	for _, p := range reads {
+		seen := make(map[int64]bool)
+		for _, v := range intList(p.Complete.Value) {
+			seen[v] = true
+			if !attempted[v] {
+				unexpected[v] = true
+			}
+		}
+		if p.Invoke.Time < lastWrite {
+			continue
+		}
+		finalReads++
+		for v := range acked {
+			if !seen[v] {
+				lost[v] = true
+			}
+		}
+	}
+
+	res := Result{
+		Valid: true,
+		Details: map[string]any{
+			"attempt-count":      len(attempted),
+			"acknowledged-count": len(acked),
+			"final-read-count":   finalReads,
+			"lost":               sortedInts(lost),
+			"unexpected":         sortedInts(unexpected),
+		},
+	}
+	if finalReads == 0 {
+		res.Valid = false
+		res.Errors = append(res.Errors, "no final reads")
+	}
+	if len(lost) > 0 {
+		res.Valid = false
+		res.Errors = append(res.Errors, fmt.Sprintf("%d acknowledged messages missing from final reads", len(lost)))
+	}
+	if len(unexpected) > 0 {
+		res.Valid = false
+		res.Errors = append(res.Errors, fmt.Sprintf("%d messages read that were never broadcast", len(unexpected)))
+	}
+	return res
+}
+
+func sortedInts(set map[int64]bool) []int64 {
+	a := make([]int64, 0, len(set))
+	for v := range set {
+		a = append(a, v)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	return a
+}
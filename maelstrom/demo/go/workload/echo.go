@@ -0,0 +1,48 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Echo is the echo workload: clients send strings and expect them back.
+type Echo struct{}
+
+// NewEcho returns a new echo workload.
+func NewEcho() *Echo { return &Echo{} }
+
+func (w *Echo) Setup(ctx context.Context, c Client, nodes []string) error { return nil }
+
+func (w *Echo) Generate(rng *rand.Rand) Op {
+	return Op{F: "echo", Value: fmt.Sprintf("Please echo %d", rng.Intn(128))}
+}
+
+// Invoke sends an echo request and completes with the reply body, as
+// Maelstrom does.
+func (w *Echo) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	msg, err := c.RPC(ctx, node, map[string]any{"type": "echo", "echo": op.Value})
+	if err != nil {
+		return nil, err
+	}
+	return decodeBody(msg)
+}
+
+func (w *Echo) Final() []Op { return nil }
+
+// Check verifies that every reply echoed its request.
+func (w *Echo) Check(ops []history.Op) Result {
+	res := Result{Valid: true}
+	for _, p := range history.Pairs(ops) {
+		if p.Complete.Type != history.OK {
+			continue
+		}
+		if got := field(p.Complete.Value, "echo"); got != p.Invoke.Value {
+			res.Valid = false
+			res.Errors = append(res.Errors, fmt.Sprintf("process %d sent %q, got %v", p.Invoke.Process, p.Invoke.Value, got))
+		}
+	}
+	return res
+}
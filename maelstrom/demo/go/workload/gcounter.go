@@ -0,0 +1,120 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// GCounter is the g-counter workload: clients add non-negative deltas to a
+// counter and read it. Once the cluster has recovered, every node must read
+// a value between the sum of acknowledged deltas and the sum of all deltas
+// attempted.
+type GCounter struct {
+	mix mix
+}
+
+// NewGCounter returns a new g-counter workload. Adds & reads are equally
+// likely by default.
+func NewGCounter(cfg Config) (*GCounter, error) {
+	m, err := newMix(map[string]float64{"add": 1, "read": 1}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &GCounter{mix: m}, nil
+}
+
+func (w *GCounter) Setup(ctx context.Context, c Client, nodes []string) error { return nil }
+
+// Generate returns reads and adds of small deltas.
+func (w *GCounter) Generate(rng *rand.Rand) Op {
+	if w.mix.next(rng) == "read" {
+		return Op{F: "read"}
+	}
+	return Op{F: "add", Value: rng.Intn(5)}
+}
+
+func (w *GCounter) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	switch op.F {
+	case "add":
+		_, err := c.RPC(ctx, node, map[string]any{"type": "add", "delta": op.Value})
+		return op.Value, err
+
+	case "read":
+		msg, err := c.RPC(ctx, node, map[string]any{"type": "read"})
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeBody(msg)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := intValue(body["value"])
+		if !ok {
+			return nil, fmt.Errorf("g-counter: invalid value %v", body["value"])
+		}
+		return v, nil
+
+	default:
+		return nil, fmt.Errorf("g-counter: unknown op %q", op.F)
+	}
+}
+
+// Final reads from every node.
+func (w *GCounter) Final() []Op { return []Op{{F: "read"}} }
+
+// Check verifies that final reads, which begin after every add completed,
+// fall between the acknowledged & attempted totals. Failed adds are known
+// not to have happened, so they count towards neither.
+func (w *GCounter) Check(ops []history.Op) Result {
+	var lower, upper int64
+	var lastWrite int64
+	var reads []history.Pair
+	for _, p := range history.Pairs(ops) {
+		switch p.Invoke.F {
+		case "add":
+			delta, _ := intValue(p.Invoke.Value)
+			switch p.Complete.Type {
+			case history.OK:
+				lower += delta
+				upper += delta
+			case history.Info:
+				upper += delta
+			}
+			if p.Complete.Time > lastWrite && p.Complete.Time != math.MaxInt64 {
+				lastWrite = p.Complete.Time
+			}
+		case "read":
+			if p.Complete.Type == history.OK {
+				reads = append(reads, p)
+			}
+		}
+	}
+
+	res := Result{Valid: true}
+	var finalReads []int64
+	for _, p := range reads {
+		if p.Invoke.Time < lastWrite {
+			continue
+		}
+		v, _ := intValue(p.Complete.Value)
+		finalReads = append(finalReads, v)
+		if v < lower || v > upper {
+			res.Valid = false
+			res.Errors = append(res.Errors, fmt.Sprintf("process %d read %d, expected %d to %d", p.Invoke.Process, v, lower, upper))
+		}
+	}
+	if len(finalReads) == 0 {
+		res.Valid = false
+		res.Errors = append(res.Errors, "no final reads")
+	}
+	res.Details = map[string]any{
+		"lower-bound": lower,
+		"upper-bound": upper,
+		"final-reads": finalReads,
+	}
+	return res
+}
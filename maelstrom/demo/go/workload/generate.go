@@ -0,0 +1,96 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Key distributions.
+const (
+	UniformKeys     = "uniform"
+	ExponentialKeys = "exponential"
+)
+
+// keyGen picks keys from 0 to count-1.
+type keyGen struct {
+	count int
+	dist  string
+}
+
+func newKeyGen(cfg Config) (keyGen, error) {
+	g := keyGen{count: cfg.KeyCount, dist: cfg.KeyDist}
+	if g.count < 1 {
+		return g, fmt.Errorf("invalid key count %d", g.count)
+	}
+	switch g.dist {
+	case "":
+		g.dist = UniformKeys
+	case UniformKeys, ExponentialKeys:
+	default:
+		return g, fmt.Errorf("unknown key distribution %q", g.dist)
+	}
+	return g, nil
+}
+
+// next returns a key. Exponentially distributed keys favor low keys, so a
+// few keys see most of the contention, as in Elle's generators.
+func (g keyGen) next(rng *rand.Rand) int {
+	if g.dist == ExponentialKeys {
+		for {
+			if k := int(rng.ExpFloat64() * float64(g.count) / 4); k < g.count {
+				return k
+			}
+		}
+	}
+	return rng.Intn(g.count)
+}
+
+// mix picks functions with given relative weights.
+type mix struct {
+	fs      []string
+	weights []float64
+	total   float64
+}
+
+// newMix returns a mix of the default weights, with any weights given in
+// cfg.Mix overriding them. Functions not in the defaults are rejected.
+func newMix(defaults map[string]float64, cfg Config) (mix, error) {
+	weights := make(map[string]float64, len(defaults))
+	for f, w := range defaults {
+		weights[f] = w
+	}
+	for f, w := range cfg.Mix {
+		if _, ok := defaults[f]; !ok {
+			return mix{}, fmt.Errorf("unknown operation %q in mix", f)
+		} else if w < 0 {
+			return mix{}, fmt.Errorf("negative weight for %q in mix", f)
+		}
+		weights[f] = w
+	}
+
+	var m mix
+	for f := range weights {
+		m.fs = append(m.fs, f)
+	}
+	sort.Strings(m.fs) // deterministic for a given seed
+	for _, f := range m.fs {
+		m.weights = append(m.weights, weights[f])
+		m.total += weights[f]
+	}
+	if m.total <= 0 {
+		return mix{}, fmt.Errorf("mix has no operations")
+	}
+	return m, nil
+}
+
+func (m mix) next(rng *rand.Rand) string {
+	x := rng.Float64() * m.total
+	for i, w := range m.weights {
+		if x < w {
+			return m.fs[i]
+		}
+		x -= w
+	}
+	return m.fs[len(m.fs)-1]
+}
@@ -0,0 +1,464 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Kafka is the kafka workload: clients send messages to logs identified by
+// string keys, poll them from the offsets they have reached, commit the
+// offsets they have processed and reassign themselves to other keys.
+//
+// As in Maelstrom, sends & polls are recorded as single micro-operations,
+// e.g. [[:send "3" [12 7]]] for message 7 assigned offset 12 of key "3", and
+// [[:poll {"3" [[12 7]]}]].
+type Kafka struct {
+	keys keyGen
+	mix  mix
+
+	mu      sync.Mutex
+	next    int
+	clients map[Client]*kafkaClient
+}
+
+// kafkaClient is the consumer state of a client.
+type kafkaClient struct {
+	// Next offset to poll from for each assigned key.
+	positions map[string]int64
+}
+
+// NewKafka returns a new kafka workload. By default most operations are
+// sends & polls.
+func NewKafka(cfg Config) (*Kafka, error) {
+	keys, err := newKeyGen(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMix(map[string]float64{
+		"send":                   1,
+		"poll":                   1,
+		"commit_offsets":         0.2,
+		"list_committed_offsets": 0.1,
+		"assign":                 0.1,
+	}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Kafka{keys: keys, mix: m, clients: make(map[Client]*kafkaClient)}, nil
+}
+
+func (w *Kafka) Setup(ctx context.Context, c Client, nodes []string) error { return nil }
+
+func (w *Kafka) Generate(rng *rand.Rand) Op {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch f := w.mix.next(rng); f {
+	case "send":
+		v := w.next
+		w.next++
+		return Op{F: f, Value: []any{[]any{edn.Keyword("send"), w.key(rng), v}}}
+	case "poll":
+		return Op{F: f, Value: []any{[]any{edn.Keyword("poll")}}}
+	case "list_committed_offsets", "assign":
+		return Op{F: f, Value: w.keySet(rng)}
+	default:
+		return Op{F: f}
+	}
+}
+
+func (w *Kafka) key(rng *rand.Rand) string { return strconv.Itoa(w.keys.next(rng)) }
+
+// keySet returns between one and all keys.
+func (w *Kafka) keySet(rng *rand.Rand) []any {
+	n := 1 + rng.Intn(w.keys.count)
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		seen[w.key(rng)] = true
+	}
+	var keys []any
+	for _, k := range sortedKeys(seen) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// client returns the consumer state of c. New clients are assigned every key
+// from offset 0, so final polls read each log from the start.
+func (w *Kafka) client(c Client) *kafkaClient {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kc := w.clients[c]
+	if kc == nil {
+		kc = &kafkaClient{positions: make(map[string]int64)}
+		for k := 0; k < w.keys.count; k++ {
+			kc.positions[strconv.Itoa(k)] = 0
+		}
+		w.clients[c] = kc
+	}
+	return kc
+}
+
+func (w *Kafka) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	kc := w.client(c)
+
+	switch op.F {
+	case "send":
+		mop := op.Value.([]any)[0].([]any)
+		msg, err := c.RPC(ctx, node, map[string]any{"type": "send", "key": mop[1], "msg": mop[2]})
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeBody(msg)
+		if err != nil {
+			return nil, err
+		}
+		offset, ok := intValue(body["offset"])
+		if !ok {
+			return nil, fmt.Errorf("kafka: invalid offset %v", body["offset"])
+		}
+		return []any{[]any{edn.Keyword("send"), mop[1], []any{offset, mop[2]}}}, nil
+
+	case "poll":
+		msg, err := c.RPC(ctx, node, map[string]any{"type": "poll", "offsets": kc.positions})
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeBody(msg)
+		if err != nil {
+			return nil, err
+		}
+		msgs, _ := body["msgs"].(map[string]any)
+		polled := make(map[any]any, len(msgs))
+		for k, v := range msgs {
+			if _, ok := kc.positions[k]; !ok {
+				continue // not assigned
+			}
+			pairs := offsetPairs(v)
+			polled[k] = pairs
+			if len(pairs) > 0 {
+				last, _ := intValue(pairs[len(pairs)-1].([]any)[0])
+				kc.positions[k] = last + 1
+			}
+		}
+		return []any{[]any{edn.Keyword("poll"), polled}}, nil
+
+	case "commit_offsets":
+		offsets := make(map[string]int64)
+		for k, pos := range kc.positions {
+			if pos > 0 {
+				offsets[k] = pos - 1
+			}
+		}
+		if _, err := c.RPC(ctx, node, map[string]any{"type": "commit_offsets", "offsets": offsets}); err != nil {
+			return nil, err
+		}
+		return stringMap(offsets), nil
+
+	case "list_committed_offsets":
+		offsets, err := w.committed(ctx, c, node, op.Value.([]any))
+		if err != nil {
+			return nil, err
+		}
+		return stringMap(offsets), nil
+
+	case "assign":
+		// Resume each key after its committed offset.
+		keys := op.Value.([]any)
+		offsets, err := w.committed(ctx, c, node, keys)
+		if err != nil {
+			return nil, err
+		}
+		kc.positions = make(map[string]int64, len(keys))
+		for _, k := range keys {
+			k := k.(string)
+			if o, ok := offsets[k]; ok {
+				kc.positions[k] = o + 1
+			} else {
+				kc.positions[k] = 0
+			}
+		}
+		return keys, nil
+
+	default:
+		return nil, fmt.Errorf("kafka: unknown op %q", op.F)
+	}
+}
+
+// committed fetches the committed offsets of keys.
+func (w *Kafka) committed(ctx context.Context, c Client, node string, keys []any) (map[string]int64, error) {
+	msg, err := c.RPC(ctx, node, map[string]any{"type": "list_committed_offsets", "keys": keys})
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := body["offsets"].(map[string]any)
+	offsets := make(map[string]int64, len(raw))
+	for k, v := range raw {
+		if o, ok := intValue(v); ok {
+			offsets[k] = o
+		}
+	}
+	return offsets, nil
+}
+
+// Final polls every key from the start a few times on each node, so that
+// acknowledged messages are observed.
+func (w *Kafka) Final() []Op {
+	ops := make([]Op, 5)
+	for i := range ops {
+		ops[i] = Op{F: "poll", Value: []any{[]any{edn.Keyword("poll")}}}
+	}
+	return ops
+}
+
+// Anomalies detected by Kafka.Check.
+const (
+	// Two messages at the same offset of a key.
+	InconsistentOffsets = "inconsistent-offsets"
+
+	// A message at two offsets of a key.
+	DuplicateMessage = "duplicate"
+
+	// An acknowledged message below an observed offset that no poll saw.
+	LostWrite = "lost-write"
+
+	// Offsets that failed to increase within a poll or between successive
+	// polls of a process.
+	NonmonotonicPoll = "nonmonotonic-poll"
+
+	// Successive polls of a process that skipped a known offset.
+	PollSkip = "poll-skip"
+)
+
+// Check verifies sends & polls agree on the messages at each offset and
+// looks for lost writes, nonmonotonic polls and skips. As Maelstrom doesn't
+// require recency, acknowledged messages never polled at all are only
+// counted as unobserved.
+func (w *Kafka) Check(ops []history.Op) Result {
+	type offset struct {
+		key string
+		off int64
+	}
+	msgAt := make(map[offset]string)      // message at each known offset
+	offsetsOf := make(map[string][]int64) // known offsets of each key
+	observed := make(map[offset]bool)
+	maxPolled := make(map[string]int64)
+	anomalies := make(map[string][]string)
+	anomaly := func(typ, format string, args ...any) {
+		anomalies[typ] = append(anomalies[typ], fmt.Sprintf(format, args...))
+	}
+	know := func(k string, o int64, msg any) {
+		m := fmt.Sprint(msg)
+		at := offset{k, o}
+		if prev, ok := msgAt[at]; !ok {
+			msgAt[at] = m
+			offsetsOf[k] = append(offsetsOf[k], o)
+		} else if prev != m {
+			anomaly(InconsistentOffsets, "key %s offset %d holds %s and %s", k, o, prev, m)
+		}
+	}
+
+	// Polls of each process, in order.
+	type poll struct {
+		index  int
+		msgs   map[string][]int64
+		assign bool
+	}
+	var acked []offset
+	polls := make(map[int][]poll)
+	for _, p := range history.Pairs(ops) {
+		if p.Complete.Type != history.OK {
+			continue
+		}
+		switch p.Invoke.F {
+		case "send":
+			k, v := kafkaMicroOp(p.Complete.Value)
+			pair, _ := v.([]any)
+			if len(pair) != 2 {
+				continue
+			}
+			o, _ := intValue(pair[0])
+			know(k, o, pair[1])
+			acked = append(acked, offset{k, o})
+
+		case "poll":
+			_, v := kafkaMicroOp(p.Complete.Value)
+			pl := poll{index: p.Invoke.Index, msgs: make(map[string][]int64)}
+			for k, pairs := range keyedValues(v) {
+				for _, pair := range pairs {
+					pair, _ := pair.([]any)
+					if len(pair) != 2 {
+						continue
+					}
+					o, _ := intValue(pair[0])
+					know(k, o, pair[1])
+					observed[offset{k, o}] = true
+					if o > maxPolled[k] {
+						maxPolled[k] = o
+					}
+					pl.msgs[k] = append(pl.msgs[k], o)
+				}
+			}
+			polls[p.Invoke.Process] = append(polls[p.Invoke.Process], pl)
+
+		case "assign":
+			polls[p.Invoke.Process] = append(polls[p.Invoke.Process], poll{assign: true})
+		}
+	}
+
+	// The same message at several offsets of a key.
+	for k, offs := range offsetsOf {
+		sort.Slice(offs, func(i, j int) bool { return offs[i] < offs[j] })
+		seen := make(map[string]int64)
+		for _, o := range offs {
+			m := msgAt[offset{k, o}]
+			if prev, ok := seen[m]; ok {
+				anomaly(DuplicateMessage, "key %s message %s at offsets %d and %d", k, m, prev, o)
+			}
+			seen[m] = o
+		}
+	}
+
+	unobserved := 0
+	for _, at := range acked {
+		if observed[at] {
+			continue
+		} else if at.off < maxPolled[at.key] {
+			anomaly(LostWrite, "key %s offset %d (message %s) was never polled, though offset %d was", at.key, at.off, msgAt[at], maxPolled[at.key])
+		} else {
+			unobserved++
+		}
+	}
+
+	// Offsets each process polled must increase without skipping known
+	// offsets, except across assigns.
+	procs := make([]int, 0, len(polls))
+	for proc := range polls {
+		procs = append(procs, proc)
+	}
+	sort.Ints(procs)
+	for _, proc := range procs {
+		last := make(map[string]int64)
+		for _, pl := range polls[proc] {
+			if pl.assign {
+				last = make(map[string]int64)
+				continue
+			}
+			for _, k := range sortedKeys(pl.msgs) {
+				for _, o := range pl.msgs[k] {
+					prev, ok := last[k]
+					if ok && o <= prev {
+						anomaly(NonmonotonicPoll, "process %d polled key %s offset %d after %d (op %d)", proc, k, o, prev, pl.index)
+					} else if ok {
+						for _, known := range offsetsOf[k] {
+							if known > prev && known < o {
+								anomaly(PollSkip, "process %d polled key %s offset %d after %d, skipping %d (op %d)", proc, k, o, prev, known, pl.index)
+								break
+							}
+						}
+					}
+					last[k] = o
+				}
+			}
+		}
+	}
+
+	res := Result{
+		Valid: len(anomalies) == 0,
+		Details: map[string]any{
+			"acknowledged-count": len(acked),
+			"unobserved-count":   unobserved,
+		},
+	}
+	for _, typ := range sortedKeys(anomalies) {
+		res.Details[typ] = anomalies[typ]
+		res.Errors = append(res.Errors, fmt.Sprintf("%d %s anomalies", len(anomalies[typ]), typ))
+	}
+	return res
+}
+
+// kafkaMicroOp returns the key (if any) and value of the single micro-op of
+// a send or poll, e.g. [[:send "3" [12 7]]].
+func kafkaMicroOp(v any) (key string, value any) {
+	a, _ := v.([]any)
+	if len(a) != 1 {
+		return "", nil
+	}
+	mop, _ := a[0].([]any)
+	switch len(mop) {
+	case 2:
+		return "", mop[1]
+	case 3:
+		key, _ := mop[1].(string)
+		return key, mop[2]
+	}
+	return "", nil
+}
+
+// keyedValues converts a map of string keys to lists, as recorded in-process
+// or read from EDN.
+func keyedValues(v any) map[string][]any {
+	m := make(map[string][]any)
+	switch v := v.(type) {
+	case map[any]any:
+		for k, x := range v {
+			if k, ok := k.(string); ok {
+				m[k], _ = x.([]any)
+			}
+		}
+	case map[string]any:
+		for k, x := range v {
+			m[k], _ = x.([]any)
+		}
+	}
+	return m
+}
+
+// offsetPairs converts a list of [offset message] pairs from a poll reply.
+func offsetPairs(v any) []any {
+	raw, _ := v.([]any)
+	pairs := make([]any, 0, len(raw))
+	for _, p := range raw {
+		pair, _ := p.([]any)
+		if len(pair) != 2 {
+			continue
+		}
+		o, ok := intValue(pair[0])
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, []any{o, pair[1]})
+	}
+	return pairs
+}
+
+// stringMap returns offsets as a map with string keys in EDN, rather than
+// the keyword keys map[string] types are encoded with.
+func stringMap(offsets map[string]int64) map[any]any {
+	m := make(map[any]any, len(offsets))
+	for k, o := range offsets {
+		m[k] = o
+	}
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
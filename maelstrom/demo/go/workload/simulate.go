@@ -0,0 +1,48 @@
+package workload
+
+import (
+	"context"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Simulate is Run for a deterministic simulation: processes are tasks of s
+// and rates, timeouts & the recovery period are measured in its virtual
+// time. It must be called from a task of s, such as the main function passed
+// to Simulation.Run, and newClient must return clients that wait through the
+// simulation, such as those of the sim package.
+func Simulate(ctx context.Context, s *maelstrom.Simulation, w Workload, cfg Config, nodes []string, newClient func(id string) Client, rec *history.Recorder) error {
+	return run(ctx, simRunner{s}, w, cfg, nodes, newClient, rec)
+}
+
+// simRunner runs processes as simulation tasks.
+type simRunner struct{ s *maelstrom.Simulation }
+
+func (r simRunner) now() time.Time { return r.s.Now() }
+
+func (r simRunner) sleep(ctx context.Context, d time.Duration) error {
+	r.s.Clock().Sleep(d)
+	return ctx.Err()
+}
+
+func (r simRunner) group(n int, fn func(i int)) {
+	if n == 0 {
+		return
+	}
+
+	done := r.s.NewWaiter()
+	left := n
+	for i := 0; i < n; i++ {
+		i := i
+		r.s.Go(func() {
+			fn(i)
+			// Tasks run one at a time, so left needs no lock.
+			if left--; left == 0 {
+				done.Wake(nil)
+			}
+		})
+	}
+	done.Wait(0)
+}
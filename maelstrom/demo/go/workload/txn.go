@@ -0,0 +1,117 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/isolation"
+)
+
+// TxnRWRegister is the txn-rw-register workload: clients run transactions of
+// reads & writes over integer keys, and the history is checked for the
+// anomalies the configured consistency model proscribes.
+type TxnRWRegister struct {
+	keys   keyGen
+	mix    mix
+	maxLen int
+	model  string
+
+	mu   sync.Mutex
+	next map[int]int // next value to write to each key
+}
+
+// NewTxnRWRegister returns a new txn-rw-register workload. The mix weighs
+// the micro-operations "r" & "w", which are equally likely by default.
+func NewTxnRWRegister(cfg Config) (*TxnRWRegister, error) {
+	keys, err := newKeyGen(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMix(map[string]float64{"r": 1, "w": 1}, cfg)
+	if err != nil {
+		return nil, err
+	} else if cfg.MaxTxnLen < 1 {
+		return nil, fmt.Errorf("invalid max transaction length %d", cfg.MaxTxnLen)
+	}
+
+	model := cfg.Consistency
+	switch model {
+	case "":
+		model = isolation.StrictSerializable
+	case isolation.ReadUncommitted, isolation.ReadCommitted, isolation.Serializable, isolation.StrictSerializable:
+	default:
+		return nil, fmt.Errorf("unknown consistency model %q", model)
+	}
+	return &TxnRWRegister{keys: keys, mix: m, maxLen: cfg.MaxTxnLen, model: model, next: make(map[int]int)}, nil
+}
+
+func (w *TxnRWRegister) Setup(ctx context.Context, c Client, nodes []string) error { return nil }
+
+// Generate returns a transaction of up to the maximum length. Every value
+// written to a key is unique, as the checker requires.
+func (w *TxnRWRegister) Generate(rng *rand.Rand) Op {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	txn := make([]any, 1+rng.Intn(w.maxLen))
+	for i := range txn {
+		k := w.keys.next(rng)
+		if w.mix.next(rng) == "r" {
+			txn[i] = []any{"r", k, nil}
+			continue
+		}
+		txn[i] = []any{"w", k, w.next[k]}
+		w.next[k]++
+	}
+	return Op{F: "txn", Value: txn}
+}
+
+// Invoke runs the transaction and completes with the transaction returned,
+// which includes the values read.
+func (w *TxnRWRegister) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	msg, err := c.RPC(ctx, node, map[string]any{"type": "txn", "txn": op.Value})
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	txn, ok := body["txn"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("txn-rw-register: invalid txn %v", body["txn"])
+	}
+	return txn, nil
+}
+
+func (w *TxnRWRegister) Final() []Op { return nil }
+
+// Check looks for anomalies with the isolation checker. Realtime edges are
+// only considered for strict serializability.
+func (w *TxnRWRegister) Check(ops []history.Op) Result {
+	res, err := isolation.Check(ops, isolation.Options{Realtime: w.model == isolation.StrictSerializable})
+	if err != nil {
+		return Result{Errors: []string{err.Error()}}
+	}
+
+	anomalies := make([]string, len(res.Anomalies))
+	for i, a := range res.Anomalies {
+		anomalies[i] = a.String()
+	}
+	r := Result{
+		Valid: res.Valid(w.model),
+		Details: map[string]any{
+			"consistency-model": w.model,
+			"txn-count":         res.Txns,
+			"anomaly-types":     res.Types(),
+			"anomalies":         anomalies,
+		},
+	}
+	if !r.Valid {
+		r.Errors = append(r.Errors, fmt.Sprintf("anomalies proscribed by %s: %v", w.model, res.Types()))
+	}
+	return r
+}
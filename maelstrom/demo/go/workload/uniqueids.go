@@ -0,0 +1,82 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// UniqueIDs is the unique-ids workload: clients ask nodes to generate IDs,
+// which must be globally unique.
+type UniqueIDs struct{}
+
+// NewUniqueIDs returns a new unique-ids workload.
+func NewUniqueIDs() *UniqueIDs { return &UniqueIDs{} }
+
+func (w *UniqueIDs) Setup(ctx context.Context, c Client, nodes []string) error { return nil }
+
+func (w *UniqueIDs) Generate(rng *rand.Rand) Op { return Op{F: "generate"} }
+
+// Invoke requests an ID, which completes the operation. IDs may be any JSON
+// value.
+func (w *UniqueIDs) Invoke(ctx context.Context, c Client, node string, op Op) (any, error) {
+	msg, err := c.RPC(ctx, node, map[string]any{"type": "generate"})
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	return body["id"], nil
+}
+
+func (w *UniqueIDs) Final() []Op { return nil }
+
+// Check verifies that no ID was generated twice. IDs are compared by their
+// EDN encoding, so IDs of any type can be compared.
+func (w *UniqueIDs) Check(ops []history.Op) Result {
+	counts := make(map[string]int)
+	attempted, acked := 0, 0
+	for _, p := range history.Pairs(ops) {
+		if p.Invoke.F != "generate" {
+			continue
+		}
+		attempted++
+		if p.Complete.Type != history.OK {
+			continue
+		}
+		acked++
+		id, err := edn.Marshal(p.Complete.Value)
+		if err != nil {
+			id = []byte(fmt.Sprint(p.Complete.Value))
+		}
+		counts[string(id)]++
+	}
+
+	var dups []string
+	for id, n := range counts {
+		if n > 1 {
+			dups = append(dups, id)
+		}
+	}
+	sort.Strings(dups)
+
+	res := Result{
+		Valid: len(dups) == 0,
+		Details: map[string]any{
+			"attempted-count":    attempted,
+			"acknowledged-count": acked,
+			"duplicated-count":   len(dups),
+			"duplicated":         dups,
+		},
+	}
+	if len(dups) > 0 {
+		res.Errors = append(res.Errors, fmt.Sprintf("%d IDs generated more than once", len(dups)))
+	}
+	return res
+}
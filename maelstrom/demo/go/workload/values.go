@@ -0,0 +1,65 @@
+package workload
+
+import (
+	"bytes"
+	"encoding/json"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+)
+
+// decodeBody decodes a reply body, keeping integers exact.
+func decodeBody(msg maelstrom.Message) (map[string]any, error) {
+	var body map[string]any
+	dec := json.NewDecoder(bytes.NewReader(msg.Body))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// field returns a field of a completion value, which is a map with string
+// keys when recorded in-process or keyword keys when read from EDN.
+func field(v any, key string) any {
+	switch m := v.(type) {
+	case map[string]any:
+		return m[key]
+	case map[any]any:
+		return m[edn.Keyword(key)]
+	}
+	return nil
+}
+
+// intValue converts an integer recorded in-process or read from EDN.
+func intValue(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// intList converts a list of integers recorded in-process or read from EDN.
+// Elements that aren't integers are skipped.
+func intList(v any) []int64 {
+	var a []int64
+	switch v := v.(type) {
+	case []int:
+		for _, x := range v {
+			a = append(a, int64(x))
+		}
+	case []any:
+		for _, x := range v {
+			if i, ok := intValue(x); ok {
+				a = append(a, i)
+			}
+		}
+	}
+	return a
+}
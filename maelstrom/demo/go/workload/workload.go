@@ -0,0 +1,338 @@
+// Package workload generates client operations for Maelstrom workloads,
+// performs them against a cluster and checks the resulting history, so nodes
+// can be tested without the Maelstrom binary.
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+)
+
+// Client sends requests to nodes. *simnet.Client implements Client.
+type Client interface {
+	RPC(ctx context.Context, dest string, body any) (maelstrom.Message, error)
+}
+
+// Op is an operation for a client to perform.
+type Op struct {
+	F     string
+	Value any
+}
+
+// Workload generates, performs & checks the operations of a test.
+type Workload interface {
+	// Setup prepares the cluster before any operations, e.g. by sending
+	// the broadcast topology.
+	Setup(ctx context.Context, c Client, nodes []string) error
+
+	// Generate returns the next operation.
+	Generate(rng *rand.Rand) Op
+
+	// Invoke performs op against node and returns the completion value.
+	Invoke(ctx context.Context, c Client, node string, op Op) (any, error)
+
+	// Final returns the operations each node performs once the cluster has
+	// recovered, such as final reads. May return nil.
+	Final() []Op
+
+	// Check checks a history produced by the workload.
+	Check(ops []history.Op) Result
+}
+
+// Result is the outcome of checking a history.
+type Result struct {
+	Valid bool
+
+	// Errors describe why the history is invalid.
+	Errors []string
+
+	// Details are workload-specific figures, such as lost messages, that
+	// are reported along with the result.
+	Details map[string]any
+}
+
+// workloads holds the constructor of each workload by name.
+var workloads = map[string]func(cfg Config) (Workload, error){
+	"broadcast":       func(cfg Config) (Workload, error) { return NewBroadcast(cfg) },
+	"echo":            func(cfg Config) (Workload, error) { return NewEcho(), nil },
+	"g-counter":       func(cfg Config) (Workload, error) { return NewGCounter(cfg) },
+	"kafka":           func(cfg Config) (Workload, error) { return NewKafka(cfg) },
+	"txn-rw-register": func(cfg Config) (Workload, error) { return NewTxnRWRegister(cfg) },
+	"unique-ids":      func(cfg Config) (Workload, error) { return NewUniqueIDs(), nil },
+}
+
+// New returns the workload with the given Maelstrom name, e.g. "broadcast",
+// generating operations as configured by cfg.
+func New(name string, cfg Config) (Workload, error) {
+	fn := workloads[name]
+	if fn == nil {
+		return nil, fmt.Errorf("unknown workload %q", name)
+	}
+	w, err := fn(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return w, nil
+}
+
+// Names returns the names of all workloads, sorted.
+func Names() []string {
+	a := make([]string, 0, len(workloads))
+	for name := range workloads {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// Default configuration values, matching Maelstrom's.
+const (
+	DefaultRate        = 5
+	DefaultConcurrency = 5
+	DefaultTimeLimit   = 10 * time.Second
+	DefaultTimeout     = 5 * time.Second
+	DefaultRecovery    = 10 * time.Second
+	DefaultKeyCount    = 10
+	DefaultMaxTxnLen   = 4
+)
+
+// Config controls how operations are driven.
+type Config struct {
+	// Total operations per second across all clients. Zero is unlimited.
+	Rate float64
+
+	// Number of concurrent client processes.
+	Concurrency int
+
+	// Duration of the main phase of the test.
+	TimeLimit time.Duration
+
+	// Time to wait for each operation before recording it as indefinite.
+	Timeout time.Duration
+
+	// Time to wait between the main phase and the final operations, e.g.
+	// for gossip to settle. Maelstrom waits 10 seconds.
+	Recovery time.Duration
+
+	// Seed for the operation generator.
+	Seed int64
+
+	// Number of keys used by keyed workloads such as kafka, and how they
+	// are picked: UniformKeys or ExponentialKeys.
+	KeyCount int
+	KeyDist  string
+
+	// Relative weights of operations, e.g. {"read": 1, "add": 3}. Functions
+	// not given keep the workload's default weight.
+	Mix map[string]float64
+
+	// Maximum number of micro-operations per transaction, and the
+	// consistency model transactions are checked against, e.g.
+	// isolation.Serializable. The blank model is strict serializability.
+	MaxTxnLen   int
+	Consistency string
+
+	// Broadcast topology, as a topology.Plan spec such as "tree:4". The
+	// blank spec is a grid.
+	Topology string
+}
+
+// NewConfig returns a configuration with Maelstrom's defaults.
+func NewConfig() Config {
+	return Config{
+		Rate:        DefaultRate,
+		Concurrency: DefaultConcurrency,
+		TimeLimit:   DefaultTimeLimit,
+		Timeout:     DefaultTimeout,
+		Recovery:    DefaultRecovery,
+		KeyCount:    DefaultKeyCount,
+		KeyDist:     UniformKeys,
+		MaxTxnLen:   DefaultMaxTxnLen,
+	}
+}
+
+// Run drives w against nodes and records every operation with rec. Each of
+// the concurrent processes talks to one node through its own client, made
+// by newClient; as in Maelstrom, a process whose operation has an unknown
+// outcome is replaced by a new process with a fresh client.
+func Run(ctx context.Context, w Workload, cfg Config, nodes []string, newClient func(id string) Client, rec *history.Recorder) error {
+	return run(ctx, realRunner{}, w, cfg, nodes, newClient, rec)
+}
+
+func run(ctx context.Context, r runner, w Workload, cfg Config, nodes []string, newClient func(id string) Client, rec *history.Recorder) error {
+	if len(nodes) == 0 {
+		return errors.New("workload: no nodes")
+	} else if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+
+	if err := w.Setup(ctx, newClient("c0"), nodes); err != nil {
+		return fmt.Errorf("workload setup: %w", err)
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	var rngMu sync.Mutex
+	generate := func() Op {
+		rngMu.Lock()
+		defer rngMu.Unlock()
+		return w.Generate(rng)
+	}
+
+	// Operations are spaced evenly across all processes.
+	var interval time.Duration
+	if cfg.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / cfg.Rate)
+	}
+	start := r.now()
+	deadline := start.Add(cfg.TimeLimit)
+	var nextMu sync.Mutex
+	next := start
+
+	procs := make([]*process, cfg.Concurrency)
+	for i := range procs {
+		procs[i] = &process{id: i, concurrency: cfg.Concurrency, newClient: newClient}
+		procs[i].reset()
+	}
+	r.group(len(procs), func(i int) {
+		p := procs[i]
+		for {
+			nextMu.Lock()
+			at := next
+			next = next.Add(interval)
+			nextMu.Unlock()
+
+			if !at.Before(deadline) {
+				return
+			} else if err := r.sleep(ctx, at.Sub(r.now())); err != nil {
+				return
+			}
+
+			p.invoke(ctx, w, cfg.Timeout, nodes, generate(), rec)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	final := w.Final()
+	if len(final) == 0 {
+		return nil
+	}
+
+	if err := r.sleep(ctx, cfg.Recovery); err != nil {
+		return err
+	}
+
+	// Each node performs the final operations through a new process of its
+	// own.
+	base := 0
+	for _, p := range procs {
+		if p.id >= base {
+			base = p.id + 1
+		}
+	}
+	base += len(nodes) - base%len(nodes) // so each process talks to node i
+	r.group(len(nodes), func(i int) {
+		p := &process{id: base + i, concurrency: len(nodes), newClient: newClient}
+		p.reset()
+		for _, op := range final {
+			p.invoke(ctx, w, cfg.Timeout, nodes, op, rec)
+		}
+	})
+	return ctx.Err()
+}
+
+// runner runs the processes of a test: in real time for Run or in the
+// virtual time of a simulation for Simulate.
+type runner interface {
+	// now returns the current time.
+	now() time.Time
+
+	// sleep waits for d or until ctx is done, returning ctx's error.
+	sleep(ctx context.Context, d time.Duration) error
+
+	// group calls fn(0) through fn(n-1) concurrently and waits for them.
+	group(n int, fn func(i int))
+}
+
+// realRunner runs processes as goroutines.
+type realRunner struct{}
+
+func (realRunner) now() time.Time { return time.Now() }
+
+func (realRunner) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func (realRunner) group(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// process is a single-threaded client of one node.
+type process struct {
+	id          int
+	concurrency int
+	newClient   func(id string) Client
+	client      Client
+}
+
+// reset starts the process with a fresh client.
+func (p *process) reset() {
+	p.client = p.newClient(fmt.Sprintf("c%d", p.id+1))
+}
+
+// invoke performs op and records its invocation & completion. Operations
+// with unknown outcomes retire the process.
+func (p *process) invoke(ctx context.Context, w Workload, timeout time.Duration, nodes []string, op Op, rec *history.Recorder) {
+	node := nodes[p.id%len(nodes)]
+	invoke := rec.Invoke(p.id, op.F, op.Value)
+
+	opCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	value, err := w.Invoke(opCtx, p.client, node, op)
+	var rpcErr *maelstrom.RPCError
+	switch {
+	case err == nil:
+		rec.Complete(invoke, history.OK, value)
+		return
+	case errors.As(err, &rpcErr) && maelstrom.IsDefinite(rpcErr.Code):
+		rec.Fail(invoke, history.Fail, []any{rpcErr.Code, rpcErr.Text})
+		return
+	case errors.As(err, &rpcErr):
+		rec.Fail(invoke, history.Info, []any{rpcErr.Code, rpcErr.Text})
+	case errors.Is(err, context.DeadlineExceeded):
+		rec.Fail(invoke, history.Info, []any{maelstrom.Timeout, "timed out"})
+	default:
+		rec.Fail(invoke, history.Info, err.Error())
+	}
+
+	p.id += p.concurrency
+	p.reset()
+}
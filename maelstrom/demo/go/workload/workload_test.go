@@ -0,0 +1,395 @@
+package workload_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/edn"
+	"github.com/jepsen-io/maelstrom/demo/go/history"
+	"github.com/jepsen-io/maelstrom/demo/go/simnet"
+	"github.com/jepsen-io/maelstrom/demo/go/workload"
+)
+
+var nodes = []string{"n0", "n1", "n2"}
+
+func newWorkload(t *testing.T, name string) workload.Workload {
+	t.Helper()
+	w, err := workload.New(name, workload.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// reply replies to msg with its own body, with the given type and fields.
+func reply(n *maelstrom.Node, msg maelstrom.Message, typ string, fields map[string]any) error {
+	var body map[string]any
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+	for k, v := range fields {
+		body[k] = v
+	}
+	body["type"] = typ
+	return n.Reply(msg, body)
+}
+
+// run drives the named workload against in-process nodes set up by fn.
+func run(t *testing.T, name string, fn func(n *maelstrom.Node)) ([]history.Op, workload.Result) {
+	t.Helper()
+	cfg := workload.NewConfig()
+	cfg.Rate, cfg.TimeLimit, cfg.Timeout = 200, 300*time.Millisecond, time.Second
+	cfg.Recovery = 50 * time.Millisecond
+	w, err := workload.New(name, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nw := simnet.New()
+	for _, id := range nodes {
+		fn(nw.NewNode(id))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := nw.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	rec := history.NewRecorder(nil)
+	newClient := func(id string) workload.Client { return nw.NewClient(id) }
+	if err := workload.Run(ctx, w, cfg, nodes, newClient, rec); err != nil {
+		t.Fatal(err)
+	}
+	ops := rec.Ops()
+	return ops, w.Check(ops)
+}
+
+func TestEcho(t *testing.T) {
+	ops, res := run(t, "echo", func(n *maelstrom.Node) {
+		n.Handle("echo", func(msg maelstrom.Message) error {
+			return reply(n, msg, "echo_ok", nil)
+		})
+	})
+	if !res.Valid {
+		t.Fatalf("invalid: %v", res.Errors)
+	} else if len(ops) < 20 {
+		t.Fatalf("only %d ops", len(ops))
+	}
+}
+
+// broadcastNode handles broadcasts, gossiping them to every peer if gossip
+// is true.
+func broadcastNode(n *maelstrom.Node, gossip bool) {
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+
+	store := func(msg maelstrom.Message) (int, bool) {
+		var body struct {
+			Message int `json:"message"`
+		}
+		json.Unmarshal(msg.Body, &body)
+		mu.Lock()
+		defer mu.Unlock()
+		isNew := !seen[body.Message]
+		seen[body.Message] = true
+		return body.Message, isNew
+	}
+
+	n.Handle("topology", func(msg maelstrom.Message) error {
+		return n.Reply(msg, map[string]any{"type": "topology_ok"})
+	})
+	n.Handle("gossip", func(msg maelstrom.Message) error {
+		store(msg)
+		return nil
+	})
+	n.Handle("broadcast", func(msg maelstrom.Message) error {
+		v, isNew := store(msg)
+		if gossip && isNew {
+			for _, peer := range n.NodeIDs() {
+				if peer != n.ID() {
+					n.Send(peer, map[string]any{"type": "gossip", "message": v})
+				}
+			}
+		}
+		return n.Reply(msg, map[string]any{"type": "broadcast_ok"})
+	})
+	n.Handle("read", func(msg maelstrom.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		messages := []int{}
+		for v := range seen {
+			messages = append(messages, v)
+		}
+		return n.Reply(msg, map[string]any{"type": "read_ok", "messages": messages})
+	})
+}
+
+func TestBroadcast(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		_, res := run(t, "broadcast", func(n *maelstrom.Node) { broadcastNode(n, true) })
+		if !res.Valid {
+			t.Fatalf("invalid: %v", res.Errors)
+		} else if got := res.Details["final-read-count"].(int); got < len(nodes) {
+			t.Fatalf("final reads=%d, want at least %d", got, len(nodes))
+		}
+	})
+
+	t.Run("Lost", func(t *testing.T) {
+		_, res := run(t, "broadcast", func(n *maelstrom.Node) { broadcastNode(n, false) })
+		if res.Valid {
+			t.Fatal("expected invalid")
+		} else if lost := res.Details["lost"].([]int64); len(lost) == 0 {
+			t.Fatal("expected lost messages")
+		}
+	})
+}
+
+// Ensure broadcast reads that return messages never broadcast are caught.
+func TestBroadcast_Check(t *testing.T) {
+	rec := history.NewRecorder(nil)
+	b := rec.Invoke(0, "broadcast", 1)
+	rec.Complete(b, history.OK, 1)
+	r := rec.Invoke(1, "read", nil)
+	rec.Complete(r, history.OK, []int{1, 2})
+
+	res := newWorkload(t, "broadcast").Check(rec.Ops())
+	if res.Valid {
+		t.Fatal("expected invalid")
+	} else if got, want := res.Details["unexpected"], []int64{2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected=%v, want %v", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	cfg := workload.NewConfig()
+	cfg.Mix = map[string]float64{"write": 1}
+	if _, err := workload.New("g-counter", cfg); err == nil || err.Error() != `g-counter: unknown operation "write" in mix` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg = workload.NewConfig()
+	cfg.KeyDist = "zipf"
+	if _, err := workload.New("kafka", cfg); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	var mu sync.Mutex
+	next := 0
+	_, res := run(t, "unique-ids", func(n *maelstrom.Node) {
+		n.Handle("generate", func(msg maelstrom.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			next++
+			return reply(n, msg, "generate_ok", map[string]any{"id": []any{n.ID(), next}})
+		})
+	})
+	if !res.Valid {
+		t.Fatalf("invalid: %v", res.Errors)
+	}
+
+	// IDs of any type are compared.
+	rec := history.NewRecorder(nil)
+	for _, id := range []any{"a", map[string]any{"x": 1}, "b", map[string]any{"x": 1}} {
+		rec.Complete(rec.Invoke(0, "generate", nil), history.OK, id)
+	}
+	if res := newWorkload(t, "unique-ids").Check(rec.Ops()); res.Valid {
+		t.Fatal("expected invalid")
+	} else if got, want := res.Details["duplicated"], []string{"{:x 1}"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("duplicated=%v, want %v", got, want)
+	}
+}
+
+func TestGCounter(t *testing.T) {
+	var mu sync.Mutex
+	sum := 0
+	_, res := run(t, "g-counter", func(n *maelstrom.Node) {
+		n.Handle("add", func(msg maelstrom.Message) error {
+			var body struct {
+				Delta int `json:"delta"`
+			}
+			json.Unmarshal(msg.Body, &body)
+			mu.Lock()
+			sum += body.Delta
+			mu.Unlock()
+			return n.Reply(msg, map[string]any{"type": "add_ok"})
+		})
+		n.Handle("read", func(msg maelstrom.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return n.Reply(msg, map[string]any{"type": "read_ok", "value": sum})
+		})
+	})
+	if !res.Valid {
+		t.Fatalf("invalid: %v", res.Errors)
+	}
+
+	// Indefinite adds may or may not have happened; failed ones didn't.
+	rec := history.NewRecorder(nil)
+	rec.Complete(rec.Invoke(0, "add", 2), history.OK, 2)
+	rec.Fail(rec.Invoke(1, "add", 3), history.Info, nil)
+	rec.Fail(rec.Invoke(2, "add", 4), history.Fail, nil)
+	rec.Complete(rec.Invoke(3, "read", nil), history.OK, int64(5))
+	rec.Complete(rec.Invoke(4, "read", nil), history.OK, int64(9))
+	res = newWorkload(t, "g-counter").Check(rec.Ops())
+	if res.Valid {
+		t.Fatal("expected invalid")
+	} else if got, want := res.Errors, []string{"process 4 read 9, expected 2 to 5"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("errors=%v, want %v", got, want)
+	}
+}
+
+func TestKafka(t *testing.T) {
+	var mu sync.Mutex
+	logs := make(map[string][]int)
+	committed := make(map[string]int)
+	_, res := run(t, "kafka", func(n *maelstrom.Node) {
+		n.Handle("send", func(msg maelstrom.Message) error {
+			var body struct {
+				Key string `json:"key"`
+				Msg int    `json:"msg"`
+			}
+			json.Unmarshal(msg.Body, &body)
+			mu.Lock()
+			defer mu.Unlock()
+			logs[body.Key] = append(logs[body.Key], body.Msg)
+			return n.Reply(msg, map[string]any{"type": "send_ok", "offset": len(logs[body.Key]) - 1})
+		})
+		n.Handle("poll", func(msg maelstrom.Message) error {
+			var body struct {
+				Offsets map[string]int `json:"offsets"`
+			}
+			json.Unmarshal(msg.Body, &body)
+			mu.Lock()
+			defer mu.Unlock()
+			msgs := make(map[string][][2]int)
+			for k, o := range body.Offsets {
+				for ; o < len(logs[k]) && len(msgs[k]) < 3; o++ {
+					msgs[k] = append(msgs[k], [2]int{o, logs[k][o]})
+				}
+			}
+			return n.Reply(msg, map[string]any{"type": "poll_ok", "msgs": msgs})
+		})
+		n.Handle("commit_offsets", func(msg maelstrom.Message) error {
+			var body struct {
+				Offsets map[string]int `json:"offsets"`
+			}
+			json.Unmarshal(msg.Body, &body)
+			mu.Lock()
+			defer mu.Unlock()
+			for k, o := range body.Offsets {
+				if o > committed[k] {
+					committed[k] = o
+				}
+			}
+			return n.Reply(msg, map[string]any{"type": "commit_offsets_ok"})
+		})
+		n.Handle("list_committed_offsets", func(msg maelstrom.Message) error {
+			var body struct {
+				Keys []string `json:"keys"`
+			}
+			json.Unmarshal(msg.Body, &body)
+			mu.Lock()
+			defer mu.Unlock()
+			offsets := make(map[string]int)
+			for _, k := range body.Keys {
+				if o, ok := committed[k]; ok {
+					offsets[k] = o
+				}
+			}
+			return n.Reply(msg, map[string]any{"type": "list_committed_offsets_ok", "offsets": offsets})
+		})
+	})
+	if !res.Valid {
+		t.Fatalf("invalid: %v %v", res.Errors, res.Details)
+	} else if res.Details["acknowledged-count"].(int) == 0 {
+		t.Fatal("no messages sent")
+	}
+}
+
+func TestKafka_Check(t *testing.T) {
+	send := func(k string, o, msg int) []any { return []any{[]any{edn.Keyword("send"), k, []any{o, msg}}} }
+	poll := func(k string, pairs ...[]any) []any {
+		return []any{[]any{edn.Keyword("poll"), map[any]any{k: append([]any{}, pairsOf(pairs)...)}}}
+	}
+
+	rec := history.NewRecorder(nil)
+	for o := 0; o < 4; o++ {
+		rec.Complete(rec.Invoke(0, "send", nil), history.OK, send("a", o, 10+o))
+	}
+	rec.Complete(rec.Invoke(1, "poll", nil), history.OK, poll("a", []any{0, 10}))
+	rec.Complete(rec.Invoke(1, "poll", nil), history.OK, poll("a", []any{2, 12}))
+	rec.Complete(rec.Invoke(2, "poll", nil), history.OK, poll("a", []any{3, 99}))
+
+	res := newWorkload(t, "kafka").Check(rec.Ops())
+	if res.Valid {
+		t.Fatal("expected invalid")
+	}
+	for typ, want := range map[string]int{
+		workload.LostWrite:           1, // offset 1
+		workload.PollSkip:            1, // process 1 skipped offset 1
+		workload.InconsistentOffsets: 1, // offset 3
+	} {
+		if got, _ := res.Details[typ].([]string); len(got) != want {
+			t.Fatalf("%s=%v, want %d", typ, got, want)
+		}
+	}
+}
+
+func pairsOf(pairs [][]any) []any {
+	a := make([]any, len(pairs))
+	for i, p := range pairs {
+		a[i] = p
+	}
+	return a
+}
+
+func TestTxnRWRegister(t *testing.T) {
+	var mu sync.Mutex
+	state := make(map[string]any)
+	_, res := run(t, "txn-rw-register", func(n *maelstrom.Node) {
+		n.Handle("txn", func(msg maelstrom.Message) error {
+			var body struct {
+				Txn [][]any `json:"txn"`
+			}
+			if err := json.Unmarshal(msg.Body, &body); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, mop := range body.Txn {
+				k := fmt.Sprint(mop[1])
+				if mop[0] == "r" {
+					mop[2] = state[k]
+				} else {
+					state[k] = mop[2]
+				}
+			}
+			return n.Reply(msg, map[string]any{"type": "txn_ok", "txn": body.Txn})
+		})
+	})
+	if !res.Valid {
+		t.Fatalf("invalid: %v", res.Errors)
+	} else if res.Details["txn-count"].(int) == 0 {
+		t.Fatal("no transactions")
+	}
+
+	// A read of a value that was never written is a G1a anomaly.
+	rec := history.NewRecorder(nil)
+	rec.Fail(rec.Invoke(0, "txn", []any{[]any{"w", 1, 1}}), history.Fail, nil)
+	rec.Complete(rec.Invoke(1, "txn", nil), history.OK, []any{[]any{"r", 1, 1}})
+	res = newWorkload(t, "txn-rw-register").Check(rec.Ops())
+	if res.Valid {
+		t.Fatal("expected invalid")
+	} else if got, want := res.Details["anomaly-types"], []string{"G1a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("anomaly types=%v, want %v", got, want)
+	}
+}
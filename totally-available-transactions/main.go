@@ -1,38 +1,65 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"sync"
 	"time"
 
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/jepsen-io/maelstrom/demo/go/antientropy"
+	"github.com/jepsen-io/maelstrom/demo/go/hlc"
 )
 
-// Record stores the value and the time it was written to handle conflicts (LWW)
+// syncInterval is how often each node reconciles with its peers
+const syncInterval = 300 * time.Millisecond
+
+// Record stores the value and the hybrid logical time it was written to
+// handle conflicts (LWW). Writer breaks ties between identical timestamps.
 type Record struct {
-	Val     any   `json:"v"`
-	Version int64 `json:"t"`
+	Val     any           `json:"v"`
+	Version hlc.Timestamp `json:"t"`
+	Writer  string        `json:"n"`
+}
+
+// newerThan reports whether r should replace other under LWW
+func (r Record) newerThan(other Record) bool {
+	if c := r.Version.Compare(other.Version); c != 0 {
+		return c > 0
+	}
+	return r.Writer > other.Writer
+}
+
+// version identifies the write for anti-entropy
+func (r Record) version() string {
+	return r.Version.String() + "/" + r.Writer
 }
 
 type TxnServer struct {
-	n     *maelstrom.Node
-	mu    sync.RWMutex
-	store map[any]Record
+	n  *maelstrom.Node
+	ae *antientropy.AntiEntropy
+	mu sync.RWMutex
+	// Records are keyed by the JSON encoding of the transaction key
+	store map[string]Record
 }
 
 func main() {
 	n := maelstrom.NewNode()
+	n.EnableHLC()
 	s := &TxnServer{
 		n:     n,
-		store: make(map[any]Record),
+		store: make(map[string]Record),
 	}
 
 	n.Handle("txn", s.handleTxn)
-	n.Handle("gossip", s.handleGossip)
 
-	// Start background gossip to ensure eventual consistency
-	go s.gossipLoop()
+	// Reconcile with every peer in the background, transferring only the
+	// records that differ, to ensure eventual consistency
+	s.ae = antientropy.New(n, s)
+	s.ae.Interval = syncInterval
+	s.ae.Peers = s.peers
+	s.ae.Start(context.Background())
 
 	if err := n.Run(); err != nil {
 		log.Fatal(err)
@@ -52,11 +79,16 @@ func (s *TxnServer) handleTxn(msg maelstrom.Message) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now().UnixNano()
+	// HLC versions respect causality even when node clocks are skewed
+	now := s.n.HLC().Now()
 
 	for _, op := range body.Txn {
 		opType := op[0].(string)
-		key := op[1]
+		buf, err := json.Marshal(op[1])
+		if err != nil {
+			return err
+		}
+		key := string(buf)
 
 		switch opType {
 		case "r":
@@ -66,10 +98,13 @@ func (s *TxnServer) handleTxn(msg maelstrom.Message) error {
 				op[2] = nil
 			}
 		case "w":
-			s.store[key] = Record{
+			record := Record{
 				Val:     op[2],
 				Version: now,
+				Writer:  s.n.ID(),
 			}
+			s.store[key] = record
+			s.ae.Update(key, record.version())
 		}
 	}
 
@@ -79,47 +114,53 @@ func (s *TxnServer) handleTxn(msg maelstrom.Message) error {
 	})
 }
 
-func (s *TxnServer) gossipLoop() {
-	ticker := time.NewTicker(300 * time.Millisecond)
-	for range ticker.C {
-		s.mu.RLock()
-		// Create a snapshot of the current store to send
-		snapshot := s.store
-		s.mu.RUnlock()
+// peers returns every other node in the cluster
+func (s *TxnServer) peers() []string {
+	var peers []string
+	for _, id := range s.n.NodeIDs() {
+		if id != s.n.ID() {
+			peers = append(peers, id)
+		}
+	}
+	return peers
+}
+
+// Get returns the encoded records for keys (antientropy.Store)
+func (s *TxnServer) Get(keys []string) map[string]json.RawMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-		if len(snapshot) == 0 {
+	values := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		record, ok := s.store[key]
+		if !ok {
 			continue
 		}
-
-		// Send gossip to all other nodes
-		for _, dest := range s.n.NodeIDs() {
-			if dest == s.n.ID() {
-				continue
-			}
-			s.n.Send(dest, map[string]any{
-				"type":  "gossip",
-				"store": snapshot,
-			})
+		buf, err := json.Marshal(record)
+		if err != nil {
+			log.Printf("failed to marshal record %s: %v", key, err)
+			continue
 		}
+		values[key] = buf
 	}
+	return values
 }
 
-func (s *TxnServer) handleGossip(msg maelstrom.Message) error {
-	var body struct {
-		Store map[string]Record `json:"store"`
-	}
-	if err := json.Unmarshal(msg.Body, &body); err != nil {
-		return err
-	}
-
+// Merge applies records received from a peer (antientropy.Store)
+func (s *TxnServer) Merge(peer string, values map[string]json.RawMessage) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Merge logic: Last-Write-Wins (LWW)
-	for key, incoming := range body.Store {
+	for key, buf := range values {
+		var incoming Record
+		if err := json.Unmarshal(buf, &incoming); err != nil {
+			return err
+		}
 		local, exists := s.store[key]
-		if !exists || incoming.Version > local.Version {
+		if !exists || incoming.newerThan(local) {
 			s.store[key] = incoming
+			s.ae.Update(key, incoming.version())
 		}
 	}
 	return nil
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"sync"
-	"time"
 
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
 )
@@ -51,7 +50,7 @@ func main() {
 			return err
 		}
 
-		id := generateID(state)
+		id := generateID(state, n.Clock())
 
 		body["type"] = "generate_ok"
 		body["id"] = id.Encode()
@@ -63,11 +62,11 @@ func main() {
 	}
 }
 
-func generateID(state *IDState) *ID {
+func generateID(state *IDState, clock maelstrom.Clock) *ID {
 	state.mu.Lock()
 	defer state.mu.Unlock()
 
-	currentEpoch := time.Now().Unix()
+	currentEpoch := clock.Now().Unix()
 
 	if currentEpoch > state.lastEpoch {
 		state.lastEpoch = currentEpoch